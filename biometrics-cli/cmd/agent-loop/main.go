@@ -1,29 +1,36 @@
+// agent-loop runs the orchestrator daemon with the terminal dashboard, the
+// self-healing monitor and a Prometheus endpoint. Task execution, including
+// the former "Sicher?" check, lives in internal/daemon.
 package main
 
 import (
-	"biometrics-cli/internal/cache"
-	"biometrics-cli/internal/chaos"
-	"biometrics-cli/internal/metrics"
-	"biometrics-cli/internal/models"
+	"biometrics-cli/internal/daemon"
 	"biometrics-cli/internal/orchestrator"
 	"biometrics-cli/internal/selfhealing"
 	"biometrics-cli/internal/state"
-	"biometrics-cli/internal/tracker"
-	"encoding/json"
+	"biometrics-cli/internal/workspace"
+	"context"
+	"flag"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func runDoctor() {
+func runDoctor(ws workspace.Workspace) {
 	fmt.Println("=== BIOMETRICS DOCTOR ===")
-	paths := []string{"/Users/jeremy/.sisyphus", "/Users/jeremy/.config/opencode/opencode.json"}
+	fmt.Printf("WORKSPACE: %s\n", ws)
+
+	paths := []string{ws.Root, ws.BoulderPath()}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "opencode", "opencode.json"))
+	}
 	for _, p := range paths {
 		if _, err := os.Stat(p); err == nil {
 			fmt.Printf("OK: %s\n", p)
@@ -33,124 +40,70 @@ func runDoctor() {
 	}
 }
 
-func runSicherCheck(agent string) {
-	prompt := "Sicher? Führe eine vollständige Selbstreflexion durch."
-	_ = exec.Command("opencode", "prompt", prompt, "--agent", agent).Run()
-}
+// mirrorStatus copies the daemon status into the state shown by the dashboard.
+func mirrorStatus(ctx context.Context, d *daemon.Daemon) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		st := d.Status()
+		state.GlobalState.PlanName = fmt.Sprintf("%s, %d active task(s)", st.State, len(st.Active))
+		if len(st.Active) > 0 {
+			a := st.Active[0]
+			state.GlobalState.CurrentAgent = fmt.Sprintf("%s (%s/%s)", a.Agent, a.Project, a.TaskID)
+		} else {
+			state.GlobalState.CurrentAgent = "NONE"
+		}
+		state.GlobalState.SetModelStatus(d.ModelPool().StatusLines())
 
-func getModelForAgent(agent string) string {
-	switch agent {
-	case "sisyphus", "build", "atlas", "deep", "oracle", "ultrabrain", "visual-engineering":
-		return "qwen3.5"
-	case "librarian", "explore", "quick", "metis", "momus":
-		return "minimax"
-	default:
-		return "qwen3.5"
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
-func readBoulder(path string) (*models.Boulder, error) {
-	if state.GlobalState.GetChaos() && rand.Intn(10) < 3 {
-		return nil, fmt.Errorf("corrupted state")
-	}
+func main() {
+	workspaceFlag := flag.String(workspace.FlagName, "", "workspace root (plans, boulder.json, logs)")
+	workers := flag.Int("workers", daemon.DefaultWorkers, "maximum number of tasks running in parallel")
+	flag.Parse()
 
-	data, err := os.ReadFile(path)
+	ws, err := workspace.Resolve(*workspaceFlag)
 	if err != nil {
-		return nil, err
+		fmt.Fprintf(os.Stderr, "failed to resolve workspace: %v\n", err)
+		os.Exit(1)
 	}
-	var b models.Boulder
-	err = json.Unmarshal(data, &b)
-	return &b, err
-}
 
-func verifySerenaProcess() error {
-	if state.GlobalState.GetChaos() && rand.Intn(10) < 5 {
-		return fmt.Errorf("chaos monkey killed connection")
-	}
-	return exec.Command("pgrep", "-f", "serena.*start-mcp-server").Run()
-}
-
-func main() {
-	if len(os.Args) > 1 && os.Args[1] == "doctor" {
-		runDoctor()
+	if flag.Arg(0) == "doctor" {
+		runDoctor(ws)
 		return
 	}
 
-	cache.New(&cache.CacheConfig{
-		DiskPath:        "./cache",
-		TTL:             5 * time.Minute,
-		CleanupInterval: 1 * time.Minute,
-	})
-	modelCache := cache.NewModelCache(2 * time.Minute)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Das Dashboard belegt das Terminal, Logs gehen in die Logdatei und die
+	// Log-Datenbank
+	d, closeAll, err := daemon.Bootstrap(ws, daemon.BootstrapOptions{Workers: *workers})
+	defer closeAll()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start daemon: %v\n", err)
+		closeAll()
+		os.Exit(1)
+	}
 
-	state.GlobalState.InitDB()
 	go orchestrator.DisplayDashboard()
-	go chaos.RunChaosMonkey()
 	go selfhealing.StartHealthMonitor()
-
+	go mirrorStatus(ctx, d)
 	go func() {
 		http.Handle("/metrics", promhttp.Handler())
 		_ = http.ListenAndServe(":59002", nil)
 	}()
 
-	modelTracker := tracker.NewModelTracker()
-	state.GlobalState.Log("INFO", "Started modular orchestrator with cache")
-
-	for {
-		start := time.Now()
-		metrics.CyclesTotal.Inc()
-
-		healer := selfhealing.NewSelfHealer()
-		healer.RunDiagnostics()
-
-		if err := verifySerenaProcess(); err != nil {
-			state.GlobalState.Log("ERROR", "Serena MCP check failed: "+err.Error())
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		b, err := readBoulder("/Users/jeremy/.sisyphus/boulder.json")
-		if err != nil {
-			state.GlobalState.Log("ERROR", "Failed to read boulder: "+err.Error())
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		state.GlobalState.PlanName = b.PlanName
-		state.GlobalState.CurrentAgent = b.Agent
-
-		if b.ActivePlan == "" {
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		model := getModelForAgent(b.Agent)
-
-		cacheKey := fmt.Sprintf("cycle:%d:agent:%s", time.Now().Unix(), b.Agent)
-		if _, found := cache.Get().Get(cacheKey); found {
-			state.GlobalState.Log("INFO", "Skipping cached cycle for "+b.Agent)
-			time.Sleep(30 * time.Second)
-			continue
-		}
-
-		if err := modelTracker.Acquire(model); err != nil {
-			time.Sleep(5 * time.Second)
-			continue
-		}
-
-		state.GlobalState.ActiveModel = model
-		metrics.ModelAcquisitions.WithLabelValues(model).Inc()
-		state.GlobalState.Log("SUCCESS", "Acquired "+model)
-
-		runSicherCheck(b.Agent)
-
-		modelCache.StoreModelResult(model, b.PlanName, "completed")
-		modelTracker.Release(model)
-		state.GlobalState.ActiveModel = "NONE"
-
-		duration := time.Since(start).Seconds()
-		metrics.CycleDuration.Observe(duration)
-
-		time.Sleep(60 * time.Second)
+	state.GlobalState.Log("INFO", "Started orchestrator daemon")
+	if err := d.Run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "daemon failed: %v\n", err)
+		closeAll()
+		os.Exit(1)
 	}
 }
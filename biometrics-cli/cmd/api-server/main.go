@@ -1,19 +1,42 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"biometrics-cli/internal/codegen"
+	"biometrics-cli/internal/collision"
+	"biometrics-cli/internal/daemon"
+	"biometrics-cli/internal/webhook"
+	"biometrics-cli/internal/workspace"
 	"github.com/gorilla/websocket"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 var (
 	generator *codegen.CodeGenerator
+	// modelStatusPath is the model pool snapshot written by the orchestrator
+	modelStatusPath string
+	// controlSocket is the daemon's control API, which also runs the scheduler
+	controlSocket string
+	// webhooksDB is the webhook outbox with its dead letters
+	webhooksDB string
+	// subscriptions are the webhook endpoints the daemon delivers to
+	subscriptions *webhook.Registry
+	// apiToken guards the admin routes, see requireAdmin
+	apiToken  string
 	wsClients = make(map[*websocket.Conn]bool)
 	wsChan    = make(chan string, 100)
 )
@@ -26,6 +49,17 @@ var upgrader = websocket.Upgrader{
 
 func main() {
 	generator = codegen.NewCodeGenerator()
+	if ws, err := workspace.Resolve(""); err == nil {
+		modelStatusPath = ws.ModelStatusPath()
+		controlSocket = ws.ControlSocketPath()
+		webhooksDB = ws.WebhooksDB()
+		if subscriptions, err = webhook.OpenRegistry(ws.WebhookSubscriptionsPath()); err != nil {
+			log.Printf("Webhook subscriptions unavailable: %v", err)
+		}
+		if apiToken, err = loadAPIToken(ws.APITokenPath()); err != nil {
+			log.Printf("Admin routes unavailable: %v", err)
+		}
+	}
 
 	// Start WebSocket broadcaster
 	go broadcastWebSocket()
@@ -43,6 +77,11 @@ func main() {
 	http.HandleFunc("/api/docker/containers", handleDockerContainers)
 	http.HandleFunc("/api/docker/stats", handleDockerStats)
 	http.HandleFunc("/api/scheduler/jobs", handleSchedulerJobs)
+	// Subscriptions carry secrets and dead letters event payloads
+	http.HandleFunc("/api/webhooks", requireAdmin(handleWebhooks))
+	http.HandleFunc("/api/webhooks/", requireAdmin(handleWebhookByID))
+	http.HandleFunc("/api/webhooks/dead-letters", requireAdmin(handleDeadLetters))
+	http.HandleFunc("/api/webhooks/dead-letters/replay", requireAdmin(handleReplayDeadLetters))
 	http.HandleFunc("/api/config", handleConfig)
 	http.HandleFunc("/api/ratelimit/stats", handleRateLimitStats)
 	http.HandleFunc("/ws", handleWebSocket)
@@ -155,6 +194,18 @@ func handleStatus(w http.ResponseWriter, r *http.Request) {
 		"version":      "1.0.0",
 		"orchestrator": "biometrics-cli",
 	}
+	if modelStatusPath != "" {
+		if snapshot, err := collision.ReadSnapshot(modelStatusPath); err == nil {
+			exhausted := make([]string, 0)
+			for _, m := range snapshot.Exhausted() {
+				exhausted = append(exhausted, m.Name)
+			}
+			status["models"] = snapshot.Models
+			status["quotas"] = snapshot.Quotas
+			status["exhausted_models"] = exhausted
+			status["models_updated_at"] = snapshot.UpdatedAt.Format(time.RFC3339)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
@@ -290,12 +341,326 @@ func handleDockerStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleSchedulerJobs lists the registered jobs with a preview of their
+// next runs; ?runs=N sets the preview length (default 5, at most 100).
 func handleSchedulerJobs(w http.ResponseWriter, r *http.Request) {
+	runs := 5
+	if v := r.URL.Query().Get("runs"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 100 {
+			http.Error(w, "runs must be between 0 and 100", http.StatusBadRequest)
+			return
+		}
+		runs = n
+	}
+
+	if controlSocket == "" {
+		http.Error(w, "no workspace, scheduler unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	jobs, err := daemon.NewClient(controlSocket).Jobs(r.Context(), runs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode([]map[string]string{
-		{"id": "health-check", "status": "enabled"},
-		{"id": "cleanup-logs", "status": "enabled"},
-	})
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// subscriptionRequest is the body of create and update requests. Fields
+// left out keep their value on update.
+type subscriptionRequest struct {
+	URL      *string   `json:"url"`
+	Secret   *string   `json:"secret"`
+	Events   *[]string `json:"events"`
+	Projects *[]string `json:"projects"`
+	Enabled  *bool     `json:"enabled"`
+}
+
+func (req *subscriptionRequest) apply(sub *webhook.Subscription) {
+	if req.URL != nil {
+		sub.URL = *req.URL
+	}
+	if req.Secret != nil {
+		sub.Secret = *req.Secret
+	}
+	if req.Events != nil {
+		sub.Events = *req.Events
+	}
+	if req.Projects != nil {
+		sub.Projects = *req.Projects
+	}
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+}
+
+// loadAPIToken returns BIOMETRICS_API_TOKEN or the token stored at path. On
+// first start it generates a random token and stores it there, readable by
+// the owner only.
+func loadAPIToken(path string) (string, error) {
+	if token := os.Getenv("BIOMETRICS_API_TOKEN"); token != "" {
+		return token, nil
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(secret)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(token + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	log.Printf("Generated API token in %s", path)
+	return token, nil
+}
+
+// requireAdmin protects routes that manage the daemon's configuration. A
+// request needs "Authorization: Bearer <token>" with the API token. Requests
+// with a body must be JSON, and browsers must not send them from another
+// origin, so a web page cannot forge them.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if apiToken == "" {
+			http.Error(w, "no API token configured", http.StatusServiceUnavailable)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r.Host) {
+			http.Error(w, "cross-origin request rejected", http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+				http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// sameOrigin reports whether the Origin header names the host the request
+// was sent to.
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, host)
+}
+
+// handleWebhooks lists the webhook subscriptions (GET) or registers one
+// (POST). Secrets are masked in listings; a created subscription is returned
+// once with its secret, which is generated when none is given.
+func handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if subscriptions == nil {
+		http.Error(w, "no workspace, webhook subscriptions unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := subscriptions.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		masked := make([]*webhook.Subscription, 0, len(list))
+		for _, sub := range list {
+			masked = append(masked, sub.Masked())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(masked)
+	case http.MethodPost:
+		var req subscriptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		sub := &webhook.Subscription{Enabled: true}
+		req.apply(sub)
+		created, err := subscriptions.Add(sub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleWebhookByID shows (GET), updates (PUT, PATCH) or removes (DELETE)
+// the subscription /api/webhooks/{id}.
+func handleWebhookByID(w http.ResponseWriter, r *http.Request) {
+	if subscriptions == nil {
+		http.Error(w, "no workspace, webhook subscriptions unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/webhooks/")
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var (
+		sub *webhook.Subscription
+		err error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		sub, err = subscriptions.Get(id)
+	case http.MethodPut, http.MethodPatch:
+		var req subscriptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		sub, err = subscriptions.Update(id, req.apply)
+	case http.MethodDelete:
+		if err = subscriptions.Remove(id); err == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case errors.Is(err, webhook.ErrUnknownSubscription):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sub.Masked())
+	}
+}
+
+// handleDeadLetters lists the webhook dead letters (GET) or purges them
+// (DELETE ?id=ID or ?all=true).
+func handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	store, ok := openOutboxStore(w)
+	if !ok {
+		return
+	}
+	defer store.Close()
+
+	switch r.Method {
+	case http.MethodGet:
+		entries, err := store.DeadLetters()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		pending, err := store.Pending()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if entries == nil {
+			entries = []*webhook.OutboxEntry{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"dead_letters": entries,
+			"pending":      pending,
+		})
+	case http.MethodDelete:
+		id, ok := deadLetterTarget(w, r)
+		if !ok {
+			return
+		}
+		n, err := store.Purge(id)
+		writeDeadLetterResult(w, "purged", n, err)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleReplayDeadLetters moves dead letters back into the outbox
+// (POST ?id=ID or ?all=true).
+func handleReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := deadLetterTarget(w, r)
+	if !ok {
+		return
+	}
+	store, ok := openOutboxStore(w)
+	if !ok {
+		return
+	}
+	defer store.Close()
+
+	n, err := store.Replay(id, time.Now())
+	writeDeadLetterResult(w, "requeued", n, err)
+}
+
+func openOutboxStore(w http.ResponseWriter) (*webhook.SQLiteOutboxStore, bool) {
+	if webhooksDB == "" {
+		http.Error(w, "no workspace, webhook outbox unavailable", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	store, err := webhook.OpenSQLiteOutboxStore(webhooksDB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return store, true
+}
+
+// deadLetterTarget reads ?id= or ?all=true; one of them is required so a
+// bare request never affects every dead letter.
+func deadLetterTarget(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.URL.Query().Get("id")
+	all := r.URL.Query().Get("all") == "true"
+	if (id == "") == !all {
+		http.Error(w, "give either id or all=true", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+func writeDeadLetterResult(w http.ResponseWriter, action string, n int, err error) {
+	if errors.Is(err, webhook.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{action: n})
 }
 
 func handleConfig(w http.ResponseWriter, r *http.Request) {
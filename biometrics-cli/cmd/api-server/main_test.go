@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"biometrics-cli/internal/webhook"
 )
 
 // skipIfNoGenerator skips test if generator is not initialized
@@ -172,3 +176,125 @@ func TestHealthCheck(t *testing.T) {
 		t.Error("Health check should return 200")
 	}
 }
+
+func TestHandleWebhookSubscriptions(t *testing.T) {
+	registry, err := webhook.OpenRegistry(filepath.Join(t.TempDir(), "webhooks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptions = registry
+	defer func() { subscriptions = nil }()
+
+	w := httptest.NewRecorder()
+	handleWebhooks(w, httptest.NewRequest(http.MethodPost, "/api/webhooks",
+		strings.NewReader(`{"url":"https://example.com/hook","events":["task.*"],"projects":["alpha"]}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", w.Code, w.Body.String())
+	}
+	var created webhook.Subscription
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.ID == "" || created.Secret == "" || !created.Enabled {
+		t.Fatalf("created = %+v", created)
+	}
+
+	w = httptest.NewRecorder()
+	handleWebhooks(w, httptest.NewRequest(http.MethodGet, "/api/webhooks", nil))
+	var list []webhook.Subscription
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 1 || list[0].Secret == created.Secret {
+		t.Errorf("list = %+v, want one entry with a masked secret", list)
+	}
+
+	w = httptest.NewRecorder()
+	handleWebhookByID(w, httptest.NewRequest(http.MethodPatch, "/api/webhooks/"+created.ID, strings.NewReader(`{"enabled":false}`)))
+	var updated webhook.Subscription
+	json.NewDecoder(w.Body).Decode(&updated)
+	if w.Code != http.StatusOK || updated.Enabled || updated.URL != created.URL {
+		t.Errorf("update: status %d, %+v", w.Code, updated)
+	}
+
+	w = httptest.NewRecorder()
+	handleWebhookByID(w, httptest.NewRequest(http.MethodPut, "/api/webhooks/"+created.ID, strings.NewReader(`{"url":"not a url"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid update: status %d, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handleWebhookByID(w, httptest.NewRequest(http.MethodDelete, "/api/webhooks/"+created.ID, nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	handleWebhookByID(w, httptest.NewRequest(http.MethodGet, "/api/webhooks/"+created.ID, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("get after delete: status %d, want 404", w.Code)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	handler := requireAdmin(func(w http.ResponseWriter, r *http.Request) {})
+	serve := func(method, auth string, header map[string]string) int {
+		req := httptest.NewRequest(method, "http://localhost:8080/api/webhooks", strings.NewReader(`{}`))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Code
+	}
+	jsonBody := map[string]string{"Content-Type": "application/json"}
+
+	apiToken = ""
+	if code := serve(http.MethodGet, "", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("without configured token: status %d, want 503", code)
+	}
+
+	apiToken = "s3cret"
+	defer func() { apiToken = "" }()
+	for _, tc := range []struct {
+		name   string
+		method string
+		auth   string
+		header map[string]string
+		want   int
+	}{
+		{"missing token", http.MethodGet, "", nil, http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "Bearer wrong", nil, http.StatusUnauthorized},
+		{"valid token", http.MethodGet, "Bearer s3cret", nil, http.StatusOK},
+		{"JSON body", http.MethodPost, "Bearer s3cret", jsonBody, http.StatusOK},
+		{"JSON body with charset", http.MethodPost, "Bearer s3cret", map[string]string{"Content-Type": "application/json; charset=utf-8"}, http.StatusOK},
+		// browsers send text/plain cross-site without a preflight
+		{"text/plain body", http.MethodPost, "Bearer s3cret", map[string]string{"Content-Type": "text/plain"}, http.StatusUnsupportedMediaType},
+		{"missing content type", http.MethodPost, "Bearer s3cret", nil, http.StatusUnsupportedMediaType},
+		{"foreign origin", http.MethodPost, "Bearer s3cret", map[string]string{"Content-Type": "application/json", "Origin": "https://evil.example"}, http.StatusForbidden},
+		{"same origin", http.MethodPost, "Bearer s3cret", map[string]string{"Content-Type": "application/json", "Origin": "http://localhost:8080"}, http.StatusOK},
+	} {
+		if code := serve(tc.method, tc.auth, tc.header); code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, code, tc.want)
+		}
+	}
+}
+
+func TestLoadAPITokenGeneratesOnce(t *testing.T) {
+	t.Setenv("BIOMETRICS_API_TOKEN", "")
+	path := filepath.Join(t.TempDir(), "api-token")
+
+	token, err := loadAPIToken(path)
+	if err != nil || len(token) != 64 {
+		t.Fatalf("loadAPIToken = %q, %v; want a generated token", token, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("token file: %v, %v; want mode 0600", info, err)
+	}
+	if again, err := loadAPIToken(path); err != nil || again != token {
+		t.Errorf("second start = %q, %v; want the stored token", again, err)
+	}
+
+	t.Setenv("BIOMETRICS_API_TOKEN", "from-env")
+	if got, _ := loadAPIToken(path); got != "from-env" {
+		t.Errorf("loadAPIToken = %q, want the environment token", got)
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"biometrics-cli/commands"
+	"biometrics-cli/internal/workspace"
+
+	_ "github.com/mattn/go-sqlite3"
 )
 
 var (
@@ -55,6 +59,18 @@ func main() {
 		runConfig()
 	case "audit":
 		runAudit()
+	case "plan":
+		runPlan()
+	case "prompt":
+		runPrompt()
+	case "daemon":
+		runDaemon()
+	case "jobs":
+		runJobs()
+	case "journal":
+		runJournal()
+	case "webhooks":
+		runWebhooks()
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -72,10 +88,16 @@ Commands:
   init          Initialize BIOMETRICS repository
   onboard       Interactive onboarding process  
   auto          Automatic AI-powered setup
-  check         Check BIOMETRICS compliance
+  check         Check BIOMETRICS compliance (--workspace <dir>)
   find-keys     Find existing API keys on system
   config        Manage configuration (init, validate, show)
   audit         Query and manage audit logs
+  plan          Inspect project plans (graph)
+  prompt        Render agent prompts from templates (render, list)
+  daemon        Control the orchestrator daemon (status, pause, resume, drain)
+  jobs          Manage scheduled maintenance jobs (list, show, trigger, enable, disable)
+  journal       Inspect and compact the durable checkpoint journal (inspect, compact)
+  webhooks      Manage webhook dead letters (dead-letters, replay, purge)
   version       Show version information
 `)
 }
@@ -146,6 +168,11 @@ func initConfig() {
 	defaultConfig := `# BIOMETRICS CLI Configuration
 version: "2.0.0"
 
+# Workspace root for plans, boulder.json, logs and transcripts.
+# Empty uses $BIOMETRICS_HOME or the XDG data directory.
+workspace:
+  root: ""
+
 # Logging configuration
 logging:
   level: info
@@ -503,6 +530,15 @@ func runBiometricsCheck() {
 	fmt.Println("=====================")
 	fmt.Println()
 
+	workspaceFlag, _ := workspace.FromArgs(os.Args[2:])
+	ws, err := workspace.Resolve(workspaceFlag)
+	if err != nil {
+		fmt.Printf("✗ Workspace root could not be resolved: %v\n", err)
+	} else {
+		fmt.Printf("Workspace root: %s\n", ws)
+		fmt.Println()
+	}
+
 	checks := []struct {
 		name    string
 		passed  bool
@@ -515,6 +551,7 @@ func runBiometricsCheck() {
 		{".env file", checkFileExists(".env"), ".env exists", ".env missing"},
 		{"oh-my-opencode.json", checkFileExists("oh-my-opencode.json"), "oh-my-opencode.json exists", "oh-my-opencode.json missing"},
 		{"requirements.txt", checkFileExists("requirements.txt"), "requirements.txt exists", "requirements.txt missing"},
+		{"workspace plans", err == nil && checkFileExists(ws.PlansDir()), "plans directory exists in workspace", "plans directory missing in workspace"},
 	}
 
 	allPassed := true
@@ -840,6 +877,293 @@ func runAudit() {
 	}
 }
 
+func runPlan() {
+	if len(os.Args) < 3 {
+		commands.PrintPlanHelp()
+		os.Exit(1)
+	}
+
+	workspaceFlag, args := workspace.FromArgs(os.Args[3:])
+
+	switch os.Args[2] {
+	case "graph":
+		flags := &commands.PlanGraphFlags{}
+		for _, arg := range args {
+			switch {
+			case arg == "--dot":
+				flags.DOT = true
+			case flags.Project == "":
+				flags.Project = arg
+			}
+		}
+		if flags.Project == "" {
+			commands.PrintPlanHelp()
+			os.Exit(1)
+		}
+
+		ws, err := workspace.Resolve(workspaceFlag)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		flags.PlansDir = ws.PlansDir()
+
+		if err := commands.RunPlanGraph(flags); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "help":
+		commands.PrintPlanHelp()
+	default:
+		fmt.Printf("Unknown plan subcommand: %s\n", os.Args[2])
+		commands.PrintPlanHelp()
+		os.Exit(1)
+	}
+}
+
+func runDaemon() {
+	if len(os.Args) < 3 {
+		commands.PrintDaemonHelp()
+		os.Exit(1)
+	}
+
+	workspaceFlag, _ := workspace.FromArgs(os.Args[3:])
+
+	switch os.Args[2] {
+	case "status", "pause", "resume", "drain":
+		ws, err := workspace.Resolve(workspaceFlag)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		flags := &commands.DaemonFlags{Action: os.Args[2], SocketPath: ws.ControlSocketPath()}
+		if err := commands.RunDaemon(flags); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "help":
+		commands.PrintDaemonHelp()
+	default:
+		fmt.Printf("Unknown daemon subcommand: %s\n", os.Args[2])
+		commands.PrintDaemonHelp()
+		os.Exit(1)
+	}
+}
+
+func runJobs() {
+	if len(os.Args) < 3 {
+		commands.PrintJobsHelp()
+		os.Exit(1)
+	}
+
+	workspaceFlag, args := workspace.FromArgs(os.Args[3:])
+
+	switch os.Args[2] {
+	case "list", "show", "trigger", "enable", "disable":
+		ws, err := workspace.Resolve(workspaceFlag)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		flags := &commands.JobsFlags{Action: os.Args[2], SocketPath: ws.ControlSocketPath()}
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--runs":
+				if i+1 < len(args) {
+					n, err := strconv.Atoi(args[i+1])
+					if err != nil {
+						fmt.Printf("Error: invalid --runs value %q\n", args[i+1])
+						os.Exit(1)
+					}
+					flags.Runs = n
+					i++
+				}
+			default:
+				flags.JobID = args[i]
+			}
+		}
+		if err := commands.RunJobs(flags); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "help":
+		commands.PrintJobsHelp()
+	default:
+		fmt.Printf("Unknown jobs subcommand: %s\n", os.Args[2])
+		commands.PrintJobsHelp()
+		os.Exit(1)
+	}
+}
+
+func runJournal() {
+	if len(os.Args) < 3 {
+		commands.PrintJournalHelp()
+		os.Exit(1)
+	}
+
+	workspaceFlag, args := workspace.FromArgs(os.Args[3:])
+
+	switch os.Args[2] {
+	case "inspect", "compact":
+		ws, err := workspace.Resolve(workspaceFlag)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		flags := &commands.JournalFlags{Dir: ws.JournalDir(), OlderThan: 7 * 24 * time.Hour}
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--agent":
+				if i+1 < len(args) {
+					flags.AgentID = args[i+1]
+					i++
+				}
+			case "--backend":
+				if i+1 < len(args) {
+					flags.Backend = args[i+1]
+					i++
+				}
+			case "--older-than":
+				if i+1 < len(args) {
+					d, err := time.ParseDuration(args[i+1])
+					if err != nil {
+						fmt.Printf("Error: invalid --older-than value %q\n", args[i+1])
+						os.Exit(1)
+					}
+					flags.OlderThan = d
+					i++
+				}
+			}
+		}
+
+		run := commands.RunJournalInspect
+		if os.Args[2] == "compact" {
+			run = commands.RunJournalCompact
+		}
+		if err := run(flags); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "help":
+		commands.PrintJournalHelp()
+	default:
+		fmt.Printf("Unknown journal subcommand: %s\n", os.Args[2])
+		commands.PrintJournalHelp()
+		os.Exit(1)
+	}
+}
+
+func runWebhooks() {
+	if len(os.Args) < 3 {
+		commands.PrintWebhooksHelp()
+		os.Exit(1)
+	}
+
+	workspaceFlag, args := workspace.FromArgs(os.Args[3:])
+
+	switch os.Args[2] {
+	case "dead-letters", "replay", "purge":
+		ws, err := workspace.Resolve(workspaceFlag)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		flags := &commands.WebhooksFlags{DB: ws.WebhooksDB()}
+		for _, arg := range args {
+			switch arg {
+			case "--all":
+				flags.All = true
+			default:
+				flags.ID = arg
+			}
+		}
+
+		run := commands.RunWebhooksDeadLetters
+		switch os.Args[2] {
+		case "replay":
+			run = commands.RunWebhooksReplay
+		case "purge":
+			run = commands.RunWebhooksPurge
+		}
+		if err := run(flags); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "help":
+		commands.PrintWebhooksHelp()
+	default:
+		fmt.Printf("Unknown webhooks subcommand: %s\n", os.Args[2])
+		commands.PrintWebhooksHelp()
+		os.Exit(1)
+	}
+}
+
+func runPrompt() {
+	if len(os.Args) < 3 {
+		commands.PrintPromptHelp()
+		os.Exit(1)
+	}
+
+	workspaceFlag, args := workspace.FromArgs(os.Args[3:])
+	ws, err := workspace.Resolve(workspaceFlag)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch os.Args[2] {
+	case "render":
+		flags := &commands.PromptRenderFlags{PlansDir: ws.PlansDir()}
+		var positional []string
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--template":
+				if i+1 < len(args) {
+					flags.Template = args[i+1]
+					i++
+				}
+			case "--plan":
+				if i+1 < len(args) {
+					flags.PlanName = args[i+1]
+					i++
+				}
+			case "--model":
+				if i+1 < len(args) {
+					flags.Model = args[i+1]
+					i++
+				}
+			default:
+				positional = append(positional, args[i])
+			}
+		}
+		if len(positional) < 2 {
+			commands.PrintPromptHelp()
+			os.Exit(1)
+		}
+		flags.Project, flags.TaskID = positional[0], positional[1]
+
+		if err := commands.RunPromptRender(flags); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "list":
+		projectID := ""
+		if len(args) > 0 {
+			projectID = args[0]
+		}
+		if err := commands.RunPromptList(ws.PlansDir(), projectID); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "help":
+		commands.PrintPromptHelp()
+	default:
+		fmt.Printf("Unknown prompt subcommand: %s\n", os.Args[2])
+		commands.PrintPromptHelp()
+		os.Exit(1)
+	}
+}
+
 func parseAuditQueryFlags() *commands.AuditQueryFlags {
 	flags := &commands.AuditQueryFlags{
 		Limit: 100,
@@ -2,91 +2,48 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
-	"biometrics-cli/internal/collision"
-	"biometrics-cli/internal/opencode"
-	"biometrics-cli/internal/project"
-	"biometrics-cli/internal/prompt"
-	"biometrics-cli/internal/quality"
+	"biometrics-cli/internal/daemon"
 	"biometrics-cli/internal/telemetry"
+	"biometrics-cli/internal/workspace"
+
+	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
-	logger := telemetry.SetupLogger()
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	workspaceFlag := flag.String(workspace.FlagName, "", "workspace root (plans, transcripts, logs)")
+	workers := flag.Int("workers", daemon.DefaultWorkers, "maximum number of tasks running in parallel")
+	flag.Parse()
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		logger.Info("Shutdown signal received")
-		cancel()
-	}()
+	logger := telemetry.SetupLogger()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	logger.Info("=== BIOMETRICS 24/7 ULTRA ORCHESTRATOR STARTING ===")
 
-	modelPool := collision.NewModelPool()
-	executor := opencode.NewExecutor(logger)
-	basePath := "/Users/jeremy/.sisyphus/plans"
-
-	for {
-		if ctx.Err() != nil {
-			break
-		}
-
-		projects, err := project.DiscoverProjects(basePath)
-		if err != nil || len(projects) == 0 {
-			logger.Warn("No projects found, waiting...")
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		for _, projID := range projects {
-			cycleCtx, traceID := telemetry.InjectTraceID(ctx)
-			logger.Info("Processing project", slog.String("project", projID), slog.String("trace_id", traceID))
-
-			task, err := project.GetNextTask(projID)
-			if err != nil {
-				logger.Debug("No pending tasks", slog.String("project", projID))
-				continue
-			}
-
-			logger.Info("Executing Task", slog.String("task_id", task.ID))
-
-			model := "qwen-3.5"
-			modelPool.Acquire(cycleCtx, model)
-
-			taskPrompt := prompt.GenerateEnterprisePrompt(projID, "active_plan.md", task.ID, task.Description)
-
-			req := opencode.AgentRequest{
-				ProjectID: projID,
-				Model:     model,
-				Prompt:    taskPrompt,
-				Category:  "build",
-			}
-
-			result := executor.RunAgent(cycleCtx, req)
-			modelPool.Release(model)
-
-			if !result.Success {
-				logger.Error("Task failed", slog.String("task_id", task.ID), slog.String("error", result.Error.Error()))
-				continue
-			}
-
-			if err := quality.EnforceQualityGate(cycleCtx, executor, req); err == nil {
-				project.MarkTaskCompleted(projID, task.ID)
-				logger.Info("Task completed and verified", slog.String("task_id", task.ID))
-			} else {
-				logger.Error("Quality Gate failed", slog.String("task_id", task.ID))
-			}
-		}
+	ws, err := workspace.Resolve(*workspaceFlag)
+	if err != nil {
+		logger.Error("Failed to resolve workspace", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-		time.Sleep(5 * time.Second)
+	d, closeAll, err := daemon.Bootstrap(ws, daemon.BootstrapOptions{Workers: *workers, Console: os.Stdout})
+	defer closeAll()
+	logger = slog.Default()
+	logger.Info("Using workspace", slog.String("root", ws.Root), slog.String("source", string(ws.Source)))
+	if err != nil {
+		logger.Error("Failed to start daemon", slog.String("error", err.Error()))
+		closeAll()
+		os.Exit(1)
+	}
+	if err := d.Run(ctx); err != nil {
+		logger.Error("Daemon failed", slog.String("error", err.Error()))
+		closeAll()
+		os.Exit(1)
 	}
 }
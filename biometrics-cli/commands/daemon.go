@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"biometrics-cli/internal/daemon"
+	"biometrics-cli/internal/project"
+)
+
+type DaemonFlags struct {
+	Action     string
+	SocketPath string
+}
+
+// RunDaemon sends a control request to the running daemon and prints the
+// resulting status.
+func RunDaemon(flags *DaemonFlags) error {
+	client := daemon.NewClient(flags.SocketPath)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var (
+		st  *daemon.Status
+		err error
+	)
+	switch flags.Action {
+	case "status":
+		st, err = client.Status(ctx)
+	case "pause":
+		st, err = client.Pause(ctx)
+	case "resume":
+		st, err = client.Resume(ctx)
+	case "drain":
+		st, err = client.Drain(ctx)
+	default:
+		return fmt.Errorf("unknown daemon action: %s", flags.Action)
+	}
+	if err != nil {
+		return err
+	}
+
+	printDaemonStatus(st)
+	return nil
+}
+
+func printDaemonStatus(st *daemon.Status) {
+	fmt.Printf("State:     %s\n", st.State)
+	fmt.Printf("Workspace: %s\n", st.Workspace)
+	if !st.StartedAt.IsZero() {
+		fmt.Printf("Uptime:    %s\n", time.Since(st.StartedAt).Round(time.Second))
+	}
+	fmt.Printf("Workers:   %d/%d busy\n", len(st.Active), st.Workers)
+	if len(st.Agents) > 0 {
+		agents := make([]string, 0, len(st.Agents))
+		for name, queued := range st.Agents {
+			agents = append(agents, fmt.Sprintf("%s=%d", name, queued))
+		}
+		sort.Strings(agents)
+		fmt.Printf("Queues:    %s\n", strings.Join(agents, " "))
+	}
+
+	for _, a := range st.Active {
+		if a.Queued {
+			fmt.Printf("  %s/%s queued for %s\n", a.Project, a.TaskID, a.Agent)
+			continue
+		}
+		fmt.Printf("  %s/%s attempt %d on %s, running %s\n",
+			a.Project, a.TaskID, a.Attempt, a.Agent, time.Since(a.StartedAt).Round(time.Second))
+	}
+
+	if len(st.Finished) > 0 {
+		statuses := make([]string, 0, len(st.Finished))
+		for s := range st.Finished {
+			statuses = append(statuses, string(s))
+		}
+		sort.Strings(statuses)
+		parts := make([]string, 0, len(statuses))
+		for _, s := range statuses {
+			parts = append(parts, fmt.Sprintf("%s=%d", s, st.Finished[project.TaskStatus(s)]))
+		}
+		fmt.Printf("Finished:  %s\n", strings.Join(parts, " "))
+	}
+
+	if len(st.Projects) > 0 {
+		names := make([]string, 0, len(st.Projects))
+		for name := range st.Projects {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println("Projects (share / entitlement):")
+		for _, name := range names {
+			p := st.Projects[name]
+			line := fmt.Sprintf("  %-20s %5.1f%% / %5.1f%%  %d/%d agents  %.0f model-min/h",
+				name, p.Share*100, p.Entitlement*100, p.Active, p.MaxConcurrent, p.ModelMinutes)
+			if p.Throttled != "" {
+				line += "  throttled: " + p.Throttled
+			}
+			fmt.Println(line)
+		}
+	}
+
+	if len(st.Models) > 0 {
+		fmt.Println("Models:")
+	}
+	for _, m := range st.Models {
+		line := fmt.Sprintf("  %-20s %d/%d", m.Name, m.InUse, m.Capacity)
+		if m.Exhausted {
+			line += " exhausted: " + m.ExhaustedReason
+		}
+		fmt.Println(line)
+	}
+}
+
+func PrintDaemonHelp() {
+	fmt.Print(`
+Daemon Commands - Control the running orchestrator daemon
+
+Usage:
+  biometrics daemon <subcommand> [options]
+
+Subcommands:
+  status            Show state, running tasks and model usage
+  pause             Stop claiming new tasks, let running ones finish
+  resume            Claim new tasks again
+  drain             Finish running tasks, then stop the daemon
+
+Options:
+  --workspace       Workspace root (default: BIOMETRICS_HOME, config or XDG)
+
+The daemon itself is started with the orchestrator or agent-loop binary.
+`)
+}
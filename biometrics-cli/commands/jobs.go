@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"biometrics-cli/internal/daemon"
+	"biometrics-cli/internal/scheduler"
+)
+
+type JobsFlags struct {
+	Action     string
+	JobID      string
+	Runs       int
+	SocketPath string
+}
+
+// RunJobs lists or controls the maintenance jobs of the running daemon.
+func RunJobs(flags *JobsFlags) error {
+	client := daemon.NewClient(flags.SocketPath)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if flags.Action == "list" {
+		jobs, err := client.Jobs(ctx, flags.Runs)
+		if err != nil {
+			return err
+		}
+		printJobList(jobs)
+		return nil
+	}
+
+	if flags.JobID == "" {
+		return fmt.Errorf("jobs %s: missing job id", flags.Action)
+	}
+	var (
+		info *scheduler.JobInfo
+		err  error
+	)
+	switch flags.Action {
+	case "show":
+		info, err = client.Job(ctx, flags.JobID)
+	case "trigger":
+		info, err = client.TriggerJob(ctx, flags.JobID)
+	case "enable":
+		info, err = client.EnableJob(ctx, flags.JobID)
+	case "disable":
+		info, err = client.DisableJob(ctx, flags.JobID)
+	default:
+		return fmt.Errorf("unknown jobs action: %s", flags.Action)
+	}
+	if err != nil {
+		return err
+	}
+
+	printJob(info)
+	return nil
+}
+
+func printJobList(jobs []scheduler.JobInfo) {
+	if len(jobs) == 0 {
+		fmt.Println("No jobs registered.")
+		return
+	}
+	fmt.Printf("%-18s %-9s %-14s %-20s %s\n", "ID", "STATE", "SCHEDULE", "NEXT RUN", "LAST RESULT")
+	for _, j := range jobs {
+		fmt.Printf("%-18s %-9s %-14s %-20s %s\n", j.ID, jobState(j), j.Schedule, formatRunTime(j.NextRun), lastResult(j.LastResult))
+		for _, next := range j.NextRuns {
+			fmt.Printf("%-18s %-9s %-14s %s\n", "", "", "", formatRunTime(next))
+		}
+	}
+}
+
+func printJob(j *scheduler.JobInfo) {
+	fmt.Printf("Job:         %s (%s)\n", j.ID, j.Name)
+	fmt.Printf("State:       %s\n", jobState(*j))
+	schedule := j.Schedule
+	if j.TimeZone != "" {
+		schedule += " in " + j.TimeZone
+	}
+	if j.Jitter != "" {
+		schedule += ", jitter " + j.Jitter
+	}
+	fmt.Printf("Schedule:    %s\n", schedule)
+	fmt.Printf("Policies:    concurrency %s, catch-up %s\n", j.Concurrency, j.CatchUp)
+	fmt.Printf("Next run:    %s\n", formatRunTime(j.NextRun))
+	fmt.Printf("Runs:        %d, %d failure(s) in a row\n", j.RunCount, j.FailCount)
+
+	if len(j.History) == 0 {
+		return
+	}
+	fmt.Println("History (newest first):")
+	for i := len(j.History) - 1; i >= 0; i-- {
+		r := j.History[i]
+		line := fmt.Sprintf("  %s  %-9s %-9s %8s", formatRunTime(r.Started), r.Outcome, r.Trigger, r.Duration.Round(time.Millisecond))
+		if r.Result != "" {
+			line += "  " + r.Result
+		}
+		if r.Error != "" {
+			line += "  error: " + r.Error
+		}
+		fmt.Println(line)
+	}
+}
+
+func jobState(j scheduler.JobInfo) string {
+	switch {
+	case j.Running > 0:
+		return "running"
+	case j.Enabled:
+		return "enabled"
+	default:
+		return "disabled"
+	}
+}
+
+func lastResult(r *scheduler.JobRun) string {
+	if r == nil {
+		return "-"
+	}
+	parts := []string{r.Outcome, formatRunTime(r.Started)}
+	if r.Error != "" {
+		parts = append(parts, r.Error)
+	} else if r.Result != "" {
+		parts = append(parts, r.Result)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatRunTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func PrintJobsHelp() {
+	fmt.Print(`
+Jobs Commands - Inspect and control the daemon's maintenance jobs
+
+Usage:
+  biometrics jobs <subcommand> [job-id] [options]
+
+Subcommands:
+  list              List jobs with state, next runs and last result
+  show <id>         Show a job with its run history
+  trigger <id>      Run a job now, even if it is disabled
+  enable <id>       Enable a job; survives daemon restarts
+  disable <id>      Disable a job; survives daemon restarts
+
+Options:
+  --runs N          Upcoming runs to preview per job in list (default: 0)
+  --workspace       Workspace root (default: BIOMETRICS_HOME, config or XDG)
+
+Run history, last runs and enable/disable decisions are kept in jobs.json in
+the workspace. Runs missed while the daemon was down are caught up according
+to each job's catch-up policy.
+`)
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"biometrics-cli/internal/durable"
+)
+
+type JournalFlags struct {
+	Dir     string
+	Backend string
+	AgentID string
+	// OlderThan is the minimum age of finished runs removed by compact.
+	OlderThan time.Duration
+}
+
+// RunJournalInspect summarises the journal per agent, or lists the
+// checkpoints of one agent. Corrupt checkpoints are listed and reported as an
+// error.
+func RunJournalInspect(flags *JournalFlags) error {
+	p, err := durable.OpenPersistence(flags.Backend, flags.Dir)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	var checkpoints []*durable.Checkpoint
+	if flags.AgentID != "" {
+		checkpoints, err = p.Load(flags.AgentID)
+	} else {
+		checkpoints, err = p.LoadAll()
+	}
+	var corrupt *durable.CorruptError
+	if err != nil && !errors.As(err, &corrupt) {
+		return err
+	}
+
+	if flags.AgentID != "" {
+		printCheckpoints(checkpoints)
+	} else {
+		printJournalSummary(checkpoints)
+	}
+
+	if corrupt != nil {
+		fmt.Printf("\nCorrupt checkpoints (%d):\n", len(corrupt.Entries))
+		for _, entry := range corrupt.Entries {
+			fmt.Printf("  %s: %v\n", entry.Location, entry.Err)
+		}
+		return fmt.Errorf("journal has %d corrupt checkpoint(s); run 'biometrics journal compact' to quarantine them", len(corrupt.Entries))
+	}
+	return nil
+}
+
+// RunJournalCompact removes finished runs older than flags.OlderThan and
+// quarantines corrupt checkpoints.
+func RunJournalCompact(flags *JournalFlags) error {
+	p, err := durable.OpenPersistence(flags.Backend, flags.Dir)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	stats, err := p.Compact(time.Now().Add(-flags.OlderThan))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %d checkpoint(s) of runs finished more than %s ago\n", stats.Removed, flags.OlderThan)
+	if stats.Quarantined > 0 {
+		fmt.Printf("Quarantined %d corrupt checkpoint(s)\n", stats.Quarantined)
+	}
+	if stats.TempFiles > 0 {
+		fmt.Printf("Removed %d leftover temp file(s)\n", stats.TempFiles)
+	}
+	return nil
+}
+
+func printJournalSummary(checkpoints []*durable.Checkpoint) {
+	if len(checkpoints) == 0 {
+		fmt.Println("Journal is empty.")
+		return
+	}
+
+	type summary struct {
+		steps, running, completed, failed int
+		last                              time.Time
+	}
+	var agents []string
+	byAgent := make(map[string]*summary)
+	for _, cp := range checkpoints {
+		s := byAgent[cp.AgentID]
+		if s == nil {
+			s = &summary{}
+			byAgent[cp.AgentID] = s
+			agents = append(agents, cp.AgentID)
+		}
+		s.steps++
+		switch cp.Status {
+		case "running":
+			s.running++
+		case "completed":
+			s.completed++
+		case "failed":
+			s.failed++
+		}
+		if cp.Timestamp.After(s.last) {
+			s.last = cp.Timestamp
+		}
+	}
+
+	fmt.Printf("%-32s %6s %8s %10s %7s  %s\n", "AGENT", "STEPS", "RUNNING", "COMPLETED", "FAILED", "LAST STEP")
+	for _, agent := range agents {
+		s := byAgent[agent]
+		fmt.Printf("%-32s %6d %8d %10d %7d  %s\n", agent, s.steps, s.running, s.completed, s.failed, formatRunTime(s.last))
+	}
+}
+
+func printCheckpoints(checkpoints []*durable.Checkpoint) {
+	if len(checkpoints) == 0 {
+		fmt.Println("No checkpoints for this agent.")
+		return
+	}
+	for _, cp := range checkpoints {
+		line := fmt.Sprintf("%3d  %-10s %s  task=%s", cp.StepNumber, cp.Status, formatRunTime(cp.Timestamp), cp.TaskID)
+		if cp.Duration > 0 {
+			line += fmt.Sprintf(" (%s)", cp.Duration.Round(time.Millisecond))
+		}
+		if cp.Error != "" {
+			line += "  error: " + cp.Error
+		}
+		fmt.Println(line)
+	}
+}
+
+func PrintJournalHelp() {
+	fmt.Print(`
+Journal Commands - Inspect and compact the durable checkpoint journal
+
+Usage:
+  biometrics journal <subcommand> [options]
+
+Subcommands:
+  inspect           Summarise checkpoints per agent and report corrupt ones
+  compact           Remove finished runs and quarantine corrupt checkpoints
+
+Options:
+  --agent ID        inspect: list the checkpoints of one agent
+  --older-than D    compact: minimum age of finished runs (default: 168h)
+  --backend NAME    file or sqlite (default: BIOMETRICS_JOURNAL_BACKEND or file)
+  --workspace       Workspace root (default: BIOMETRICS_HOME, config or XDG)
+
+A run is only removed once all of its checkpoints are finished. Corrupt
+checkpoints are renamed to *.json.corrupt (file) or moved to the
+checkpoint_quarantine table (sqlite).
+`)
+}
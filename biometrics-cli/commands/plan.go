@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"biometrics-cli/internal/project"
+)
+
+type PlanGraphFlags struct {
+	Project  string
+	PlansDir string
+	DOT      bool
+}
+
+// RunPlanGraph prints the task dependency graph of a project. It returns an
+// error if the graph contains cycles, duplicate IDs or unknown dependencies.
+func RunPlanGraph(flags *PlanGraphFlags) error {
+	store := project.NewFileTaskStore(flags.PlansDir)
+	b, err := store.Load(flags.Project)
+	if err != nil {
+		return fmt.Errorf("failed to load plan for %s: %w", flags.Project, err)
+	}
+
+	graph := project.BuildGraph(b)
+	if flags.DOT {
+		graph.WriteDOT(os.Stdout)
+	} else {
+		graph.WriteText(os.Stdout)
+	}
+
+	if !graph.Valid() {
+		return fmt.Errorf("plan %s is invalid: %d cycle(s), %d task(s) with missing dependencies, %d duplicate(s)",
+			flags.Project, len(graph.Cycles), len(graph.Missing), len(graph.Duplicates))
+	}
+	return nil
+}
+
+func PrintPlanHelp() {
+	fmt.Print(`
+Plan Commands - Inspect project plans
+
+Usage:
+  biometrics plan <subcommand> [options]
+
+Subcommands:
+  graph <project>   Print the task dependency graph and flag cycles
+
+Graph Options:
+  --dot             Output Graphviz DOT instead of text
+  --workspace       Workspace root (default: BIOMETRICS_HOME, config or XDG)
+`)
+}
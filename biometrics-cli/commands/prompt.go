@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+
+	"biometrics-cli/internal/project"
+	"biometrics-cli/internal/prompt"
+)
+
+type PromptRenderFlags struct {
+	Project  string
+	TaskID   string
+	PlansDir string
+	Template string
+	PlanName string
+	Model    string
+}
+
+// RunPromptRender prints the prompt an agent would receive for a task,
+// using the same templates and project overrides as the orchestrator.
+func RunPromptRender(flags *PromptRenderFlags) error {
+	store := project.NewFileTaskStore(flags.PlansDir)
+	b, err := store.Load(flags.Project)
+	if err != nil {
+		return fmt.Errorf("failed to load plan for %s: %w", flags.Project, err)
+	}
+
+	task := b.FindTask(flags.TaskID)
+	if task == nil {
+		return fmt.Errorf("task %s not found in project %s", flags.TaskID, flags.Project)
+	}
+
+	name := flags.Template
+	if name == "" {
+		name = prompt.TaskTemplate
+	}
+	planName := flags.PlanName
+	if planName == "" {
+		planName = "active_plan.md"
+	}
+	category := task.Category
+	if category == "" {
+		category = "build"
+	}
+
+	tmpl, err := prompt.LoaderFor(flags.PlansDir, flags.Project).Load(name)
+	if err != nil {
+		return err
+	}
+
+	data := prompt.TaskData{
+		ProjectID:          flags.Project,
+		PlanName:           planName,
+		TaskID:             task.ID,
+		TaskDescription:    task.Description,
+		Category:           category,
+		Agent:              task.Agent,
+		Model:              flags.Model,
+		AcceptanceCriteria: task.AcceptanceCriteria,
+	}
+	rendered, err := tmpl.Render(data.Vars(flags.PlansDir))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("# template: %s (%s)\n", tmpl.Name, tmpl.Path)
+	fmt.Println(rendered)
+	return nil
+}
+
+// RunPromptList prints all templates visible to a project.
+func RunPromptList(plansDir, projectID string) error {
+	loader := prompt.LoaderFor(plansDir, projectID)
+	templates, err := loader.List()
+	if err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		return fmt.Errorf("no templates found in %v", loader.Dirs())
+	}
+
+	for _, t := range templates {
+		fmt.Printf("%-16s %s\n", t.Name, t.Description)
+		fmt.Printf("%-16s vars: %v\n", "", t.Vars)
+		fmt.Printf("%-16s path: %s\n", "", t.Path)
+	}
+	return nil
+}
+
+func PrintPromptHelp() {
+	fmt.Print(`
+Prompt Commands - Preview agent prompts
+
+Usage:
+  biometrics prompt <subcommand> [options]
+
+Subcommands:
+  render <project> <task>   Render the prompt an agent receives for a task
+  list [project]            List templates and their declared variables
+
+Render Options:
+  --template      Template name (default: sub-agent)
+  --plan          Plan file name (default: active_plan.md)
+  --model         Model to pass to the template
+  --workspace     Workspace root (default: BIOMETRICS_HOME, config or XDG)
+
+Templates are read from <workspace>/plans/<project>/templates first, then
+from templates/prompts (or BIOMETRICS_TEMPLATES_DIR).
+`)
+}
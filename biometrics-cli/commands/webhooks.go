@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"biometrics-cli/internal/webhook"
+)
+
+type WebhooksFlags struct {
+	// DB is the outbox database of the workspace.
+	DB string
+	// ID selects one dead letter for replay and purge.
+	ID string
+	// All applies replay or purge to every dead letter.
+	All bool
+}
+
+// RunWebhooksDeadLetters lists the dead letters and the number of deliveries
+// still pending in the outbox.
+func RunWebhooksDeadLetters(flags *WebhooksFlags) error {
+	store, err := webhook.OpenSQLiteOutboxStore(flags.DB)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	entries, err := store.DeadLetters()
+	if err != nil {
+		return err
+	}
+	pending, err := store.Pending()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No dead letters.")
+	} else {
+		fmt.Printf("%-40s %-12s %-16s %8s  %-19s  %s\n", "ID", "SUBSCRIPTION", "TYPE", "ATTEMPTS", "DEAD SINCE", "LAST ERROR")
+		for _, e := range entries {
+			sub := e.Subscription
+			if sub == "" {
+				sub = "env"
+			}
+			fmt.Printf("%-40s %-12s %-16s %8d  %-19s  %s\n", e.ID, sub, e.Event.Type, e.Attempts, formatRunTime(e.DeadAt), e.LastError)
+		}
+	}
+	fmt.Printf("\n%d delivery(ies) pending in the outbox\n", pending)
+	return nil
+}
+
+// RunWebhooksReplay moves dead letters back into the outbox with a fresh
+// attempt budget. The daemon delivers them on its next pass.
+func RunWebhooksReplay(flags *WebhooksFlags) error {
+	if err := requireTarget(flags); err != nil {
+		return err
+	}
+	store, err := webhook.OpenSQLiteOutboxStore(flags.DB)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	n, err := store.Replay(flags.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Requeued %d dead letter(s)\n", n)
+	return nil
+}
+
+// RunWebhooksPurge deletes dead letters for good.
+func RunWebhooksPurge(flags *WebhooksFlags) error {
+	if err := requireTarget(flags); err != nil {
+		return err
+	}
+	store, err := webhook.OpenSQLiteOutboxStore(flags.DB)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	n, err := store.Purge(flags.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d dead letter(s)\n", n)
+	return nil
+}
+
+// requireTarget makes "all" explicit so a missing ID never touches every
+// dead letter by accident.
+func requireTarget(flags *WebhooksFlags) error {
+	switch {
+	case flags.ID == "" && !flags.All:
+		return errors.New("give a dead letter ID or --all")
+	case flags.ID != "" && flags.All:
+		return errors.New("an ID and --all are mutually exclusive")
+	}
+	return nil
+}
+
+func PrintWebhooksHelp() {
+	fmt.Print(`
+Webhooks Commands - Manage the webhook outbox
+
+Usage:
+  biometrics webhooks <subcommand> [options]
+
+Subcommands:
+  dead-letters      List deliveries that were given up and the pending count
+  replay ID|--all   Move dead letters back into the outbox
+  purge ID|--all    Delete dead letters
+
+Options:
+  --all             replay/purge: apply to every dead letter
+  --workspace       Workspace root (default: BIOMETRICS_HOME, config or XDG)
+
+The daemon delivers every event to the matching subscriptions, which are
+managed through the api-server at /api/webhooks, and to BIOMETRICS_WEBHOOK_URL
+if set ("env"). The api-server requires the API token as a Bearer token and a
+JSON body on /api/webhooks. The token is BIOMETRICS_API_TOKEN or, without it,
+<workspace>/api-token, which the api-server generates on first start.
+Failed deliveries are retried with exponential backoff; after 10 attempts,
+when the receiver rejects an event with a 4xx status, or when the
+subscription was disabled or removed, the delivery becomes a dead letter.
+`)
+}
@@ -156,6 +156,22 @@ func (c *Cache) Len() int {
 	return len(c.items)
 }
 
+// Warm lädt die Einträge von der Platte neu, verwirft abgelaufene und liefert
+// die Anzahl der Einträge danach.
+func (c *Cache) Warm() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loadFromDisk()
+	now := time.Now()
+	for key, item := range c.items {
+		if now.After(item.Expiration) {
+			delete(c.items, key)
+		}
+	}
+	return len(c.items)
+}
+
 func (c *Cache) startCleanup(interval time.Duration) {
 	if interval <= 0 {
 		return
@@ -243,7 +259,7 @@ func (c *Cache) loadFromDisk() {
 			continue
 		}
 
-		expiration := time.Unix(data.CreatedAt, 0).Add(time.Duration(data.TTL) * time.Second)
+		expiration := time.Unix(data.CreatedAt, 0).Add(time.Duration(data.TTL))
 		if time.Now().Before(expiration) {
 			c.items[key] = CacheItem{
 				Key:        key,
@@ -2,9 +2,11 @@ package codegen
 
 import (
 	"biometrics-cli/internal/metrics"
+	"biometrics-cli/internal/opencode"
 	"biometrics-cli/internal/state"
+	"context"
 	"fmt"
-	"os/exec"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -15,6 +17,7 @@ type CodeGenerator struct {
 	activeTasks map[string]*Task
 	workers     int
 	queue       chan *Task
+	executor    *opencode.Executor
 }
 
 type Task struct {
@@ -26,6 +29,7 @@ type Task struct {
 	Progress    int
 	Output      string
 	Error       string
+	Transcript  string
 	CreatedAt   time.Time
 	StartedAt   time.Time
 	CompletedAt time.Time
@@ -43,12 +47,20 @@ func NewCodeGenerator() *CodeGenerator {
 			activeTasks: make(map[string]*Task),
 			workers:     3,
 			queue:       make(chan *Task, 100),
+			executor:    opencode.NewExecutor(slog.Default()),
 		}
 		go generator.workerPool()
 	})
 	return generator
 }
 
+// SetExecutor replaces the executor used to run code generation agents.
+func (g *CodeGenerator) SetExecutor(executor *opencode.Executor) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.executor = executor
+}
+
 func (g *CodeGenerator) CreateTask(title, description, agent string) (*Task, error) {
 	task := &Task{
 		ID:          fmt.Sprintf("task-%d", time.Now().UnixNano()),
@@ -88,29 +100,35 @@ func (g *CodeGenerator) RunCodeGeneration(taskID string, progressChan chan<- str
 	task.Status = "running"
 	task.StartedAt = time.Now()
 	g.activeTasks[task.ID] = task
+	executor := g.executor
 	g.mu.Unlock()
 
 	progressChan <- "Starting code generation..."
 	metrics.TasksStartedTotal.Inc()
 
-	cmd := exec.Command("opencode", task.Description, "--agent", task.Agent)
-	output, err := cmd.CombinedOutput()
+	result := executor.RunAgent(context.Background(), opencode.AgentRequest{
+		Prompt: task.Description,
+		Agent:  task.Agent,
+		TaskID: task.ID,
+	})
 
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	if err != nil {
+	task.Transcript = result.TranscriptRef
+	if !result.Success {
 		task.Status = "failed"
-		task.Error = err.Error()
+		task.Error = result.Error.Error()
+		task.Output = result.Output
 		task.CompletedAt = time.Now()
 		delete(g.activeTasks, task.ID)
-		progressChan <- fmt.Sprintf("Error: %v", err)
+		progressChan <- fmt.Sprintf("Error: %v", result.Error)
 		metrics.TasksFailedTotal.Inc()
-		return err
+		return result.Error
 	}
 
 	task.Status = "completed"
-	task.Output = string(output)
+	task.Output = result.Output
 	task.CompletedAt = time.Now()
 	task.Progress = 100
 	delete(g.activeTasks, task.ID)
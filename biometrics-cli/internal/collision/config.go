@@ -0,0 +1,215 @@
+package collision
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"biometrics-cli/internal/config"
+	"biometrics-cli/internal/ratelimit"
+)
+
+// DefaultCostUnitTokens gilt für Modelle ohne cost_unit_tokens.
+const DefaultCostUnitTokens = 32000
+
+// DefaultChain ist die Fallback-Kette für Kategorien ohne eigene Kette.
+const DefaultChain = "default"
+
+// DefaultConfig enthält die Limits aus Mandat 0.37, das RPM-Limit des
+// NVIDIA-NIM Free Tiers und die Fallback-Ketten. Sie bilden die Basis, die
+// opencode.json erweitert oder überschreibt.
+func DefaultConfig() *config.OpenCodeConfig {
+	return &config.OpenCodeConfig{
+		Provider: map[string]config.ProviderConfig{
+			"nvidia-nim": {
+				Provider:  "nvidia-nim",
+				RateLimit: config.RateLimit{RPM: 40},
+				Models: map[string]config.Model{
+					"qwen-3.5-397b": {ID: "qwen/qwen3.5-397b-a17b", Concurrency: 1, Aliases: []string{"qwen-3.5", "qwen3.5"}},
+				},
+			},
+			"opencode-zen": {
+				Provider: "opencode-zen",
+				Models: map[string]config.Model{
+					"kimi-k2.5-free":    {ID: "opencode/kimi-k2.5-free", Concurrency: 1, Aliases: []string{"kimi-k2.5", "kimi"}},
+					"minimax-m2.5-free": {ID: "opencode/minimax-m2.5-free", Concurrency: 10, Aliases: []string{"minimax-m2.5", "minimax"}},
+				},
+			},
+			"google": {
+				Provider: "google",
+				Models: map[string]config.Model{
+					"gemini-3-flash-preview": {ID: "google/gemini-3-flash-preview", Concurrency: 1, Aliases: []string{"gemini-3-flash"}},
+				},
+			},
+		},
+		Fallback: map[string][]string{
+			DefaultChain: {"qwen-3.5", "kimi-k2.5", "minimax-m2.5"},
+			"deep":       {"kimi-k2.5", "qwen-3.5", "minimax-m2.5"},
+			"quick":      {"minimax-m2.5", "kimi-k2.5"},
+			"explore":    {"minimax-m2.5", "kimi-k2.5"},
+		},
+	}
+}
+
+// NewModelPool erstellt einen Pool aus DefaultConfig, erweitert um cfg.
+func NewModelPool(cfg *config.OpenCodeConfig) *ModelPool {
+	p := &ModelPool{wake: make(chan struct{}), quota: ratelimit.NewQuota()}
+	p.Reconfigure(cfg)
+	return p
+}
+
+// LoadModelPool lädt die OpenCode-Konfiguration von path. Fehlt die Datei,
+// gelten nur die Defaults.
+func LoadModelPool(path string) (*ModelPool, error) {
+	cfg, err := config.LoadOpenCodeConfig(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewModelPool(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewModelPool(cfg), nil
+}
+
+// Reconfigure ersetzt Modelle, Provider und Aliase. Laufende Leases bleiben
+// gültig und zählen gegen die neuen Limits; sinkt ein Limit unter die
+// aktuelle Belegung, warten neue Acquire-Aufrufe, bis genug frei ist.
+func (p *ModelPool) Reconfigure(cfg *config.OpenCodeConfig) {
+	models := make(map[string]*modelSlot)
+	providers := make(map[string]*providerSlot)
+	aliases := make(map[string]string)
+	chains := make(map[string][]string)
+
+	add := func(c *config.OpenCodeConfig) {
+		if c == nil {
+			return
+		}
+		for category, chain := range c.Fallback {
+			chains[category] = append([]string(nil), chain...)
+		}
+		for providerName, provider := range c.Provider {
+			prov, ok := providers[providerName]
+			if !ok {
+				prov = &providerSlot{}
+				providers[providerName] = prov
+			}
+			if provider.Concurrency > 0 {
+				prov.capacity = int64(provider.Concurrency)
+			}
+			if provider.RateLimit != (config.RateLimit{}) {
+				prov.quota = ratelimit.QuotaLimits(provider.RateLimit)
+			}
+
+			for key, model := range provider.Models {
+				slot := &modelSlot{
+					name:     providerName + "/" + key,
+					id:       model.ID,
+					provider: providerName,
+					capacity: int64(model.Concurrency),
+					costUnit: model.CostUnitTokens,
+				}
+				if slot.id == "" {
+					slot.id = slot.name
+				}
+				if slot.capacity <= 0 {
+					slot.capacity = 1
+				}
+				if slot.costUnit <= 0 {
+					slot.costUnit = DefaultCostUnitTokens
+				}
+
+				// Ein Modell mit derselben ID ersetzt den Default, behält aber dessen Aliase
+				for name, existing := range models {
+					if existing.id == slot.id && name != slot.name {
+						delete(models, name)
+						for alias, target := range aliases {
+							if target == name {
+								aliases[alias] = slot.name
+							}
+						}
+						aliases[name] = slot.name
+					}
+				}
+
+				models[slot.name] = slot
+				aliases[slot.id] = slot.name
+				aliases[key] = slot.name
+				for _, alias := range model.Aliases {
+					aliases[alias] = slot.name
+				}
+			}
+		}
+	}
+	add(DefaultConfig())
+	add(cfg)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name, slot := range models {
+		if old, ok := p.models[name]; ok {
+			slot.used = old.used
+			slot.acquired = old.acquired
+		}
+	}
+	for name, prov := range providers {
+		if old, ok := p.providers[name]; ok {
+			prov.used = old.used
+		}
+	}
+
+	p.models = models
+	p.providers = providers
+	p.aliases = aliases
+	p.chains = chains
+	for name, prov := range providers {
+		p.quota.SetLimits(name, prov.quota)
+	}
+	p.broadcast()
+}
+
+// Chain liefert die Fallback-Kette für category, sonst die DefaultChain.
+func (p *ModelPool) Chain(category string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	chain, ok := p.chains[category]
+	if !ok {
+		chain = p.chains[DefaultChain]
+	}
+	return append([]string(nil), chain...)
+}
+
+// WatchConfig lädt path neu, sobald sich die Datei ändert, bis ctx endet.
+// onReload wird nach jedem Versuch mit dem Ergebnis aufgerufen.
+func (p *ModelPool) WatchConfig(ctx context.Context, path string, interval time.Duration, onReload func(error)) {
+	var lastMod time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().After(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+
+		cfg, err := config.LoadOpenCodeConfig(path)
+		if err == nil {
+			p.Reconfigure(cfg)
+		}
+		if onReload != nil {
+			onReload(err)
+		}
+	}
+}
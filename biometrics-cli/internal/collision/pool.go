@@ -0,0 +1,322 @@
+package collision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"biometrics-cli/internal/ratelimit"
+)
+
+var (
+	// ErrUnknownModel wird geliefert, wenn kein Modell und kein Alias passt.
+	ErrUnknownModel = errors.New("unknown model")
+	// ErrAtCapacity liefert TryAcquire, wenn Modell oder Provider voll sind.
+	ErrAtCapacity = errors.New("model at capacity")
+)
+
+type modelSlot struct {
+	name     string // <provider>/<key>
+	id       string
+	provider string
+	capacity int64
+	costUnit int
+	used     int64
+	acquired int64
+}
+
+type providerSlot struct {
+	capacity int64 // 0 = unbegrenzt
+	used     int64
+	quota    ratelimit.QuotaLimits
+}
+
+// ModelPool begrenzt parallele Agent-Läufe pro Modell und pro Provider.
+// Kapazitäten sind Kosten-Einheiten: große Prompts belegen mehr als eine.
+// Zusätzlich prüft jede Belegung die Provider-Kontingente (RPM, TPM, pro Tag).
+// Die Konfiguration kann zur Laufzeit per Reconfigure getauscht werden.
+type ModelPool struct {
+	mu        sync.Mutex
+	models    map[string]*modelSlot
+	providers map[string]*providerSlot
+	aliases   map[string]string
+	chains    map[string][]string
+	quota     *ratelimit.Quota
+	// wake wird bei jeder Freigabe geschlossen und ersetzt
+	wake chan struct{}
+}
+
+// Lease ist eine gehaltene Kapazität. Release ist idempotent.
+type Lease struct {
+	pool     *ModelPool
+	model    string
+	provider string
+	weight   int64
+	once     sync.Once
+}
+
+// Model ist der kanonische Name (<provider>/<key>) des belegten Modells.
+func (l *Lease) Model() string {
+	return l.model
+}
+
+// Weight sind die belegten Kosten-Einheiten.
+func (l *Lease) Weight() int64 {
+	return l.weight
+}
+
+// Release gibt die Kapazität frei. MUSS via defer aufgerufen werden!
+// Auf einer nil-Lease ist Release ein No-op.
+func (l *Lease) Release() {
+	if l == nil {
+		return
+	}
+	l.once.Do(func() {
+		l.pool.release(l)
+	})
+}
+
+// Resolve liefert den kanonischen Namen für einen Modellnamen, eine
+// Modell-ID oder einen Alias.
+func (p *ModelPool) Resolve(name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	slot, err := p.resolve(name)
+	if err != nil {
+		return "", err
+	}
+	return slot.name, nil
+}
+
+func (p *ModelPool) resolve(name string) (*modelSlot, error) {
+	if slot, ok := p.models[name]; ok {
+		return slot, nil
+	}
+	if canonical, ok := p.aliases[name]; ok {
+		if slot, ok := p.models[canonical]; ok {
+			return slot, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownModel, name)
+}
+
+// Cost berechnet die Kosten eines Prompts: eine Einheit plus eine pro
+// vollem CostUnitTokens-Block, höchstens die Kapazität von Modell und Provider.
+func (p *ModelPool) Cost(name string, promptTokens int) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	slot, err := p.resolve(name)
+	if err != nil {
+		return 1
+	}
+	weight := int64(1 + promptTokens/slot.costUnit)
+	if weight > slot.capacity {
+		weight = slot.capacity
+	}
+	if prov := p.providers[slot.provider]; prov.capacity > 0 && weight > prov.capacity {
+		weight = prov.capacity
+	}
+	if weight < 1 {
+		weight = 1
+	}
+	return weight
+}
+
+// Acquire blockiert, bis weight Einheiten von Modell und Provider frei sind.
+// Ist ein Provider-Kontingent erschöpft, kehrt Acquire sofort mit einem
+// Fehler zurück, für den errors.Is(err, ratelimit.ErrQuotaExceeded) gilt.
+func (p *ModelPool) Acquire(ctx context.Context, name string, weight int64) (*Lease, error) {
+	return p.acquire(ctx, name, weight, 0)
+}
+
+// AcquirePrompt belegt Kapazität für einen Prompt mit promptTokens Tokens:
+// das Gewicht kommt aus Cost, die Tokens zählen gegen das TPM-Kontingent.
+func (p *ModelPool) AcquirePrompt(ctx context.Context, name string, promptTokens int) (*Lease, error) {
+	return p.acquire(ctx, name, p.Cost(name, promptTokens), promptTokens)
+}
+
+func (p *ModelPool) acquire(ctx context.Context, name string, weight int64, tokens int) (*Lease, error) {
+	for {
+		lease, wake, err := p.tryAcquire(name, weight, tokens)
+		if err == nil {
+			return lease, nil
+		}
+		if !errors.Is(err, ErrAtCapacity) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// TryAcquire belegt Kapazität ohne zu warten oder liefert ErrAtCapacity.
+func (p *ModelPool) TryAcquire(name string, weight int64) (*Lease, error) {
+	lease, _, err := p.tryAcquire(name, weight, 0)
+	return lease, err
+}
+
+func (p *ModelPool) tryAcquire(name string, weight int64, tokens int) (*Lease, <-chan struct{}, error) {
+	if weight < 1 {
+		weight = 1
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	slot, err := p.resolve(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	prov := p.providers[slot.provider]
+
+	if slot.used+weight > slot.capacity || (prov.capacity > 0 && prov.used+weight > prov.capacity) {
+		return nil, p.wake, fmt.Errorf("%w: %s", ErrAtCapacity, slot.name)
+	}
+	if err := p.quota.Reserve(slot.provider, tokens); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", slot.name, err)
+	}
+
+	slot.used += weight
+	slot.acquired++
+	prov.used += weight
+	return &Lease{pool: p, model: slot.name, provider: slot.provider, weight: weight}, nil, nil
+}
+
+func (p *ModelPool) release(l *Lease) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Nach Reconfigure kann das Modell fehlen; dann gibt es nichts freizugeben
+	if slot, ok := p.models[l.model]; ok {
+		slot.used -= l.weight
+		if slot.used < 0 {
+			slot.used = 0
+		}
+	}
+	if prov, ok := p.providers[l.provider]; ok {
+		prov.used -= l.weight
+		if prov.used < 0 {
+			prov.used = 0
+		}
+	}
+	p.broadcast()
+}
+
+// broadcast weckt alle wartenden Acquire-Aufrufe; mu muss gehalten werden.
+func (p *ModelPool) broadcast() {
+	close(p.wake)
+	p.wake = make(chan struct{})
+}
+
+// Fallback liefert den ersten Kandidaten, dessen Provider-Kontingent für
+// promptTokens reicht. Volle Kapazität zählt nicht, darauf kann Acquire
+// warten. Unbekannte Modelle werden übersprungen; ist kein Kandidat erlaubt,
+// kommt der erste Kontingent-Fehler zurück.
+func (p *ModelPool) Fallback(candidates []string, promptTokens int) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var quotaErr error
+	for _, name := range candidates {
+		slot, err := p.resolve(name)
+		if err != nil {
+			continue
+		}
+		if err := p.quota.Check(slot.provider, promptTokens); err != nil {
+			if quotaErr == nil {
+				quotaErr = fmt.Errorf("%s: %w", slot.name, err)
+			}
+			continue
+		}
+		return name, nil
+	}
+	if quotaErr != nil {
+		return "", quotaErr
+	}
+	return "", fmt.Errorf("%w: %v", ErrUnknownModel, candidates)
+}
+
+// ReportThrottle meldet eine 429-Antwort für das Modell name. Sein Provider
+// wird für retryAfter gesperrt und sein RPM-Limit gesenkt.
+func (p *ModelPool) ReportThrottle(name string, retryAfter time.Duration) error {
+	p.mu.Lock()
+	slot, err := p.resolve(name)
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	p.quota.Throttled(slot.provider, retryAfter)
+	return nil
+}
+
+// Quotas liefert den Kontingent-Stand aller Provider.
+func (p *ModelPool) Quotas() []ratelimit.QuotaStatus {
+	return p.quota.Statuses()
+}
+
+// ModelStats beschreibt die Auslastung eines Modells.
+type ModelStats struct {
+	Name             string `json:"name"`
+	ID               string `json:"id"`
+	Provider         string `json:"provider"`
+	Capacity         int64  `json:"capacity"`
+	InUse            int64  `json:"in_use"`
+	Acquired         int64  `json:"acquired"`
+	ProviderCapacity int64  `json:"provider_capacity"`
+	ProviderInUse    int64  `json:"provider_in_use"`
+	// Exhausted ist true, solange das Provider-Kontingent erschöpft ist
+	Exhausted       bool      `json:"exhausted"`
+	ExhaustedReason string    `json:"exhausted_reason,omitempty"`
+	RetryAt         time.Time `json:"retry_at,omitempty"`
+}
+
+// Stats liefert die Auslastung aller Modelle, sortiert nach Namen.
+func (p *ModelPool) Stats() []ModelStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]ModelStats, 0, len(p.models))
+	for _, slot := range p.models {
+		stats = append(stats, p.statLocked(slot))
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// Stat liefert die Auslastung eines Modells.
+func (p *ModelPool) Stat(name string) (ModelStats, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	slot, err := p.resolve(name)
+	if err != nil {
+		return ModelStats{}, err
+	}
+	return p.statLocked(slot), nil
+}
+
+func (p *ModelPool) statLocked(slot *modelSlot) ModelStats {
+	prov := p.providers[slot.provider]
+	quota := p.quota.Status(slot.provider)
+	return ModelStats{
+		Name:             slot.name,
+		ID:               slot.id,
+		Provider:         slot.provider,
+		Capacity:         slot.capacity,
+		InUse:            slot.used,
+		Acquired:         slot.acquired,
+		ProviderCapacity: prov.capacity,
+		ProviderInUse:    prov.used,
+		Exhausted:        quota.Exhausted,
+		ExhaustedReason:  quota.Reason,
+		RetryAt:          quota.RetryAt,
+	}
+}
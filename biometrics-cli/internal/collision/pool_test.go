@@ -0,0 +1,175 @@
+package collision
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"biometrics-cli/internal/config"
+	"biometrics-cli/internal/ratelimit"
+)
+
+func TestResolveAliases(t *testing.T) {
+	p := NewModelPool(nil)
+
+	for _, name := range []string{"qwen-3.5", "qwen3.5", "qwen/qwen3.5-397b-a17b", "nvidia-nim/qwen-3.5-397b"} {
+		got, err := p.Resolve(name)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", name, err)
+		}
+		if got != "nvidia-nim/qwen-3.5-397b" {
+			t.Errorf("Resolve(%q) = %q", name, got)
+		}
+	}
+
+	if _, err := p.Resolve("gpt-99"); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("expected ErrUnknownModel, got %v", err)
+	}
+}
+
+func TestProviderLimitSpansModels(t *testing.T) {
+	p := NewModelPool(&config.OpenCodeConfig{
+		Provider: map[string]config.ProviderConfig{
+			"opencode-zen": {Concurrency: 2},
+		},
+	})
+
+	a, err := p.TryAcquire("minimax", 1)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	b, err := p.TryAcquire("kimi", 1)
+	if err != nil {
+		t.Fatalf("second acquire: %v", err)
+	}
+	if _, err := p.TryAcquire("minimax", 1); !errors.Is(err, ErrAtCapacity) {
+		t.Fatalf("expected provider limit, got %v", err)
+	}
+
+	a.Release()
+	a.Release() // idempotent
+	if _, err := p.TryAcquire("minimax", 1); err != nil {
+		t.Errorf("acquire after release: %v", err)
+	}
+	b.Release()
+
+	stat, _ := p.Stat("minimax")
+	if stat.ProviderInUse != 1 || stat.InUse != 1 {
+		t.Errorf("unexpected stats after release: %+v", stat)
+	}
+}
+
+func TestCostIsCappedByCapacity(t *testing.T) {
+	p := NewModelPool(nil)
+
+	if got := p.Cost("minimax", 1000); got != 1 {
+		t.Errorf("small prompt cost = %d, want 1", got)
+	}
+	if got := p.Cost("minimax", 3*DefaultCostUnitTokens); got != 4 {
+		t.Errorf("large prompt cost = %d, want 4", got)
+	}
+	if got := p.Cost("qwen-3.5", 10*DefaultCostUnitTokens); got != 1 {
+		t.Errorf("cost above capacity = %d, want 1", got)
+	}
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	p := NewModelPool(nil)
+
+	held, err := p.TryAcquire("qwen-3.5", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		lease, err := p.Acquire(ctx, "qwen3.5", 1)
+		if err == nil {
+			lease.Release()
+		}
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	held.Release()
+
+	if err := <-done; err != nil {
+		t.Errorf("waiting acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	held, _ = p.TryAcquire("qwen-3.5", 1)
+	defer held.Release()
+	if _, err := p.Acquire(ctx, "qwen-3.5", 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestReconfigureKeepsUsage(t *testing.T) {
+	p := NewModelPool(nil)
+
+	lease, err := p.TryAcquire("minimax", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Reconfigure(&config.OpenCodeConfig{
+		Provider: map[string]config.ProviderConfig{
+			"opencode-zen": {Models: map[string]config.Model{
+				"minimax-m2.5-free": {ID: "opencode/minimax-m2.5-free", Concurrency: 6},
+			}},
+		},
+	})
+
+	stat, err := p.Stat("minimax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Capacity != 6 || stat.InUse != 5 {
+		t.Errorf("unexpected stats after reconfigure: %+v", stat)
+	}
+	if _, err := p.TryAcquire("minimax", 2); !errors.Is(err, ErrAtCapacity) {
+		t.Errorf("expected ErrAtCapacity, got %v", err)
+	}
+	lease.Release()
+	if stat, _ := p.Stat("minimax"); stat.InUse != 0 {
+		t.Errorf("usage not released: %+v", stat)
+	}
+}
+
+func TestQuotaFallback(t *testing.T) {
+	p := NewModelPool(&config.OpenCodeConfig{
+		Provider: map[string]config.ProviderConfig{
+			"nvidia-nim": {RateLimit: config.RateLimit{RPM: 1}},
+		},
+	})
+
+	lease, err := p.AcquirePrompt(context.Background(), "qwen-3.5", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease.Release()
+
+	if _, err := p.TryAcquire("qwen-3.5", 1); !errors.Is(err, ratelimit.ErrQuotaExceeded) {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+	if stat, _ := p.Stat("qwen-3.5"); !stat.Exhausted {
+		t.Errorf("expected exhausted model in stats: %+v", stat)
+	}
+
+	got, err := p.Fallback([]string{"qwen-3.5", "unknown", "minimax"}, 100)
+	if err != nil || got != "minimax" {
+		t.Errorf("Fallback = %q, %v; want minimax", got, err)
+	}
+
+	if err := p.ReportThrottle("minimax", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Fallback([]string{"qwen-3.5", "kimi"}, 0); !errors.Is(err, ratelimit.ErrQuotaExceeded) {
+		t.Errorf("expected all candidates exhausted, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package collision
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"biometrics-cli/internal/ratelimit"
+)
+
+// Snapshot ist der veröffentlichte Zustand des Pools. Andere Prozesse
+// (API-Server, Dashboard) lesen ihn aus der Datei, die PublishSnapshots schreibt.
+type Snapshot struct {
+	UpdatedAt time.Time               `json:"updated_at"`
+	Models    []ModelStats            `json:"models"`
+	Quotas    []ratelimit.QuotaStatus `json:"quotas"`
+}
+
+// Exhausted liefert alle Modelle mit erschöpftem Provider-Kontingent.
+func (s *Snapshot) Exhausted() []ModelStats {
+	var out []ModelStats
+	for _, m := range s.Models {
+		if m.Exhausted {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Snapshot erfasst Auslastung und Kontingente aller Modelle.
+func (p *ModelPool) Snapshot() Snapshot {
+	return Snapshot{
+		UpdatedAt: time.Now(),
+		Models:    p.Stats(),
+		Quotas:    p.Quotas(),
+	}
+}
+
+// StatusLines beschreibt jedes Modell in einer Zeile, z.B. für das Dashboard.
+func (p *ModelPool) StatusLines() map[string]string {
+	lines := make(map[string]string)
+	for _, m := range p.Stats() {
+		line := fmt.Sprintf("%d/%d in use", m.InUse, m.Capacity)
+		if m.Exhausted {
+			line += fmt.Sprintf(", EXHAUSTED (%s) until %s", m.ExhaustedReason, m.RetryAt.Format("15:04:05"))
+		}
+		lines[m.Name] = line
+	}
+	return lines
+}
+
+// WriteSnapshot schreibt s atomar nach path.
+func WriteSnapshot(path string, s Snapshot) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal model snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write model snapshot: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadSnapshot liest einen mit WriteSnapshot geschriebenen Zustand.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse model snapshot %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// PublishSnapshots schreibt den Zustand alle interval nach path, bis ctx endet.
+func (p *ModelPool) PublishSnapshots(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := WriteSnapshot(path, p.Snapshot()); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -27,6 +27,19 @@ type ProviderConfig struct {
 	Enabled  bool              `json:"enabled"`
 	APIKey   string            `json:"api_key,omitempty"`
 	BaseURL  string            `json:"base_url,omitempty"`
+	// Concurrency limits the concurrent runs across all models of the
+	// provider; 0 means no provider-wide limit.
+	Concurrency int `json:"concurrency,omitempty"`
+	// RateLimit holds the provider's request and token quotas.
+	RateLimit RateLimit `json:"rate_limit,omitempty"`
+}
+
+// RateLimit describes provider quotas; 0 means unlimited.
+type RateLimit struct {
+	RPM           int `json:"rpm,omitempty"`
+	TPM           int `json:"tpm,omitempty"`
+	DailyRequests int `json:"daily_requests,omitempty"`
+	DailyTokens   int `json:"daily_tokens,omitempty"`
 }
 
 type Model struct {
@@ -35,6 +48,13 @@ type Model struct {
 	Limit      ModelLimit                        `json:"limit"`
 	Modalities map[string][]string               `json:"modalities,omitempty"`
 	Variants   map[string]map[string]interface{} `json:"variants,omitempty"`
+	// Concurrency is the capacity of the model in cost units (default 1).
+	Concurrency int `json:"concurrency,omitempty"`
+	// CostUnitTokens: every started block of this many prompt tokens costs
+	// one more unit of capacity.
+	CostUnitTokens int `json:"cost_unit_tokens,omitempty"`
+	// Aliases are additional names the model can be requested by.
+	Aliases []string `json:"aliases,omitempty"`
 }
 
 type ModelLimit struct {
@@ -46,6 +66,9 @@ type OpenCodeConfig struct {
 	Provider map[string]ProviderConfig `json:"provider"`
 	MCP      map[string]MCPConfig      `json:"mcp,omitempty"`
 	Plugin   []string                  `json:"plugin,omitempty"`
+	// Fallback maps a task category to the models tried in order when an
+	// agent run fails; the "default" chain covers unlisted categories.
+	Fallback map[string][]string `json:"fallback,omitempty"`
 }
 
 type MCPConfig struct {
@@ -307,8 +330,13 @@ func LoadOpenCodeConfig(path string) (*OpenCodeConfig, error) {
 	return &config, nil
 }
 
+// DefaultOpenCodeConfigPath is the user's OpenCode configuration file.
+func DefaultOpenCodeConfigPath() string {
+	return os.ExpandEnv("$HOME/.config/opencode/opencode.json")
+}
+
 func GetProviderConfig(name string) (*ProviderConfig, error) {
-	path := os.ExpandEnv("$HOME/.config/opencode/opencode.json")
+	path := DefaultOpenCodeConfigPath()
 	config, err := LoadOpenCodeConfig(path)
 	if err != nil {
 		return nil, err
@@ -336,8 +364,43 @@ func GetModelConfig(providerName, modelName string) (*Model, error) {
 	return &model, nil
 }
 
+// DefaultModelLimit is assumed for models without a limit in opencode.json.
+var DefaultModelLimit = ModelLimit{Context: 128000, Output: 8192}
+
+// ModelLimit returns the limit of a model. modelID may be the bare model key
+// or prefixed with its provider ("provider/model").
+func (c *OpenCodeConfig) ModelLimit(modelID string) (ModelLimit, bool) {
+	for providerName, provider := range c.Provider {
+		for key, model := range provider.Models {
+			if key == modelID || model.ID == modelID || providerName+"/"+key == modelID {
+				if model.Limit.Context > 0 {
+					return model.Limit, true
+				}
+			}
+		}
+	}
+	return ModelLimit{}, false
+}
+
+// LookupModelLimit reads the limit of modelID from the user's opencode.json
+// and falls back to DefaultModelLimit.
+func LookupModelLimit(modelID string) ModelLimit {
+	path := DefaultOpenCodeConfigPath()
+	config, err := LoadOpenCodeConfig(path)
+	if err != nil {
+		return DefaultModelLimit
+	}
+	if limit, ok := config.ModelLimit(modelID); ok {
+		if limit.Output <= 0 {
+			limit.Output = DefaultModelLimit.Output
+		}
+		return limit
+	}
+	return DefaultModelLimit
+}
+
 func GetMCPConfig(name string) (*MCPConfig, error) {
-	path := os.ExpandEnv("$HOME/.config/opencode/opencode.json")
+	path := DefaultOpenCodeConfigPath()
 	config, err := LoadOpenCodeConfig(path)
 	if err != nil {
 		return nil, err
@@ -352,7 +415,7 @@ func GetMCPConfig(name string) (*MCPConfig, error) {
 }
 
 func init() {
-	configPath := os.ExpandEnv("$HOME/.config/opencode/opencode.json")
+	configPath := DefaultOpenCodeConfigPath()
 	if err := GlobalConfig.Load(configPath); err != nil {
 		state.GlobalState.Log("WARN", fmt.Sprintf("Failed to load config: %v", err))
 	}
@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"biometrics-cli/internal/heartbeat"
+	"biometrics-cli/internal/orchestrator"
+	"biometrics-cli/internal/project"
+	"biometrics-cli/internal/telemetry"
+)
+
+// defaultCategory gilt für Tasks ohne Kategorie, wie im taskRunner.
+const defaultCategory = "build"
+
+// AgentSpec ist ein Agent-Worker. Skills sind die Kategorien und Fähigkeiten,
+// deren Tasks er ausführen und von anderen Agenten stehlen darf.
+type AgentSpec struct {
+	Name   string   `json:"name"`
+	Skills []string `json:"skills"`
+}
+
+// DefaultAgents ist die Standardbesetzung, wenn Config.Agents leer ist.
+var DefaultAgents = []AgentSpec{
+	{Name: "sisyphus", Skills: []string{"build", "deep", "quick", "coding"}},
+	{Name: "atlas", Skills: []string{"build", "deep", "heavy", "coding"}},
+	{Name: "prometheus", Skills: []string{"planning", "deep"}},
+	{Name: "librarian", Skills: []string{"explore", "research", "documentation", "writing"}},
+	{Name: "explore", Skills: []string{"explore", "quick", "research"}},
+}
+
+// queuedTask ist die Nutzlast eines WorkStealer-Tasks.
+type queuedTask struct {
+	key     string
+	project string
+	task    *project.Task
+	release func()
+}
+
+// enqueue legt einen geclaimten Task in die Queue seines Agenten: des
+// gepinnten, sonst des am wenigsten belasteten passenden.
+func (d *Daemon) enqueue(projID string, task *project.Task, release func()) {
+	category := task.Category
+	if category == "" {
+		category = defaultCategory
+	}
+	wt := &orchestrator.Task{
+		ID:        projID + "/" + task.ID,
+		Priority:  task.Priority,
+		Category:  category,
+		Skills:    task.Skills,
+		CreatedAt: time.Now(),
+		Payload:   &queuedTask{key: projID + "/" + task.ID, project: projID, task: task, release: release},
+	}
+
+	home := ""
+	if d.isAgent(task.Agent) {
+		home = task.Agent
+		wt.AgentID = home
+		wt.Pinned = true
+	} else {
+		for _, a := range d.cfg.Agents {
+			if d.stealer.CanRun(a.Name, wt) && (home == "" || d.stealer.GetLoad(a.Name) < d.stealer.GetLoad(home)) {
+				home = a.Name
+			}
+		}
+		if home == "" {
+			// Kein Agent deckt den Task ab: der erste führt ihn trotzdem aus
+			home = d.cfg.Agents[0].Name
+			wt.AgentID = home
+			wt.Pinned = true
+			d.logger.Warn("No agent has the skills for task",
+				slog.String("task_id", task.ID),
+				slog.String("category", category),
+				slog.String("agent", home),
+			)
+		}
+	}
+
+	d.mu.Lock()
+	d.active[wt.ID] = ActiveTask{
+		Project:  projID,
+		TaskID:   task.ID,
+		Category: task.Category,
+		Agent:    home,
+		Attempt:  task.Attempts,
+		Queued:   true,
+	}
+	d.mu.Unlock()
+
+	if err := d.stealer.Enqueue(home, wt); err != nil {
+		d.logger.Error("Enqueue failed", slog.String("task_id", task.ID), slog.String("error", err.Error()))
+		d.requeue(wt)
+		return
+	}
+	d.wakeAgents()
+}
+
+func (d *Daemon) isAgent(name string) bool {
+	for _, a := range d.cfg.Agents {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// wakeAgents weckt alle Agent-Worker, ohne zu blockieren.
+func (d *Daemon) wakeAgents() {
+	for _, ch := range d.agentWake {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// agentLoop arbeitet die eigene Queue ab und stiehlt passende Tasks von
+// überlasteten Agenten, bis ctx endet.
+func (d *Daemon) agentLoop(ctx context.Context, agent string) {
+	defer d.workers.Done()
+
+	if d.cfg.Heartbeats != nil {
+		d.cfg.Heartbeats.RegisterAgent(agent, "", "")
+	}
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if wt, err := d.stealer.Take(agent); err == nil {
+			d.beat(agent, heartbeat.StatusBusy, wt.ID)
+			d.execute(ctx, agent, wt)
+			if d.cfg.Heartbeats != nil {
+				d.cfg.Heartbeats.IncrementTasksDone(agent)
+			}
+			continue
+		}
+		d.beat(agent, heartbeat.StatusIdle, "")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.agentWake[agent]:
+		case <-ticker.C:
+		}
+	}
+}
+
+// beat meldet den Zustand eines Agent-Workers an Config.Heartbeats.
+func (d *Daemon) beat(agent string, status heartbeat.Status, task string) {
+	if d.cfg.Heartbeats == nil {
+		return
+	}
+	if err := d.cfg.Heartbeats.Beat(agent, status, task); err != nil {
+		d.logger.Warn("Heartbeat failed", slog.String("agent", agent), slog.String("error", err.Error()))
+	}
+}
+
+// logHeartbeatAlerts schreibt die Alerts des Monitors ins Log. Ohne Leser
+// blockiert der Monitor, sobald sein Puffer voll ist.
+func (d *Daemon) logHeartbeatAlerts(ctx context.Context) {
+	alerts := d.cfg.Heartbeats.GetAlerts()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-alerts:
+			d.logger.Warn("Agent heartbeat alert",
+				slog.String("agent", a.AgentID),
+				slog.String("type", a.Type),
+				slog.String("message", a.Message),
+			)
+		}
+	}
+}
+
+func (d *Daemon) execute(ctx context.Context, agent string, wt *orchestrator.Task) {
+	q := wt.Payload.(*queuedTask)
+	if ctx.Err() != nil {
+		d.requeue(wt)
+		return
+	}
+
+	d.mu.Lock()
+	a := d.active[q.key]
+	a.Agent = agent
+	a.Queued = false
+	a.StartedAt = time.Now()
+	d.active[q.key] = a
+	d.mu.Unlock()
+
+	// Der ausführende Agent wird an OpenCode übergeben, auch wenn er den
+	// Task gestohlen hat
+	task := *q.task
+	task.Agent = agent
+
+	taskCtx, traceID := telemetry.InjectTraceID(ctx)
+	d.logger.Info("Executing Task",
+		slog.String("project", q.project),
+		slog.String("task_id", task.ID),
+		slog.String("agent", agent),
+		slog.Int("attempt", task.Attempts),
+		slog.String("trace_id", traceID),
+	)
+	status := d.runner.run(taskCtx, q.project, &task)
+	d.done(q, status)
+}
+
+// requeue gibt einen nicht gestarteten Task an den Store zurück.
+func (d *Daemon) requeue(wt *orchestrator.Task) {
+	q := wt.Payload.(*queuedTask)
+	status := d.runner.finish(q.project, q.task, project.StatusPending, "")
+	d.done(q, status)
+}
+
+func (d *Daemon) done(q *queuedTask, status project.TaskStatus) {
+	q.release()
+
+	d.mu.Lock()
+	delete(d.active, q.key)
+	if status != "" {
+		d.finished[status]++
+	}
+	d.mu.Unlock()
+	d.notify()
+}
@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"database/sql"
+	"io"
+	"log/slog"
+	"time"
+
+	"biometrics-cli/internal/durable"
+	"biometrics-cli/internal/heartbeat"
+	"biometrics-cli/internal/orchestrator"
+	"biometrics-cli/internal/scheduler"
+	"biometrics-cli/internal/session"
+	"biometrics-cli/internal/skills"
+	"biometrics-cli/internal/state"
+	"biometrics-cli/internal/telemetry"
+	"biometrics-cli/internal/workspace"
+	"biometrics-cli/pkg/logging"
+)
+
+// Vorgaben für das Daemon-Log
+const (
+	logMaxSize    = 50 << 20
+	logMaxBackups = 7
+	logMaxAge     = 14
+)
+
+// BootstrapOptions sind die Einstellungen des Hosts für Bootstrap.
+type BootstrapOptions struct {
+	Workers int
+	// Console erhält das Log zusätzlich zur Logdatei, nil schreibt nur in
+	// die Datei
+	Console io.Writer
+}
+
+// Bootstrap baut den Daemon samt allem, was ein Daemon-Prozess im Workspace
+// öffnet: Logdatei, Log-Datenbank samt Todos, Heartbeats, Wartungsjobs mit
+// Historie, Journal samt Idempotenz-Schlüsseln, Sagas und Webhook-Outbox.
+// Was sich nicht öffnen lässt, wird mit einer Warnung weggelassen. Der Logger
+// wird zum slog-Default. closeAll schließt alles wieder und ist auch bei
+// einem Fehler gesetzt.
+func Bootstrap(ws workspace.Workspace, opts BootstrapOptions) (d *Daemon, closeAll func(), err error) {
+	var closers []func() error
+	closeAll = func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+
+	out := opts.Console
+	logFile, logErr := openLog(ws)
+	if logErr == nil {
+		closers = append(closers, logFile.Close)
+		if out != nil {
+			out = io.MultiWriter(out, logFile)
+		} else {
+			out = logFile
+		}
+	}
+	if out == nil {
+		out = io.Discard
+	}
+	logger := telemetry.SetupLoggerTo(out)
+	if logErr != nil {
+		logger.Warn("Running without log file", slog.String("error", logErr.Error()))
+	}
+
+	if err := state.GlobalState.InitDB(ws.LogsDB()); err != nil {
+		logger.Warn("Running without log database", slog.String("error", err.Error()))
+	} else {
+		closers = append(closers, state.GlobalState.DB.Close)
+		if err := useTodoStore(state.GlobalState.DB); err != nil {
+			logger.Warn("Todos are kept in memory only", slog.String("error", err.Error()))
+		}
+	}
+	session.SessionManager.SetPath(ws.SessionsPath())
+	if err := skills.LoadGeneratedSkills(ws.GeneratedSkillsPath()); err != nil {
+		logger.Warn("Ignoring generated skills", slog.String("error", err.Error()))
+	}
+
+	heartbeats := newHeartbeatMonitor(ws, 0)
+	sagas := durable.NewSagaExecutor(durable.NewFileSagaStore(ws.SagasDir()), NewSagaRegistry())
+	env := scheduler.NewWorkspaceEnv(ws)
+	env.Heartbeats = heartbeats
+	env.Sagas = sagas
+	if logFile != nil {
+		env.LogWriters = append(env.LogWriters, logFile)
+	}
+	scheduler.RegisterDefaultJobs(env)
+	if err := scheduler.Sched.UseStore(scheduler.NewFileHistoryStore(ws.JobsPath())); err != nil {
+		logger.Warn("Failed to load job history", slog.String("error", err.Error()))
+	}
+
+	journal, err := OpenJournal(ws)
+	if err != nil {
+		logger.Warn("Running without journal, interrupted tasks cannot be resumed", slog.String("error", err.Error()))
+	} else {
+		closers = append(closers, journal.Close)
+	}
+
+	keys, err := LoadIdempotencyKeys(ws)
+	if err != nil {
+		logger.Warn("Using default idempotency keys", slog.String("error", err.Error()))
+	}
+
+	webhooks, err := OpenWebhooks(ws, logger)
+	if err != nil {
+		logger.Warn("Webhooks are delivered without outbox", slog.String("error", err.Error()))
+	} else {
+		closers = append(closers, webhooks.Close)
+	}
+
+	d, err = New(Config{
+		Workspace:       ws,
+		Workers:         opts.Workers,
+		Logger:          logger,
+		Jobs:            scheduler.Sched,
+		Journal:         journal,
+		IdempotencyKeys: keys,
+		Sagas:           sagas,
+		Webhooks:        webhooks,
+		Heartbeats:      heartbeats,
+	})
+	return d, closeAll, err
+}
+
+// useTodoStore lädt die Todos des Dashboards aus der Log-Datenbank und
+// speichert dort jede Änderung.
+func useTodoStore(db *sql.DB) error {
+	store, err := orchestrator.NewSQLiteTodoStore(db)
+	if err != nil {
+		return err
+	}
+	return orchestrator.DefaultOrchestrator.UseStore(store)
+}
+
+// openLog öffnet das rotierende Daemon-Log im Workspace. Rotiert wird über
+// den Job metrics-rotate und beim Erreichen der Maximalgröße.
+func openLog(ws workspace.Workspace) (*logging.RotatingWriter, error) {
+	return logging.NewRotatingWriter(ws.DaemonLogPath(), logging.RotationConfig{
+		Enabled:    true,
+		MaxSize:    logMaxSize,
+		MaxBackups: logMaxBackups,
+		MaxAge:     logMaxAge,
+		Compress:   true,
+	})
+}
+
+// newHeartbeatMonitor erstellt den Monitor für Config.Heartbeats. Ein
+// Agent-Worker ohne Task meldet sich jede pollInterval; bleibt das länger
+// als das Zehnfache aus, gilt er als tot.
+func newHeartbeatMonitor(ws workspace.Workspace, pollInterval time.Duration) *heartbeat.Monitor {
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+	return heartbeat.NewHeartbeatMonitor(&heartbeat.HeartbeatConfig{
+		Interval: pollInterval,
+		Timeout:  10 * pollInterval,
+		Dir:      ws.HeartbeatsDir(),
+	})
+}
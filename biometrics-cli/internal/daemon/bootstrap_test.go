@@ -0,0 +1,143 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"biometrics-cli/internal/opencode"
+	"biometrics-cli/internal/orchestrator"
+	"biometrics-cli/internal/project"
+	"biometrics-cli/internal/quality"
+	"biometrics-cli/internal/webhook"
+	"biometrics-cli/internal/workspace"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestBootstrapOpensWorkspace(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		webhook.SetDefaultOutbox(nil)
+	})
+
+	ws := workspace.New(t.TempDir())
+	d, closeAll, err := Bootstrap(ws, BootstrapOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll()
+
+	if d.cfg.Workers != 2 || d.cfg.Journal == nil || d.cfg.Webhooks == nil || d.cfg.Heartbeats == nil || d.cfg.Jobs == nil || d.cfg.Sagas == nil {
+		t.Errorf("Bootstrap left subsystems unset: %+v", d.cfg)
+	}
+	for _, path := range []string{ws.DaemonLogPath(), ws.WebhooksDB(), ws.HeartbeatsDir(), ws.SagasDir(), filepath.Dir(ws.JournalDir())} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s: %v", path, err)
+		}
+	}
+}
+
+func TestBootstrapRestoresTodos(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		webhook.SetDefaultOutbox(nil)
+	})
+
+	ws := workspace.New(t.TempDir())
+	_, closeAll, err := Bootstrap(ws, BootstrapOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	todos := orchestrator.DefaultOrchestrator
+	todos.AutoCreateTodos()
+	failing := todos.GetNextTask()
+	todos.FailTask(failing.ID, "exit status 1")
+	todos.GetNextTask()
+	closeAll()
+
+	// Neustart: die Todos kommen aus der Log-Datenbank, der laufende zurück
+	// auf pending
+	_, closeAll, err = Bootstrap(ws, BootstrapOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll()
+	stats := todos.GetStats()
+	if stats["persistent"] != true || stats["total_tasks"] != 3 || stats["pending"] != 3 || stats["retries"] != 1 {
+		t.Errorf("stats after restart = %v, want 3 pending todos with 1 retry", stats)
+	}
+}
+
+func TestBootstrapLoadsIdempotencyKeys(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		webhook.SetDefaultOutbox(nil)
+	})
+
+	ws := workspace.New(t.TempDir())
+	// Der Agent gilt über alle Claims des Tasks hinweg als gelaufen
+	keys := `{"agent": "{project}/{task}/{model}/agent"}`
+	if err := os.WriteFile(ws.IdempotencyKeysPath(), []byte(keys), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "done"), nil, 0644)
+	dir := ws.ProjectDir("demo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	boulder := `{"workdir":"` + workDir + `","tasks":[{"id":"t1","description":"do it","status":"pending"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "boulder.json"), []byte(boulder), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pipeline := "stages:\n  - kind: command\n    command: [\"test\", \"-f\", \"done\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, quality.PipelineFile), []byte(pipeline), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, closeAll, err := Bootstrap(ws, BootstrapOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll()
+	if got := d.cfg.IdempotencyKeys[PhaseAgent]; got != "{project}/{task}/{model}/agent" {
+		t.Fatalf("agent key = %q, want the override", got)
+	}
+	backend := &scriptedBackend{}
+	runner := d.runner
+	runner.executor = opencode.NewExecutorWithBackend(slog.New(slog.NewTextHandler(io.Discard, nil)), backend)
+	runner.gate = quality.NewGate(runner.executor, nil)
+
+	// Erster Claim: der Agent ist gelaufen, dann ging der Claim verloren
+	task, err := runner.store.Claim("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := runner.pool.Chain("build")[0]
+	jr := newJournalRun(d.cfg.Journal, runner.keys, runner.logger, "demo", task)
+	jr.step(PhaseAgent, model, func() (map[string]interface{}, error) {
+		return map[string]interface{}{"success": true, "model": model, "transcript": "t.jsonl"}, nil
+	})
+	if err := runner.store.Transition("demo", task.ID, task.Attempts, project.StatusPending, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Zweiter Claim: ohne {attempt} im Schlüssel übernimmt er das Ergebnis
+	task, err = runner.store.Claim("demo")
+	if err != nil || task.Attempts != 2 {
+		t.Fatalf("reclaim = %+v, %v", task, err)
+	}
+	if status := runner.run(context.Background(), "demo", task); status != project.StatusCompleted {
+		t.Fatalf("second claim ended %s", status)
+	}
+	if len(backend.models) != 0 {
+		t.Errorf("agent ran again for %v although its key ignores the attempt", backend.models)
+	}
+}
@@ -0,0 +1,289 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"biometrics-cli/internal/scheduler"
+)
+
+// ErrAlreadyRunning: auf dem Control-Socket antwortet bereits ein Daemon.
+var ErrAlreadyRunning = errors.New("daemon already running")
+
+// Handler ist die Control-API:
+//
+//	GET  /status   Status
+//	POST /pause    keine neuen Tasks
+//	POST /resume   wieder Tasks claimen
+//	POST /drain    laufende Tasks beenden, dann stoppen
+//
+// Jede Antwort ist der Status nach der Aktion als JSON. Mit Config.Jobs
+// kommen die Wartungsjobs hinzu, jeweils als scheduler.JobInfo:
+//
+//	GET  /jobs?runs=N        alle Jobs mit den nächsten N Läufen
+//	GET  /jobs/{id}          ein Job samt Laufhistorie
+//	POST /jobs/{id}/trigger  sofort ausführen
+//	POST /jobs/{id}/enable   anschalten
+//	POST /jobs/{id}/disable  abschalten
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		d.writeStatus(w)
+	})
+	for path, action := range map[string]func() error{
+		"/pause":  d.Pause,
+		"/resume": d.Resume,
+		"/drain":  d.Drain,
+	} {
+		action := action
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if err := action(); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			d.writeStatus(w)
+		})
+	}
+
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		jobs, ok := d.jobs(w)
+		if !ok {
+			return
+		}
+		runs := 5
+		if v := r.URL.Query().Get("runs"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > 100 {
+				http.Error(w, "runs must be between 0 and 100", http.StatusBadRequest)
+				return
+			}
+			runs = n
+		}
+		writeJSON(w, jobs.Describe(runs))
+	})
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		d.writeJob(w, r.PathValue("id"))
+	})
+	for action, fn := range map[string]func(*scheduler.Scheduler, string) error{
+		"trigger": (*scheduler.Scheduler).Trigger,
+		"enable":  (*scheduler.Scheduler).EnableJob,
+		"disable": (*scheduler.Scheduler).DisableJob,
+	} {
+		fn := fn
+		mux.HandleFunc("POST /jobs/{id}/"+action, func(w http.ResponseWriter, r *http.Request) {
+			jobs, ok := d.jobs(w)
+			if !ok {
+				return
+			}
+			id := r.PathValue("id")
+			if err := fn(jobs, id); err != nil {
+				code := http.StatusConflict
+				if _, lookupErr := jobs.GetJob(id); lookupErr != nil {
+					code = http.StatusNotFound
+				}
+				http.Error(w, err.Error(), code)
+				return
+			}
+			d.writeJob(w, id)
+		})
+	}
+	return mux
+}
+
+// jobs liefert den Scheduler oder antwortet mit 404, wenn es keinen gibt.
+func (d *Daemon) jobs(w http.ResponseWriter) (*scheduler.Scheduler, bool) {
+	if d.cfg.Jobs == nil {
+		http.Error(w, "daemon runs no scheduler", http.StatusNotFound)
+		return nil, false
+	}
+	return d.cfg.Jobs, true
+}
+
+func (d *Daemon) writeJob(w http.ResponseWriter, id string) {
+	jobs, ok := d.jobs(w)
+	if !ok {
+		return
+	}
+	info, err := jobs.Info(id, 5)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (d *Daemon) writeStatus(w http.ResponseWriter) {
+	writeJSON(w, d.Status())
+}
+
+// serveControl lauscht auf dem Unix-Socket. Ein verwaister Socket eines
+// abgestürzten Daemons wird entfernt, ein lebender führt zu ErrAlreadyRunning.
+func (d *Daemon) serveControl() (stop func(), err error) {
+	path := d.cfg.SocketPath
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket dir: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: %s", ErrAlreadyRunning, path)
+		}
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict %s: %w", path, err)
+	}
+
+	server := &http.Server{Handler: d.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			d.logger.Error("Control API stopped", slog.String("error", err.Error()))
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+		os.Remove(path)
+	}, nil
+}
+
+// Client spricht die Control-API eines laufenden Daemons an.
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+// NewClient erstellt einen Client für den Unix-Socket socketPath.
+func NewClient(socketPath string) *Client {
+	return &Client{
+		socket: socketPath,
+		http: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+// Status fragt den aktuellen Status ab.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	return c.do(ctx, http.MethodGet, "/status")
+}
+
+// Pause hält das Claimen neuer Tasks an.
+func (c *Client) Pause(ctx context.Context) (*Status, error) {
+	return c.do(ctx, http.MethodPost, "/pause")
+}
+
+// Resume nimmt das Claimen wieder auf.
+func (c *Client) Resume(ctx context.Context) (*Status, error) {
+	return c.do(ctx, http.MethodPost, "/resume")
+}
+
+// Drain beendet den Daemon, sobald alle laufenden Tasks fertig sind.
+func (c *Client) Drain(ctx context.Context) (*Status, error) {
+	return c.do(ctx, http.MethodPost, "/drain")
+}
+
+// Jobs listet die Wartungsjobs mit den nächsten runs Läufen.
+func (c *Client) Jobs(ctx context.Context, runs int) ([]scheduler.JobInfo, error) {
+	var jobs []scheduler.JobInfo
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/jobs?runs=%d", runs), &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Job liefert einen Wartungsjob samt Laufhistorie.
+func (c *Client) Job(ctx context.Context, id string) (*scheduler.JobInfo, error) {
+	return c.job(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id))
+}
+
+// TriggerJob startet einen Job sofort.
+func (c *Client) TriggerJob(ctx context.Context, id string) (*scheduler.JobInfo, error) {
+	return c.job(ctx, http.MethodPost, "/jobs/"+url.PathEscape(id)+"/trigger")
+}
+
+// EnableJob schaltet einen Job dauerhaft an.
+func (c *Client) EnableJob(ctx context.Context, id string) (*scheduler.JobInfo, error) {
+	return c.job(ctx, http.MethodPost, "/jobs/"+url.PathEscape(id)+"/enable")
+}
+
+// DisableJob schaltet einen Job dauerhaft ab.
+func (c *Client) DisableJob(ctx context.Context, id string) (*scheduler.JobInfo, error) {
+	return c.job(ctx, http.MethodPost, "/jobs/"+url.PathEscape(id)+"/disable")
+}
+
+func (c *Client) job(ctx context.Context, method, path string) (*scheduler.JobInfo, error) {
+	var info scheduler.JobInfo
+	if err := c.call(ctx, method, path, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string) (*Status, error) {
+	var st Status
+	if err := c.call(ctx, method, path, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (c *Client) call(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, "http://daemon"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("daemon not reachable at %s: %w", c.socket, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("daemon %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode daemon response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,469 @@
+// Package daemon ist der eine, langlebige Orchestrator: er findet Projekte,
+// claimt Tasks aus den boulder.json Dateien, führt sie über die
+// Fallback-Kette mit Quality Gate aus und ist über eine lokale Control-API
+// steuerbar (pause, resume, drain, status).
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"biometrics-cli/internal/collision"
+	"biometrics-cli/internal/config"
+	"biometrics-cli/internal/durable"
+	"biometrics-cli/internal/heartbeat"
+	"biometrics-cli/internal/opencode"
+	"biometrics-cli/internal/orchestrator"
+	"biometrics-cli/internal/project"
+	"biometrics-cli/internal/quality"
+	"biometrics-cli/internal/scheduler"
+	"biometrics-cli/internal/webhook"
+	"biometrics-cli/internal/workspace"
+)
+
+// State ist der Betriebszustand des Daemons.
+type State string
+
+const (
+	// StateRunning: neue Tasks werden geclaimt
+	StateRunning State = "running"
+	// StatePaused: keine neuen Tasks, laufende arbeiten weiter
+	StatePaused State = "paused"
+	// StateDraining: keine neuen Tasks, Run endet, sobald alle fertig sind
+	StateDraining State = "draining"
+	// StateStopped: Run ist beendet
+	StateStopped State = "stopped"
+)
+
+const (
+	DefaultPollInterval = 5 * time.Second
+	DefaultWorkers      = 1
+)
+
+// Config konfiguriert den Daemon. Nur Workspace ist Pflicht.
+type Config struct {
+	Workspace workspace.Workspace
+	// Workers begrenzt geclaimte Tasks (wartend und laufend); pro Projekt gilt
+	// zusätzlich dessen Budget aus der boulder.json
+	Workers int
+	// Agents sind die Agent-Worker mit eigener Queue, Standard DefaultAgents
+	Agents []AgentSpec
+	// PollInterval ist der Abstand zwischen zwei Runden ohne Ereignis
+	PollInterval time.Duration
+	// ModelConfigPath ist die OpenCode-Konfiguration für den Model-Pool
+	ModelConfigPath string
+	// SocketPath ist der Unix-Socket der Control-API
+	SocketPath string
+	Logger     *slog.Logger
+	// Backend überschreibt das Agent-Backend aus der Umgebung
+	Backend opencode.AgentBackend
+	// Aging ist die Wartezeit, nach der ein Projekt vorgezogen wird
+	Aging time.Duration
+	// Jobs ist der Scheduler für Wartungsjobs, den der Daemon mit ausführt
+	// und über die Control-API steuerbar macht
+	Jobs *scheduler.Scheduler
+	// Journal hält jede Phase eines Task-Laufs fest; beim Start setzt der
+	// Daemon unterbrochene Läufe daraus fort
+	Journal *durable.Journal
+	// IdempotencyKeys überschreibt DefaultIdempotencyKeys je Phase;
+	// Bootstrap lädt sie mit LoadIdempotencyKeys
+	IdempotencyKeys map[string]string
+	// Sagas setzt der Daemon beim Start fort oder rollt sie zurück, bevor er
+	// Tasks verteilt; die Handler kommen aus NewSagaRegistry
+	Sagas *durable.SagaExecutor
+	// Webhooks stellt Ereignisse aus der Outbox zu, solange der Daemon läuft
+	Webhooks *webhook.Outbox
+	// Heartbeats erhält von jedem Agent-Worker einen Heartbeat, idle beim
+	// Warten und busy mit dem laufenden Task
+	Heartbeats *heartbeat.Monitor
+}
+
+func (c *Config) applyDefaults() {
+	if c.Workers <= 0 {
+		c.Workers = DefaultWorkers
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	if c.ModelConfigPath == "" {
+		c.ModelConfigPath = config.DefaultOpenCodeConfigPath()
+	}
+	if c.SocketPath == "" {
+		c.SocketPath = c.Workspace.ControlSocketPath()
+	}
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+	if len(c.Agents) == 0 {
+		c.Agents = DefaultAgents
+	}
+}
+
+// ActiveTask ist ein geclaimter Task, wartend in der Queue seines Agenten
+// oder laufend.
+type ActiveTask struct {
+	Project   string    `json:"project"`
+	TaskID    string    `json:"task_id"`
+	Category  string    `json:"category,omitempty"`
+	Agent     string    `json:"agent"`
+	Attempt   int       `json:"attempt"`
+	Queued    bool      `json:"queued,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+}
+
+// Status ist die Antwort der Control-API.
+type Status struct {
+	State     State        `json:"state"`
+	StartedAt time.Time    `json:"started_at"`
+	Workspace string       `json:"workspace"`
+	Workers   int          `json:"workers"`
+	Active    []ActiveTask `json:"active"`
+	// Finished zählt beendete Läufe nach Endstatus
+	Finished map[project.TaskStatus]int `json:"finished"`
+	Models   []collision.ModelStats     `json:"models"`
+	// Projects zeigt Anteil und Anspruch je Projekt
+	Projects map[string]project.ShareStats `json:"projects"`
+	// Agents ist die Queue-Länge je Agent
+	Agents map[string]int `json:"agents"`
+}
+
+// Daemon vereint Projekt-Discovery, Scheduling, Ausführung, Quality Gates
+// und Persistenz in einem Prozess.
+type Daemon struct {
+	cfg     Config
+	logger  *slog.Logger
+	store   *project.FileTaskStore
+	pool    *collision.ModelPool
+	runner  *taskRunner
+	fair    *project.FairScheduler
+	stealer *orchestrator.WorkStealer
+
+	mu        sync.Mutex
+	state     State
+	startedAt time.Time
+	active    map[string]ActiveTask // nach Projekt/Task
+	finished  map[project.TaskStatus]int
+	wake      chan struct{}
+	agentWake map[string]chan struct{}
+	workers   sync.WaitGroup
+}
+
+// New baut den Daemon mit allen Abhängigkeiten aus cfg.
+func New(cfg Config) (*Daemon, error) {
+	cfg.applyDefaults()
+	logger := cfg.Logger
+
+	pool, err := collision.LoadModelPool(cfg.ModelConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load model pool: %w", err)
+	}
+
+	backend := cfg.Backend
+	if backend == nil {
+		if backend, err = opencode.NewBackendFromEnv(logger); err != nil {
+			return nil, fmt.Errorf("failed to select agent backend: %w", err)
+		}
+	}
+	executor := opencode.NewExecutorWithBackend(logger, backend)
+	executor.SetTranscriptStore(opencode.NewFileTranscriptStore(cfg.Workspace.TranscriptsDir()))
+
+	basePath := cfg.Workspace.PlansDir()
+	store := project.NewFileTaskStore(basePath)
+
+	stealer := orchestrator.NewWorkStealer(1, cfg.Workers, 0)
+	agentWake := make(map[string]chan struct{}, len(cfg.Agents))
+	for _, a := range cfg.Agents {
+		stealer.RegisterAgent(a.Name, a.Skills...)
+		agentWake[a.Name] = make(chan struct{}, 1)
+	}
+
+	return &Daemon{
+		cfg:     cfg,
+		stealer: stealer,
+		logger:  logger,
+		store:   store,
+		pool:    pool,
+		fair:    project.NewFairScheduler(cfg.Aging),
+		runner: &taskRunner{
+			logger:   logger,
+			store:    store,
+			pool:     pool,
+			executor: executor,
+			gate:     quality.NewGate(executor, quality.NewReportStore(cfg.Workspace.QualityReportsDir())),
+			basePath: basePath,
+			backoff:  fallbackBackoff,
+			journal:  cfg.Journal,
+			keys:     cfg.IdempotencyKeys,
+		},
+		state:     StateRunning,
+		active:    make(map[string]ActiveTask),
+		finished:  make(map[project.TaskStatus]int),
+		wake:      make(chan struct{}, 1),
+		agentWake: agentWake,
+	}, nil
+}
+
+// Run arbeitet Tasks ab, bis ctx endet oder ein Drain abgeschlossen ist.
+// Bei Abbruch über ctx gehen laufende Tasks zurück auf pending.
+func (d *Daemon) Run(ctx context.Context) error {
+	stopControl, err := d.serveControl()
+	if err != nil {
+		return err
+	}
+	defer stopControl()
+
+	d.mu.Lock()
+	d.startedAt = time.Now()
+	d.mu.Unlock()
+
+	go d.pool.WatchConfig(ctx, d.cfg.ModelConfigPath, 10*time.Second, func(err error) {
+		if err != nil {
+			d.logger.Error("Model pool reload failed", slog.String("error", err.Error()))
+			return
+		}
+		d.logger.Info("Model pool reconfigured")
+	})
+	go d.pool.PublishSnapshots(ctx, d.cfg.Workspace.ModelStatusPath(), 5*time.Second, func(err error) {
+		d.logger.Warn("Failed to publish model status", slog.String("error", err.Error()))
+	})
+
+	if d.cfg.Heartbeats != nil {
+		go d.logHeartbeatAlerts(ctx)
+	}
+	for _, a := range d.cfg.Agents {
+		d.workers.Add(1)
+		go d.agentLoop(ctx, a.Name)
+	}
+
+	if d.cfg.Jobs != nil {
+		jobsCtx, stopJobs := context.WithCancel(ctx)
+		jobsDone := make(chan struct{})
+		go func() {
+			defer close(jobsDone)
+			d.cfg.Jobs.Run(jobsCtx)
+		}()
+		// Auch nach einem Drain laufen keine Jobs weiter
+		defer func() {
+			stopJobs()
+			<-jobsDone
+		}()
+	}
+
+	if d.cfg.Webhooks != nil {
+		webhooksCtx, stopWebhooks := context.WithCancel(ctx)
+		webhooksDone := make(chan struct{})
+		go func() {
+			defer close(webhooksDone)
+			d.cfg.Webhooks.Run(webhooksCtx)
+		}()
+		defer func() {
+			stopWebhooks()
+			<-webhooksDone
+		}()
+	}
+
+	if d.cfg.Journal != nil {
+		d.resume()
+	}
+	if d.cfg.Sagas != nil {
+		d.recoverSagas(ctx)
+	}
+
+	d.logger.Info("Daemon started",
+		slog.String("workspace", d.cfg.Workspace.Root),
+		slog.String("socket", d.cfg.SocketPath),
+		slog.Int("workers", d.cfg.Workers),
+		slog.Int("agents", len(d.cfg.Agents)),
+	)
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		switch d.State() {
+		case StateRunning:
+			d.dispatch()
+		case StateDraining:
+			if d.activeCount() == 0 {
+				d.logger.Info("Drain complete")
+				d.setState(StateStopped)
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			d.logger.Info("Daemon stopping", slog.Int("active", d.activeCount()))
+			d.workers.Wait()
+			// Noch wartende Tasks gehen zurück auf pending
+			for _, wt := range d.stealer.Remove() {
+				d.requeue(wt)
+			}
+			d.setState(StateStopped)
+			return nil
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// dispatch claimt Tasks in der Reihenfolge des FairSchedulers, bis alle
+// Worker belegt sind oder kein Projekt mehr Arbeit und Budget hat.
+func (d *Daemon) dispatch() {
+	projects, err := project.DiscoverProjects(d.cfg.Workspace.PlansDir())
+	if err != nil {
+		d.logger.Debug("Project discovery failed", slog.String("error", err.Error()))
+		return
+	}
+	d.syncBudgets(projects)
+
+	for {
+		started := false
+		for _, projID := range d.fair.Order(projects) {
+			if d.activeCount() >= d.cfg.Workers {
+				return
+			}
+			if d.claim(projID) {
+				started = true
+				// nach jedem Start neu ordnen, damit Anteile und Aging greifen
+				break
+			}
+		}
+		if !started {
+			return
+		}
+	}
+}
+
+// syncBudgets übernimmt die Budgets aus den boulder.json Dateien und vergisst
+// Projekte, die es nicht mehr gibt.
+func (d *Daemon) syncBudgets(projects []string) {
+	known := make(map[string]bool, len(projects))
+	for _, projID := range projects {
+		known[projID] = true
+		var budget project.Budget
+		if b, err := d.store.Load(projID); err == nil && b.Schedule != nil {
+			budget = *b.Schedule
+		}
+		d.fair.SetBudget(projID, budget)
+	}
+	for projID := range d.fair.Stats() {
+		if !known[projID] {
+			d.fair.Forget(projID)
+		}
+	}
+}
+
+// claim belegt das Budget des Projekts und reiht dessen nächsten Task bei
+// einem Agenten ein.
+func (d *Daemon) claim(projID string) bool {
+	release, err := d.fair.Acquire(projID)
+	if err != nil {
+		return false
+	}
+	task, err := d.store.Claim(projID)
+	if err != nil {
+		release()
+		if errors.Is(err, project.ErrNoPendingTask) {
+			d.fair.Idle(projID)
+		} else {
+			d.logger.Warn("Claim failed", slog.String("project", projID), slog.String("error", err.Error()))
+		}
+		return false
+	}
+	d.enqueue(projID, task, release)
+	return true
+}
+
+// notify weckt die Hauptschleife, ohne zu blockieren.
+func (d *Daemon) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// ModelPool ist der Pool, gegen den der Daemon Modelle belegt.
+func (d *Daemon) ModelPool() *collision.ModelPool {
+	return d.pool
+}
+
+// State liefert den aktuellen Betriebszustand.
+func (d *Daemon) State() State {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state
+}
+
+func (d *Daemon) setState(s State) {
+	d.mu.Lock()
+	d.state = s
+	d.mu.Unlock()
+	d.notify()
+}
+
+// changeState wechselt nach to, außer der Daemon ist schon beendet.
+func (d *Daemon) changeState(to State) error {
+	d.mu.Lock()
+	if d.state == StateStopped {
+		d.mu.Unlock()
+		return fmt.Errorf("daemon is stopped")
+	}
+	from := d.state
+	d.state = to
+	d.mu.Unlock()
+
+	d.logger.Info("Daemon state changed", slog.String("from", string(from)), slog.String("to", string(to)))
+	d.notify()
+	return nil
+}
+
+// Pause hält das Claimen neuer Tasks an; laufende arbeiten weiter.
+func (d *Daemon) Pause() error { return d.changeState(StatePaused) }
+
+// Resume nimmt das Claimen wieder auf, auch nach einem Drain.
+func (d *Daemon) Resume() error { return d.changeState(StateRunning) }
+
+// Drain lässt laufende Tasks fertig werden und beendet dann Run.
+func (d *Daemon) Drain() error { return d.changeState(StateDraining) }
+
+func (d *Daemon) activeCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.active)
+}
+
+// Status beschreibt Zustand, laufende Tasks und Modell-Auslastung.
+func (d *Daemon) Status() Status {
+	d.mu.Lock()
+	st := Status{
+		State:     d.state,
+		StartedAt: d.startedAt,
+		Workspace: d.cfg.Workspace.Root,
+		Workers:   d.cfg.Workers,
+		Active:    make([]ActiveTask, 0, len(d.active)),
+		Finished:  make(map[project.TaskStatus]int, len(d.finished)),
+	}
+	for _, a := range d.active {
+		st.Active = append(st.Active, a)
+	}
+	for k, v := range d.finished {
+		st.Finished[k] = v
+	}
+	d.mu.Unlock()
+
+	sort.Slice(st.Active, func(i, j int) bool {
+		if st.Active[i].Project != st.Active[j].Project {
+			return st.Active[i].Project < st.Active[j].Project
+		}
+		return st.Active[i].TaskID < st.Active[j].TaskID
+	})
+	st.Models = d.pool.Stats()
+	st.Projects = d.fair.Stats()
+	st.Agents = d.stealer.GetAgentLoads()
+	return st
+}
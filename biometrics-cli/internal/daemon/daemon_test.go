@@ -0,0 +1,182 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"biometrics-cli/internal/project"
+	"biometrics-cli/internal/quality"
+	"biometrics-cli/internal/scheduler"
+	"biometrics-cli/internal/workspace"
+)
+
+func newTestDaemon(t *testing.T) *Daemon {
+	t.Helper()
+	root := t.TempDir()
+	d, err := New(Config{
+		Workspace:       workspace.Workspace{Root: root},
+		PollInterval:    10 * time.Millisecond,
+		ModelConfigPath: filepath.Join(root, "missing.json"),
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Backend:         &scriptedBackend{},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return d
+}
+
+func TestControlAPIChangesState(t *testing.T) {
+	d := newTestDaemon(t)
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		path string
+		want State
+	}{
+		{"/pause", StatePaused},
+		{"/resume", StateRunning},
+		{"/drain", StateDraining},
+	} {
+		resp, err := http.Post(srv.URL+tc.path, "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %d", tc.path, resp.StatusCode)
+		}
+		if got := d.State(); got != tc.want {
+			t.Errorf("%s: state = %s, want %s", tc.path, got, tc.want)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/pause")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /pause: status %d, want 405", resp.StatusCode)
+	}
+
+	d.setState(StateStopped)
+	if err := d.Resume(); err == nil {
+		t.Error("Resume on a stopped daemon should fail")
+	}
+}
+
+func TestControlAPIJobs(t *testing.T) {
+	d := newTestDaemon(t)
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /jobs without scheduler: status %d, want 404", resp.StatusCode)
+	}
+
+	d.cfg.Jobs = scheduler.New()
+	d.cfg.Jobs.Register(&scheduler.Job{ID: "nightly", Schedule: "@daily", Enabled: true,
+		Handler: func(ctx context.Context) error { return nil }})
+
+	for _, tc := range []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "/jobs?runs=2", http.StatusOK},
+		{http.MethodGet, "/jobs?runs=500", http.StatusBadRequest},
+		{http.MethodGet, "/jobs/nightly", http.StatusOK},
+		{http.MethodGet, "/jobs/missing", http.StatusNotFound},
+		{http.MethodPost, "/jobs/nightly/disable", http.StatusOK},
+		{http.MethodPost, "/jobs/missing/disable", http.StatusNotFound},
+		// Der Scheduler läuft nicht
+		{http.MethodPost, "/jobs/nightly/trigger", http.StatusConflict},
+	} {
+		req, _ := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.code {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.path, resp.StatusCode, tc.code)
+		}
+	}
+	if job, _ := d.cfg.Jobs.GetJob("nightly"); job.Enabled {
+		t.Error("nightly still enabled after POST /jobs/nightly/disable")
+	}
+}
+
+func TestRunCompletesTasksAndDrains(t *testing.T) {
+	d := newTestDaemon(t)
+	d.cfg.Heartbeats = newHeartbeatMonitor(d.cfg.Workspace, d.cfg.PollInterval)
+	dir := d.cfg.Workspace.ProjectDir("demo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Die Pipeline prüft nur, dass das Arbeitsverzeichnis existiert
+	pipeline := "workdir: " + dir + "\nstages:\n  - kind: command\n    command: [\"test\", \"-d\", \".\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, quality.PipelineFile), []byte(pipeline), 0644); err != nil {
+		t.Fatal(err)
+	}
+	boulder := `{"tasks":[{"id":"t1","description":"one","status":"pending"},{"id":"t2","description":"two","status":"pending"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "boulder.json"), []byte(boulder), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+
+	client := NewClient(d.cfg.SocketPath)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st, err := client.Status(ctx)
+		if err == nil && st.Finished[project.StatusCompleted] == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tasks not completed: status %+v, err %v", st, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if _, err := client.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if d.State() != StateStopped {
+		t.Errorf("state = %s, want stopped", d.State())
+	}
+	if _, err := os.Stat(d.cfg.SocketPath); !os.IsNotExist(err) {
+		t.Errorf("control socket not removed: %v", err)
+	}
+
+	// Nach dem Drain warten die Agent-Worker auf das Ende von ctx
+	cancel()
+	d.workers.Wait()
+	heartbeats := d.cfg.Heartbeats.GetAllHeartbeats()
+	tasksDone := 0
+	for _, hb := range heartbeats {
+		tasksDone += hb.TasksDone
+	}
+	if len(heartbeats) != len(d.cfg.Agents) || tasksDone != 2 {
+		t.Errorf("%d heartbeat(s) with %d task(s) done, want %d agents and 2 tasks", len(heartbeats), tasksDone, len(d.cfg.Agents))
+	}
+}
@@ -0,0 +1,337 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"biometrics-cli/internal/durable"
+	"biometrics-cli/internal/project"
+	"biometrics-cli/internal/workspace"
+)
+
+// Phasen eines Task-Laufs im Journal, in Ausführungsreihenfolge
+const (
+	PhasePrompt = "prompt"
+	PhaseAgent  = "agent"
+	PhaseGate   = "gate"
+	PhaseCommit = "commit"
+)
+
+// DefaultJournalSteps begrenzt die Checkpoints je Task im Journal.
+const DefaultJournalSteps = 1000
+
+// DefaultIdempotencyKeys legt fest, wann eine Phase schon erledigt ist: gibt
+// es einen abgeschlossenen Checkpoint mit gleichem Schlüssel, übernimmt der
+// Lauf dessen Ergebnis, statt die Phase zu wiederholen. Platzhalter sind
+// {project}, {task}, {attempt} (Nummer des Claims) und {model}. Ohne
+// {attempt} gilt eine Phase über alle Claims des Tasks hinweg als erledigt.
+var DefaultIdempotencyKeys = map[string]string{
+	PhasePrompt: "{project}/{task}/{attempt}/{model}/prompt",
+	PhaseAgent:  "{project}/{task}/{attempt}/{model}/agent",
+	PhaseGate:   "{project}/{task}/{attempt}/{model}/gate",
+	PhaseCommit: "{project}/{task}/commit",
+}
+
+// IdempotencyKeysEnvVar zeigt auf eine JSON-Datei mit Overrides für
+// DefaultIdempotencyKeys und ersetzt die Datei im Workspace.
+const IdempotencyKeysEnvVar = "BIOMETRICS_IDEMPOTENCY_KEYS"
+
+// LoadIdempotencyKeys liest die Overrides für DefaultIdempotencyKeys aus
+// BIOMETRICS_IDEMPOTENCY_KEYS oder, ohne die Variable, aus der Datei im
+// Workspace. Die Datei ist ein JSON-Objekt von Phase auf Schlüssel, etwa
+// {"agent": "{project}/{task}/{model}/agent"}. Fehlt die Datei im Workspace,
+// gelten die Vorgaben.
+func LoadIdempotencyKeys(ws workspace.Workspace) (map[string]string, error) {
+	path := os.Getenv(IdempotencyKeysEnvVar)
+	if path == "" {
+		path = ws.IdempotencyKeysPath()
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency keys: %w", err)
+	}
+	var keys map[string]string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid idempotency keys in %s: %w", path, err)
+	}
+	for phase, key := range keys {
+		if _, ok := DefaultIdempotencyKeys[phase]; !ok {
+			return nil, fmt.Errorf("invalid idempotency keys in %s: unknown phase %q", path, phase)
+		}
+		if !strings.Contains(key, "{task}") {
+			return nil, fmt.Errorf("invalid idempotency keys in %s: key for %s needs {task}", path, phase)
+		}
+	}
+	return keys, nil
+}
+
+// OpenJournal öffnet das Journal im Workspace mit dem Backend aus
+// BIOMETRICS_JOURNAL_BACKEND.
+func OpenJournal(ws workspace.Workspace) (*durable.Journal, error) {
+	persistence, err := durable.OpenPersistence("", ws.JournalDir())
+	if err != nil {
+		return nil, err
+	}
+	journal, err := durable.OpenJournal(persistence, DefaultJournalSteps)
+	if err != nil {
+		persistence.Close()
+		return nil, err
+	}
+	return journal, nil
+}
+
+// journalRunID ist die Agent-ID der Checkpoints eines Tasks. Sie landet in
+// Dateinamen, daher wird alles außer Buchstaben, Ziffern, '.' und '-' ersetzt.
+// Damit verschiedene Tasks nicht auf dieselbe ID fallen, hängt ein Hash über
+// die unveränderten IDs an.
+func journalRunID(projID, taskID string) string {
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+				return r
+			}
+			return '-'
+		}, s)
+	}
+	sum := sha256.Sum256([]byte(projID + "\x00" + taskID))
+	return "task." + clean(projID) + "." + clean(taskID) + "." + hex.EncodeToString(sum[:8])
+}
+
+// journalRun verbindet einen Task-Lauf mit dem Journal. Ohne Journal ist er
+// nil, und step führt jede Phase einfach aus.
+type journalRun struct {
+	journal *durable.Journal
+	logger  *slog.Logger
+	id      string
+	projID  string
+	taskID  string
+	attempt int
+	keys    map[string]string
+	// done hält die Ergebnisse abgeschlossener Phasen nach Schlüssel
+	done map[string]map[string]interface{}
+	// baseRef ist der Commit vor dem ersten Versuch dieses Claims
+	baseRef string
+}
+
+// newJournalRun spielt das Journal des Tasks nach: ein noch laufender
+// Checkpoint wurde unterbrochen und gilt als gescheitert, abgeschlossene
+// Phasen werden über ihre Schlüssel wiedererkannt.
+func newJournalRun(journal *durable.Journal, keys map[string]string, logger *slog.Logger, projID string, task *project.Task) *journalRun {
+	if journal == nil {
+		return nil
+	}
+	jr := &journalRun{
+		journal: journal,
+		logger:  logger,
+		id:      journalRunID(projID, task.ID),
+		projID:  projID,
+		taskID:  task.ID,
+		attempt: task.Attempts,
+		keys:    keys,
+		done:    make(map[string]map[string]interface{}),
+	}
+
+	for cp := journal.GetRunningStep(jr.id); cp != nil; cp = journal.GetRunningStep(jr.id) {
+		if err := journal.FailCheckpoint(cp.ID, "interrupted"); err != nil {
+			logger.Warn("Failed to mark interrupted checkpoint", slog.String("checkpoint", cp.ID), slog.String("error", err.Error()))
+			break
+		}
+	}
+
+	replayed, err := journal.Replay(jr.id, func(cp *durable.Checkpoint) error {
+		if cp.Status != "completed" {
+			return nil
+		}
+		if key, ok := cp.Input["key"].(string); ok {
+			jr.done[key] = cp.Output
+		}
+		if ref, ok := cp.Input["base_ref"].(string); ok && inputInt(cp.Input, "attempt") == jr.attempt {
+			jr.baseRef = ref
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warn("Journal replay failed", slog.String("task_id", task.ID), slog.String("error", err.Error()))
+	}
+	if len(replayed) > 0 {
+		logger.Info("Replayed task journal", slog.String("task_id", task.ID), slog.Int("checkpoints", len(replayed)))
+	}
+	return jr
+}
+
+// useBaseRef liefert den Commit, gegen den das Quality Gate prüft: beim
+// Fortsetzen den des unterbrochenen Claims, sonst current.
+func (jr *journalRun) useBaseRef(current string) string {
+	if jr == nil {
+		return current
+	}
+	if jr.baseRef == "" {
+		jr.baseRef = current
+	}
+	return jr.baseRef
+}
+
+func (jr *journalRun) key(phase, model string) string {
+	template, ok := jr.keys[phase]
+	if !ok {
+		template = DefaultIdempotencyKeys[phase]
+	}
+	return strings.NewReplacer(
+		"{project}", jr.projID,
+		"{task}", jr.taskID,
+		"{attempt}", strconv.Itoa(jr.attempt),
+		"{model}", model,
+	).Replace(template)
+}
+
+// replayed ist true, wenn die Phase für model schon abgeschlossen ist.
+func (jr *journalRun) replayed(phase, model string) bool {
+	if jr == nil {
+		return false
+	}
+	_, ok := jr.done[jr.key(phase, model)]
+	return ok
+}
+
+// step führt eine Phase aus oder übernimmt ihr Ergebnis aus dem Journal.
+// Liefert fn einen Fehler, wird der Checkpoint als gescheitert markiert und
+// beim nächsten Lauf wiederholt; das Ergebnis von fn kommt trotzdem zurück.
+// Fehler des Journals selbst halten den Lauf nicht auf.
+func (jr *journalRun) step(phase, model string, fn func() (map[string]interface{}, error)) (map[string]interface{}, bool, error) {
+	if jr == nil {
+		out, err := fn()
+		return out, false, err
+	}
+	key := jr.key(phase, model)
+	if out, ok := jr.done[key]; ok {
+		return out, true, nil
+	}
+
+	cp, err := jr.journal.CreateCheckpoint(jr.id, jr.projID, jr.taskID, map[string]interface{}{
+		"phase":    phase,
+		"key":      key,
+		"attempt":  jr.attempt,
+		"model":    model,
+		"base_ref": jr.baseRef,
+	})
+	if err != nil {
+		jr.logger.Warn("Failed to create checkpoint", slog.String("task_id", jr.taskID), slog.String("phase", phase), slog.String("error", err.Error()))
+	}
+
+	out, runErr := fn()
+	if cp == nil {
+		return out, false, runErr
+	}
+	if runErr != nil {
+		err = jr.journal.FailCheckpoint(cp.ID, runErr.Error())
+	} else {
+		err = jr.journal.CompleteCheckpoint(cp.ID, out)
+		jr.done[key] = out
+	}
+	if err != nil {
+		jr.logger.Warn("Failed to update checkpoint", slog.String("checkpoint", cp.ID), slog.String("error", err.Error()))
+	}
+	return out, false, runErr
+}
+
+// resume setzt unterbrochene Läufe fort: Tasks, die noch geleast sind, deren
+// Inhaber nicht mehr lebt und deren aktueller Claim Checkpoints im Journal
+// hat, werden übernommen und wieder eingereiht. Der taskRunner überspringt
+// dann die schon erledigten Phasen.
+func (d *Daemon) resume() {
+	projects, err := project.DiscoverProjects(d.cfg.Workspace.PlansDir())
+	if err != nil {
+		d.logger.Debug("Project discovery failed", slog.String("error", err.Error()))
+		return
+	}
+	d.syncBudgets(projects)
+
+	for _, projID := range projects {
+		b, err := d.store.Load(projID)
+		if err != nil {
+			continue
+		}
+		for _, ref := range b.AllTasks() {
+			task := ref.Task
+			if task.Status != project.StatusInProgress && task.Status != project.StatusVerifying {
+				continue
+			}
+			if !hasAttempt(d.cfg.Journal, projID, task.ID, task.Attempts) {
+				continue
+			}
+			if d.activeCount() >= d.cfg.Workers {
+				d.logger.Warn("No worker left to resume task; it is reclaimed when its lease expires",
+					slog.String("project", projID), slog.String("task_id", task.ID))
+				continue
+			}
+			release, err := d.fair.Acquire(projID)
+			if err != nil {
+				continue
+			}
+			adopted, err := d.store.Adopt(projID, task.ID, task.Attempts)
+			if err != nil {
+				release()
+				d.logger.Info("Not resuming task", slog.String("task_id", task.ID), slog.String("reason", err.Error()))
+				continue
+			}
+			d.logger.Info("Resuming interrupted task",
+				slog.String("project", projID),
+				slog.String("task_id", task.ID),
+				slog.Int("attempt", adopted.Attempts),
+			)
+			d.enqueue(projID, adopted, release)
+		}
+	}
+}
+
+// hasAttempt ist true, wenn das Journal Checkpoints des Claims attempt hat.
+func hasAttempt(journal *durable.Journal, projID, taskID string, attempt int) bool {
+	id := journalRunID(projID, taskID)
+	if cp := journal.GetRunningStep(id); cp != nil && inputInt(cp.Input, "attempt") == attempt {
+		return true
+	}
+	for _, cp := range journal.GetCompletedSteps(id) {
+		if inputInt(cp.Input, "attempt") == attempt {
+			return true
+		}
+	}
+	return false
+}
+
+// inputInt liest Zahlen aus Checkpoint-Daten; nach dem Laden aus JSON sind
+// sie float64.
+func inputInt(m map[string]interface{}, key string) int {
+	switch v := m[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+func outputString(m map[string]interface{}, key string) string {
+	if s, ok := m[key].(string); ok {
+		return s
+	}
+	if v, ok := m[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func outputBool(m map[string]interface{}, key string) bool {
+	b, _ := m[key].(bool)
+	return b
+}
@@ -0,0 +1,444 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"biometrics-cli/internal/collision"
+	"biometrics-cli/internal/durable"
+	"biometrics-cli/internal/opencode"
+	"biometrics-cli/internal/project"
+	"biometrics-cli/internal/prompt"
+	"biometrics-cli/internal/quality"
+	"biometrics-cli/internal/ratelimit"
+)
+
+const (
+	fallbackBackoffBase = 5 * time.Second
+	fallbackBackoffMax  = 2 * time.Minute
+)
+
+// fallbackBackoff ist die Wartezeit vor dem n-ten Modellwechsel (n ab 1).
+func fallbackBackoff(n int) time.Duration {
+	d := fallbackBackoffBase
+	for i := 1; i < n && d < fallbackBackoffMax; i++ {
+		d *= 2
+	}
+	if d > fallbackBackoffMax {
+		d = fallbackBackoffMax
+	}
+	return d
+}
+
+// taskRunner führt einen geclaimten Task aus. Schlägt ein Lauf fehl, wird
+// das nächste Modell der Fallback-Kette der Kategorie versucht; failed wird
+// der Task erst, wenn die Kette erschöpft ist. Mit Journal hält er jede Phase
+// als Checkpoint fest und überspringt beim Fortsetzen erledigte Phasen.
+type taskRunner struct {
+	logger   *slog.Logger
+	store    *project.FileTaskStore
+	pool     *collision.ModelPool
+	executor *opencode.Executor
+	gate     *quality.Gate
+	basePath string
+	backoff  func(n int) time.Duration
+	journal  *durable.Journal
+	keys     map[string]string
+}
+
+// attemptResult ist das Ergebnis eines Modells: Agent-Lauf plus Quality Gate.
+type attemptResult struct {
+	class     opencode.FailureClass
+	err       string
+	completed bool
+}
+
+// runState ist der Zustand eines Laufs über die Modelle der Kette hinweg.
+type runState struct {
+	projID   string
+	task     *project.Task
+	category string
+	pipeline *quality.Pipeline
+	// workDir ist der Checkout des Projekts
+	workDir string
+	baseRef string
+	// status ist der Status des Tasks im Store; nach einem Resume kann er
+	// schon verifying sein
+	status  project.TaskStatus
+	journal *journalRun
+}
+
+// run liefert den Status, in dem der Task am Ende steht.
+func (r *taskRunner) run(ctx context.Context, projID string, task *project.Task) project.TaskStatus {
+	releaseLease := r.store.HoldLease(ctx, projID, task.ID, task.Attempts)
+	defer releaseLease()
+
+	pipeline, err := quality.PipelineFor(r.basePath, projID)
+	if err != nil {
+		r.logger.Error("Invalid quality pipeline", slog.String("project", projID), slog.String("error", err.Error()))
+		return r.finish(projID, task, project.StatusFailed, "quality pipeline: "+err.Error())
+	}
+	workDir, err := r.projectDir(projID, pipeline)
+	if err != nil {
+		r.logger.Error("No project checkout", slog.String("project", projID), slog.String("error", err.Error()))
+		return r.finish(projID, task, project.StatusFailed, err.Error())
+	}
+
+	st := &runState{
+		projID:   projID,
+		task:     task,
+		category: task.Category,
+		pipeline: pipeline,
+		workDir:  workDir,
+		status:   task.Status,
+		journal:  newJournalRun(r.journal, r.keys, r.logger, projID, task),
+	}
+	if st.category == "" {
+		st.category = "build"
+	}
+	if st.status == "" {
+		st.status = project.StatusInProgress
+	}
+	st.baseRef = st.journal.useBaseRef(quality.HeadRevision(workDir))
+	chain := r.pool.Chain(st.category)
+
+	tried := 0
+	lastErr := "no model available"
+	for i := 0; i < len(chain); i++ {
+		replayed := st.journal.replayed(PhaseAgent, chain[i])
+		if tried > 0 && !replayed {
+			wait := r.backoff(tried)
+			r.logger.Info("Retrying with next model", slog.String("task_id", task.ID), slog.String("model", chain[i]), slog.Duration("backoff", wait))
+			select {
+			case <-ctx.Done():
+				return r.finish(projID, task, project.StatusPending, "")
+			case <-time.After(wait):
+			}
+		}
+
+		model := chain[i]
+		taskPrompt, err := r.prompt(st, model)
+		if err != nil {
+			r.logger.Error("Prompt rendering failed", slog.String("task_id", task.ID), slog.String("error", err.Error()))
+			return r.finish(projID, task, project.StatusFailed, "prompt: "+err.Error())
+		}
+
+		// Ein schon gelaufener Agent braucht kein Modell mehr
+		var lease *collision.Lease
+		if !replayed {
+			promptTokens := prompt.EstimateTokens(taskPrompt)
+
+			// Modelle mit erschöpftem Kontingent überspringen, ohne einen Versuch zu zählen
+			chosen, err := r.pool.Fallback(chain[i:], promptTokens)
+			if err != nil {
+				if errors.Is(err, ratelimit.ErrQuotaExceeded) {
+					lastErr = err.Error()
+				}
+				r.logger.Warn("No model of the fallback chain available", slog.String("task_id", task.ID), slog.String("error", err.Error()))
+				break
+			}
+			if chosen != model {
+				r.logger.Warn("Model quota exhausted, falling back",
+					slog.String("task_id", task.ID),
+					slog.String("model", model),
+					slog.String("fallback", chosen),
+				)
+				for chain[i] != chosen {
+					i++
+				}
+				model = chosen
+				if taskPrompt, err = r.prompt(st, model); err != nil {
+					return r.finish(projID, task, project.StatusFailed, "prompt: "+err.Error())
+				}
+			}
+
+			lease, err = r.pool.AcquirePrompt(ctx, model, promptTokens)
+			if err != nil {
+				if ctx.Err() != nil {
+					return r.finish(projID, task, project.StatusPending, "")
+				}
+				r.logger.Warn("Model not available", slog.String("task_id", task.ID), slog.String("model", model), slog.String("error", err.Error()))
+				continue
+			}
+
+			// nach abgelehntem Gate steht der Task auf verifying
+			if !r.moveTo(st, project.StatusInProgress) {
+				lease.Release()
+				return project.StatusVerifying
+			}
+		}
+		tried++
+
+		res := r.attempt(ctx, st, model, lease, taskPrompt)
+		if res.completed {
+			return project.StatusCompleted
+		}
+		lastErr = res.err
+
+		switch {
+		case res.class == opencode.FailureCanceled || ctx.Err() != nil:
+			return r.finish(projID, task, project.StatusPending, "")
+		case !res.class.Retryable():
+			return r.finish(projID, task, project.StatusFailed, res.err)
+		}
+	}
+
+	if tried == 0 {
+		// Nichts ist gelaufen, z.B. weil alle Kontingente erschöpft sind
+		return r.finish(projID, task, project.StatusPending, "")
+	}
+	r.logger.Error("Fallback chain exhausted", slog.String("task_id", task.ID), slog.Int("models_tried", tried), slog.String("error", lastErr))
+	return r.finish(projID, task, project.StatusFailed, "fallback chain exhausted: "+lastErr)
+}
+
+// projectDir liefert den Checkout, in dem der Agent eingesperrt läuft und
+// das Quality Gate prüft: workdir aus der boulder.json des Projekts, sonst
+// aus der quality.yaml. Workspaces von vor dieser Einstellung haben keins;
+// für sie bleibt es wie bisher beim Arbeitsverzeichnis des Daemons.
+func (r *taskRunner) projectDir(projID string, pipeline *quality.Pipeline) (string, error) {
+	dir := ""
+	if b, err := r.store.Load(projID); err == nil {
+		dir = b.WorkDir
+	}
+	if dir == "" {
+		dir = pipeline.WorkDir
+	}
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("no project checkout: set workdir in boulder.json or %s", quality.PipelineFile)
+		}
+		r.logger.Warn("No workdir configured, using the daemon's working directory",
+			slog.String("project", projID),
+			slog.String("workdir", wd),
+			slog.String("hint", "set workdir in boulder.json or "+quality.PipelineFile),
+		)
+		return wd, nil
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.basePath, projID, dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("project checkout: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("project checkout %s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// prompt rendert den Prompt für model oder übernimmt ihn aus dem Journal, damit
+// ein fortgesetzter Lauf denselben Prompt sieht.
+func (r *taskRunner) prompt(st *runState, model string) (string, error) {
+	out, _, err := st.journal.step(PhasePrompt, model, func() (map[string]interface{}, error) {
+		taskPrompt, err := r.renderPrompt(st.projID, st.task, st.category, model)
+		return map[string]interface{}{"prompt": taskPrompt}, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return outputString(out, "prompt"), nil
+}
+
+// attempt lässt ein Modell den Task bearbeiten, prüft das Ergebnis mit dem
+// Quality Gate und hält den Lauf in der History des Tasks fest. Ist lease
+// nil, stammt der Agent-Lauf aus dem Journal.
+func (r *taskRunner) attempt(ctx context.Context, st *runState, model string, lease *collision.Lease, taskPrompt string) attemptResult {
+	projID, task := st.projID, st.task
+	// model ist der Eintrag der Kette und Teil der Schlüssel, die Lease
+	// liefert die aufgelöste Modell-ID
+	runModel := model
+	if lease != nil {
+		runModel = lease.Model()
+	}
+	rec := project.AttemptRecord{
+		Attempt:   task.Attempts,
+		Model:     runModel,
+		StartedAt: time.Now(),
+	}
+	defer func() {
+		rec.DurationMS = time.Since(rec.StartedAt).Milliseconds()
+		if err := r.store.RecordAttempt(projID, task.ID, rec); err != nil {
+			r.logger.Error("Failed to record attempt", slog.String("task_id", task.ID), slog.String("error", err.Error()))
+		}
+	}()
+
+	req := opencode.AgentRequest{
+		ProjectID:  projID,
+		Model:      runModel,
+		Prompt:     taskPrompt,
+		Category:   st.category,
+		Agent:      task.Agent,
+		TaskID:     task.ID,
+		WorkDir:    st.workDir,
+		ProjectDir: st.workDir,
+	}
+	agent, replayed, _ := st.journal.step(PhaseAgent, model, func() (map[string]interface{}, error) {
+		result := r.executor.RunAgent(ctx, req)
+		lease.Release()
+
+		out := map[string]interface{}{
+			"success":    result.Success,
+			"model":      runModel,
+			"transcript": result.TranscriptRef,
+		}
+		if result.Success {
+			return out, nil
+		}
+		class := opencode.Classify(result.ErrorKind)
+		if class == opencode.FailureRateLimited {
+			if err := r.pool.ReportThrottle(runModel, result.RetryAfter); err != nil {
+				r.logger.Error("Failed to report throttle", slog.String("error", err.Error()))
+			}
+		}
+		msg := string(result.ErrorKind)
+		if result.Error != nil {
+			msg = result.Error.Error()
+		}
+		out["class"] = string(class)
+		out["error"] = msg
+		out["exit_code"] = result.ExitCode
+		if class == opencode.FailureCanceled {
+			// abgebrochene Läufe nicht als erledigt festhalten
+			return out, errors.New(msg)
+		}
+		return out, nil
+	})
+	rec.Transcript = outputString(agent, "transcript")
+	if replayed {
+		runModel = outputString(agent, "model")
+		rec.Model = runModel
+		req.Model = runModel
+		r.logger.Info("Agent run replayed from journal", slog.String("task_id", task.ID), slog.String("model", runModel))
+	}
+
+	if !outputBool(agent, "success") {
+		class := opencode.FailureClass(outputString(agent, "class"))
+		msg := outputString(agent, "error")
+		r.logger.Error("Task failed",
+			slog.String("task_id", task.ID),
+			slog.String("model", runModel),
+			slog.String("error", msg),
+			slog.String("failure_class", string(class)),
+			slog.Int("exit_code", inputInt(agent, "exit_code")),
+			slog.String("transcript", rec.Transcript),
+		)
+		rec.Outcome = string(class)
+		rec.Error = msg
+		return attemptResult{class: class, err: msg}
+	}
+
+	if !r.moveTo(st, project.StatusVerifying) {
+		rec.Outcome = "aborted"
+		return attemptResult{class: opencode.FailureCanceled, err: "transition to verifying failed"}
+	}
+
+	gate, _, _ := st.journal.step(PhaseGate, model, func() (map[string]interface{}, error) {
+		report, reportRef, err := r.gate.Run(ctx, st.pipeline, quality.Input{
+			ProjectID: projID,
+			TaskID:    task.ID,
+			WorkDir:   st.workDir,
+			BaseRef:   st.baseRef,
+			Request:   req,
+		})
+		if err != nil {
+			r.logger.Error("Quality Gate error", slog.String("task_id", task.ID), slog.String("error", err.Error()))
+		}
+		if reportRef != "" {
+			if err := r.store.AttachQualityReport(projID, task.ID, reportRef); err != nil {
+				r.logger.Error("Failed to attach quality report", slog.String("task_id", task.ID), slog.String("error", err.Error()))
+			}
+		}
+		out := map[string]interface{}{
+			"passed":   report.Passed(),
+			"failures": report.Failures(),
+			"status":   string(report.Status),
+			"report":   reportRef,
+		}
+		// ein abgebrochenes Gate wird beim Fortsetzen wiederholt
+		return out, ctx.Err()
+	})
+	rec.QualityReport = outputString(gate, "report")
+
+	if !outputBool(gate, "passed") {
+		r.logger.Error("Quality Gate failed",
+			slog.String("task_id", task.ID),
+			slog.String("model", runModel),
+			slog.String("failures", outputString(gate, "failures")),
+			slog.String("report", rec.QualityReport),
+		)
+		rec.Outcome = string(opencode.FailureQualityGate)
+		rec.Error = "quality gate: " + outputString(gate, "failures")
+		return attemptResult{class: opencode.FailureQualityGate, err: rec.Error}
+	}
+
+	rec.Outcome = "success"
+	st.journal.step(PhaseCommit, model, func() (map[string]interface{}, error) {
+		if !r.moveTo(st, project.StatusCompleted) {
+			return nil, errors.New("transition to completed failed")
+		}
+		return map[string]interface{}{"status": string(project.StatusCompleted)}, nil
+	})
+	r.logger.Info("Task completed and verified",
+		slog.String("task_id", task.ID),
+		slog.String("model", runModel),
+		slog.String("quality", outputString(gate, "status")),
+		slog.String("report", rec.QualityReport),
+	)
+	return attemptResult{completed: true}
+}
+
+func (r *taskRunner) renderPrompt(projID string, task *project.Task, category, model string) (string, error) {
+	taskPrompt, err := prompt.RenderTaskPrompt(r.basePath, prompt.TaskData{
+		ProjectID:          projID,
+		PlanName:           "active_plan.md",
+		TaskID:             task.ID,
+		TaskDescription:    task.Description,
+		Category:           category,
+		Agent:              task.Agent,
+		Model:              model,
+		AcceptanceCriteria: task.AcceptanceCriteria,
+	})
+	return taskPrompt, err
+}
+
+// finish setzt den Endstatus eines Laufs. Schlägt der Wechsel fehl, ist der
+// Status unbekannt und das Ergebnis leer.
+func (r *taskRunner) finish(projID string, task *project.Task, to project.TaskStatus, reason string) project.TaskStatus {
+	if !r.transition(projID, task, to, reason) {
+		return ""
+	}
+	return to
+}
+
+// moveTo wechselt den Status des Laufs, sofern er nicht schon dort steht.
+func (r *taskRunner) moveTo(st *runState, to project.TaskStatus) bool {
+	if st.status == to {
+		return true
+	}
+	if !r.transition(st.projID, st.task, to, "") {
+		return false
+	}
+	st.status = to
+	return true
+}
+
+// transition loggt fehlgeschlagene Statuswechsel, statt den Loop abzubrechen.
+// Hat ein anderer Claim die Lease übernommen, scheitert der Wechsel.
+func (r *taskRunner) transition(projID string, task *project.Task, to project.TaskStatus, reason string) bool {
+	if err := r.store.Transition(projID, task.ID, task.Attempts, to, reason); err != nil {
+		r.logger.Error("Task transition failed",
+			slog.String("task_id", task.ID),
+			slog.String("to", string(to)),
+			slog.String("error", err.Error()),
+		)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,310 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"biometrics-cli/internal/collision"
+	"biometrics-cli/internal/durable"
+	"biometrics-cli/internal/opencode"
+	"biometrics-cli/internal/project"
+	"biometrics-cli/internal/quality"
+)
+
+// scriptedBackend beantwortet jedes Modell mit einer festen Reaktion.
+type scriptedBackend struct {
+	onModel map[string]func() opencode.AgentResult
+	models  []string
+}
+
+type scriptedRun struct {
+	lines  chan opencode.OutputLine
+	result opencode.AgentResult
+}
+
+func (r *scriptedRun) Stream() <-chan opencode.OutputLine { return r.lines }
+func (r *scriptedRun) Cancel() error                      { return nil }
+func (r *scriptedRun) Result() opencode.AgentResult       { return r.result }
+
+func (b *scriptedBackend) Start(ctx context.Context, req opencode.AgentRequest) (opencode.AgentRun, error) {
+	b.models = append(b.models, req.Model)
+	run := &scriptedRun{lines: make(chan opencode.OutputLine), result: opencode.AgentResult{Success: true}}
+	if fn, ok := b.onModel[req.Model]; ok {
+		run.result = fn()
+	}
+	close(run.lines)
+	return run, nil
+}
+
+func newTestRunner(t *testing.T, backend opencode.AgentBackend, workDir string) (*taskRunner, *project.FileTaskStore) {
+	t.Helper()
+	base := t.TempDir()
+	dir := filepath.Join(base, "demo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	boulder := `{"tasks":[{"id":"t1","description":"do it","status":"pending"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "boulder.json"), []byte(boulder), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pipeline := "workdir: " + workDir + "\nstages:\n  - kind: command\n    command: [\"test\", \"-f\", \"done\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, quality.PipelineFile), []byte(pipeline), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	executor := opencode.NewExecutorWithBackend(logger, backend)
+	store := project.NewFileTaskStore(base)
+	return &taskRunner{
+		logger:   logger,
+		store:    store,
+		pool:     collision.NewModelPool(nil),
+		executor: executor,
+		gate:     quality.NewGate(executor, nil),
+		basePath: base,
+		backoff:  func(int) time.Duration { return 0 },
+	}, store
+}
+
+func TestRunnerWalksFallbackChain(t *testing.T) {
+	workDir := t.TempDir()
+	backend := &scriptedBackend{onModel: map[string]func() opencode.AgentResult{
+		"nvidia-nim/qwen-3.5-397b": func() opencode.AgentResult {
+			return opencode.AgentResult{Error: errors.New("exit status 1"), ErrorKind: opencode.ErrorKindAgentFailed, ExitCode: 1}
+		},
+		// kimi hat keinen Eintrag: läuft durch, legt done aber nicht an
+		"opencode-zen/minimax-m2.5-free": func() opencode.AgentResult {
+			os.WriteFile(filepath.Join(workDir, "done"), nil, 0644)
+			return opencode.AgentResult{Success: true}
+		},
+	}}
+	runner, store := newTestRunner(t, backend, workDir)
+
+	task, err := store.Claim("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.run(context.Background(), "demo", task)
+
+	b, err := store.Load("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := b.Tasks[0]
+	if got.Status != project.StatusCompleted {
+		t.Fatalf("expected completed task, got %s (%s)", got.Status, got.Error)
+	}
+
+	want := []struct{ model, outcome string }{
+		{"nvidia-nim/qwen-3.5-397b", "crash"},
+		{"opencode-zen/kimi-k2.5-free", "quality_gate"},
+		{"opencode-zen/minimax-m2.5-free", "success"},
+	}
+	if len(got.History) != len(want) {
+		t.Fatalf("expected %d attempts, got %+v", len(want), got.History)
+	}
+	for i, w := range want {
+		if got.History[i].Model != w.model || got.History[i].Outcome != w.outcome || got.History[i].Attempt != 1 {
+			t.Errorf("attempt %d = %+v, want %s/%s", i, got.History[i], w.model, w.outcome)
+		}
+	}
+}
+
+func TestRunnerFailsWhenChainExhausted(t *testing.T) {
+	crash := func() opencode.AgentResult {
+		return opencode.AgentResult{Error: errors.New("boom"), ErrorKind: opencode.ErrorKindOOM}
+	}
+	backend := &scriptedBackend{onModel: map[string]func() opencode.AgentResult{
+		"opencode-zen/minimax-m2.5-free": crash,
+		"opencode-zen/kimi-k2.5-free":    crash,
+	}}
+	runner, store := newTestRunner(t, backend, t.TempDir())
+
+	if err := store.Update("demo", func(b *project.Boulder) error {
+		b.Tasks[0].Category = "quick"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	task, err := store.Claim("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.run(context.Background(), "demo", task)
+
+	b, _ := store.Load("demo")
+	got := b.Tasks[0]
+	if got.Status != project.StatusFailed || len(got.History) != 2 {
+		t.Fatalf("expected failed task after 2 attempts, got %s with %+v", got.Status, got.History)
+	}
+	if len(backend.models) != 2 {
+		t.Errorf("expected quick chain of 2 models, ran %v", backend.models)
+	}
+}
+
+func TestRunnerStopsOnPolicyViolation(t *testing.T) {
+	backend := &scriptedBackend{onModel: map[string]func() opencode.AgentResult{
+		"nvidia-nim/qwen-3.5-397b": func() opencode.AgentResult {
+			return opencode.AgentResult{Error: errors.New("denied"), ErrorKind: opencode.ErrorKindPolicy}
+		},
+	}}
+	runner, store := newTestRunner(t, backend, t.TempDir())
+
+	task, _ := store.Claim("demo")
+	runner.run(context.Background(), "demo", task)
+
+	b, _ := store.Load("demo")
+	if got := b.Tasks[0]; got.Status != project.StatusFailed || len(backend.models) != 1 {
+		t.Errorf("policy violation must not fall back: status %s, models %v", got.Status, backend.models)
+	}
+}
+
+func TestFallbackBackoff(t *testing.T) {
+	if got := fallbackBackoff(1); got != fallbackBackoffBase {
+		t.Errorf("first backoff = %s", got)
+	}
+	if got := fallbackBackoff(3); got != 4*fallbackBackoffBase {
+		t.Errorf("third backoff = %s", got)
+	}
+	if got := fallbackBackoff(20); got != fallbackBackoffMax {
+		t.Errorf("backoff not capped: %s", got)
+	}
+}
+
+func TestRunnerResumesFromJournal(t *testing.T) {
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "done"), nil, 0644)
+	backend := &scriptedBackend{}
+	runner, store := newTestRunner(t, backend, workDir)
+	journalDir := t.TempDir()
+
+	task, err := store.Claim("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := runner.pool.Chain("build")[0]
+
+	// Erster Lauf: Prompt und Agent erledigt, im Quality Gate abgestürzt
+	journal, err := durable.OpenJournal(durable.NewFilePersistence(journalDir), DefaultJournalSteps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jr := newJournalRun(journal, nil, runner.logger, "demo", task)
+	jr.useBaseRef("")
+	jr.step(PhasePrompt, model, func() (map[string]interface{}, error) {
+		return map[string]interface{}{"prompt": "journaled prompt"}, nil
+	})
+	jr.step(PhaseAgent, model, func() (map[string]interface{}, error) {
+		return map[string]interface{}{"success": true, "model": "nvidia-nim/qwen-3.5-397b", "transcript": "t.jsonl"}, nil
+	})
+	journal.CreateCheckpoint(journalRunID("demo", task.ID), "demo", task.ID, map[string]interface{}{
+		"phase": PhaseGate, "key": jr.key(PhaseGate, model), "attempt": task.Attempts, "model": model,
+	})
+
+	// Neustart: Journal neu laden und den Task fortsetzen
+	reopened, err := durable.OpenJournal(durable.NewFilePersistence(journalDir), DefaultJournalSteps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.journal = reopened
+	if status := runner.run(context.Background(), "demo", task); status != project.StatusCompleted {
+		t.Fatalf("resumed run ended %s", status)
+	}
+
+	if len(backend.models) != 0 {
+		t.Errorf("agent ran again for %v although the journal had its result", backend.models)
+	}
+	b, _ := store.Load("demo")
+	if got := b.Tasks[0]; len(got.History) != 1 || got.History[0].Transcript != "t.jsonl" || got.History[0].Model != "nvidia-nim/qwen-3.5-397b" {
+		t.Errorf("history = %+v", got.History)
+	}
+
+	phases := map[string]string{}
+	for _, cp := range reopened.GetCompletedSteps(journalRunID("demo", task.ID)) {
+		phases[cp.Input["phase"].(string)] = cp.Status
+	}
+	if phases[PhaseGate] != "completed" || phases[PhaseCommit] != "completed" {
+		t.Errorf("completed phases = %v", phases)
+	}
+	if running := reopened.GetRunningStep(journalRunID("demo", task.ID)); running != nil {
+		t.Errorf("interrupted checkpoint still running: %+v", running)
+	}
+}
+
+func TestJournalRunIDIsInjective(t *testing.T) {
+	pairs := [][2]string{
+		{"a.b", "c"},
+		{"a", "b.c"},
+		{"a/b", "c"},
+		{"a-b", "c"},
+		{"demo", "task 1"},
+		{"demo", "task-1"},
+	}
+	seen := make(map[string][2]string)
+	for _, p := range pairs {
+		id := journalRunID(p[0], p[1])
+		if other, ok := seen[id]; ok {
+			t.Errorf("journalRunID(%q, %q) = journalRunID(%q, %q) = %s", p[0], p[1], other[0], other[1], id)
+		}
+		seen[id] = p
+		if strings.ContainsAny(id, "/ ") {
+			t.Errorf("journalRunID(%q, %q) = %q is not a safe file name", p[0], p[1], id)
+		}
+	}
+}
+
+func TestRunnerFallsBackToDaemonWorkDir(t *testing.T) {
+	// Workspace ohne workdir: Agent und Gate laufen wie früher im
+	// Arbeitsverzeichnis des Daemons
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "done"), nil, 0644)
+	t.Chdir(workDir)
+
+	runner, store := newTestRunner(t, &scriptedBackend{}, "")
+	task, err := store.Claim("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := runner.run(context.Background(), "demo", task); status != project.StatusCompleted {
+		b, _ := store.Load("demo")
+		t.Fatalf("status = %s (%s), want completed", status, b.Tasks[0].Error)
+	}
+}
+
+func TestRunnerRunsAgentInJailedCheckout(t *testing.T) {
+	jail := t.TempDir()
+	workDir := filepath.Join(jail, "repo")
+	if err := os.Mkdir(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Der "Agent" legt done im Arbeitsverzeichnis an, das Gate prüft es dort
+	agent := filepath.Join(t.TempDir(), "agent.sh")
+	if err := os.WriteFile(agent, []byte("#!/bin/sh\ntouch done\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	backend := opencode.NewCLIBackend(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	backend.Binary = agent
+	backend.Policies = opencode.PolicySet{Default: opencode.ExecutionPolicy{JailRoot: jail, WallClockSeconds: 30}}
+
+	runner, store := newTestRunner(t, backend, workDir)
+	task, err := store.Claim("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.run(context.Background(), "demo", task)
+
+	b, _ := store.Load("demo")
+	if got := b.Tasks[0]; got.Status != project.StatusCompleted {
+		t.Fatalf("status = %s (%s), want completed", got.Status, got.Error)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "done")); err != nil {
+		t.Errorf("agent did not run in the project checkout: %v", err)
+	}
+}
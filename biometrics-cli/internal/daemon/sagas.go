@@ -0,0 +1,182 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"biometrics-cli/internal/durable"
+)
+
+// Handler der Sagas, die der Daemon ausführt und nach einem Neustart
+// fortsetzt oder zurückrollt. Alle Eingaben sind JSON-Daten; jede Action und
+// Kompensation darf nach einem Absturz erneut laufen.
+const (
+	// SagaBranch legt "branch" ab "base" (Vorgabe HEAD) im Checkout
+	// "workdir" an und wechselt hinein. Die Kompensation wechselt zurück und
+	// löscht den Branch.
+	SagaBranch = "git.branch"
+	// SagaCheck führt "command" (Liste von Argumenten) im Checkout aus, etwa
+	// die Tests. Es gibt nichts zu kompensieren.
+	SagaCheck = "check"
+	// SagaMerge führt "branch" mit --no-ff in "into" zusammen. Die
+	// Kompensation setzt "into" auf den Stand vor dem Merge zurück.
+	SagaMerge = "git.merge"
+)
+
+// NewSagaRegistry liefert die Step-Handler des Daemons.
+func NewSagaRegistry() *durable.Registry {
+	r := durable.NewRegistry()
+	r.Register(SagaBranch, durable.StepHandler{Action: sagaBranch, Compensate: undoSagaBranch})
+	r.Register(SagaCheck, durable.StepHandler{Action: sagaCheck})
+	r.Register(SagaMerge, durable.StepHandler{Action: sagaMerge, Compensate: undoSagaMerge})
+	return r
+}
+
+// recoverSagas setzt unfertige Sagas fort oder rollt sie zurück, bevor der
+// Daemon Tasks verteilt.
+func (d *Daemon) recoverSagas(ctx context.Context) {
+	n, err := d.cfg.Sagas.Recover(ctx)
+	if n > 0 {
+		d.logger.Info("Recovered sagas", slog.Int("sagas", n))
+	}
+	if err != nil {
+		d.logger.Warn("Saga recovery incomplete", slog.String("error", err.Error()))
+	}
+}
+
+func sagaBranch(ctx context.Context, in map[string]interface{}) (map[string]interface{}, error) {
+	dir, branch := inputString(in, "workdir"), inputString(in, "branch")
+	if dir == "" || branch == "" {
+		return nil, fmt.Errorf("%s needs workdir and branch", SagaBranch)
+	}
+	base := inputString(in, "base")
+	if base == "" {
+		base = "HEAD"
+	}
+
+	previous, err := currentRef(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if previous == branch {
+		// Erneuter Lauf nach einem Absturz, der Branch ist schon ausgecheckt
+		previous = base
+	}
+	if _, err := runGit(ctx, dir, "checkout", "-B", branch, base); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"previous": previous}, nil
+}
+
+func undoSagaBranch(ctx context.Context, in, out map[string]interface{}) error {
+	dir, branch := inputString(in, "workdir"), inputString(in, "branch")
+	previous := inputString(out, "previous")
+	if previous == "" {
+		previous = inputString(in, "base")
+	}
+	if previous != "" {
+		if _, err := runGit(ctx, dir, "checkout", "-f", previous); err != nil {
+			return err
+		}
+	}
+	if _, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		// Schon gelöscht
+		return nil
+	}
+	_, err := runGit(ctx, dir, "branch", "-D", branch)
+	return err
+}
+
+func sagaCheck(ctx context.Context, in map[string]interface{}) (map[string]interface{}, error) {
+	args, _ := in["command"].([]interface{})
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s needs a command", SagaCheck)
+	}
+	argv := make([]string, len(args))
+	for i, a := range args {
+		argv[i] = fmt.Sprint(a)
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = inputString(in, "workdir")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", strings.Join(argv, " "), err, lastLines(string(output), 20))
+	}
+	return nil, nil
+}
+
+func sagaMerge(ctx context.Context, in map[string]interface{}) (map[string]interface{}, error) {
+	dir, branch, into := inputString(in, "workdir"), inputString(in, "branch"), inputString(in, "into")
+	if dir == "" || branch == "" || into == "" {
+		return nil, fmt.Errorf("%s needs workdir, branch and into", SagaMerge)
+	}
+	if _, err := runGit(ctx, dir, "checkout", into); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(ctx, dir, "merge-base", "--is-ancestor", branch, "HEAD"); err == nil {
+		// Erneuter Lauf: der Merge-Commit existiert schon, sein erster
+		// Elter ist der Stand davor
+		before, err := runGit(ctx, dir, "rev-parse", "HEAD^1")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"before": before}, nil
+	}
+
+	before, err := runGit(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runGit(ctx, dir, "merge", "--no-ff", "--no-edit", branch); err != nil {
+		runGit(context.WithoutCancel(ctx), dir, "merge", "--abort")
+		return nil, err
+	}
+	return map[string]interface{}{"before": before}, nil
+}
+
+func undoSagaMerge(ctx context.Context, in, out map[string]interface{}) error {
+	dir, into, before := inputString(in, "workdir"), inputString(in, "into"), inputString(out, "before")
+	if before == "" {
+		return fmt.Errorf("%s: no pre-merge revision recorded", SagaMerge)
+	}
+	if _, err := runGit(ctx, dir, "checkout", "-f", into); err != nil {
+		return err
+	}
+	_, err := runGit(ctx, dir, "reset", "--hard", before)
+	return err
+}
+
+// currentRef liefert den ausgecheckten Branch oder, ohne Branch, die Revision.
+func currentRef(ctx context.Context, dir string) (string, error) {
+	ref, err := runGit(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil || ref != "HEAD" {
+		return ref, err
+	}
+	return runGit(ctx, dir, "rev-parse", "HEAD")
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func inputString(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// lastLines kürzt eine Ausgabe auf ihre letzten n Zeilen.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
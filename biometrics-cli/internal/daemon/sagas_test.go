@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"biometrics-cli/internal/durable"
+)
+
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "daemon@example.com"},
+		{"config", "user.name", "Daemon"},
+		{"commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if _, err := runGit(context.Background(), repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestRunCompensatesInterruptedSaga(t *testing.T) {
+	repo := initTestRepo(t)
+	// Die Prüfung hängt beim ersten Lauf und schlägt bei jedem weiteren fehl
+	marker := filepath.Join(t.TempDir(), "started")
+	check := []interface{}{"sh", "-c", `if [ -f "$0" ]; then exit 1; fi; touch "$0"; exec sleep 10`, marker}
+	steps := []*durable.SagaStep{
+		{Name: "branch", Handler: SagaBranch, ActionInput: map[string]interface{}{"workdir": repo, "branch": "feature", "base": "main"}},
+		{Name: "test", Handler: SagaCheck, ActionInput: map[string]interface{}{"workdir": repo, "command": check}},
+		{Name: "merge", Handler: SagaMerge, ActionInput: map[string]interface{}{"workdir": repo, "branch": "feature", "into": "main"}},
+	}
+
+	d := newTestDaemon(t)
+	dir := d.cfg.Workspace.SagasDir()
+	first := durable.NewSagaExecutor(durable.NewFileSagaStore(dir), NewSagaRegistry())
+	saga, err := first.CreateSaga("feature", steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Absturz während der Tests
+	ctx, crash := context.WithCancel(context.Background())
+	go func() {
+		for ctx.Err() == nil {
+			if _, err := os.Stat(marker); err == nil {
+				crash()
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	if err := first.Execute(ctx, saga.ID); err == nil {
+		t.Fatal("Execute should report the interruption")
+	}
+	if ref, _ := currentRef(context.Background(), repo); ref != "feature" {
+		t.Fatalf("checked out %s before the restart, want feature", ref)
+	}
+
+	// Neustart: Run setzt die Saga vor dem ersten Dispatch fort, die Tests
+	// schlagen fehl und der Branch wird zurückgerollt
+	d.cfg.Sagas = durable.NewSagaExecutor(durable.NewFileSagaStore(dir), NewSagaRegistry())
+	runCtx, stop := context.WithTimeout(context.Background(), 10*time.Second)
+	defer stop()
+	done := make(chan error, 1)
+	go func() { done <- d.Run(runCtx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for d.cfg.Sagas.GetStats()["rolled_back"] != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("saga not rolled back: %v", d.cfg.Sagas.GetStats())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	stop()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if ref, _ := currentRef(context.Background(), repo); ref != "main" {
+		t.Errorf("checked out %s after compensation, want main", ref)
+	}
+	if _, err := runGit(context.Background(), repo, "rev-parse", "--verify", "--quiet", "refs/heads/feature"); err == nil {
+		t.Error("branch feature survived the compensation")
+	}
+}
+
+func TestSagaMergeCompensationResetsTarget(t *testing.T) {
+	repo := initTestRepo(t)
+	ctx := context.Background()
+	in := map[string]interface{}{"workdir": repo, "branch": "feature", "into": "main"}
+
+	if _, err := sagaBranch(ctx, map[string]interface{}{"workdir": repo, "branch": "feature"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(ctx, repo, "commit", "-q", "--allow-empty", "-m", "edit"); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := runGit(ctx, repo, "rev-parse", "main")
+
+	out, err := sagaMerge(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ein erneuter Lauf findet den Merge und denselben Stand davor
+	again, err := sagaMerge(ctx, in)
+	if err != nil || again["before"] != before || out["before"] != before {
+		t.Fatalf("merge outputs = %v, %v (%v), want before %s", out, again, err, before)
+	}
+
+	if err := undoSagaMerge(ctx, in, out); err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := runGit(ctx, repo, "rev-parse", "main"); head != before {
+		t.Errorf("main = %s after compensation, want %s", head, before)
+	}
+}
@@ -0,0 +1,24 @@
+package daemon
+
+import (
+	"log/slog"
+
+	"biometrics-cli/internal/webhook"
+	"biometrics-cli/internal/workspace"
+)
+
+// OpenWebhooks öffnet die Webhook-Outbox im Workspace und leitet die
+// Send*-Helfer darüber. Zugestellt wird an die Subscriptions aus
+// WebhookSubscriptionsPath und, falls gesetzt, an BIOMETRICS_WEBHOOK_URL.
+func OpenWebhooks(ws workspace.Workspace, logger *slog.Logger) (*webhook.Outbox, error) {
+	subscriptions, err := webhook.OpenRegistry(ws.WebhookSubscriptionsPath())
+	if err != nil {
+		return nil, err
+	}
+	outbox, err := webhook.OpenOutbox(ws.WebhooksDB(), subscriptions, webhook.ClientFromEnv(), logger)
+	if err != nil {
+		return nil, err
+	}
+	webhook.SetDefaultOutbox(outbox)
+	return outbox, nil
+}
@@ -4,10 +4,12 @@ import (
 	"biometrics-cli/internal/metrics"
 	"biometrics-cli/internal/state"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
 )
@@ -383,6 +385,35 @@ func (m *Manager) HealthCheck() error {
 	return nil
 }
 
+// Prune entfernt gestoppte Container und ungenutzte Images und liefert den
+// laut Docker freigegebenen Speicher je Schritt, z.B. "container 12MB".
+func (m *Manager) Prune(ctx context.Context) ([]string, error) {
+	var reclaimed []string
+	for _, kind := range []string{"container", "image"} {
+		out, err := exec.CommandContext(ctx, "docker", kind, "prune", "-f").CombinedOutput()
+		if err != nil {
+			return reclaimed, fmt.Errorf("docker %s prune failed: %w: %s", kind, err, bytes.TrimSpace(out))
+		}
+		reclaimed = append(reclaimed, kind+" "+reclaimedSpace(out))
+	}
+
+	state.GlobalState.Log("INFO", fmt.Sprintf("Docker prune: %s", strings.Join(reclaimed, ", ")))
+	if err := m.RefreshContainers(); err != nil {
+		return reclaimed, fmt.Errorf("failed to refresh containers after prune: %w", err)
+	}
+	return reclaimed, nil
+}
+
+// reclaimedSpace liest "Total reclaimed space: 1.2GB" aus der Prune-Ausgabe.
+func reclaimedSpace(out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "Total reclaimed space:"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return "0B"
+}
+
 func (m *Manager) GetStats() map[string]interface{} {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -39,8 +39,17 @@ type PersistenceLayer interface {
 	Save(checkpoint *Checkpoint) error
 	Load(agentID string) ([]*Checkpoint, error)
 	Delete(checkpointID string) error
+	// LoadAll liefert alle Checkpoints nach Agent und Schritt geordnet. Bei
+	// unlesbaren Einträgen kommt zusätzlich ein *CorruptError.
+	LoadAll() ([]*Checkpoint, error)
+	// Compact löscht abgeschlossene Läufe, die vor before endeten, und stellt
+	// unlesbare Einträge beiseite.
+	Compact(before time.Time) (CompactStats, error)
+	Close() error
 }
 
+// FilePersistence speichert jeden Checkpoint als JSON-Datei. Geschrieben wird
+// über writeFileSync, ein Absturz hinterlässt also keine halben Dateien.
 type FilePersistence struct {
 	journalDir string
 }
@@ -50,39 +59,119 @@ func NewFilePersistence(journalDir string) *FilePersistence {
 	return &FilePersistence{journalDir: journalDir}
 }
 
+func (fp *FilePersistence) path(checkpointID string) string {
+	return filepath.Join(fp.journalDir, fmt.Sprintf("%s.json", checkpointID))
+}
+
 func (fp *FilePersistence) Save(checkpoint *Checkpoint) error {
-	filename := filepath.Join(fp.journalDir, fmt.Sprintf("%s.json", checkpoint.ID))
 	data, err := json.MarshalIndent(checkpoint, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal checkpoint: %w", err)
 	}
-	return os.WriteFile(filename, data, 0644)
+	if err := writeFileSync(fp.path(checkpoint.ID), data); err != nil {
+		return fmt.Errorf("failed to write checkpoint %s: %w", checkpoint.ID, err)
+	}
+	return nil
 }
 
 func (fp *FilePersistence) Load(agentID string) ([]*Checkpoint, error) {
-	pattern := filepath.Join(fp.journalDir, fmt.Sprintf("%s_*.json", agentID))
-	matches, err := filepath.Glob(pattern)
+	checkpoints, corrupt, err := fp.scan(fmt.Sprintf("%s_*.json", agentID))
+	if err != nil {
+		return nil, err
+	}
+	// das Muster trifft auch Agenten, deren ID mit agentID_ beginnt
+	own := checkpoints[:0]
+	for _, cp := range checkpoints {
+		if cp.AgentID == agentID {
+			own = append(own, cp)
+		}
+	}
+	return own, corruptErr(corrupt)
+}
+
+func (fp *FilePersistence) LoadAll() ([]*Checkpoint, error) {
+	checkpoints, corrupt, err := fp.scan("*.json")
 	if err != nil {
 		return nil, err
 	}
+	return checkpoints, corruptErr(corrupt)
+}
 
-	checkpoints := make([]*Checkpoint, 0)
+// scan liest alle Dateien zu pattern; unlesbare landen in corrupt.
+func (fp *FilePersistence) scan(pattern string) ([]*Checkpoint, []CorruptEntry, error) {
+	matches, err := filepath.Glob(filepath.Join(fp.journalDir, pattern))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	checkpoints := make([]*Checkpoint, 0, len(matches))
+	var corrupt []CorruptEntry
 	for _, match := range matches {
 		data, err := os.ReadFile(match)
 		if err != nil {
+			corrupt = append(corrupt, CorruptEntry{Location: match, Err: err})
 			continue
 		}
 		var cp Checkpoint
-		if err := json.Unmarshal(data, &cp); err == nil {
-			checkpoints = append(checkpoints, &cp)
+		if err := json.Unmarshal(data, &cp); err != nil {
+			corrupt = append(corrupt, CorruptEntry{Location: match, Err: err})
+			continue
+		}
+		if cp.ID == "" || cp.AgentID == "" {
+			corrupt = append(corrupt, CorruptEntry{Location: match, Err: fmt.Errorf("missing id or agent_id")})
+			continue
 		}
+		checkpoints = append(checkpoints, &cp)
 	}
-	return checkpoints, nil
+	sortCheckpoints(checkpoints)
+	return checkpoints, corrupt, nil
 }
 
 func (fp *FilePersistence) Delete(checkpointID string) error {
-	filename := filepath.Join(fp.journalDir, fmt.Sprintf("%s.json", checkpointID))
-	return os.Remove(filename)
+	if err := os.Remove(fp.path(checkpointID)); err != nil {
+		return err
+	}
+	return syncDir(fp.journalDir)
+}
+
+// Compact entfernt Reste abgebrochener Schreibvorgänge, benennt unlesbare
+// Dateien in *.json.corrupt um und löscht abgeschlossene Läufe.
+func (fp *FilePersistence) Compact(before time.Time) (CompactStats, error) {
+	var stats CompactStats
+
+	temps, err := filepath.Glob(filepath.Join(fp.journalDir, "*.tmp"))
+	if err != nil {
+		return stats, err
+	}
+	for _, tmp := range temps {
+		if err := os.Remove(tmp); err != nil {
+			return stats, fmt.Errorf("failed to remove %s: %w", tmp, err)
+		}
+		stats.TempFiles++
+	}
+
+	checkpoints, corrupt, err := fp.scan("*.json")
+	if err != nil {
+		return stats, err
+	}
+	for _, entry := range corrupt {
+		if err := os.Rename(entry.Location, entry.Location+".corrupt"); err != nil {
+			return stats, fmt.Errorf("failed to quarantine %s: %w", entry.Location, err)
+		}
+		stats.Quarantined++
+	}
+	for _, cp := range compactable(checkpoints, before) {
+		if err := os.Remove(fp.path(cp.ID)); err != nil {
+			return stats, fmt.Errorf("failed to remove checkpoint %s: %w", cp.ID, err)
+		}
+		stats.Removed++
+	}
+
+	return stats, syncDir(fp.journalDir)
+}
+
+func (fp *FilePersistence) Close() error {
+	return nil
 }
 
 func NewJournal(journalDir string, maxSteps int) *Journal {
@@ -95,6 +184,31 @@ func NewJournal(journalDir string, maxSteps int) *Journal {
 	}
 }
 
+// OpenJournal öffnet ein Journal auf persistence und lädt alle gespeicherten
+// Checkpoints. Unlesbare Checkpoints sind ein Fehler: ein Lauf mit Lücken
+// ließe sich nicht korrekt fortsetzen (siehe "biometrics journal compact").
+func OpenJournal(persistence PersistenceLayer, maxSteps int) (*Journal, error) {
+	checkpoints, err := persistence.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load journal: %w", err)
+	}
+
+	j := &Journal{
+		checkpoints: make(map[string][]*Checkpoint),
+		maxSteps:    maxSteps,
+		persistence: persistence,
+	}
+	for _, cp := range checkpoints {
+		j.checkpoints[cp.AgentID] = append(j.checkpoints[cp.AgentID], cp)
+	}
+	return j, nil
+}
+
+// Close schließt das Backend.
+func (j *Journal) Close() error {
+	return j.persistence.Close()
+}
+
 func (j *Journal) CreateCheckpoint(agentID, sessionID, taskID string, input map[string]interface{}) (*Checkpoint, error) {
 	j.mu.Lock()
 	defer j.mu.Unlock()
@@ -117,11 +231,10 @@ func (j *Journal) CreateCheckpoint(agentID, sessionID, taskID string, input map[
 		Timestamp:  time.Now(),
 	}
 
-	j.checkpoints[agentID] = append(j.checkpoints[agentID], checkpoint)
-
 	if err := j.persistence.Save(checkpoint); err != nil {
 		return nil, err
 	}
+	j.checkpoints[agentID] = append(j.checkpoints[agentID], checkpoint)
 
 	metrics.DurableCheckpointsCreated.WithLabelValues(agentID).Inc()
 
@@ -129,51 +242,57 @@ func (j *Journal) CreateCheckpoint(agentID, sessionID, taskID string, input map[
 }
 
 func (j *Journal) CompleteCheckpoint(checkpointID string, output map[string]interface{}) error {
-	j.mu.Lock()
-	defer j.mu.Unlock()
-
-	for agentID, checkpoints := range j.checkpoints {
-		for i, cp := range checkpoints {
-			if cp.ID == checkpointID {
-				now := time.Now()
-				cp.Output = output
-				cp.Status = "completed"
-				cp.CompletedAt = &now
-				cp.Duration = time.Since(cp.Timestamp)
+	agentID, err := j.update(checkpointID, func(cp *Checkpoint) {
+		now := time.Now()
+		cp.Output = output
+		cp.Status = "completed"
+		cp.CompletedAt = &now
+		cp.Duration = now.Sub(cp.Timestamp)
+	})
+	if err != nil {
+		return err
+	}
 
-				j.checkpoints[agentID][i] = cp
-				j.persistence.Save(cp)
+	metrics.DurableCheckpointsCompleted.WithLabelValues(agentID).Inc()
+	return nil
+}
 
-				metrics.DurableCheckpointsCompleted.WithLabelValues(agentID).Inc()
-				return nil
-			}
-		}
+func (j *Journal) FailCheckpoint(checkpointID, errMsg string) error {
+	agentID, err := j.update(checkpointID, func(cp *Checkpoint) {
+		failedAt := cp.Timestamp
+		cp.Status = "failed"
+		cp.Error = errMsg
+		cp.CompletedAt = &failedAt
+	})
+	if err != nil {
+		return err
 	}
 
-	return fmt.Errorf("checkpoint not found: %s", checkpointID)
+	metrics.DurableCheckpointsFailed.WithLabelValues(agentID).Inc()
+	return nil
 }
 
-func (j *Journal) FailCheckpoint(checkpointID, errMsg string) error {
+// update ändert eine Kopie des Checkpoints und übernimmt sie erst, wenn das
+// Backend sie gespeichert hat. Speicher und Backend laufen so nie auseinander.
+func (j *Journal) update(checkpointID string, change func(*Checkpoint)) (string, error) {
 	j.mu.Lock()
 	defer j.mu.Unlock()
 
 	for agentID, checkpoints := range j.checkpoints {
-		for i, cp := range checkpoints {
+		for _, cp := range checkpoints {
 			if cp.ID == checkpointID {
-				cp.Status = "failed"
-				cp.Error = errMsg
-				cp.CompletedAt = &cp.Timestamp
-
-				j.checkpoints[agentID][i] = cp
-				j.persistence.Save(cp)
-
-				metrics.DurableCheckpointsFailed.WithLabelValues(agentID).Inc()
-				return nil
+				updated := *cp
+				change(&updated)
+				if err := j.persistence.Save(&updated); err != nil {
+					return agentID, err
+				}
+				*cp = updated
+				return agentID, nil
 			}
 		}
 	}
 
-	return fmt.Errorf("checkpoint not found: %s", checkpointID)
+	return "", fmt.Errorf("checkpoint not found: %s", checkpointID)
 }
 
 func (j *Journal) GetCompletedSteps(agentID string) []*Checkpoint {
@@ -202,10 +321,8 @@ func (j *Journal) GetRunningStep(agentID string) *Checkpoint {
 }
 
 func (j *Journal) Replay(agentID string, executor func(*Checkpoint) error) ([]*Checkpoint, error) {
-	j.mu.RLock()
 	completed := j.GetCompletedSteps(agentID)
 	running := j.GetRunningStep(agentID)
-	j.mu.RUnlock()
 
 	replayed := make([]*Checkpoint, 0)
 
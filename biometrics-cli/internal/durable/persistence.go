@@ -0,0 +1,165 @@
+package durable
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// BackendEnvVar wählt das Backend für OpenPersistence, wenn keins
+	// angegeben ist: "file" (Standard) oder "sqlite".
+	BackendEnvVar = "BIOMETRICS_JOURNAL_BACKEND"
+
+	// SQLiteFile ist der Dateiname der SQLite-Datenbank im Journal-Verzeichnis
+	SQLiteFile = "journal.db"
+)
+
+// OpenPersistence öffnet das Backend backend im Journal-Verzeichnis dir
+// (siehe workspace.Workspace.JournalDir). Ist backend leer, gilt
+// BIOMETRICS_JOURNAL_BACKEND. Für "sqlite" muss das Programm den Treiber
+// github.com/mattn/go-sqlite3 einbinden.
+func OpenPersistence(backend, dir string) (PersistenceLayer, error) {
+	if backend == "" {
+		backend = os.Getenv(BackendEnvVar)
+	}
+	switch backend {
+	case "", "file":
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create journal dir: %w", err)
+		}
+		return NewFilePersistence(dir), nil
+	case "sqlite":
+		return OpenSQLitePersistence(filepath.Join(dir, SQLiteFile))
+	default:
+		return nil, fmt.Errorf("unknown journal backend: %s", backend)
+	}
+}
+
+// CorruptEntry ist ein Checkpoint, der nicht gelesen werden konnte.
+type CorruptEntry struct {
+	// Location ist die Datei bzw. die Zeile in der Datenbank
+	Location string
+	Err      error
+}
+
+// CorruptError meldet unlesbare Checkpoints. Load und LoadAll liefern die
+// lesbaren Checkpoints trotzdem mit; Compact stellt die kaputten beiseite.
+type CorruptError struct {
+	Entries []CorruptEntry
+}
+
+func (e *CorruptError) Error() string {
+	first := e.Entries[0]
+	if len(e.Entries) == 1 {
+		return fmt.Sprintf("corrupt checkpoint %s: %v", first.Location, first.Err)
+	}
+	return fmt.Sprintf("%d corrupt checkpoints, first %s: %v", len(e.Entries), first.Location, first.Err)
+}
+
+func corruptErr(entries []CorruptEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return &CorruptError{Entries: entries}
+}
+
+// CompactStats fasst zusammen, was Compact aufgeräumt hat.
+type CompactStats struct {
+	// Removed zählt gelöschte Checkpoints abgeschlossener Läufe
+	Removed int `json:"removed"`
+	// Quarantined zählt beiseitegestellte unlesbare Checkpoints
+	Quarantined int `json:"quarantined"`
+	// TempFiles zählt Reste abgebrochener Schreibvorgänge
+	TempFiles int `json:"temp_files"`
+}
+
+// compactable wählt die Checkpoints, die Compact löschen darf: alle eines
+// Agenten, dessen Checkpoints sämtlich abgeschlossen sind und vor before
+// endeten. Teilweise gelöschte Läufe ließen sich nicht mehr fortsetzen.
+func compactable(checkpoints []*Checkpoint, before time.Time) []*Checkpoint {
+	byAgent := make(map[string][]*Checkpoint)
+	for _, cp := range checkpoints {
+		byAgent[cp.AgentID] = append(byAgent[cp.AgentID], cp)
+	}
+
+	var remove []*Checkpoint
+	for _, cps := range byAgent {
+		done := true
+		for _, cp := range cps {
+			if cp.Status == "running" || !finishedAt(cp).Before(before) {
+				done = false
+				break
+			}
+		}
+		if done {
+			remove = append(remove, cps...)
+		}
+	}
+	return remove
+}
+
+func finishedAt(cp *Checkpoint) time.Time {
+	if cp.CompletedAt != nil {
+		return *cp.CompletedAt
+	}
+	return cp.Timestamp
+}
+
+// sortCheckpoints ordnet nach Agent und Schritt.
+func sortCheckpoints(checkpoints []*Checkpoint) {
+	sort.SliceStable(checkpoints, func(a, b int) bool {
+		ca, cb := checkpoints[a], checkpoints[b]
+		if ca.AgentID != cb.AgentID {
+			return ca.AgentID < cb.AgentID
+		}
+		if ca.StepNumber != cb.StepNumber {
+			return ca.StepNumber < cb.StepNumber
+		}
+		return ca.Timestamp.Before(cb.Timestamp)
+	})
+}
+
+// writeFileSync schreibt path absturzsicher: erst in eine temporäre Datei im
+// selben Verzeichnis, dann fsync, rename und fsync des Verzeichnisses. Nach
+// einem Absturz liegt entweder der alte oder der neue Inhalt vor.
+func writeFileSync(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir macht Anlegen, Umbenennen und Löschen im Verzeichnis dauerhaft.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
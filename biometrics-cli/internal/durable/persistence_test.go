@@ -0,0 +1,126 @@
+package durable
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func eachBackend(t *testing.T, test func(t *testing.T, open func() PersistenceLayer)) {
+	for _, backend := range []string{"file", "sqlite"} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			test(t, func() PersistenceLayer {
+				p, err := OpenPersistence(backend, dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { p.Close() })
+				return p
+			})
+		})
+	}
+}
+
+func TestJournalSurvivesReopen(t *testing.T) {
+	eachBackend(t, func(t *testing.T, open func() PersistenceLayer) {
+		j, err := OpenJournal(open(), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, _ := j.CreateCheckpoint("agent", "s", "task", map[string]interface{}{"phase": "prompt"})
+		if err := j.CompleteCheckpoint(first.ID, map[string]interface{}{"ok": true}); err != nil {
+			t.Fatal(err)
+		}
+		second, _ := j.CreateCheckpoint("agent", "s", "task", nil)
+		// ein Agent, dessen ID mit der des ersten beginnt
+		j.CreateCheckpoint("agent_2", "s", "task", nil)
+
+		reopened, err := OpenJournal(open(), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		completed := reopened.GetCompletedSteps("agent")
+		if len(completed) != 1 || completed[0].Output["ok"] != true {
+			t.Errorf("completed steps after reopen = %+v", completed)
+		}
+		if running := reopened.GetRunningStep("agent"); running == nil || running.ID != second.ID {
+			t.Errorf("running step after reopen = %+v, want %s", running, second.ID)
+		}
+		if next, _ := reopened.CreateCheckpoint("agent", "s", "task", nil); next.StepNumber != 3 {
+			t.Errorf("next step = %d, want 3", next.StepNumber)
+		}
+	})
+}
+
+func TestCompactRemovesOnlyFinishedRuns(t *testing.T) {
+	eachBackend(t, func(t *testing.T, open func() PersistenceLayer) {
+		p := open()
+		old := time.Now().Add(-48 * time.Hour)
+		done := old.Add(time.Minute)
+		save := func(cp *Checkpoint) {
+			if err := p.Save(cp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		save(&Checkpoint{ID: "done_1", AgentID: "done", StepNumber: 1, Status: "completed", Timestamp: old, CompletedAt: &done})
+		save(&Checkpoint{ID: "done_2", AgentID: "done", StepNumber: 2, Status: "failed", Timestamp: old, CompletedAt: &done})
+		save(&Checkpoint{ID: "busy_1", AgentID: "busy", StepNumber: 1, Status: "completed", Timestamp: old, CompletedAt: &done})
+		save(&Checkpoint{ID: "busy_2", AgentID: "busy", StepNumber: 2, Status: "running", Timestamp: old})
+
+		stats, err := p.Compact(time.Now().Add(-24 * time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Removed != 2 {
+			t.Errorf("removed %d checkpoints, want 2", stats.Removed)
+		}
+		left, err := p.LoadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(left) != 2 || left[0].ID != "busy_1" || left[1].ID != "busy_2" {
+			t.Errorf("left after compaction = %+v", left)
+		}
+	})
+}
+
+func TestFilePersistenceReportsCorruptCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	p := NewFilePersistence(dir)
+	if err := p.Save(&Checkpoint{ID: "a_1", AgentID: "a", StepNumber: 1, Status: "running", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "a_2.json"), []byte(`{"id": "a_2", "agent`), 0644)
+	os.WriteFile(filepath.Join(dir, "a_3.json.123.tmp"), []byte(`{`), 0644)
+
+	checkpoints, err := p.Load("a")
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) || len(corrupt.Entries) != 1 {
+		t.Fatalf("Load error = %v, want one corrupt checkpoint", err)
+	}
+	if len(checkpoints) != 1 {
+		t.Errorf("Load returned %d readable checkpoints, want 1", len(checkpoints))
+	}
+	if _, err := OpenJournal(p, 10); err == nil {
+		t.Error("OpenJournal accepted a corrupt journal")
+	}
+
+	stats, err := p.Compact(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Quarantined != 1 || stats.TempFiles != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a_2.json.corrupt")); err != nil {
+		t.Errorf("corrupt checkpoint not quarantined: %v", err)
+	}
+	if _, err := OpenJournal(p, 10); err != nil {
+		t.Errorf("OpenJournal after compaction: %v", err)
+	}
+}
@@ -1,7 +1,13 @@
 package durable
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 )
@@ -17,111 +23,353 @@ type Saga struct {
 	mu          sync.RWMutex
 }
 
+// SagaStep verweist über Handler auf einen registrierten StepHandler; mehr
+// als Name und JSON-Daten wird nicht gespeichert.
 type SagaStep struct {
-	Name         string                 `json:"name"`
-	Action       func() error           `json:"-"`
-	Compensate   func() error           `json:"-"`
+	Name string `json:"name"`
+	// Handler ist der Name im Registry, leer heißt wie Name
+	Handler      string                 `json:"handler,omitempty"`
 	ActionInput  map[string]interface{} `json:"action_input,omitempty"`
 	ActionOutput map[string]interface{} `json:"action_output,omitempty"`
-	Status       string                 `json:"status"` // "pending", "running", "completed", "failed", "compensated"
+	Status       string                 `json:"status"` // "pending", "running", "completed", "failed", "compensating", "compensated"
 	Error        string                 `json:"error,omitempty"`
 	StartedAt    time.Time              `json:"started_at"`
 	CompletedAt  *time.Time             `json:"completed_at,omitempty"`
 }
 
+func (s *SagaStep) handler() string {
+	if s.Handler != "" {
+		return s.Handler
+	}
+	return s.Name
+}
+
+// ActionFunc führt einen Schritt aus. Die Ausgabe wird gespeichert und an
+// die Kompensation übergeben. Nach einem Absturz mitten im Schritt läuft die
+// Action erneut, sie muss also idempotent sein.
+type ActionFunc func(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error)
+
+// CompensateFunc macht einen abgeschlossenen Schritt rückgängig; auch sie
+// kann nach einem Absturz erneut laufen.
+type CompensateFunc func(ctx context.Context, input, output map[string]interface{}) error
+
+// StepHandler ist die Logik hinter einem Schritt. Compensate ist optional.
+type StepHandler struct {
+	Action     ActionFunc
+	Compensate CompensateFunc
+}
+
+// Registry ordnet Handler-Namen ihre StepHandler zu. Nach einem Neustart
+// müssen dieselben Namen registriert sein, sonst bleibt die Saga liegen.
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]StepHandler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]StepHandler)}
+}
+
+// Register registriert h unter name; ein vorhandener Handler wird ersetzt.
+func (r *Registry) Register(name string, h StepHandler) error {
+	if name == "" || h.Action == nil {
+		return fmt.Errorf("step handler needs a name and an action")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[name] = h
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (StepHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.handlers[name]
+	return h, ok
+}
+
+// check meldet Schritte, deren Handler nicht registriert ist.
+func (r *Registry) check(steps []*SagaStep) error {
+	for _, step := range steps {
+		if _, ok := r.Lookup(step.handler()); !ok {
+			return fmt.Errorf("step %s: unknown handler %q", step.Name, step.handler())
+		}
+	}
+	return nil
+}
+
+// SagaStore speichert Sagas über Neustarts hinweg.
+type SagaStore interface {
+	Save(saga *Saga) error
+	// LoadAll liefert alle Sagas; bei unlesbaren kommt ein *CorruptError
+	LoadAll() ([]*Saga, error)
+	Delete(sagaID string) error
+}
+
+// FileSagaStore schreibt jede Saga absturzsicher als JSON-Datei.
+type FileSagaStore struct {
+	dir string
+}
+
+func NewFileSagaStore(dir string) *FileSagaStore {
+	os.MkdirAll(dir, 0755)
+	return &FileSagaStore{dir: dir}
+}
+
+func (fs *FileSagaStore) path(sagaID string) string {
+	return filepath.Join(fs.dir, sagaID+".json")
+}
+
+func (fs *FileSagaStore) Save(saga *Saga) error {
+	data, err := json.MarshalIndent(saga, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal saga: %w", err)
+	}
+	if err := writeFileSync(fs.path(saga.ID), data); err != nil {
+		return fmt.Errorf("failed to write saga %s: %w", saga.ID, err)
+	}
+	return nil
+}
+
+func (fs *FileSagaStore) LoadAll() ([]*Saga, error) {
+	matches, err := filepath.Glob(filepath.Join(fs.dir, "saga_*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	sagas := make([]*Saga, 0, len(matches))
+	var corrupt []CorruptEntry
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err != nil {
+			corrupt = append(corrupt, CorruptEntry{Location: match, Err: err})
+			continue
+		}
+		saga := &Saga{}
+		if err := json.Unmarshal(data, saga); err != nil {
+			corrupt = append(corrupt, CorruptEntry{Location: match, Err: err})
+			continue
+		}
+		sagas = append(sagas, saga)
+	}
+	sort.Slice(sagas, func(i, j int) bool { return sagas[i].StartedAt.Before(sagas[j].StartedAt) })
+	return sagas, corruptErr(corrupt)
+}
+
+func (fs *FileSagaStore) Delete(sagaID string) error {
+	if err := os.Remove(fs.path(sagaID)); err != nil {
+		return err
+	}
+	return syncDir(fs.dir)
+}
+
 type SagaExecutor struct {
-	sagas       map[string]*Saga
-	sagaDir     string
-	persistence *FilePersistence
-	mu          sync.RWMutex
+	sagas    map[string]*Saga
+	store    SagaStore
+	registry *Registry
+	mu       sync.RWMutex
 }
 
-func NewSagaExecutor(sagaDir string) *SagaExecutor {
+func NewSagaExecutor(store SagaStore, registry *Registry) *SagaExecutor {
 	return &SagaExecutor{
-		sagas:       make(map[string]*Saga),
-		sagaDir:     sagaDir,
-		persistence: NewFilePersistence(sagaDir),
+		sagas:    make(map[string]*Saga),
+		store:    store,
+		registry: registry,
 	}
 }
 
-func (se *SagaExecutor) CreateSaga(name string, steps []*SagaStep) *Saga {
+// CreateSaga legt eine Saga an und speichert sie. Alle Handler müssen
+// registriert sein.
+func (se *SagaExecutor) CreateSaga(name string, steps []*SagaStep) (*Saga, error) {
+	if err := se.registry.check(steps); err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		step.Status = "pending"
+	}
 	saga := &Saga{
-		ID:          fmt.Sprintf("saga_%d_%s", time.Now().UnixNano(), name),
+		ID:          fmt.Sprintf("saga_%d", time.Now().UnixNano()),
 		Name:        name,
 		Steps:       steps,
 		Status:      "running",
 		StartedAt:   time.Now(),
 		CurrentStep: 0,
 	}
+	if err := se.store.Save(saga); err != nil {
+		return nil, err
+	}
 
 	se.mu.Lock()
 	defer se.mu.Unlock()
 	se.sagas[saga.ID] = saga
 
-	return saga
+	return saga, nil
 }
 
-func (se *SagaExecutor) Execute(sagaID string) error {
-	se.mu.RLock()
-	saga, exists := se.sagas[sagaID]
-	se.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("saga not found: %s", sagaID)
+// Execute führt die Saga ab CurrentStep aus und speichert ihren Zustand nach
+// jedem Schritt. Scheitert ein Schritt, werden die abgeschlossenen in
+// umgekehrter Reihenfolge kompensiert. Endet ctx, bleibt die Saga, wie sie
+// ist, und Recover setzt sie beim nächsten Start fort.
+func (se *SagaExecutor) Execute(ctx context.Context, sagaID string) error {
+	saga, err := se.GetSaga(sagaID)
+	if err != nil {
+		return err
 	}
 
 	saga.mu.Lock()
 	defer saga.mu.Unlock()
 
+	switch saga.Status {
+	case "running":
+	case "compensating":
+		return se.compensate(ctx, saga)
+	default:
+		return fmt.Errorf("saga %s is %s", saga.ID, saga.Status)
+	}
+
 	for saga.CurrentStep < len(saga.Steps) {
 		step := saga.Steps[saga.CurrentStep]
+		handler, ok := se.registry.Lookup(step.handler())
+		if !ok {
+			return fmt.Errorf("saga %s step %s: unknown handler %q", saga.ID, step.Name, step.handler())
+		}
+
 		step.Status = "running"
 		step.StartedAt = time.Now()
+		step.Error = ""
+		if err := se.store.Save(saga); err != nil {
+			return err
+		}
 
-		err := step.Action()
+		output, err := handler.Action(ctx, step.ActionInput)
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("saga %s interrupted at step %d: %w", saga.ID, saga.CurrentStep, ctx.Err())
+		}
 		if err != nil {
 			step.Status = "failed"
 			step.Error = err.Error()
 			saga.Status = "compensating"
+			if serr := se.store.Save(saga); serr != nil {
+				return serr
+			}
 
-			se.compensate(saga)
+			if cerr := se.compensate(ctx, saga); cerr != nil {
+				return fmt.Errorf("saga failed at step %d: %w (%v)", saga.CurrentStep, err, cerr)
+			}
 			return fmt.Errorf("saga failed at step %d: %w", saga.CurrentStep, err)
 		}
 
 		step.Status = "completed"
+		step.ActionOutput = output
 		now := time.Now()
 		step.CompletedAt = &now
 		saga.CurrentStep++
+		if err := se.store.Save(saga); err != nil {
+			return err
+		}
 	}
 
 	saga.Status = "completed"
 	now := time.Now()
 	saga.CompletedAt = &now
 
-	return nil
+	return se.store.Save(saga)
 }
 
-func (se *SagaExecutor) compensate(saga *Saga) {
-	saga.mu.Lock()
-	defer saga.mu.Unlock()
-
+// compensate rollt die abgeschlossenen Schritte vor CurrentStep zurück. Schlägt
+// eine Kompensation fehl, endet die Saga als failed und braucht einen Menschen.
+// Der Aufrufer hält saga.mu.
+func (se *SagaExecutor) compensate(ctx context.Context, saga *Saga) error {
+	var failed error
 	for i := saga.CurrentStep - 1; i >= 0; i-- {
 		step := saga.Steps[i]
-		if step.Status == "completed" && step.Compensate != nil {
-			step.Status = "compensating"
+		if step.Status != "completed" && step.Status != "compensating" {
+			continue
+		}
+		handler, ok := se.registry.Lookup(step.handler())
+		if !ok {
+			return fmt.Errorf("saga %s step %s: unknown handler %q", saga.ID, step.Name, step.handler())
+		}
+		if handler.Compensate == nil {
+			continue
+		}
 
-			if err := step.Compensate(); err != nil {
-				step.Error = fmt.Sprintf("compensation failed: %v", err)
-				continue
-			}
+		step.Status = "compensating"
+		if err := se.store.Save(saga); err != nil {
+			return err
+		}
 
+		if err := handler.Compensate(ctx, step.ActionInput, step.ActionOutput); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("saga %s interrupted while compensating: %w", saga.ID, ctx.Err())
+			}
+			step.Status = "failed"
+			step.Error = fmt.Sprintf("compensation failed: %v", err)
+			failed = errors.Join(failed, fmt.Errorf("compensate %s: %w", step.Name, err))
+		} else {
 			step.Status = "compensated"
 			now := time.Now()
 			step.CompletedAt = &now
 		}
+		if err := se.store.Save(saga); err != nil {
+			return err
+		}
 	}
 
 	saga.Status = "rolled_back"
+	if failed != nil {
+		saga.Status = "failed"
+	}
+	now := time.Now()
+	saga.CompletedAt = &now
+	if err := se.store.Save(saga); err != nil {
+		return err
+	}
+	return failed
+}
+
+// Recover lädt die gespeicherten Sagas und bringt unfertige zu Ende:
+// laufende werden ab dem unterbrochenen Schritt fortgesetzt, kompensierende
+// weiter zurückgerollt. Sagas mit unbekannten Handlern bleiben unangetastet,
+// abgeschlossene und zurückgerollte bleiben nur im Store, bis Prune sie löscht.
+// Der Fehler fasst zusammen, was nicht glatt ging.
+func (se *SagaExecutor) Recover(ctx context.Context) (int, error) {
+	sagas, err := se.store.LoadAll()
+	var corrupt *CorruptError
+	if err != nil && !errors.As(err, &corrupt) {
+		return 0, err
+	}
+	errs := []error{err}
+
+	var pending []string
+	se.mu.Lock()
+	for _, saga := range sagas {
+		if _, exists := se.sagas[saga.ID]; exists || finished(saga.Status) {
+			continue
+		}
+		se.sagas[saga.ID] = saga
+		if saga.Status == "running" || saga.Status == "compensating" {
+			pending = append(pending, saga.ID)
+		}
+	}
+	se.mu.Unlock()
+
+	recovered := 0
+	for _, id := range pending {
+		saga, _ := se.GetSaga(id)
+		if err := se.registry.check(saga.Steps); err != nil {
+			errs = append(errs, fmt.Errorf("saga %s not recovered: %w", id, err))
+			continue
+		}
+		recovered++
+		if err := se.Execute(ctx, id); err != nil {
+			errs = append(errs, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return recovered, errors.Join(errs...)
 }
 
 func (se *SagaExecutor) GetSaga(sagaID string) (*Saga, error) {
@@ -135,6 +383,54 @@ func (se *SagaExecutor) GetSaga(sagaID string) (*Saga, error) {
 	return nil, fmt.Errorf("saga not found: %s", sagaID)
 }
 
+// finished ist true für Sagas, mit denen nichts mehr zu tun ist. Gescheiterte
+// Sagas zählen nicht dazu, sie brauchen einen Menschen.
+func finished(status string) bool {
+	return status == "completed" || status == "rolled_back"
+}
+
+// Prune löscht abgeschlossene und zurückgerollte Sagas, die vor before
+// endeten, aus dem Speicher und dem Store, und liefert ihre Zahl.
+func (se *SagaExecutor) Prune(before time.Time) (int, error) {
+	stored, err := se.store.LoadAll()
+	var corrupt *CorruptError
+	if err != nil && !errors.As(err, &corrupt) {
+		return 0, err
+	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	candidates := make(map[string]*Saga, len(stored)+len(se.sagas))
+	for _, saga := range stored {
+		candidates[saga.ID] = saga
+	}
+	for id, saga := range se.sagas {
+		// Eine laufende Execute hält saga.mu und ist ohnehin nicht fertig
+		if !saga.mu.TryRLock() {
+			delete(candidates, id)
+			continue
+		}
+		candidates[id] = &Saga{ID: saga.ID, Status: saga.Status, CompletedAt: saga.CompletedAt}
+		saga.mu.RUnlock()
+	}
+
+	pruned := 0
+	var errs []error
+	for id, saga := range candidates {
+		if !finished(saga.Status) || saga.CompletedAt == nil || !saga.CompletedAt.Before(before) {
+			continue
+		}
+		if err := se.store.Delete(id); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to delete saga %s: %w", id, err))
+			continue
+		}
+		delete(se.sagas, id)
+		pruned++
+	}
+	return pruned, errors.Join(errs...)
+}
+
 func (se *SagaExecutor) GetStats() map[string]interface{} {
 	se.mu.RLock()
 	defer se.mu.RUnlock()
@@ -145,7 +441,10 @@ func (se *SagaExecutor) GetStats() map[string]interface{} {
 	failed := 0
 
 	for _, saga := range se.sagas {
-		switch saga.Status {
+		saga.mu.RLock()
+		status := saga.Status
+		saga.mu.RUnlock()
+		switch status {
 		case "running":
 			running++
 		case "completed":
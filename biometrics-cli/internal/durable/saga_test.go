@@ -0,0 +1,157 @@
+package durable
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// gitFlow registriert branch → edit → test → merge und protokolliert jeden
+// Aufruf in calls.
+func gitFlow(calls *[]string, testErr func() error) *Registry {
+	r := NewRegistry()
+	step := func(name string, fail func() error) {
+		r.Register(name, StepHandler{
+			Action: func(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+				*calls = append(*calls, name)
+				if fail != nil {
+					if err := fail(); err != nil {
+						return nil, err
+					}
+				}
+				return map[string]interface{}{"ref": name + "-" + input["branch"].(string)}, nil
+			},
+			Compensate: func(ctx context.Context, input, output map[string]interface{}) error {
+				*calls = append(*calls, "undo "+output["ref"].(string))
+				return nil
+			},
+		})
+	}
+	step("branch", nil)
+	step("edit", nil)
+	step("test", testErr)
+	step("merge", nil)
+	return r
+}
+
+func flowSteps() []*SagaStep {
+	var steps []*SagaStep
+	for _, name := range []string{"branch", "edit", "test", "merge"} {
+		steps = append(steps, &SagaStep{Name: name, ActionInput: map[string]interface{}{"branch": "feature"}})
+	}
+	return steps
+}
+
+func TestSagaCompensatesInReverseOrder(t *testing.T) {
+	var calls []string
+	store := NewFileSagaStore(t.TempDir())
+	se := NewSagaExecutor(store, gitFlow(&calls, func() error { return errors.New("tests failed") }))
+
+	saga, err := se.CreateSaga("feature", flowSteps())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := se.Execute(context.Background(), saga.ID); err == nil {
+		t.Fatal("Execute succeeded although the tests failed")
+	}
+
+	want := []string{"branch", "edit", "test", "undo edit-feature", "undo branch-feature"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("calls = %v, want %v", calls, want)
+		}
+	}
+
+	stored, err := store.LoadAll()
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("LoadAll = %v, %v", stored, err)
+	}
+	if stored[0].Status != "rolled_back" || stored[0].Steps[0].Status != "compensated" || stored[0].Steps[2].Status != "failed" {
+		t.Errorf("stored saga = %+v", stored[0])
+	}
+}
+
+func TestSagaResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	var calls []string
+	ctx, crash := context.WithCancel(context.Background())
+	first := NewSagaExecutor(NewFileSagaStore(dir), gitFlow(&calls, func() error {
+		// Absturz mitten in den Tests
+		crash()
+		return ctx.Err()
+	}))
+	saga, err := first.CreateSaga("feature", flowSteps())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Execute(ctx, saga.ID); err == nil {
+		t.Fatal("Execute should report the interruption")
+	}
+
+	calls = nil
+	restarted := NewSagaExecutor(NewFileSagaStore(dir), gitFlow(&calls, nil))
+	n, err := restarted.Recover(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("Recover = %d, %v", n, err)
+	}
+	if len(calls) != 2 || calls[0] != "test" || calls[1] != "merge" {
+		t.Errorf("resumed calls = %v, want [test merge]", calls)
+	}
+	resumed, _ := restarted.GetSaga(saga.ID)
+	if resumed.Status != "completed" || resumed.Steps[3].ActionOutput["ref"] != "merge-feature" {
+		t.Errorf("resumed saga = %+v", resumed)
+	}
+}
+
+func TestSagaRequiresRegisteredHandlers(t *testing.T) {
+	dir := t.TempDir()
+	var calls []string
+	se := NewSagaExecutor(NewFileSagaStore(dir), gitFlow(&calls, nil))
+	if _, err := se.CreateSaga("bad", []*SagaStep{{Name: "deploy"}}); err == nil {
+		t.Error("CreateSaga accepted an unknown handler")
+	}
+
+	// Eine Saga, deren Handler nach dem Neustart fehlen, bleibt liegen
+	saga, _ := se.CreateSaga("feature", flowSteps())
+	restarted := NewSagaExecutor(NewFileSagaStore(dir), NewRegistry())
+	if n, err := restarted.Recover(context.Background()); n != 0 || err == nil {
+		t.Errorf("Recover = %d, %v; want 0 and an error", n, err)
+	}
+	if got, _ := restarted.GetSaga(saga.ID); got.Status != "running" {
+		t.Errorf("status = %s, want running", got.Status)
+	}
+}
+
+func TestSagaPruneDropsFinishedSagas(t *testing.T) {
+	dir := t.TempDir()
+	var calls []string
+	se := NewSagaExecutor(NewFileSagaStore(dir), gitFlow(&calls, nil))
+	done, _ := se.CreateSaga("feature", flowSteps())
+	if err := se.Execute(context.Background(), done.ID); err != nil {
+		t.Fatal(err)
+	}
+	pending, _ := se.CreateSaga("feature", flowSteps())
+
+	if n, err := se.Prune(done.StartedAt); n != 0 || err != nil {
+		t.Fatalf("Prune before completion = %d, %v", n, err)
+	}
+	if n, err := se.Prune(time.Now().Add(time.Second)); n != 1 || err != nil {
+		t.Fatalf("Prune = %d, %v; want 1", n, err)
+	}
+	if _, err := se.GetSaga(done.ID); err == nil {
+		t.Error("pruned saga is still known")
+	}
+
+	// Nach einem Neustart ist nur die offene Saga übrig
+	restarted := NewSagaExecutor(NewFileSagaStore(dir), gitFlow(&calls, nil))
+	if n, err := restarted.Recover(context.Background()); n != 1 || err != nil {
+		t.Fatalf("Recover = %d, %v", n, err)
+	}
+	if stats := restarted.GetStats(); stats["total_sagas"] != 1 {
+		t.Errorf("stats = %v, want only %s", stats, pending.ID)
+	}
+}
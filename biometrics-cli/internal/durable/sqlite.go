@@ -0,0 +1,197 @@
+package durable
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SQLitePersistence hält Checkpoints in einer SQLite-Datenbank. Jede Änderung
+// läuft in einer Transaktion; die Spalten neben data dienen nur der Abfrage.
+type SQLitePersistence struct {
+	db *sql.DB
+	// owned: Close schließt die Datenbank nur, wenn sie hier geöffnet wurde
+	owned bool
+}
+
+// OpenSQLitePersistence öffnet die Datenbank unter path im WAL-Modus mit
+// synchronous=FULL, damit ein bestätigter Commit einen Absturz übersteht.
+func OpenSQLitePersistence(path string) (*SQLitePersistence, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal dir: %w", err)
+	}
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_synchronous=FULL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal database: %w", err)
+	}
+	p, err := NewSQLitePersistence(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	p.owned = true
+	return p, nil
+}
+
+// NewSQLitePersistence legt die Tabellen checkpoints und
+// checkpoint_quarantine in db an.
+func NewSQLitePersistence(db *sql.DB) (*SQLitePersistence, error) {
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS checkpoints (
+			id TEXT PRIMARY KEY,
+			agent_id TEXT NOT NULL,
+			step_number INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			finished_at TEXT NOT NULL,
+			data TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS checkpoints_agent ON checkpoints (agent_id, step_number)`,
+		`CREATE TABLE IF NOT EXISTS checkpoint_quarantine (
+			id TEXT PRIMARY KEY,
+			data TEXT NOT NULL,
+			error TEXT NOT NULL,
+			quarantined_at TEXT NOT NULL
+		)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("failed to create journal schema: %w", err)
+		}
+	}
+	return &SQLitePersistence{db: db}, nil
+}
+
+// Save legt den Checkpoint an oder ersetzt ihn in einer Transaktion.
+func (p *SQLitePersistence) Save(checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO checkpoints (id, agent_id, step_number, status, finished_at, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET agent_id = excluded.agent_id, step_number = excluded.step_number,
+			status = excluded.status, finished_at = excluded.finished_at, data = excluded.data`,
+		checkpoint.ID, checkpoint.AgentID, checkpoint.StepNumber, checkpoint.Status,
+		finishedAt(checkpoint).UTC().Format(time.RFC3339Nano), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint %s: %w", checkpoint.ID, err)
+	}
+	return tx.Commit()
+}
+
+func (p *SQLitePersistence) Load(agentID string) ([]*Checkpoint, error) {
+	checkpoints, corrupt, err := p.query(`SELECT id, data FROM checkpoints WHERE agent_id = ?
+		ORDER BY step_number, id`, agentID)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoints, corruptErr(corrupt)
+}
+
+func (p *SQLitePersistence) LoadAll() ([]*Checkpoint, error) {
+	checkpoints, corrupt, err := p.query(`SELECT id, data FROM checkpoints ORDER BY agent_id, step_number, id`)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoints, corruptErr(corrupt)
+}
+
+func (p *SQLitePersistence) query(query string, args ...interface{}) ([]*Checkpoint, []CorruptEntry, error) {
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	checkpoints := make([]*Checkpoint, 0)
+	var corrupt []CorruptEntry
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan checkpoint: %w", err)
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal([]byte(data), &cp); err != nil {
+			corrupt = append(corrupt, CorruptEntry{Location: id, Err: err})
+			continue
+		}
+		checkpoints = append(checkpoints, &cp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	sortCheckpoints(checkpoints)
+	return checkpoints, corrupt, nil
+}
+
+func (p *SQLitePersistence) Delete(checkpointID string) error {
+	res, err := p.db.Exec(`DELETE FROM checkpoints WHERE id = ?`, checkpointID)
+	if err != nil {
+		return fmt.Errorf("failed to delete checkpoint %s: %w", checkpointID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("checkpoint not found: %s", checkpointID)
+	}
+	return nil
+}
+
+// Compact verschiebt unlesbare Zeilen nach checkpoint_quarantine und löscht
+// abgeschlossene Läufe in einer Transaktion; danach gibt VACUUM den Platz frei.
+func (p *SQLitePersistence) Compact(before time.Time) (CompactStats, error) {
+	var stats CompactStats
+
+	checkpoints, corrupt, err := p.query(`SELECT id, data FROM checkpoints`)
+	if err != nil {
+		return stats, err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, entry := range corrupt {
+		_, err := tx.Exec(`INSERT OR REPLACE INTO checkpoint_quarantine (id, data, error, quarantined_at)
+			SELECT id, data, ?, ? FROM checkpoints WHERE id = ?`, entry.Err.Error(), now, entry.Location)
+		if err != nil {
+			return stats, fmt.Errorf("failed to quarantine checkpoint %s: %w", entry.Location, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM checkpoints WHERE id = ?`, entry.Location); err != nil {
+			return stats, fmt.Errorf("failed to quarantine checkpoint %s: %w", entry.Location, err)
+		}
+		stats.Quarantined++
+	}
+	for _, cp := range compactable(checkpoints, before) {
+		if _, err := tx.Exec(`DELETE FROM checkpoints WHERE id = ?`, cp.ID); err != nil {
+			return stats, fmt.Errorf("failed to delete checkpoint %s: %w", cp.ID, err)
+		}
+		stats.Removed++
+	}
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit compaction: %w", err)
+	}
+
+	if _, err := p.db.Exec(`VACUUM`); err != nil {
+		return stats, fmt.Errorf("failed to vacuum journal: %w", err)
+	}
+	return stats, nil
+}
+
+func (p *SQLitePersistence) Close() error {
+	if !p.owned {
+		return nil
+	}
+	return p.db.Close()
+}
@@ -453,6 +453,10 @@ func (r *Repository) GetLastCommit() string {
 	return r.lastCommit
 }
 
+func (r *Repository) GetPath() string {
+	return r.path
+}
+
 func (r *Repository) GetRemoteURL() string {
 	return r.remoteURL
 }
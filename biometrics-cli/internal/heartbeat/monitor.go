@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -41,6 +42,8 @@ type HeartbeatConfig struct {
 	StuckThreshold time.Duration
 	MaxLoad        float64
 	PID            int
+	// Dir nimmt die Heartbeat-Dateien auf, Standard ./heartbeat-data
+	Dir string
 }
 
 type Monitor struct {
@@ -73,6 +76,9 @@ func NewHeartbeatMonitor(config *HeartbeatConfig) *Monitor {
 	if config.StuckThreshold == 0 {
 		config.StuckThreshold = 30 * time.Minute
 	}
+	if config.Dir == "" {
+		config.Dir = "./heartbeat-data"
+	}
 
 	return &Monitor{
 		heartbeats:  make(map[string]*Heartbeat),
@@ -80,7 +86,7 @@ func NewHeartbeatMonitor(config *HeartbeatConfig) *Monitor {
 		alertChan:   make(chan *Alert, 100),
 		ctx:         ctx,
 		cancel:      cancel,
-		persistence: NewFilePersistence("./heartbeat-data"),
+		persistence: NewFilePersistence(config.Dir),
 	}
 }
 
@@ -192,7 +198,9 @@ func (m *Monitor) checkHeartbeats() {
 	for agentID, hb := range m.heartbeats {
 		sinceLastBeat := now.Sub(hb.LastBeat)
 
-		if sinceLastBeat > m.config.Timeout {
+		// Ein beschäftigter Agent meldet sich erst nach seinem Task wieder,
+		// für ihn gilt StuckThreshold statt Timeout
+		if sinceLastBeat > m.config.Timeout && hb.Status != StatusBusy && hb.Status != StatusStuck && hb.Status != StatusDead {
 			hb.Status = StatusDead
 			m.alertChan <- &Alert{
 				AgentID:   agentID,
@@ -226,6 +234,23 @@ func (m *Monitor) checkHeartbeats() {
 	}
 }
 
+// Check prüft alle Heartbeats sofort und liefert die Agenten je Status.
+func (m *Monitor) Check() map[Status][]string {
+	m.checkHeartbeats()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byStatus := make(map[Status][]string)
+	for agentID, hb := range m.heartbeats {
+		byStatus[hb.Status] = append(byStatus[hb.Status], agentID)
+	}
+	for _, ids := range byStatus {
+		sort.Strings(ids)
+	}
+	return byStatus
+}
+
 func (m *Monitor) GetHeartbeat(agentID string) (*Heartbeat, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
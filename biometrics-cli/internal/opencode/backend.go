@@ -0,0 +1,68 @@
+package opencode
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// AgentBackend starts agent runs. The OpenCode CLI is the production
+// implementation; ReplayBackend replays recorded transcripts so the
+// orchestrator loop can run end to end without a real model.
+type AgentBackend interface {
+	Start(ctx context.Context, req AgentRequest) (AgentRun, error)
+}
+
+// AgentRun is a single started agent.
+//
+// Stream must be drained by the caller: backends block on it instead of
+// dropping lines. The channel is closed once the agent has exited.
+type AgentRun interface {
+	// Stream returns the agent's stdout and stderr lines in arrival order.
+	Stream() <-chan OutputLine
+	// Cancel stops the run, including every process the agent spawned.
+	Cancel() error
+	// Result blocks until the run has finished and returns its outcome.
+	Result() AgentResult
+}
+
+const (
+	// BackendEnvVar selects the backend used by NewBackendFromEnv.
+	BackendEnvVar = "BIOMETRICS_AGENT_BACKEND"
+	// RecordingsEnvVar points the replay backend at a directory of recordings.
+	RecordingsEnvVar = "BIOMETRICS_AGENT_RECORDINGS"
+	// PolicyEnvVar points the CLI backend at a JSON PolicySet.
+	PolicyEnvVar = "BIOMETRICS_AGENT_POLICY"
+)
+
+// NewBackendFromEnv returns the backend named by BIOMETRICS_AGENT_BACKEND:
+// "opencode" (default) or "replay", which loads its recordings from
+// BIOMETRICS_AGENT_RECORDINGS. The opencode backend reads its execution
+// policies from BIOMETRICS_AGENT_POLICY when set.
+func NewBackendFromEnv(logger *slog.Logger) (AgentBackend, error) {
+	switch name := os.Getenv(BackendEnvVar); name {
+	case "", "opencode":
+		backend := NewCLIBackend(logger)
+		if path := os.Getenv(PolicyEnvVar); path != "" {
+			policies, err := LoadPolicies(path)
+			if err != nil {
+				return nil, err
+			}
+			backend.Policies = policies
+		}
+		return backend, nil
+	case "replay":
+		dir := os.Getenv(RecordingsEnvVar)
+		if dir == "" {
+			return nil, fmt.Errorf("%s=replay requires %s", BackendEnvVar, RecordingsEnvVar)
+		}
+		recordings, err := LoadRecordings(dir)
+		if err != nil {
+			return nil, err
+		}
+		return NewReplayBackend(recordings...), nil
+	default:
+		return nil, fmt.Errorf("unknown agent backend: %s", name)
+	}
+}
@@ -0,0 +1,104 @@
+package opencode
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestReplayBackendFromRecordings(t *testing.T) {
+	recordings, err := LoadRecordings("testdata/recordings")
+	if err != nil {
+		t.Fatalf("LoadRecordings failed: %v", err)
+	}
+	if len(recordings) != 2 {
+		t.Fatalf("Expected 2 recordings, got %d", len(recordings))
+	}
+
+	backend := NewReplayBackend(recordings...)
+	executor := NewExecutorWithBackend(testLogger(), backend)
+	executor.SetTranscriptStore(nil)
+
+	result := executor.RunAgent(context.Background(), AgentRequest{ProjectID: "demo", Category: "build", Prompt: "do it"})
+	if !result.Success {
+		t.Fatalf("Expected success, got %v", result.Error)
+	}
+	if result.Output != "Reading plan file\ngo fmt ./...\nTask complete" {
+		t.Errorf("Unexpected output: %q", result.Output)
+	}
+
+	result = executor.RunAgent(context.Background(), AgentRequest{ProjectID: "demo", Category: "quick"})
+	if result.Success {
+		t.Error("Expected failure for non-zero exit code")
+	}
+
+	if got := len(backend.Requests()); got != 2 {
+		t.Errorf("Expected 2 recorded requests, got %d", got)
+	}
+}
+
+func TestReplayBackendNoMatch(t *testing.T) {
+	backend := NewReplayBackend(&Recording{Name: "only-deep", Category: "deep"})
+	executor := NewExecutorWithBackend(testLogger(), backend)
+	executor.SetTranscriptStore(nil)
+
+	result := executor.RunAgent(context.Background(), AgentRequest{Category: "build"})
+	if result.Success || result.Error == nil {
+		t.Error("Expected error when no recording matches")
+	}
+}
+
+func TestReplayRunCancel(t *testing.T) {
+	backend := NewReplayBackend(&Recording{
+		Name:  "slow",
+		Lines: []RecordedLine{{Text: "first"}, {Text: "never", DelayMS: 10000}},
+	})
+
+	run, err := backend.Start(context.Background(), AgentRequest{})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if line := <-run.Stream(); line.Text != "first" {
+		t.Errorf("Expected first line, got %q", line.Text)
+	}
+	run.Cancel()
+
+	done := make(chan AgentResult)
+	go func() {
+		for range run.Stream() {
+		}
+		done <- run.Result()
+	}()
+
+	select {
+	case result := <-done:
+		if result.Success {
+			t.Error("Expected cancelled run to fail")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Cancel did not stop the run")
+	}
+}
+
+func TestCLIBackendArgs(t *testing.T) {
+	backend := NewCLIBackend(testLogger())
+	args := backend.Args(AgentRequest{Model: "qwen", Agent: "sisyphus", Prompt: "hi"})
+
+	expected := []string{"--model", "qwen", "--agent", "sisyphus", "--prompt", "hi"}
+	if len(args) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, args)
+	}
+	for i := range expected {
+		if args[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, args)
+			break
+		}
+	}
+}
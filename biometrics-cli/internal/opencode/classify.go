@@ -0,0 +1,45 @@
+package opencode
+
+// FailureClass fasst Fehler für Retry-Entscheidungen zusammen: Timeout,
+// Rate-Limit und Absturz lassen sich mit einem anderen Modell erneut
+// versuchen, Policy-Verstöße und Abbrüche nicht.
+type FailureClass string
+
+const (
+	FailureNone        FailureClass = ""
+	FailureTimeout     FailureClass = "timeout"
+	FailureRateLimited FailureClass = "rate_limited"
+	FailureCrash       FailureClass = "crash"
+	FailureQualityGate FailureClass = "quality_gate"
+	FailurePolicy      FailureClass = "policy"
+	FailureCanceled    FailureClass = "canceled"
+)
+
+// Classify ordnet einem ErrorKind seine FailureClass zu.
+func Classify(kind ErrorKind) FailureClass {
+	switch kind {
+	case ErrorKindNone:
+		return FailureNone
+	case ErrorKindTimeout:
+		return FailureTimeout
+	case ErrorKindRateLimited:
+		return FailureRateLimited
+	case ErrorKindPolicy:
+		return FailurePolicy
+	case ErrorKindCanceled:
+		return FailureCanceled
+	default:
+		// agent_failed, start_failed, oom, cpu_limit, file_size_limit
+		return FailureCrash
+	}
+}
+
+// Retryable ist true, wenn ein Versuch mit dem nächsten Modell sinnvoll ist.
+func (c FailureClass) Retryable() bool {
+	switch c {
+	case FailureTimeout, FailureRateLimited, FailureCrash, FailureQualityGate:
+		return true
+	default:
+		return false
+	}
+}
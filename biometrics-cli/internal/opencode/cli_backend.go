@@ -0,0 +1,203 @@
+package opencode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// CLIBackend runs agents through the opencode binary.
+type CLIBackend struct {
+	// Binary is the executable to run, "opencode" unless overridden.
+	Binary string
+	// Policies selects the execution policy by AgentRequest.Category.
+	Policies PolicySet
+	logger   *slog.Logger
+}
+
+// NewCLIBackend creates a backend for the opencode binary found in PATH.
+func NewCLIBackend(logger *slog.Logger) *CLIBackend {
+	return &CLIBackend{Binary: "opencode", Policies: DefaultPolicies(), logger: logger}
+}
+
+// Args builds the opencode command line for req.
+func (b *CLIBackend) Args(req AgentRequest) []string {
+	var args []string
+	if req.Model != "" {
+		args = append(args, "--model", req.Model)
+	}
+	if req.Agent != "" {
+		args = append(args, "--agent", req.Agent)
+	}
+	return append(args, "--prompt", req.Prompt)
+}
+
+// Start launches the agent process in its own process group, confined by
+// the execution policy for req.Category. Unless the policy names its own
+// jail, the agent is jailed to req.ProjectDir.
+func (b *CLIBackend) Start(ctx context.Context, req AgentRequest) (AgentRun, error) {
+	policy := b.Policies.For(req.Category)
+	if policy.JailRoot == "" {
+		policy.JailRoot = req.ProjectDir
+	}
+	workDir := req.WorkDir
+	if workDir == "" {
+		workDir = req.ProjectDir
+	}
+
+	workDir, err := policy.ResolveWorkDir(workDir)
+	if err != nil {
+		return nil, &RunError{Kind: ErrorKindPolicy, Err: err}
+	}
+
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if budget := policy.WallClock(); budget > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, budget)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+
+	cmd := exec.CommandContext(runCtx, b.Binary, b.Args(req)...)
+	cmd.Dir = workDir
+
+	// PFLICHT: Process Group ID setzen, damit wir den ganzen Tree killen können
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	// Environment nur über die Allowlist (Mandat 0.38 - Project Isolation)
+	cmd.Env = append(policy.Env(os.Environ()), fmt.Sprintf("PROJECT_ID=%s", req.ProjectID))
+
+	box, err := prepareSandbox(cmd, policy, fmt.Sprintf("agent-%d", time.Now().UnixNano()))
+	if err != nil {
+		cancel()
+		return nil, &RunError{Kind: ErrorKindPolicy, Err: err}
+	}
+
+	run := &cliRun{
+		ctx:     runCtx,
+		cancel:  cancel,
+		cmd:     cmd,
+		policy:  policy,
+		sandbox: box,
+		done:    make(chan struct{}),
+	}
+
+	// Stdout/Stderr Streaming (siehe stream.go)
+	streamDone := make(chan struct{})
+	run.lines = streamOutput(b.logger, cmd, streamDone)
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		box.cleanup()
+		return nil, &RunError{Kind: ErrorKindStartFailed, Err: fmt.Errorf("failed to start agent: %w", err)}
+	}
+
+	if err := box.started(cmd.Process.Pid); err != nil {
+		run.policyErr = err
+		run.Cancel()
+		b.logger.Error("Failed to apply execution policy", "error", err)
+	}
+
+	go run.wait(streamDone)
+
+	return run, nil
+}
+
+type cliRun struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	cmd     *exec.Cmd
+	policy  ExecutionPolicy
+	sandbox *sandbox
+	// policyErr is set when the sandbox could not be applied
+	policyErr error
+	lines     <-chan OutputLine
+	done      chan struct{}
+	result    AgentResult
+}
+
+func (r *cliRun) Stream() <-chan OutputLine {
+	return r.lines
+}
+
+func (r *cliRun) Cancel() error {
+	if r.cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-r.cmd.Process.Pid, syscall.SIGKILL)
+}
+
+func (r *cliRun) Result() AgentResult {
+	<-r.done
+	return r.result
+}
+
+// wait reaps the process once its pipes have been read to EOF.
+func (r *cliRun) wait(streamDone <-chan struct{}) {
+	defer close(r.done)
+	defer r.sandbox.cleanup()
+	defer r.cancel()
+
+	<-streamDone
+	err := r.cmd.Wait()
+	exitCode := r.cmd.ProcessState.ExitCode()
+
+	if r.policyErr != nil {
+		r.result = AgentResult{Success: false, Error: &RunError{Kind: ErrorKindPolicy, Err: r.policyErr}, ErrorKind: ErrorKindPolicy, ExitCode: exitCode}
+		return
+	}
+
+	// Cleanup: Falls Context canceled wurde, kille die GANZE Process Group!
+	if ctxErr := r.ctx.Err(); ctxErr != nil {
+		r.Cancel()
+		kind := ErrorKindCanceled
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			kind = ErrorKindTimeout
+		}
+		r.result = AgentResult{Success: false, Error: &RunError{Kind: kind, Err: ctxErr}, ErrorKind: kind, ExitCode: exitCode}
+		return
+	}
+
+	if err != nil {
+		kind := r.classify()
+		if kind != ErrorKindAgentFailed {
+			err = &RunError{Kind: kind, Err: err}
+		}
+		r.result = AgentResult{Success: false, Error: err, ErrorKind: kind, ExitCode: exitCode}
+		return
+	}
+
+	r.result = AgentResult{Success: true, ExitCode: exitCode}
+}
+
+// classify maps how the process died to an ErrorKind.
+func (r *cliRun) classify() ErrorKind {
+	if r.sandbox.oomKilled() {
+		return ErrorKindOOM
+	}
+
+	if status, ok := r.cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		switch status.Signal() {
+		case syscall.SIGXCPU:
+			return ErrorKindCPULimit
+		case syscall.SIGXFSZ:
+			return ErrorKindFileSizeLimit
+		case syscall.SIGKILL:
+			// Hartes CPU-Limit: der Kernel killt nach SIGXCPU mit SIGKILL
+			used := r.cmd.ProcessState.UserTime() + r.cmd.ProcessState.SystemTime()
+			if r.policy.CPUSeconds > 0 && used >= time.Duration(r.policy.CPUSeconds)*time.Second {
+				return ErrorKindCPULimit
+			}
+		}
+	}
+
+	return ErrorKindAgentFailed
+}
@@ -1,64 +1,128 @@
 package opencode
 
 import (
+	"biometrics-cli/internal/ratelimit"
 	"biometrics-cli/internal/telemetry"
 	"context"
-	"fmt"
 	"log/slog"
-	"os/exec"
-	"syscall"
+	"strings"
+	"time"
 )
 
 type Executor struct {
-	logger *slog.Logger
+	logger      *slog.Logger
+	backend     AgentBackend
+	transcripts TranscriptStore
 }
 
+// NewExecutor nutzt die OpenCode CLI als Backend.
 func NewExecutor(logger *slog.Logger) *Executor {
-	return &Executor{logger: logger}
+	return NewExecutorWithBackend(logger, NewCLIBackend(logger))
 }
 
-// RunAgent startet den OpenCode Prozess. Es MUSS SysProcAttr für Process Groups nutzen!
+// NewExecutorWithBackend erlaubt ein anderes Backend, z.B. ReplayBackend in der CI.
+func NewExecutorWithBackend(logger *slog.Logger, backend AgentBackend) *Executor {
+	return &Executor{
+		logger:      logger,
+		backend:     backend,
+		transcripts: NewFileTranscriptStore(DefaultTranscriptDir()),
+	}
+}
+
+// Backend gibt das verwendete AgentBackend zurück.
+func (e *Executor) Backend() AgentBackend {
+	return e.backend
+}
+
+// SetTranscriptStore legt fest, wo Transcripts gespeichert werden. nil deaktiviert das Speichern.
+func (e *Executor) SetTranscriptStore(store TranscriptStore) {
+	e.transcripts = store
+}
+
+// Transcripts gibt den TranscriptStore zurück (kann nil sein).
+func (e *Executor) Transcripts() TranscriptStore {
+	return e.transcripts
+}
+
+// RunAgent startet den Agenten über das Backend und wartet auf sein Ende.
+// Jeder Lauf erzeugt ein Transcript; AgentResult.TranscriptRef verweist darauf.
 func (e *Executor) RunAgent(ctx context.Context, req AgentRequest) AgentResult {
 	telemetry.LogWithTrace(ctx, e.logger, slog.LevelInfo, "Starting OpenCode Agent",
 		slog.String("model", req.Model),
 		slog.String("project", req.ProjectID),
+		slog.String("task", req.TaskID),
 	)
 
-	// Command Aufbau
-	cmd := exec.CommandContext(ctx, "opencode", "--model", req.Model, "--prompt", req.Prompt)
-
-	// PFLICHT: Process Group ID setzen, damit wir den ganzen Tree killen können
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-
-	// Environment Variablen setzen (Mandat 0.38 - Project Isolation)
-	cmd.Env = append(cmd.Environ(), fmt.Sprintf("PROJECT_ID=%s", req.ProjectID))
-
-	// Stdout/Stderr Streaming (siehe stream.go)
-	outChan := e.streamOutput(cmd)
+	transcript := &Transcript{
+		ProjectID: req.ProjectID,
+		TaskID:    req.TaskID,
+		TraceID:   telemetry.GetTraceID(ctx),
+		Model:     req.Model,
+		Category:  req.Category,
+		Agent:     req.Agent,
+		StartedAt: time.Now(),
+		Lines:     make([]OutputLine, 0),
+	}
 
-	err := cmd.Start()
+	var result AgentResult
+	run, err := e.backend.Start(ctx, req)
 	if err != nil {
-		return AgentResult{Success: false, Error: fmt.Errorf("failed to start agent: %w", err)}
+		result = AgentResult{Success: false, Error: err, ErrorKind: ErrorKindOf(err), ExitCode: -1}
+	} else {
+		// Output MUSS komplett gelesen werden, sonst blockiert das Backend
+		var output []string
+		for line := range run.Stream() {
+			level := slog.LevelDebug
+			if line.Stream == StreamStderr {
+				level = slog.LevelInfo
+			}
+			telemetry.LogWithTrace(ctx, e.logger, level, "Agent Output",
+				slog.String("stream", line.Stream),
+				slog.String("line", line.Text),
+			)
+			transcript.Lines = append(transcript.Lines, line)
+			output = append(output, line.Text)
+		}
+
+		result = run.Result()
+		result.Output = strings.Join(output, "\n")
 	}
 
-	// Logge Output asynchron
-	go func() {
-		for line := range outChan {
-			telemetry.LogWithTrace(ctx, e.logger, slog.LevelDebug, "Agent Output", slog.String("line", line))
+	// Ein abgebrochener Lauf mit 429 in der Ausgabe ist ein Rate-Limit, kein Agent-Fehler
+	if result.ErrorKind == ErrorKindAgentFailed {
+		if retryAfter, ok := ratelimit.DetectThrottle(result.Output); ok {
+			result.ErrorKind = ErrorKindRateLimited
+			result.RetryAfter = retryAfter
 		}
-	}()
-
-	err = cmd.Wait()
+	}
 
-	// Cleanup: Falls Context canceled wurde, kille die GANZE Process Group!
-	if ctx.Err() != nil {
-		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-		return AgentResult{Success: false, Error: ctx.Err()}
+	transcript.DurationMS = time.Since(transcript.StartedAt).Milliseconds()
+	transcript.ExitCode = result.ExitCode
+	transcript.Success = result.Success
+	transcript.ErrorKind = result.ErrorKind
+	if result.Error != nil {
+		transcript.Error = result.Error.Error()
 	}
 
-	if err != nil {
-		return AgentResult{Success: false, Error: err}
+	if e.transcripts != nil {
+		ref, err := e.transcripts.Save(transcript)
+		if err != nil {
+			telemetry.LogWithTrace(ctx, e.logger, slog.LevelError, "Failed to save transcript",
+				slog.String("error", err.Error()),
+			)
+		} else {
+			result.TranscriptRef = ref
+		}
 	}
 
-	return AgentResult{Success: true, Output: "Agent finished successfully"}
+	telemetry.LogWithTrace(ctx, e.logger, slog.LevelInfo, "OpenCode Agent finished",
+		slog.String("project", req.ProjectID),
+		slog.Bool("success", result.Success),
+		slog.String("error_kind", string(result.ErrorKind)),
+		slog.Int("exit_code", result.ExitCode),
+		slog.Int64("duration_ms", transcript.DurationMS),
+		slog.String("transcript", result.TranscriptRef),
+	)
+
+	return result
 }
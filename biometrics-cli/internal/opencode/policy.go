@@ -0,0 +1,142 @@
+package opencode
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExecutionPolicy limits what a single agent run may consume and see.
+// Zero values mean "no limit".
+type ExecutionPolicy struct {
+	// WallClockSeconds kills the agent after this many seconds.
+	WallClockSeconds int `json:"wall_clock_seconds,omitempty"`
+
+	// Resource limits applied to the agent process (Linux only).
+	CPUSeconds   uint64 `json:"cpu_seconds,omitempty"`
+	MemoryBytes  uint64 `json:"memory_bytes,omitempty"`
+	MaxFileBytes uint64 `json:"max_file_bytes,omitempty"`
+	MaxOpenFiles uint64 `json:"max_open_files,omitempty"`
+	MaxProcesses uint64 `json:"max_processes,omitempty"`
+
+	// EnvAllowlist lists the variables passed through from our environment.
+	// Entries ending in "*" match by prefix. Empty means DefaultEnvAllowlist.
+	EnvAllowlist []string `json:"env_allowlist,omitempty"`
+
+	// JailRoot confines AgentRequest.WorkDir to this directory tree. Empty
+	// means AgentRequest.ProjectDir.
+	JailRoot string `json:"jail_root,omitempty"`
+
+	// Cgroup places the agent into a fresh cgroup v2 below this directory,
+	// e.g. /sys/fs/cgroup/biometrics (Linux only).
+	Cgroup          string `json:"cgroup,omitempty"`
+	CgroupMemoryMax string `json:"cgroup_memory_max,omitempty"`
+	CgroupCPUMax    string `json:"cgroup_cpu_max,omitempty"`
+	CgroupPidsMax   string `json:"cgroup_pids_max,omitempty"`
+}
+
+// DefaultEnvAllowlist is passed to agents when a policy has no allowlist.
+var DefaultEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_*", "TERM", "TZ", "TMPDIR",
+	"OPENCODE_*", "NVIDIA_API_KEY", "MOONSHOT_API_KEY", "OPENROUTER_API_KEY", "ANTHROPIC_API_KEY",
+	"GOPATH", "GOCACHE", "GOMODCACHE", "GOFLAGS",
+	"GIT_AUTHOR_*", "GIT_COMMITTER_*", "SSH_AUTH_SOCK",
+}
+
+// WallClock returns the wall-clock budget as a duration.
+func (p ExecutionPolicy) WallClock() time.Duration {
+	return time.Duration(p.WallClockSeconds) * time.Second
+}
+
+// HasRlimits reports whether any process resource limit is configured.
+func (p ExecutionPolicy) HasRlimits() bool {
+	return p.CPUSeconds > 0 || p.MemoryBytes > 0 || p.MaxFileBytes > 0 || p.MaxOpenFiles > 0 || p.MaxProcesses > 0
+}
+
+// Env filters environ through the allowlist.
+func (p ExecutionPolicy) Env(environ []string) []string {
+	allow := p.EnvAllowlist
+	if len(allow) == 0 {
+		allow = DefaultEnvAllowlist
+	}
+
+	env := make([]string, 0, len(allow))
+	for _, kv := range environ {
+		name, _, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		for _, pattern := range allow {
+			if pattern == name || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(name, strings.TrimSuffix(pattern, "*"))) {
+				env = append(env, kv)
+				break
+			}
+		}
+	}
+	return env
+}
+
+// ResolveWorkDir checks that dir lies inside the jail and returns its
+// absolute, symlink-free form. Without a jail dir is returned as is.
+func (p ExecutionPolicy) ResolveWorkDir(dir string) (string, error) {
+	if p.JailRoot == "" {
+		return dir, nil
+	}
+	if dir == "" {
+		return "", fmt.Errorf("policy requires a working directory inside %s", p.JailRoot)
+	}
+
+	root, err := filepath.EvalSymlinks(p.JailRoot)
+	if err != nil {
+		return "", fmt.Errorf("invalid jail root: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory: %w", err)
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("working directory %s escapes jail %s", dir, p.JailRoot)
+	}
+	return resolved, nil
+}
+
+// PolicySet maps agent categories to execution policies.
+type PolicySet struct {
+	Default    ExecutionPolicy            `json:"default"`
+	Categories map[string]ExecutionPolicy `json:"categories,omitempty"`
+}
+
+// DefaultPolicies applies the default env allowlist and a one hour wall
+// clock to every category.
+func DefaultPolicies() PolicySet {
+	return PolicySet{
+		Default: ExecutionPolicy{WallClockSeconds: 3600},
+	}
+}
+
+// For returns the policy for category, falling back to the default.
+func (s PolicySet) For(category string) ExecutionPolicy {
+	if p, ok := s.Categories[category]; ok {
+		return p
+	}
+	return s.Default
+}
+
+// LoadPolicies reads a PolicySet from a JSON file.
+func LoadPolicies(path string) (PolicySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PolicySet{}, err
+	}
+
+	var set PolicySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return PolicySet{}, fmt.Errorf("failed to parse execution policy %s: %w", path, err)
+	}
+	return set, nil
+}
@@ -0,0 +1,119 @@
+package opencode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeAgent writes a shell script that stands in for the opencode binary.
+func fakeAgent(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fake-opencode")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write fake agent: %v", err)
+	}
+	return path
+}
+
+func TestPolicyEnvAllowlist(t *testing.T) {
+	policy := ExecutionPolicy{EnvAllowlist: []string{"PATH", "OPENCODE_*"}}
+	env := policy.Env([]string{"PATH=/bin", "OPENCODE_KEY=x", "AWS_SECRET_ACCESS_KEY=y", "OPENCODEX"})
+
+	if strings.Join(env, ",") != "PATH=/bin,OPENCODE_KEY=x" {
+		t.Errorf("Unexpected filtered env: %v", env)
+	}
+}
+
+func TestPolicyJail(t *testing.T) {
+	root := t.TempDir()
+	inside := filepath.Join(root, "project")
+	os.Mkdir(inside, 0755)
+
+	policy := ExecutionPolicy{JailRoot: root}
+	if _, err := policy.ResolveWorkDir(inside); err != nil {
+		t.Errorf("Expected project dir to be allowed: %v", err)
+	}
+	if _, err := policy.ResolveWorkDir(t.TempDir()); err == nil {
+		t.Error("Expected directory outside the jail to be rejected")
+	}
+	if _, err := policy.ResolveWorkDir(""); err == nil {
+		t.Error("Expected empty working directory to be rejected")
+	}
+}
+
+func TestCLIBackendPolicyEnforcement(t *testing.T) {
+	t.Setenv("BIOMETRICS_TEST_SECRET", "leaked")
+
+	backend := NewCLIBackend(testLogger())
+	backend.Binary = fakeAgent(t, `echo "secret=$BIOMETRICS_TEST_SECRET project=$PROJECT_ID"; echo oops >&2; exit 3`)
+
+	executor := NewExecutorWithBackend(testLogger(), backend)
+	executor.SetTranscriptStore(nil)
+
+	result := executor.RunAgent(context.Background(), AgentRequest{ProjectID: "demo"})
+	if result.Success {
+		t.Fatal("Expected failure for exit code 3")
+	}
+	if result.ErrorKind != ErrorKindAgentFailed || result.ExitCode != 3 {
+		t.Errorf("Expected agent_failed/3, got %s/%d", result.ErrorKind, result.ExitCode)
+	}
+	if !strings.Contains(result.Output, "secret= project=demo") {
+		t.Errorf("Expected filtered environment, got %q", result.Output)
+	}
+	if !strings.Contains(result.Output, "oops") {
+		t.Errorf("Expected stderr in output, got %q", result.Output)
+	}
+}
+
+func TestCLIBackendWallClock(t *testing.T) {
+	backend := NewCLIBackend(testLogger())
+	backend.Binary = fakeAgent(t, "sleep 30")
+	backend.Policies = PolicySet{Default: ExecutionPolicy{WallClockSeconds: 1}}
+
+	executor := NewExecutorWithBackend(testLogger(), backend)
+	executor.SetTranscriptStore(nil)
+
+	result := executor.RunAgent(context.Background(), AgentRequest{})
+	if result.ErrorKind != ErrorKindTimeout {
+		t.Errorf("Expected timeout, got %s (%v)", result.ErrorKind, result.Error)
+	}
+}
+
+func TestCLIBackendJailViolation(t *testing.T) {
+	backend := NewCLIBackend(testLogger())
+	backend.Binary = fakeAgent(t, "true")
+	backend.Policies = PolicySet{Categories: map[string]ExecutionPolicy{
+		"build": {JailRoot: t.TempDir()},
+	}}
+
+	executor := NewExecutorWithBackend(testLogger(), backend)
+	executor.SetTranscriptStore(nil)
+
+	result := executor.RunAgent(context.Background(), AgentRequest{Category: "build", WorkDir: t.TempDir()})
+	if result.ErrorKind != ErrorKindPolicy {
+		t.Errorf("Expected policy violation, got %s (%v)", result.ErrorKind, result.Error)
+	}
+}
+
+func TestCLIBackendJailsToProjectDir(t *testing.T) {
+	backend := NewCLIBackend(testLogger())
+	backend.Binary = fakeAgent(t, "pwd")
+
+	executor := NewExecutorWithBackend(testLogger(), backend)
+	executor.SetTranscriptStore(nil)
+
+	project := t.TempDir()
+	result := executor.RunAgent(context.Background(), AgentRequest{Category: "build", WorkDir: t.TempDir(), ProjectDir: project})
+	if result.ErrorKind != ErrorKindPolicy {
+		t.Errorf("Expected policy violation outside the project, got %s (%v)", result.ErrorKind, result.Error)
+	}
+
+	result = executor.RunAgent(context.Background(), AgentRequest{Category: "build", ProjectDir: project})
+	want, _ := filepath.EvalSymlinks(project)
+	if !result.Success || strings.TrimSpace(result.Output) != want {
+		t.Errorf("Expected agent to run in %s, got %q (%v)", want, result.Output, result.Error)
+	}
+}
@@ -0,0 +1,207 @@
+package opencode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recording is a scripted agent run replayed by ReplayBackend.
+//
+// Project, Category and Match select which requests the recording answers;
+// empty selectors match everything. Match is a substring of the prompt.
+// ErrorKind lets a recording simulate e.g. an OOM kill or a timeout.
+type Recording struct {
+	Name      string         `json:"name"`
+	Project   string         `json:"project,omitempty"`
+	Category  string         `json:"category,omitempty"`
+	Match     string         `json:"match,omitempty"`
+	Lines     []RecordedLine `json:"lines"`
+	ExitCode  int            `json:"exit_code"`
+	Error     string         `json:"error,omitempty"`
+	ErrorKind ErrorKind      `json:"error_kind,omitempty"`
+}
+
+// RecordedLine is one line of recorded agent output.
+type RecordedLine struct {
+	Stream  string `json:"stream,omitempty"`
+	Text    string `json:"text"`
+	DelayMS int    `json:"delay_ms,omitempty"`
+}
+
+func (r *Recording) matches(req AgentRequest) bool {
+	if r.Project != "" && r.Project != req.ProjectID {
+		return false
+	}
+	if r.Category != "" && r.Category != req.Category {
+		return false
+	}
+	if r.Match != "" && !strings.Contains(req.Prompt, r.Match) {
+		return false
+	}
+	return true
+}
+
+// LoadRecordings reads every *.json recording in dir, sorted by file name.
+func LoadRecordings(dir string) ([]*Recording, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	recordings := make([]*Recording, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recording %s: %w", file, err)
+		}
+
+		var rec Recording
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("failed to parse recording %s: %w", file, err)
+		}
+		if rec.Name == "" {
+			rec.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+		}
+		recordings = append(recordings, &rec)
+	}
+
+	return recordings, nil
+}
+
+// ReplayBackend answers agent requests with recorded transcripts. The first
+// recording whose selectors match a request is replayed.
+type ReplayBackend struct {
+	mu         sync.Mutex
+	recordings []*Recording
+	requests   []AgentRequest
+}
+
+// NewReplayBackend creates a backend that replays the given recordings.
+func NewReplayBackend(recordings ...*Recording) *ReplayBackend {
+	return &ReplayBackend{recordings: recordings}
+}
+
+// Add appends a recording. Earlier recordings take precedence.
+func (b *ReplayBackend) Add(rec *Recording) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.recordings = append(b.recordings, rec)
+}
+
+// Requests returns every request the backend has received so far.
+func (b *ReplayBackend) Requests() []AgentRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]AgentRequest(nil), b.requests...)
+}
+
+func (b *ReplayBackend) Start(ctx context.Context, req AgentRequest) (AgentRun, error) {
+	b.mu.Lock()
+	b.requests = append(b.requests, req)
+	var rec *Recording
+	for _, r := range b.recordings {
+		if r.matches(req) {
+			rec = r
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	if rec == nil {
+		return nil, fmt.Errorf("no recording matches project %q category %q", req.ProjectID, req.Category)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	run := &replayRun{
+		lines:  make(chan OutputLine),
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	go run.play(runCtx, rec)
+
+	return run, nil
+}
+
+type replayRun struct {
+	lines  chan OutputLine
+	done   chan struct{}
+	cancel context.CancelFunc
+	result AgentResult
+}
+
+func (r *replayRun) Stream() <-chan OutputLine {
+	return r.lines
+}
+
+func (r *replayRun) Cancel() error {
+	r.cancel()
+	return nil
+}
+
+func (r *replayRun) Result() AgentResult {
+	<-r.done
+	return r.result
+}
+
+func (r *replayRun) play(ctx context.Context, rec *Recording) {
+	defer close(r.done)
+	defer r.cancel()
+
+	for _, line := range rec.Lines {
+		if line.DelayMS > 0 {
+			select {
+			case <-time.After(time.Duration(line.DelayMS) * time.Millisecond):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		stream := line.Stream
+		if stream == "" {
+			stream = StreamStdout
+		}
+		select {
+		case r.lines <- OutputLine{Time: time.Now(), Stream: stream, Text: line.Text}:
+		case <-ctx.Done():
+		}
+	}
+	close(r.lines)
+
+	var err error
+	switch {
+	case ctx.Err() != nil:
+		kind := ErrorKindCanceled
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			kind = ErrorKindTimeout
+		}
+		r.result = AgentResult{Success: false, Error: &RunError{Kind: kind, Err: ctx.Err()}, ErrorKind: kind, ExitCode: -1}
+		return
+	case rec.Error != "":
+		err = errors.New(rec.Error)
+	case rec.ExitCode != 0:
+		err = fmt.Errorf("exit status %d", rec.ExitCode)
+	case rec.ErrorKind != ErrorKindNone:
+		err = errors.New(string(rec.ErrorKind))
+	default:
+		r.result = AgentResult{Success: true}
+		return
+	}
+
+	kind := rec.ErrorKind
+	if kind == ErrorKindNone {
+		kind = ErrorKindAgentFailed
+	} else if kind != ErrorKindAgentFailed {
+		err = &RunError{Kind: kind, Err: err}
+	}
+	r.result = AgentResult{Success: false, Error: err, ErrorKind: kind, ExitCode: rec.ExitCode}
+}
@@ -0,0 +1,237 @@
+//go:build linux
+
+package opencode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// shimEnv carries the rlimits to the re-executed sandbox shim, see
+// runShim. Its value is "<status fd>,<resource>:<soft>:<hard>,...".
+const shimEnv = "BIOMETRICS_SANDBOX_RLIMITS"
+
+// Any binary that starts agents through CLIBackend doubles as the sandbox
+// shim: started with shimEnv set, it applies the limits to itself and execs
+// the agent, so the limits are in place before the agent's first
+// instruction and every process it forks inherits them.
+func init() {
+	if spec, ok := os.LookupEnv(shimEnv); ok {
+		runShim(spec)
+	}
+}
+
+// sandbox applies an ExecutionPolicy's resource limits to one agent process.
+type sandbox struct {
+	policy    ExecutionPolicy
+	cgroupDir string
+	cgroupFD  *os.File
+	// status is written by the shim when it cannot apply a limit; it reads
+	// EOF once the agent was exec'd
+	status, statusW *os.File
+}
+
+type rlimit struct {
+	resource int
+	value    uint64
+	slack    uint64
+}
+
+func (p ExecutionPolicy) rlimits() []rlimit {
+	return []rlimit{
+		// Soft CPU limit delivers SIGXCPU, the hard limit a few seconds later SIGKILL
+		{unix.RLIMIT_CPU, p.CPUSeconds, 5},
+		{unix.RLIMIT_AS, p.MemoryBytes, 0},
+		{unix.RLIMIT_FSIZE, p.MaxFileBytes, 0},
+		{unix.RLIMIT_NOFILE, p.MaxOpenFiles, 0},
+		{unix.RLIMIT_NPROC, p.MaxProcesses, 0},
+	}
+}
+
+// prepareSandbox creates the run's cgroup and arranges for the process to
+// be started inside it. With rlimits the command is routed through the
+// shim. cmd.SysProcAttr must already be set.
+func prepareSandbox(cmd *exec.Cmd, policy ExecutionPolicy, runID string) (*sandbox, error) {
+	s := &sandbox{policy: policy}
+	if policy.HasRlimits() {
+		if err := s.wrapShim(cmd); err != nil {
+			return nil, err
+		}
+	}
+	if policy.Cgroup == "" {
+		return s, nil
+	}
+
+	s.cgroupDir = filepath.Join(policy.Cgroup, runID)
+	if err := os.Mkdir(s.cgroupDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	limits := map[string]string{
+		"memory.max": policy.CgroupMemoryMax,
+		"cpu.max":    policy.CgroupCPUMax,
+		"pids.max":   policy.CgroupPidsMax,
+	}
+	for file, value := range limits {
+		if value == "" {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(s.cgroupDir, file), []byte(value), 0644); err != nil {
+			s.cleanup()
+			return nil, fmt.Errorf("failed to set %s: %w", file, err)
+		}
+	}
+
+	fd, err := os.Open(s.cgroupDir)
+	if err != nil {
+		s.cleanup()
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	s.cgroupFD = fd
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(fd.Fd())
+
+	return s, nil
+}
+
+// wrapShim replaces cmd by the shim, which applies the rlimits and then
+// execs the original command.
+func (s *sandbox) wrapShim(cmd *exec.Cmd) error {
+	if cmd.Err != nil {
+		// Start reports the missing binary
+		return nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate sandbox shim: %w", err)
+	}
+	s.status, s.statusW, err = os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox status pipe: %w", err)
+	}
+
+	cmd.ExtraFiles = append(cmd.ExtraFiles, s.statusW)
+	spec := []string{strconv.Itoa(2 + len(cmd.ExtraFiles))}
+	for _, l := range s.policy.rlimits() {
+		if l.value > 0 {
+			spec = append(spec, fmt.Sprintf("%d:%d:%d", l.resource, l.value, l.value+l.slack))
+		}
+	}
+	cmd.Env = append(cmd.Env, shimEnv+"="+strings.Join(spec, ","))
+	cmd.Args = append([]string{cmd.Args[0], cmd.Path}, cmd.Args...)
+	cmd.Path = self
+	return nil
+}
+
+// started waits until the shim has exec'd the agent and returns the error
+// the shim reported if it could not apply the limits.
+func (s *sandbox) started(pid int) error {
+	if s.status == nil {
+		return nil
+	}
+	s.statusW.Close()
+	s.statusW = nil
+	msg, err := io.ReadAll(s.status)
+	s.status.Close()
+	s.status = nil
+	if err != nil {
+		return fmt.Errorf("failed to read sandbox status: %w", err)
+	}
+	if len(msg) > 0 {
+		return errors.New(string(msg))
+	}
+	return nil
+}
+
+// runShim is the sandbox shim: os.Args are [argv0, path, argv...]. It never
+// returns; failures are reported on the status fd.
+func runShim(spec string) {
+	fields := strings.Split(spec, ",")
+	fd, err := strconv.Atoi(fields[0])
+	if err != nil || len(os.Args) < 3 {
+		fmt.Fprintf(os.Stderr, "invalid sandbox shim invocation\n")
+		os.Exit(127)
+	}
+	status := os.NewFile(uintptr(fd), "sandbox-status")
+	fail := func(err error) {
+		fmt.Fprint(status, err.Error())
+		os.Exit(127)
+	}
+
+	var limits []rlimit
+	var rlims []unix.Rlimit
+	for _, f := range fields[1:] {
+		var l rlimit
+		var hard uint64
+		if _, err := fmt.Sscanf(f, "%d:%d:%d", &l.resource, &l.value, &hard); err != nil {
+			fail(fmt.Errorf("invalid rlimit %q", f))
+		}
+		limits = append(limits, l)
+		rlims = append(rlims, unix.Rlimit{Cur: l.value, Max: hard})
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, shimEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	unix.CloseOnExec(fd)
+
+	// Zuletzt setzen: ein niedriges RLIMIT_AS darf den Shim nicht mehr treffen
+	for i, l := range limits {
+		if err := unix.Setrlimit(l.resource, &rlims[i]); err != nil {
+			fail(fmt.Errorf("failed to apply rlimit %d: %w", l.resource, err))
+		}
+	}
+	fail(fmt.Errorf("failed to exec agent: %w", unix.Exec(os.Args[1], os.Args[2:], env)))
+}
+
+// oomKilled reports whether the kernel OOM-killed a process in the cgroup.
+func (s *sandbox) oomKilled() bool {
+	if s.cgroupDir == "" {
+		return false
+	}
+
+	f, err := os.Open(filepath.Join(s.cgroupDir, "memory.events"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if ok && key == "oom_kill" {
+			n, _ := strconv.Atoi(value)
+			return n > 0
+		}
+	}
+	return false
+}
+
+// cleanup removes the run's cgroup once every process in it has exited.
+func (s *sandbox) cleanup() {
+	for _, f := range []*os.File{s.status, s.statusW} {
+		if f != nil {
+			f.Close()
+		}
+	}
+	s.status, s.statusW = nil, nil
+	if s.cgroupFD != nil {
+		s.cgroupFD.Close()
+		s.cgroupFD = nil
+	}
+	if s.cgroupDir != "" {
+		os.Remove(s.cgroupDir)
+	}
+}
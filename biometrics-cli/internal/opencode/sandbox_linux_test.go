@@ -0,0 +1,39 @@
+package opencode
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCLIBackendRlimitsBeforeExec(t *testing.T) {
+	backend := NewCLIBackend(testLogger())
+	backend.Binary = fakeAgent(t, "ulimit -n")
+	backend.Policies = PolicySet{Default: ExecutionPolicy{MaxOpenFiles: 64}}
+
+	executor := NewExecutorWithBackend(testLogger(), backend)
+	executor.SetTranscriptStore(nil)
+
+	result := executor.RunAgent(context.Background(), AgentRequest{})
+	if !result.Success {
+		t.Fatalf("Expected success, got %s: %v", result.ErrorKind, result.Error)
+	}
+	if strings.TrimSpace(result.Output) != "64" {
+		t.Errorf("Expected the agent to start with 64 open files, got %q", result.Output)
+	}
+}
+
+func TestCLIBackendRlimitFailureIsPolicyError(t *testing.T) {
+	backend := NewCLIBackend(testLogger())
+	backend.Binary = fakeAgent(t, "exit 0")
+	// Über fs.nr_open, scheitert auch als root
+	backend.Policies = PolicySet{Default: ExecutionPolicy{MaxOpenFiles: 1 << 40}}
+
+	executor := NewExecutorWithBackend(testLogger(), backend)
+	executor.SetTranscriptStore(nil)
+
+	result := executor.RunAgent(context.Background(), AgentRequest{})
+	if result.Success || result.ErrorKind != ErrorKindPolicy {
+		t.Errorf("Expected policy error, got %s: %v", result.ErrorKind, result.Error)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package opencode
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+type sandbox struct{}
+
+// prepareSandbox rejects policies that need rlimits or cgroups, which are
+// only implemented on Linux.
+func prepareSandbox(cmd *exec.Cmd, policy ExecutionPolicy, runID string) (*sandbox, error) {
+	if policy.HasRlimits() || policy.Cgroup != "" {
+		return nil, fmt.Errorf("resource limits and cgroups are only supported on linux")
+	}
+	return &sandbox{}, nil
+}
+
+func (s *sandbox) started(pid int) error { return nil }
+
+func (s *sandbox) oomKilled() bool { return false }
+
+func (s *sandbox) cleanup() {}
@@ -3,54 +3,61 @@ package opencode
 import (
 	"bufio"
 	"io"
+	"log/slog"
 	"os/exec"
+	"sync"
+	"time"
 )
 
-// streamOutput reads stdout and stderr from cmd and sends lines to a channel.
-// It launches an async goroutine that uses bufio.Scanner to read lines from
-// both stdout and stderr, sending each line to the returned channel.
-func (e *Executor) streamOutput(cmd *exec.Cmd) <-chan string {
-	outChan := make(chan string)
+// streamOutput reads stdout and stderr from cmd and sends timestamped lines
+// to a channel. Each pipe is scanned by its own goroutine so lines arrive in
+// the order the agent wrote them; sends block instead of dropping output.
+// done is closed once both pipes have been read to EOF.
+func streamOutput(logger *slog.Logger, cmd *exec.Cmd, done chan<- struct{}) <-chan OutputLine {
+	outChan := make(chan OutputLine)
 
 	// Create pipes for stdout and stderr
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
-		e.logger.Error("Failed to create stdout pipe", "error", err)
+		logger.Error("Failed to create stdout pipe", "error", err)
 		close(outChan)
+		close(done)
 		return outChan
 	}
 
 	stderrPipe, err := cmd.StderrPipe()
 	if err != nil {
-		e.logger.Error("Failed to create stderr pipe", "error", err)
+		logger.Error("Failed to create stderr pipe", "error", err)
 		close(outChan)
+		close(done)
 		return outChan
 	}
 
-	// Launch async reading goroutine
-	go func() {
-		defer close(outChan)
-
-		// Combine stdout and stderr into single reader
-		multiReader := io.MultiReader(stdoutPipe, stderrPipe)
-		scanner := bufio.NewScanner(multiReader)
+	var wg sync.WaitGroup
+	scan := func(stream string, r io.Reader) {
+		defer wg.Done()
 
-		// Read lines and send to channel
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 		for scanner.Scan() {
-			line := scanner.Text()
-			select {
-			case outChan <- line:
-				// Line sent successfully
-			default:
-				// Channel full or closed, stop sending
-				return
-			}
+			outChan <- OutputLine{Time: time.Now(), Stream: stream, Text: scanner.Text()}
 		}
 
-		// Handle scanner errors
+		// Handle scanner errors; keep draining so the agent never blocks on a full pipe
 		if err := scanner.Err(); err != nil {
-			e.logger.Error("Scanner error while reading output", "error", err)
+			logger.Error("Scanner error while reading output", "stream", stream, "error", err)
+			io.Copy(io.Discard, r)
 		}
+	}
+
+	wg.Add(2)
+	go scan(StreamStdout, stdoutPipe)
+	go scan(StreamStderr, stderrPipe)
+
+	go func() {
+		wg.Wait()
+		close(outChan)
+		close(done)
 	}()
 
 	return outChan
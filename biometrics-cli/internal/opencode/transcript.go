@@ -0,0 +1,161 @@
+package opencode
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"biometrics-cli/internal/workspace"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// OutputLine is one line of agent output as it was read from the process.
+type OutputLine struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"`
+	Text   string    `json:"text"`
+}
+
+// Transcript is the persisted record of a single agent run.
+type Transcript struct {
+	ProjectID  string       `json:"project_id"`
+	TaskID     string       `json:"task_id,omitempty"`
+	TraceID    string       `json:"trace_id"`
+	Model      string       `json:"model,omitempty"`
+	Category   string       `json:"category,omitempty"`
+	Agent      string       `json:"agent,omitempty"`
+	StartedAt  time.Time    `json:"started_at"`
+	DurationMS int64        `json:"duration_ms"`
+	ExitCode   int          `json:"exit_code"`
+	Success    bool         `json:"success"`
+	Error      string       `json:"error,omitempty"`
+	ErrorKind  ErrorKind    `json:"error_kind,omitempty"`
+	Lines      []OutputLine `json:"lines"`
+}
+
+// Duration returns how long the agent ran.
+func (t *Transcript) Duration() time.Duration {
+	return time.Duration(t.DurationMS) * time.Millisecond
+}
+
+// Recording converts the transcript into a Recording for ReplayBackend,
+// preserving the original timing between lines.
+func (t *Transcript) Recording() *Recording {
+	rec := &Recording{
+		Name:      fmt.Sprintf("%s-%s", t.ProjectID, t.TraceID),
+		Project:   t.ProjectID,
+		Category:  t.Category,
+		ExitCode:  t.ExitCode,
+		ErrorKind: t.ErrorKind,
+		Lines:     make([]RecordedLine, 0, len(t.Lines)),
+	}
+	if !t.Success && t.ExitCode == 0 {
+		rec.Error = t.Error
+	}
+
+	prev := t.StartedAt
+	for _, line := range t.Lines {
+		rec.Lines = append(rec.Lines, RecordedLine{
+			Stream:  line.Stream,
+			Text:    line.Text,
+			DelayMS: int(line.Time.Sub(prev).Milliseconds()),
+		})
+		prev = line.Time
+	}
+	return rec
+}
+
+// TranscriptStore persists agent transcripts. Save returns a reference that
+// Load accepts and that is handed out via AgentResult.TranscriptRef.
+type TranscriptStore interface {
+	Save(t *Transcript) (string, error)
+	Load(ref string) (*Transcript, error)
+	List(projectID, taskID string) ([]string, error)
+}
+
+// FileTranscriptStore writes one JSON file per run to
+// <root>/<project>/<task>/<timestamp>-<trace>.json.
+type FileTranscriptStore struct {
+	root string
+}
+
+// NewFileTranscriptStore creates a store rooted at dir.
+func NewFileTranscriptStore(dir string) *FileTranscriptStore {
+	return &FileTranscriptStore{root: dir}
+}
+
+// DefaultTranscriptDir returns the directory transcripts are written to
+// when no store is configured. Binaries that resolved a workspace should
+// call Executor.SetTranscriptStore with its TranscriptsDir instead.
+func DefaultTranscriptDir() string {
+	ws, err := workspace.Resolve("")
+	if err != nil {
+		return filepath.Join(os.TempDir(), "biometrics", "transcripts")
+	}
+	return ws.TranscriptsDir()
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func pathSegment(s, fallback string) string {
+	s = unsafePathChars.ReplaceAllString(s, "_")
+	if s == "" || s == "." || s == ".." {
+		return fallback
+	}
+	return s
+}
+
+func (s *FileTranscriptStore) dir(projectID, taskID string) string {
+	return filepath.Join(s.root, pathSegment(projectID, "_"), pathSegment(taskID, "_"))
+}
+
+func (s *FileTranscriptStore) Save(t *Transcript) (string, error) {
+	dir := s.dir(t.ProjectID, t.TaskID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create transcript dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.json", t.StartedAt.UTC().Format("20060102T150405.000Z"), pathSegment(t.TraceID, "unknown"))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write transcript: %w", err)
+	}
+
+	return path, nil
+}
+
+func (s *FileTranscriptStore) Load(ref string) (*Transcript, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript %s: %w", ref, err)
+	}
+	return &t, nil
+}
+
+// List returns the references of all transcripts for a task, oldest first.
+func (s *FileTranscriptStore) List(projectID, taskID string) ([]string, error) {
+	refs, err := filepath.Glob(filepath.Join(s.dir(projectID, taskID), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(refs)
+	return refs, nil
+}
@@ -0,0 +1,60 @@
+package opencode
+
+import (
+	"context"
+	"testing"
+
+	"biometrics-cli/internal/telemetry"
+)
+
+func TestExecutorPersistsTranscript(t *testing.T) {
+	backend := NewReplayBackend(&Recording{
+		Name: "mixed",
+		Lines: []RecordedLine{
+			{Stream: StreamStdout, Text: "building"},
+			{Stream: StreamStderr, Text: "warning: unused variable"},
+			{Stream: StreamStdout, Text: "done"},
+		},
+		ExitCode: 2,
+	})
+	store := NewFileTranscriptStore(t.TempDir())
+	executor := NewExecutorWithBackend(testLogger(), backend)
+	executor.SetTranscriptStore(store)
+
+	ctx, traceID := telemetry.InjectTraceID(context.Background())
+	result := executor.RunAgent(ctx, AgentRequest{ProjectID: "demo", TaskID: "task-1", Category: "build"})
+
+	if result.Success {
+		t.Fatal("Expected failure for exit code 2")
+	}
+	if result.TranscriptRef == "" {
+		t.Fatal("Expected transcript reference on result")
+	}
+
+	transcript, err := store.Load(result.TranscriptRef)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if transcript.TraceID != traceID {
+		t.Errorf("Expected trace ID %s, got %s", traceID, transcript.TraceID)
+	}
+	if transcript.ExitCode != 2 {
+		t.Errorf("Expected exit code 2, got %d", transcript.ExitCode)
+	}
+	if len(transcript.Lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(transcript.Lines))
+	}
+	if transcript.Lines[1].Stream != StreamStderr || transcript.Lines[1].Text != "warning: unused variable" {
+		t.Errorf("Unexpected stderr line: %+v", transcript.Lines[1])
+	}
+
+	refs, err := store.List("demo", "task-1")
+	if err != nil || len(refs) != 1 {
+		t.Errorf("Expected 1 transcript for task, got %v (%v)", refs, err)
+	}
+
+	rec := transcript.Recording()
+	if rec.ExitCode != 2 || len(rec.Lines) != 3 {
+		t.Errorf("Recording does not match transcript: %+v", rec)
+	}
+}
@@ -1,14 +1,76 @@
 package opencode
 
+import (
+	"errors"
+	"time"
+)
+
 type AgentRequest struct {
 	ProjectID string
 	Model     string
 	Prompt    string
 	Category  string
+	Agent     string
+	TaskID    string
+	// WorkDir ist das Projektverzeichnis, in dem der Agent läuft; leer
+	// heißt ProjectDir
+	WorkDir string
+	// ProjectDir ist der Checkout des Projekts. Policies ohne JailRoot
+	// sperren den Agent darin ein.
+	ProjectDir string
 }
 
 type AgentResult struct {
-	Success bool
-	Output  string
-	Error   error
+	Success   bool
+	Output    string
+	Error     error
+	ErrorKind ErrorKind
+	ExitCode  int
+	// TranscriptRef verweist auf das gespeicherte Transcript (leer, wenn keins geschrieben wurde)
+	TranscriptRef string
+	// RetryAfter ist die vom Provider genannte Wartezeit bei ErrorKindRateLimited
+	RetryAfter time.Duration
+}
+
+// ErrorKind unterscheidet, warum ein Agent-Lauf nicht erfolgreich war.
+type ErrorKind string
+
+const (
+	ErrorKindNone          ErrorKind = ""
+	ErrorKindAgentFailed   ErrorKind = "agent_failed"
+	ErrorKindStartFailed   ErrorKind = "start_failed"
+	ErrorKindCanceled      ErrorKind = "canceled"
+	ErrorKindTimeout       ErrorKind = "timeout"
+	ErrorKindOOM           ErrorKind = "oom"
+	ErrorKindCPULimit      ErrorKind = "cpu_limit"
+	ErrorKindFileSizeLimit ErrorKind = "file_size_limit"
+	ErrorKindPolicy        ErrorKind = "policy_violation"
+	// ErrorKindRateLimited: der Provider hat mit 429/Rate-Limit geantwortet
+	ErrorKindRateLimited ErrorKind = "rate_limited"
+)
+
+// RunError ist ein Fehler mit zugehörigem ErrorKind.
+type RunError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *RunError) Error() string {
+	return string(e.Kind) + ": " + e.Err.Error()
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorKindOf liefert den ErrorKind eines Fehlers, ErrorKindAgentFailed falls unbekannt.
+func ErrorKindOf(err error) ErrorKind {
+	if err == nil {
+		return ErrorKindNone
+	}
+	var runErr *RunError
+	if errors.As(err, &runErr) {
+		return runErr.Kind
+	}
+	return ErrorKindAgentFailed
 }
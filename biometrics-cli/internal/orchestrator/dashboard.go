@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"biometrics-cli/internal/state"
 	"fmt"
+	"sort"
 	"time"
 )
 
@@ -23,6 +24,21 @@ func DisplayDashboard() {
 		fmt.Printf("PLAN:       %s\n", state.GlobalState.PlanName)
 		fmt.Printf("AGENT:      %s\n", state.GlobalState.CurrentAgent)
 		fmt.Printf("MODEL:      %s\n", state.GlobalState.ActiveModel)
+		todos := DefaultOrchestrator.GetStats()
+		fmt.Printf("TODOS:      %d pending, %d running, %d completed, %d failed (%d retries)\n",
+			todos["pending"], todos["running"], todos["completed"], todos["failed"], todos["retries"])
+		if status := state.GlobalState.GetModelStatus(); len(status) > 0 {
+			fmt.Println("--------------------------------------------------------------")
+			fmt.Println("MODELS:")
+			names := make([]string, 0, len(status))
+			for name := range status {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("  %-36s %s\n", name, status[name])
+			}
+		}
 		fmt.Println("--------------------------------------------------------------")
 		fmt.Println("RECENT LOGS:")
 		for _, l := range state.GlobalState.Logs {
@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"biometrics-cli/internal/heartbeat"
 	"biometrics-cli/internal/metrics"
+	"biometrics-cli/internal/project"
 	"context"
 	"fmt"
 	"path/filepath"
@@ -16,6 +17,7 @@ type MultiProjectOrchestrator struct {
 	heartbeat   *heartbeat.Monitor
 	schedulers  map[string]*ProjectScheduler
 	workStealer *WorkStealer
+	fair        *project.FairScheduler
 }
 
 type Project struct {
@@ -25,6 +27,8 @@ type Project struct {
 	Active        bool
 	LastProcessed time.Time
 	Priority      int
+	// Budget begrenzt das Projekt; das Gewicht folgt Priority
+	Budget project.Budget
 }
 
 type ProjectScheduler struct {
@@ -51,6 +55,7 @@ func NewMultiProjectOrchestrator(heartbeat *heartbeat.Monitor) *MultiProjectOrch
 		projects:   make(map[string]*Project),
 		heartbeat:  heartbeat,
 		schedulers: make(map[string]*ProjectScheduler),
+		fair:       project.NewFairScheduler(project.DefaultAging),
 	}
 }
 
@@ -70,6 +75,7 @@ func (mpo *MultiProjectOrchestrator) RegisterProject(name, path, planPath string
 		LastProcessed: time.Now(),
 		Priority:      priority,
 	}
+	mpo.fair.SetBudget(name, project.Budget{Weight: weightFor(priority)})
 
 	metrics.ProjectsRegistered.WithLabelValues(name).Inc()
 
@@ -85,6 +91,7 @@ func (mpo *MultiProjectOrchestrator) UnregisterProject(name string) error {
 	}
 
 	delete(mpo.projects, name)
+	mpo.fair.Forget(name)
 	metrics.ProjectsUnregistered.WithLabelValues(name).Inc()
 
 	return nil
@@ -140,29 +147,56 @@ func (mpo *MultiProjectOrchestrator) SetProjectActive(name string, active bool)
 	return nil
 }
 
+// GetNextProject returns the active project furthest below its fair share.
+// Waiting time ages every project, so low priorities still get their turn.
 func (mpo *MultiProjectOrchestrator) GetNextProject() *Project {
 	mpo.mu.RLock()
 	defer mpo.mu.RUnlock()
 
-	var next *Project
-	var highestPriority int = -1
-
-	for _, p := range mpo.projects {
-		if !p.Active {
-			continue
+	var candidates []string
+	for name, p := range mpo.projects {
+		if p.Active {
+			candidates = append(candidates, name)
 		}
+	}
 
-		if p.Priority > highestPriority {
-			highestPriority = p.Priority
-			next = p
-		}
+	name, ok := mpo.fair.Next(candidates)
+	if !ok {
+		return nil
 	}
+	next := mpo.projects[name]
+	next.LastProcessed = time.Now()
+	return next
+}
+
+// SetProjectBudget limits concurrent agents and model-minutes per hour of a
+// project. The weight is always derived from its priority.
+func (mpo *MultiProjectOrchestrator) SetProjectBudget(name string, budget project.Budget) error {
+	mpo.mu.Lock()
+	defer mpo.mu.Unlock()
 
-	if next != nil {
-		next.LastProcessed = time.Now()
+	p, exists := mpo.projects[name]
+	if !exists {
+		return fmt.Errorf("project %s not found", name)
 	}
 
-	return next
+	p.Budget = budget
+	budget.Weight = weightFor(p.Priority)
+	mpo.fair.SetBudget(name, budget)
+	return nil
+}
+
+// AcquireProject reserves an agent slot within the project's budget. The
+// returned release books the elapsed time as model-minutes.
+func (mpo *MultiProjectOrchestrator) AcquireProject(name string) (func(), error) {
+	return mpo.fair.Acquire(name)
+}
+
+func weightFor(priority int) float64 {
+	if priority < 1 {
+		return 1
+	}
+	return float64(priority)
 }
 
 func (mpo *MultiProjectOrchestrator) ScheduleTask(project, taskType string, interval time.Duration, callback func() error) error {
@@ -237,7 +271,7 @@ func (mpo *MultiProjectOrchestrator) runScheduler(ctx context.Context, scheduler
 	}
 }
 
-func (mpo *MultiProjectOrchestrator) DiscoverProjects(basePath string) error {
+func (mpo *MultiProjectOrchestrator) DiscoverProjects(basePath, plansDir string) error {
 	projectsDir := []string{
 		filepath.Join(basePath, "BIOMETRICS"),
 		filepath.Join(basePath, "SIN-Solver"),
@@ -246,7 +280,7 @@ func (mpo *MultiProjectOrchestrator) DiscoverProjects(basePath string) error {
 
 	for _, dir := range projectsDir {
 		projectName := filepath.Base(dir)
-		planPath := filepath.Join("/Users/jeremy/.sisyphus/plans", projectName)
+		planPath := filepath.Join(plansDir, projectName)
 
 		if err := mpo.RegisterProject(projectName, dir, planPath, 1); err != nil {
 			continue
@@ -276,20 +310,29 @@ func (mpo *MultiProjectOrchestrator) GetStats() map[string]interface{} {
 		"active_projects":   active,
 		"inactive_projects": inactive,
 		"schedulers":        len(mpo.schedulers),
+		"fair_share":        mpo.fair.Stats(),
 	}
 }
 
+// BalanceProjects re-derives every project's fair-share weight from its
+// current priority and drops unregistered projects from the scheduler.
 func (mpo *MultiProjectOrchestrator) BalanceProjects() {
-	projects := mpo.GetActiveProjects()
+	mpo.mu.RLock()
+	defer mpo.mu.RUnlock()
 
-	if len(projects) == 0 {
+	if len(mpo.projects) == 0 {
 		return
 	}
 
-	basePriority := 100 / len(projects)
-
-	for i, p := range projects {
-		p.Priority = basePriority * (len(projects) - i)
+	for name, p := range mpo.projects {
+		budget := p.Budget
+		budget.Weight = weightFor(p.Priority)
+		mpo.fair.SetBudget(name, budget)
+	}
+	for name := range mpo.fair.Stats() {
+		if _, ok := mpo.projects[name]; !ok {
+			mpo.fair.Forget(name)
+		}
 	}
 
 	metrics.ProjectRebalanced.Inc()
@@ -0,0 +1,35 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"biometrics-cli/internal/project"
+)
+
+func TestGetNextProjectSkipsInactiveAndExhausted(t *testing.T) {
+	mpo := NewMultiProjectOrchestrator(nil)
+	mpo.RegisterProject("a", "/a", "/plans/a", 5)
+	mpo.RegisterProject("b", "/b", "/plans/b", 1)
+	mpo.SetProjectActive("b", false)
+
+	if next := mpo.GetNextProject(); next == nil || next.Name != "a" {
+		t.Fatalf("next = %v, want a", next)
+	}
+
+	if err := mpo.SetProjectBudget("a", project.Budget{MaxConcurrent: 1}); err != nil {
+		t.Fatal(err)
+	}
+	release, err := mpo.AcquireProject("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := mpo.GetNextProject(); next != nil {
+		t.Errorf("next = %s, want none while a is at its budget", next.Name)
+	}
+	release()
+
+	stats := mpo.GetStats()["fair_share"].(map[string]project.ShareStats)
+	if got := stats["a"].Weight; got != 5 {
+		t.Errorf("weight of a = %v, want 5 (from priority)", got)
+	}
+}
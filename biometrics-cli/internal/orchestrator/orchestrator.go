@@ -1,14 +1,18 @@
 package orchestrator
 
 import (
+	"biometrics-cli/internal/collision"
 	"biometrics-cli/internal/metrics"
 	"biometrics-cli/internal/state"
-	"biometrics-cli/internal/tracker"
 	"fmt"
-	"os/exec"
+	"sync"
 	"time"
 )
 
+// DefaultTodoRetries ist die Zahl der Wiederholungen für automatisch
+// erzeugte Todos.
+const DefaultTodoRetries = 2
+
 type AgentConfig struct {
 	Name          string   `json:"name"`
 	Model         string   `json:"model"`
@@ -26,14 +30,26 @@ type TodoTask struct {
 	Status      string    `json:"status"`
 	CreatedAt   time.Time `json:"created_at"`
 	Agent       string    `json:"agent"`
+	// Retries zählt fehlgeschlagene Versuche, MaxRetries davon werden wiederholt
+	Retries     int         `json:"retries"`
+	MaxRetries  int         `json:"max_retries"`
+	Errors      []TodoError `json:"errors,omitempty"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	StartedAt   *time.Time  `json:"started_at,omitempty"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
 }
 
+// Orchestrator verwaltet die Todos, die das Dashboard anzeigt.
+//
+// Deprecated: Tasks führt internal/daemon aus.
 type Orchestrator struct {
-	agents       map[string]*AgentConfig
-	todos        []TodoTask
-	modelTracker *tracker.ModelTracker
-	autoMode     bool
-	cycleCount   int
+	mu         sync.Mutex
+	agents     map[string]*AgentConfig
+	todos      []TodoTask
+	store      TodoStore
+	modelPool  *collision.ModelPool
+	autoMode   bool
+	cycleCount int
 }
 
 var DefaultOrchestrator = &Orchestrator{
@@ -86,11 +102,60 @@ func Init() {
 		},
 	}
 
-	DefaultOrchestrator.modelTracker = tracker.NewModelTracker()
+	DefaultOrchestrator.modelPool = collision.NewModelPool(nil)
 	state.GlobalState.Log("INFO", "Orchestrator initialized with agents: sisyphus, prometheus, atlas, librarian, explore")
 }
 
+// UseStore lädt die Todos aus store und speichert jede weitere Änderung
+// dort. Todos, die beim letzten Beenden liefen, gehen zurück auf pending.
+func (o *Orchestrator) UseStore(store TodoStore) error {
+	todos, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load todos: %w", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.store = store
+	o.todos = todos
+	requeued := 0
+	for i := range o.todos {
+		if o.todos[i].Status == "running" {
+			o.todos[i].Status = "pending"
+			o.todos[i].StartedAt = nil
+			o.persist(&o.todos[i])
+			requeued++
+		}
+	}
+	state.GlobalState.Log("INFO", fmt.Sprintf("Loaded %d todos, requeued %d interrupted", len(todos), requeued))
+	return nil
+}
+
+// persist speichert ein Todo, falls ein Store gesetzt ist. o.mu muss gehalten werden.
+func (o *Orchestrator) persist(todo *TodoTask) {
+	todo.UpdatedAt = time.Now()
+	if o.store == nil {
+		return
+	}
+	if err := o.store.Save(*todo); err != nil {
+		state.GlobalState.Log("ERROR", fmt.Sprintf("Failed to persist todo %s: %v", todo.ID, err))
+	}
+}
+
+func (o *Orchestrator) find(taskID string) *TodoTask {
+	for i := range o.todos {
+		if o.todos[i].ID == taskID {
+			return &o.todos[i]
+		}
+	}
+	return nil
+}
+
 func (o *Orchestrator) GetAgentForTask(taskType string) *AgentConfig {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
 	skills := map[string][]string{
 		"coding":        {"sisyphus", "atlas"},
 		"planning":      {"prometheus"},
@@ -131,85 +196,130 @@ func (o *Orchestrator) getRunningAgentCount(agentName string) int {
 	return count
 }
 
+// AutoCreateTodos legt Leerlauf-Todos an, sobald nichts mehr offen ist.
 func (o *Orchestrator) AutoCreateTodos() {
-	if len(o.todos) > 0 {
-		return
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, t := range o.todos {
+		if t.Status == "pending" || t.Status == "running" {
+			return
+		}
 	}
 
+	now := time.Now()
 	idleTasks := []TodoTask{
 		{
-			ID:          fmt.Sprintf("auto-%d", time.Now().Unix()),
+			ID:          fmt.Sprintf("auto-%d", now.Unix()),
 			Title:       "Check system health status",
 			Description: "Run health checks on all services",
 			Priority:    "medium",
-			Status:      "pending",
-			CreatedAt:   time.Now(),
 			Agent:       "sisyphus",
 		},
 		{
-			ID:          fmt.Sprintf("auto-%d", time.Now().Unix()+1),
+			ID:          fmt.Sprintf("auto-%d", now.Unix()+1),
 			Title:       "Review active sessions",
 			Description: "Check for idle or stuck sessions",
 			Priority:    "low",
-			Status:      "pending",
-			CreatedAt:   time.Now(),
 			Agent:       "explore",
 		},
 		{
-			ID:          fmt.Sprintf("auto-%d", time.Now().Unix()+2),
+			ID:          fmt.Sprintf("auto-%d", now.Unix()+2),
 			Title:       "Update documentation",
 			Description: "Review and update project docs",
 			Priority:    "low",
-			Status:      "pending",
-			CreatedAt:   time.Now(),
 			Agent:       "librarian",
 		},
 	}
 
+	for i := range idleTasks {
+		idleTasks[i].Status = "pending"
+		idleTasks[i].CreatedAt = now
+		idleTasks[i].MaxRetries = DefaultTodoRetries
+		o.persist(&idleTasks[i])
+	}
 	o.todos = append(o.todos, idleTasks...)
 	metrics.TasksCreatedTotal.Add(float64(len(idleTasks)))
 	state.GlobalState.Log("INFO", fmt.Sprintf("Auto-created %d idle tasks", len(idleTasks)))
 }
 
+// GetNextTask startet das älteste pending Todo und liefert eine Kopie.
 func (o *Orchestrator) GetNextTask() *TodoTask {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
 	for i := range o.todos {
 		if o.todos[i].Status == "pending" {
+			now := time.Now()
 			o.todos[i].Status = "running"
-			return &o.todos[i]
+			o.todos[i].StartedAt = &now
+			o.persist(&o.todos[i])
+			task := o.todos[i]
+			return &task
 		}
 	}
 	return nil
 }
 
 func (o *Orchestrator) CompleteTask(taskID string) {
-	for i := range o.todos {
-		if o.todos[i].ID == taskID {
-			o.todos[i].Status = "completed"
-			metrics.TasksCompletedTotal.Inc()
-			state.GlobalState.Log("INFO", fmt.Sprintf("Task completed: %s", taskID))
-			return
-		}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if t := o.find(taskID); t != nil {
+		now := time.Now()
+		t.Status = "completed"
+		t.CompletedAt = &now
+		o.persist(t)
+		metrics.TasksCompletedTotal.Inc()
+		state.GlobalState.Log("INFO", fmt.Sprintf("Task completed: %s", taskID))
 	}
 }
 
+// FailTask hält den Fehler fest. Solange Retries MaxRetries nicht
+// überschreitet, geht das Todo zurück auf pending, sonst auf failed.
 func (o *Orchestrator) FailTask(taskID string, err string) {
-	for i := range o.todos {
-		if o.todos[i].ID == taskID {
-			o.todos[i].Status = "failed"
-			metrics.TasksFailedTotal.Inc()
-			state.GlobalState.Log("ERROR", fmt.Sprintf("Task failed: %s - %s", taskID, err))
-			return
-		}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	t := o.find(taskID)
+	if t == nil {
+		return
 	}
+
+	now := time.Now()
+	t.Retries++
+	t.Errors = append(t.Errors, TodoError{Attempt: t.Retries, At: now, Message: err})
+	if len(t.Errors) > MaxTodoErrors {
+		t.Errors = t.Errors[len(t.Errors)-MaxTodoErrors:]
+	}
+	t.StartedAt = nil
+
+	if t.Retries <= t.MaxRetries {
+		t.Status = "pending"
+		o.persist(t)
+		state.GlobalState.Log("WARN", fmt.Sprintf("Task failed, retry %d/%d: %s - %s", t.Retries, t.MaxRetries, taskID, err))
+		return
+	}
+
+	t.Status = "failed"
+	t.CompletedAt = &now
+	o.persist(t)
+	metrics.TasksFailedTotal.Inc()
+	state.GlobalState.Log("ERROR", fmt.Sprintf("Task failed: %s - %s", taskID, err))
 }
 
 func (o *Orchestrator) GetStats() map[string]interface{} {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
 	pending := 0
 	running := 0
 	completed := 0
 	failed := 0
+	retries := 0
 
 	for _, t := range o.todos {
+		retries += t.Retries
 		switch t.Status {
 		case "pending":
 			pending++
@@ -228,6 +338,8 @@ func (o *Orchestrator) GetStats() map[string]interface{} {
 		"running":       running,
 		"completed":     completed,
 		"failed":        failed,
+		"retries":       retries,
+		"persistent":    o.store != nil,
 		"cycle_count":   o.cycleCount,
 		"auto_mode":     o.autoMode,
 		"active_agents": o.getActiveAgents(),
@@ -249,46 +361,6 @@ func (o *Orchestrator) getActiveAgents() []string {
 	return agents
 }
 
-func (o *Orchestrator) RunCycle() {
-	o.cycleCount++
-	metrics.CyclesTotal.Inc()
-
-	if o.autoMode {
-		o.AutoCreateTodos()
-	}
-
-	task := o.GetNextTask()
-	if task == nil {
-		state.GlobalState.Log("INFO", "No tasks to process")
-		time.Sleep(30 * time.Second)
-		return
-	}
-
-	agent := o.GetAgentForTask("default")
-	if agent == nil {
-		o.FailTask(task.ID, "No available agent")
-		return
-	}
-
-	state.GlobalState.Log("INFO", fmt.Sprintf("Executing task %s with agent %s", task.ID, agent.Name))
-
-	cmd := exec.Command("opencode", task.Description, "--agent", agent.Name)
-	cmd.Start()
-
-	time.Sleep(10 * time.Second)
-	o.CompleteTask(task.ID)
-}
-
-func (o *Orchestrator) Start(autoMode bool) {
-	o.autoMode = autoMode
-	state.GlobalState.Log("INFO", "Starting orchestrator in auto mode")
-
-	for {
-		o.RunCycle()
-		time.Sleep(60 * time.Second)
-	}
-}
-
 func init() {
 	Init()
 }
@@ -16,8 +16,8 @@ func TestOrchestratorInit(t *testing.T) {
 		t.Error("Expected sisyphus agent to be registered")
 	}
 
-	if DefaultOrchestrator.modelTracker == nil {
-		t.Error("Expected model tracker to be initialized")
+	if DefaultOrchestrator.modelPool == nil {
+		t.Error("Expected model pool to be initialized")
 	}
 }
 
@@ -115,3 +115,26 @@ func TestGetStats(t *testing.T) {
 		t.Error("Expected cycle_count in stats")
 	}
 }
+
+func TestFailTaskRetriesThenFails(t *testing.T) {
+	o := &Orchestrator{agents: map[string]*AgentConfig{}}
+	o.AutoCreateTodos()
+
+	failing := o.GetNextTask()
+	o.FailTask(failing.ID, "exit status 1")
+	if retry := o.GetNextTask(); retry == nil || retry.ID != failing.ID {
+		t.Fatalf("retry should run %s again, got %v", failing.ID, retry)
+	}
+	for i := 0; i < DefaultTodoRetries; i++ {
+		o.FailTask(failing.ID, "still broken")
+		o.GetNextTask()
+	}
+
+	got := o.find(failing.ID)
+	if got.Status != "failed" || got.Retries != DefaultTodoRetries+1 || len(got.Errors) != DefaultTodoRetries+1 {
+		t.Errorf("todo = %+v, want failed after %d retries", got, DefaultTodoRetries)
+	}
+	if got.Errors[0].Message != "exit status 1" {
+		t.Errorf("first error = %q", got.Errors[0].Message)
+	}
+}
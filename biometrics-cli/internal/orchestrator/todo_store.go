@@ -0,0 +1,178 @@
+package orchestrator
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// MaxTodoErrors begrenzt die gespeicherte Fehlerhistorie je Todo.
+const MaxTodoErrors = 10
+
+// TodoError ist ein fehlgeschlagener Versuch eines Todos.
+type TodoError struct {
+	Attempt int       `json:"attempt"`
+	At      time.Time `json:"at"`
+	Message string    `json:"message"`
+}
+
+// TodoStore speichert Todos dauerhaft, damit ein Neustart keine Arbeit verliert.
+type TodoStore interface {
+	// Load liefert alle Todos in Erstellungsreihenfolge
+	Load() ([]TodoTask, error)
+	// Save legt ein Todo an oder überschreibt es samt Fehlerhistorie
+	Save(todo TodoTask) error
+}
+
+// SQLiteTodoStore hält Todos in der Log-Datenbank (state.InitDB).
+type SQLiteTodoStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteTodoStore legt die Tabellen todos und todo_errors an.
+func NewSQLiteTodoStore(db *sql.DB) (*SQLiteTodoStore, error) {
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS todos (
+			id TEXT PRIMARY KEY,
+			title TEXT NOT NULL,
+			description TEXT NOT NULL,
+			priority TEXT NOT NULL,
+			status TEXT NOT NULL,
+			agent TEXT NOT NULL,
+			retries INTEGER NOT NULL DEFAULT 0,
+			max_retries INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			started_at TEXT,
+			completed_at TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS todo_errors (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			todo_id TEXT NOT NULL,
+			attempt INTEGER NOT NULL,
+			at TEXT NOT NULL,
+			message TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS todo_errors_todo ON todo_errors (todo_id)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("failed to create todo schema: %w", err)
+		}
+	}
+	return &SQLiteTodoStore{db: db}, nil
+}
+
+func formatTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(s sql.NullString) *time.Time {
+	if !s.Valid {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// Load liest alle Todos samt Fehlerhistorie.
+func (s *SQLiteTodoStore) Load() ([]TodoTask, error) {
+	rows, err := s.db.Query(`SELECT id, title, description, priority, status, agent, retries, max_retries,
+		created_at, updated_at, started_at, completed_at FROM todos ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todos: %w", err)
+	}
+	defer rows.Close()
+
+	var todos []TodoTask
+	index := make(map[string]int)
+	for rows.Next() {
+		var (
+			t                  TodoTask
+			created, updated   sql.NullString
+			started, completed sql.NullString
+		)
+		if err := rows.Scan(&t.ID, &t.Title, &t.Description, &t.Priority, &t.Status, &t.Agent,
+			&t.Retries, &t.MaxRetries, &created, &updated, &started, &completed); err != nil {
+			return nil, fmt.Errorf("failed to scan todo: %w", err)
+		}
+		if c := parseTime(created); c != nil {
+			t.CreatedAt = *c
+		}
+		if u := parseTime(updated); u != nil {
+			t.UpdatedAt = *u
+		}
+		t.StartedAt = parseTime(started)
+		t.CompletedAt = parseTime(completed)
+		index[t.ID] = len(todos)
+		todos = append(todos, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read todos: %w", err)
+	}
+
+	errRows, err := s.db.Query(`SELECT todo_id, attempt, at, message FROM todo_errors ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todo errors: %w", err)
+	}
+	defer errRows.Close()
+	for errRows.Next() {
+		var (
+			id string
+			e  TodoError
+			at sql.NullString
+		)
+		if err := errRows.Scan(&id, &e.Attempt, &at, &e.Message); err != nil {
+			return nil, fmt.Errorf("failed to scan todo error: %w", err)
+		}
+		if t := parseTime(at); t != nil {
+			e.At = *t
+		}
+		if i, ok := index[id]; ok {
+			todos[i].Errors = append(todos[i].Errors, e)
+		}
+	}
+	return todos, errRows.Err()
+}
+
+// Save schreibt ein Todo in einer Transaktion.
+func (s *SQLiteTodoStore) Save(todo TodoTask) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO todos (id, title, description, priority, status, agent, retries, max_retries,
+			created_at, updated_at, started_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET title = excluded.title, description = excluded.description,
+			priority = excluded.priority, status = excluded.status, agent = excluded.agent,
+			retries = excluded.retries, max_retries = excluded.max_retries,
+			updated_at = excluded.updated_at, started_at = excluded.started_at,
+			completed_at = excluded.completed_at`,
+		todo.ID, todo.Title, todo.Description, todo.Priority, todo.Status, todo.Agent,
+		todo.Retries, todo.MaxRetries, formatTime(&todo.CreatedAt), formatTime(&todo.UpdatedAt),
+		formatTime(todo.StartedAt), formatTime(todo.CompletedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save todo %s: %w", todo.ID, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM todo_errors WHERE todo_id = ?`, todo.ID); err != nil {
+		return fmt.Errorf("failed to replace errors of todo %s: %w", todo.ID, err)
+	}
+	for _, e := range todo.Errors {
+		if _, err := tx.Exec(`INSERT INTO todo_errors (todo_id, attempt, at, message) VALUES (?, ?, ?, ?)`,
+			todo.ID, e.Attempt, formatTime(&e.At), e.Message); err != nil {
+			return fmt.Errorf("failed to save error of todo %s: %w", todo.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
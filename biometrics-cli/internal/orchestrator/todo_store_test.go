@@ -0,0 +1,68 @@
+package orchestrator
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTodoStore(t *testing.T, path string) *SQLiteTodoStore {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := NewSQLiteTodoStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestTodosSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+
+	first := &Orchestrator{agents: map[string]*AgentConfig{}}
+	if err := first.UseStore(openTodoStore(t, path)); err != nil {
+		t.Fatal(err)
+	}
+	first.AutoCreateTodos()
+
+	failing := first.GetNextTask()
+	first.FailTask(failing.ID, "exit status 1")
+	running := first.GetNextTask()
+	if running == nil || running.ID != failing.ID {
+		t.Fatalf("retry should run %s again, got %v", failing.ID, running)
+	}
+
+	// Neustart: ein neuer Orchestrator liest dieselbe Datenbank
+	second := &Orchestrator{agents: map[string]*AgentConfig{}}
+	if err := second.UseStore(openTodoStore(t, path)); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := second.GetStats()
+	if stats["total_tasks"] != 3 || stats["pending"] != 3 || stats["running"] != 0 || stats["retries"] != 1 {
+		t.Errorf("stats after restart = %v, want 3 pending with 1 retry", stats)
+	}
+
+	restored := second.find(failing.ID)
+	if restored == nil || len(restored.Errors) != 1 || restored.Errors[0].Message != "exit status 1" {
+		t.Fatalf("error history not restored: %+v", restored)
+	}
+	if restored.Agent != failing.Agent || restored.MaxRetries != DefaultTodoRetries || restored.CreatedAt.IsZero() {
+		t.Errorf("restored todo = %+v", restored)
+	}
+
+	// Nach MaxRetries Wiederholungen ist das Todo endgültig failed
+	for i := 0; i < DefaultTodoRetries; i++ {
+		task := second.GetNextTask()
+		second.FailTask(task.ID, "still broken")
+	}
+	if got := second.find(failing.ID).Status; got != "failed" {
+		t.Errorf("status = %s, want failed after %d retries", got, DefaultTodoRetries)
+	}
+}
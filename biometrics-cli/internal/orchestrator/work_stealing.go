@@ -3,26 +3,40 @@ package orchestrator
 import (
 	"biometrics-cli/internal/lock"
 	"biometrics-cli/internal/metrics"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
 
+// ErrNoTask: weder die eigene Queue noch ein überlasteter Peer hat einen
+// passenden Task.
+var ErrNoTask = errors.New("no task available")
+
 type WorkStealer struct {
 	mu             sync.RWMutex
 	agentQueues    map[string][]*Task
 	agentLoads     map[string]int
+	agentSkills    map[string][]string
 	minLoad        int
 	maxLoad        int
 	stealThreshold int
-	stolenTasks    map[string][]*Task
+	stolenTasks    map[string]int // Zahl gestohlener Tasks je Agent
 	lock           *lock.DistributedLock
+	stop           chan struct{}
+	stopOnce       sync.Once
 }
 
 type Task struct {
-	ID          string
-	AgentID     string
-	Priority    int
+	ID       string
+	AgentID  string
+	Priority int
+	// Category und Skills muss ein Agent abdecken, um den Task zu stehlen
+	Category string
+	Skills   []string
+	// Pinned Tasks bleiben beim Agenten, dem sie zugeteilt wurden
+	Pinned      bool
 	Payload     interface{}
 	CreatedAt   time.Time
 	StartedAt   *time.Time
@@ -43,12 +57,16 @@ func NewWorkStealer(minLoad, maxLoad, stealThreshold int) *WorkStealer {
 		minLoad:        minLoad,
 		maxLoad:        maxLoad,
 		stealThreshold: stealThreshold,
-		stolenTasks:    make(map[string][]*Task),
+		agentSkills:    make(map[string][]string),
+		stolenTasks:    make(map[string]int),
 		lock:           lock.NewDistributedLock(""),
+		stop:           make(chan struct{}),
 	}
 }
 
-func (ws *WorkStealer) RegisterAgent(agentID string) {
+// RegisterAgent legt die Queue eines Agenten an. skills sind die Kategorien
+// und Fähigkeiten, die er abdeckt; ohne skills nimmt er jeden Task.
+func (ws *WorkStealer) RegisterAgent(agentID string, skills ...string) {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 
@@ -56,6 +74,41 @@ func (ws *WorkStealer) RegisterAgent(agentID string) {
 		ws.agentQueues[agentID] = make([]*Task, 0)
 		ws.agentLoads[agentID] = 0
 	}
+	ws.agentSkills[agentID] = skills
+}
+
+// CanRun prüft, ob ein Agent Kategorie und Skills des Tasks abdeckt.
+func (ws *WorkStealer) CanRun(agentID string, task *Task) bool {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return ws.canRunLocked(agentID, task)
+}
+
+func (ws *WorkStealer) canRunLocked(agentID string, task *Task) bool {
+	skills, exists := ws.agentSkills[agentID]
+	if !exists {
+		return false
+	}
+	if task.Pinned {
+		return task.AgentID == agentID
+	}
+	if len(skills) == 0 {
+		return true
+	}
+
+	has := make(map[string]bool, len(skills))
+	for _, s := range skills {
+		has[s] = true
+	}
+	if task.Category != "" && !has[task.Category] {
+		return false
+	}
+	for _, s := range task.Skills {
+		if !has[s] {
+			return false
+		}
+	}
+	return true
 }
 
 func (ws *WorkStealer) UnregisterAgent(agentID string) {
@@ -64,6 +117,7 @@ func (ws *WorkStealer) UnregisterAgent(agentID string) {
 
 	delete(ws.agentQueues, agentID)
 	delete(ws.agentLoads, agentID)
+	delete(ws.agentSkills, agentID)
 }
 
 func (ws *WorkStealer) Enqueue(agentID string, task *Task) error {
@@ -105,33 +159,78 @@ func (ws *WorkStealer) Dequeue(agentID string) (*Task, error) {
 	return task, nil
 }
 
+// Steal verschiebt den jüngsten Task von fromAgent, den toAgent ausführen
+// kann, in die Queue von toAgent.
 func (ws *WorkStealer) Steal(fromAgent, toAgent string) (*Task, error) {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 
-	fromQueue, exists := ws.agentQueues[fromAgent]
-	if !exists || len(fromQueue) == 0 {
-		return nil, fmt.Errorf("no tasks to steal from agent %s", fromAgent)
+	if _, exists := ws.agentQueues[toAgent]; !exists {
+		return nil, fmt.Errorf("agent %s not registered", toAgent)
 	}
+	return ws.stealTaskLocked(fromAgent, toAgent)
+}
 
-	task := fromQueue[len(fromQueue)-1]
-	ws.agentQueues[fromAgent] = fromQueue[:len(fromQueue)-1]
-	ws.agentLoads[fromAgent]--
+// Take liefert den nächsten Task für agentID: zuerst aus der eigenen Queue,
+// sonst gestohlen vom am stärksten belasteten Peer mit passendem Task.
+func (ws *WorkStealer) Take(agentID string) (*Task, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
 
-	task.AgentID = toAgent
-	ws.agentQueues[toAgent] = append(ws.agentQueues[toAgent], task)
-	ws.agentLoads[toAgent]++
+	if _, exists := ws.agentQueues[agentID]; !exists {
+		return nil, fmt.Errorf("agent %s not registered", agentID)
+	}
 
-	if ws.stolenTasks[toAgent] == nil {
-		ws.stolenTasks[toAgent] = make([]*Task, 0)
+	if len(ws.agentQueues[agentID]) == 0 {
+		peers := make([]string, 0, len(ws.agentLoads))
+		for peer, load := range ws.agentLoads {
+			if peer != agentID && load > ws.stealThreshold {
+				peers = append(peers, peer)
+			}
+		}
+		sort.Slice(peers, func(i, j int) bool {
+			if ws.agentLoads[peers[i]] != ws.agentLoads[peers[j]] {
+				return ws.agentLoads[peers[i]] > ws.agentLoads[peers[j]]
+			}
+			return peers[i] < peers[j]
+		})
+
+		stolen := false
+		for _, peer := range peers {
+			if _, err := ws.stealTaskLocked(peer, agentID); err == nil {
+				stolen = true
+				break
+			}
+		}
+		if !stolen {
+			return nil, ErrNoTask
+		}
 	}
-	ws.stolenTasks[toAgent] = append(ws.stolenTasks[toAgent], task)
 
-	metrics.WorkStealingTasksStolen.Inc()
+	queue := ws.agentQueues[agentID]
+	task := queue[0]
+	ws.agentQueues[agentID] = queue[1:]
+	ws.agentLoads[agentID]--
 
+	now := time.Now()
+	task.StartedAt = &now
 	return task, nil
 }
 
+// Remove nimmt alle noch wartenden Tasks aus den Queues, etwa beim Beenden.
+func (ws *WorkStealer) Remove() []*Task {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	var tasks []*Task
+	for agentID, queue := range ws.agentQueues {
+		tasks = append(tasks, queue...)
+		ws.agentQueues[agentID] = make([]*Task, 0)
+		ws.agentLoads[agentID] = 0
+	}
+	return tasks
+}
+
 func (ws *WorkStealer) FindVictims() []string {
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
@@ -227,19 +326,25 @@ func (ws *WorkStealer) findReceiversLocked() []string {
 
 func (ws *WorkStealer) stealTaskLocked(fromAgent, toAgent string) (*Task, error) {
 	queue := ws.agentQueues[fromAgent]
-	if len(queue) == 0 {
-		return nil, fmt.Errorf("no tasks to steal")
-	}
+	for i := len(queue) - 1; i >= 0; i-- {
+		task := queue[i]
+		if !ws.canRunLocked(toAgent, task) {
+			continue
+		}
 
-	task := queue[len(queue)-1]
-	ws.agentQueues[fromAgent] = queue[:len(queue)-1]
-	ws.agentLoads[fromAgent]--
+		ws.agentQueues[fromAgent] = append(queue[:i:i], queue[i+1:]...)
+		ws.agentLoads[fromAgent]--
 
-	task.AgentID = toAgent
-	ws.agentQueues[toAgent] = append(ws.agentQueues[toAgent], task)
-	ws.agentLoads[toAgent]++
+		task.AgentID = toAgent
+		ws.agentQueues[toAgent] = append(ws.agentQueues[toAgent], task)
+		ws.agentLoads[toAgent]++
+		ws.stolenTasks[toAgent]++
 
-	return task, nil
+		metrics.WorkStealingTasksStolen.Inc()
+		return task, nil
+	}
+
+	return nil, fmt.Errorf("no tasks to steal from agent %s for agent %s", fromAgent, toAgent)
 }
 
 func (ws *WorkStealer) StartRebalancer(interval time.Duration) {
@@ -249,6 +354,8 @@ func (ws *WorkStealer) StartRebalancer(interval time.Duration) {
 
 		for {
 			select {
+			case <-ws.stop:
+				return
 			case <-ticker.C:
 				results := ws.Rebalance()
 				if len(results) > 0 {
@@ -259,18 +366,30 @@ func (ws *WorkStealer) StartRebalancer(interval time.Duration) {
 	}()
 }
 
+// Stop beendet den Rebalancer.
 func (ws *WorkStealer) Stop() {
+	ws.stopOnce.Do(func() { close(ws.stop) })
 }
 
 func (ws *WorkStealer) GetStats() map[string]interface{} {
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
 
+	total, stolen := 0, 0
+	loads := make(map[string]int, len(ws.agentLoads))
+	for agentID, load := range ws.agentLoads {
+		total += load
+		loads[agentID] = load
+	}
+	for _, n := range ws.stolenTasks {
+		stolen += n
+	}
+
 	return map[string]interface{}{
 		"total_agents":    len(ws.agentQueues),
-		"total_tasks":     ws.GetTotalLoad(),
-		"agent_loads":     ws.agentLoads,
-		"stolen_tasks":    len(ws.stolenTasks),
+		"total_tasks":     total,
+		"agent_loads":     loads,
+		"stolen_tasks":    stolen,
 		"steal_threshold": ws.stealThreshold,
 	}
 }
@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTakeStealsOnlyCompatibleTasks(t *testing.T) {
+	ws := NewWorkStealer(1, 10, 0)
+	ws.RegisterAgent("sisyphus", "build", "coding")
+	ws.RegisterAgent("librarian", "explore", "research")
+
+	for _, task := range []*Task{
+		{ID: "docs", Category: "explore"},
+		{ID: "code", Category: "build"},
+		{ID: "pinned", Category: "explore", AgentID: "sisyphus", Pinned: true},
+	} {
+		if err := ws.Enqueue("sisyphus", task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// librarian stiehlt den jüngsten passenden Task, nicht den gepinnten
+	task, err := ws.Take("librarian")
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if task.ID != "docs" || task.AgentID != "librarian" {
+		t.Errorf("stole %s for %s, want docs for librarian", task.ID, task.AgentID)
+	}
+	if _, err := ws.Take("librarian"); !errors.Is(err, ErrNoTask) {
+		t.Errorf("second Take: err = %v, want ErrNoTask", err)
+	}
+
+	for _, want := range []string{"code", "pinned"} {
+		task, err := ws.Take("sisyphus")
+		if err != nil || task.ID != want {
+			t.Fatalf("Take(sisyphus) = %v, %v, want %s", task, err, want)
+		}
+	}
+	if got := ws.GetTotalLoad(); got != 0 {
+		t.Errorf("total load = %d, want 0", got)
+	}
+	if got := ws.GetStats()["stolen_tasks"]; got != 1 {
+		t.Errorf("stolen_tasks = %v, want 1", got)
+	}
+}
+
+func TestAgentWithoutSkillsTakesAnything(t *testing.T) {
+	ws := NewWorkStealer(1, 10, 0)
+	ws.RegisterAgent("busy", "build")
+	ws.RegisterAgent("generalist")
+
+	ws.Enqueue("busy", &Task{ID: "t1", Category: "build", Skills: []string{"go"}})
+	if ws.CanRun("busy", &Task{Category: "build", Skills: []string{"go"}}) {
+		t.Error("busy lacks skill go")
+	}
+	if _, err := ws.Take("generalist"); err != nil {
+		t.Errorf("generalist should steal: %v", err)
+	}
+	ws.Enqueue("busy", &Task{ID: "t2", Category: "build"})
+	if removed := ws.Remove(); len(removed) != 1 || ws.GetTotalLoad() != 0 {
+		t.Errorf("Remove returned %d tasks, want 1", len(removed))
+	}
+}
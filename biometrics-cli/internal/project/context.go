@@ -15,9 +15,9 @@ type ProjectContext struct {
 	Boulder  *models.Boulder
 }
 
-// LoadProjectContext lädt die projektspezifische boulder.json
-func LoadProjectContext(projectID string) (*ProjectContext, error) {
-	basePath := filepath.Join("/Users/jeremy/.sisyphus/plans", projectID)
+// LoadProjectContext lädt die projektspezifische boulder.json aus plansDir
+func LoadProjectContext(plansDir string, projectID string) (*ProjectContext, error) {
+	basePath := filepath.Join(plansDir, projectID)
 	boulderPath := filepath.Join(basePath, "boulder.json")
 
 	data, err := os.ReadFile(boulderPath)
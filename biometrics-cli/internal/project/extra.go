@@ -0,0 +1,94 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// extraFields hält Schlüssel einer boulder.json, die dieser Stand nicht
+// kennt, etwa von neueren Versionen oder anderen Werkzeugen. Sie werden
+// unverändert zurückgeschrieben, damit ein Update sie nicht verwirft.
+type extraFields map[string]json.RawMessage
+
+// unknownFields liefert die Schlüssel aus data, die kein Feld von known
+// (Struct-Wert) belegt. Wie encoding/json vergleicht sie ohne Groß- und
+// Kleinschreibung.
+func unknownFields(data []byte, known interface{}) (extraFields, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	names := jsonNames(reflect.TypeOf(known))
+	var extra extraFields
+	for key, value := range all {
+		if !containsFold(names, key) {
+			if extra == nil {
+				extra = make(extraFields)
+			}
+			extra[key] = value
+		}
+	}
+	return extra, nil
+}
+
+// withExtra kodiert v und hängt die unbekannten Schlüssel sortiert an.
+func withExtra(v interface{}, extra extraFields) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for i, key := range keys {
+		if i > 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(extra[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonNames liefert die JSON-Namen der Felder von t. Schlüssel von Feldern
+// mit `json:"-"` liest encoding/json nicht, sie bleiben unbekannt.
+func jsonNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func containsFold(names []string, key string) bool {
+	for _, name := range names {
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ShareWindow ist das Fenster, über das Model-Minuten gezählt werden.
+const ShareWindow = time.Hour
+
+// DefaultAging ist die Wartezeit, nach der selbst ein überversorgtes Projekt
+// jedes Projekt überholt, das gerade genau seinen Anteil bekommt.
+const DefaultAging = 5 * time.Minute
+
+// maxRatio kappt Share/Entitlement, damit Aging in begrenzter Zeit greift.
+const maxRatio = 2.0
+
+// ErrBudgetExhausted wird von Acquire zurückgegeben, wenn ein Projekt sein
+// Budget ausgeschöpft hat.
+var ErrBudgetExhausted = errors.New("project budget exhausted")
+
+// Budget begrenzt und gewichtet ein Projekt. Es steht als "schedule" in der
+// boulder.json.
+type Budget struct {
+	// Weight ist der relative Anteil an der Rechenzeit (Standard 1)
+	Weight float64 `json:"weight,omitempty"`
+	// MaxConcurrent begrenzt parallel laufende Agenten (Standard 1)
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// MaxModelMinutesPerHour begrenzt die Model-Minuten im ShareWindow, 0 = unbegrenzt
+	MaxModelMinutesPerHour float64 `json:"max_model_minutes_per_hour,omitempty"`
+}
+
+func (b Budget) normalize() Budget {
+	if b.Weight <= 0 {
+		b.Weight = 1
+	}
+	if b.MaxConcurrent <= 0 {
+		b.MaxConcurrent = 1
+	}
+	return b
+}
+
+// ShareStats beschreibt, was ein Projekt bekommt und was ihm zusteht.
+type ShareStats struct {
+	Weight float64 `json:"weight"`
+	// Entitlement ist Weight geteilt durch die Summe aller Gewichte
+	Entitlement float64 `json:"entitlement"`
+	// Share ist der Anteil an allen Model-Minuten im ShareWindow
+	Share                  float64       `json:"share"`
+	ModelMinutes           float64       `json:"model_minutes"`
+	MaxModelMinutesPerHour float64       `json:"max_model_minutes_per_hour,omitempty"`
+	Active                 int           `json:"active"`
+	MaxConcurrent          int           `json:"max_concurrent"`
+	Waiting                time.Duration `json:"waiting"`
+	// Throttled nennt das ausgeschöpfte Budget, sonst leer
+	Throttled string `json:"throttled,omitempty"`
+}
+
+type usage struct {
+	end     time.Time
+	minutes float64
+}
+
+type fairProject struct {
+	budget Budget
+	// runs sind die Startzeiten laufender Agenten
+	runs map[int]time.Time
+	// used sind abgeschlossene Läufe im ShareWindow
+	used []usage
+	// waitingSince ist gesetzt, solange das Projekt Arbeit hat und nicht bedient wird
+	waitingSince time.Time
+}
+
+// FairScheduler verteilt Agenten gewichtet auf Projekte. Ein Projekt, das
+// weniger als seinen Anteil bekommt, kommt zuerst dran; Wartezeit senkt den
+// Rang weiter, sodass auch niedrig gewichtete Projekte nicht verhungern.
+type FairScheduler struct {
+	mu       sync.Mutex
+	projects map[string]*fairProject
+	aging    time.Duration
+	nextRun  int
+	now      func() time.Time
+}
+
+// NewFairScheduler erstellt einen Scheduler. aging <= 0 nimmt DefaultAging.
+func NewFairScheduler(aging time.Duration) *FairScheduler {
+	if aging <= 0 {
+		aging = DefaultAging
+	}
+	return &FairScheduler{
+		projects: make(map[string]*fairProject),
+		aging:    aging,
+		now:      time.Now,
+	}
+}
+
+func (s *FairScheduler) project(name string) *fairProject {
+	p, ok := s.projects[name]
+	if !ok {
+		p = &fairProject{budget: Budget{}.normalize(), runs: make(map[int]time.Time)}
+		s.projects[name] = p
+	}
+	return p
+}
+
+// SetBudget setzt Gewicht und Limits eines Projekts.
+func (s *FairScheduler) SetBudget(name string, b Budget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.project(name).budget = b.normalize()
+}
+
+// Forget entfernt ein Projekt. Laufende Releases bleiben folgenlos.
+func (s *FairScheduler) Forget(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.projects, name)
+}
+
+// minutes zählt abgeschlossene und laufende Model-Minuten im ShareWindow.
+func (p *fairProject) minutes(now time.Time) float64 {
+	cutoff := now.Add(-ShareWindow)
+	kept := p.used[:0]
+	total := 0.0
+	for _, u := range p.used {
+		if u.end.After(cutoff) {
+			kept = append(kept, u)
+			total += u.minutes
+		}
+	}
+	p.used = kept
+
+	for _, start := range p.runs {
+		if start.Before(cutoff) {
+			start = cutoff
+		}
+		total += now.Sub(start).Minutes()
+	}
+	return total
+}
+
+// throttled nennt das ausgeschöpfte Budget oder "".
+func (p *fairProject) throttled(minutes float64) string {
+	if len(p.runs) >= p.budget.MaxConcurrent {
+		return fmt.Sprintf("max_concurrent %d reached", p.budget.MaxConcurrent)
+	}
+	if p.budget.MaxModelMinutesPerHour > 0 && minutes >= p.budget.MaxModelMinutesPerHour {
+		return fmt.Sprintf("%.0f of %.0f model-minutes/hour used", minutes, p.budget.MaxModelMinutesPerHour)
+	}
+	return ""
+}
+
+// Order sortiert die Projekte mit Arbeit nach Dringlichkeit und lässt
+// Projekte ohne freies Budget weg. Der Rang ist Share/Entitlement (höchstens
+// maxRatio) minus Wartezeit/Aging, kleiner ist dringender.
+func (s *FairScheduler) Order(candidates []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, name := range candidates {
+		if p := s.project(name); p.waitingSince.IsZero() {
+			p.waitingSince = now
+		}
+	}
+
+	minutes, total, weights := s.totals(now)
+	type ranked struct {
+		name string
+		rank float64
+	}
+	var eligible []ranked
+	for _, name := range candidates {
+		p := s.projects[name]
+		if p.throttled(minutes[name]) != "" {
+			continue
+		}
+		share := 0.0
+		if total > 0 {
+			share = minutes[name] / total
+		}
+		ratio := math.Min(share/(p.budget.Weight/weights), maxRatio)
+		rank := ratio - float64(now.Sub(p.waitingSince))/float64(s.aging)
+		eligible = append(eligible, ranked{name, rank})
+	}
+
+	sort.SliceStable(eligible, func(i, j int) bool {
+		if eligible[i].rank != eligible[j].rank {
+			return eligible[i].rank < eligible[j].rank
+		}
+		return eligible[i].name < eligible[j].name
+	})
+	order := make([]string, len(eligible))
+	for i, e := range eligible {
+		order[i] = e.name
+	}
+	return order
+}
+
+func (s *FairScheduler) totals(now time.Time) (minutes map[string]float64, total, weights float64) {
+	minutes = make(map[string]float64, len(s.projects))
+	for name, p := range s.projects {
+		m := p.minutes(now)
+		minutes[name] = m
+		total += m
+		weights += p.budget.Weight
+	}
+	return minutes, total, weights
+}
+
+// Next liefert das dringendste Projekt und setzt seine Wartezeit zurück.
+func (s *FairScheduler) Next(candidates []string) (string, bool) {
+	order := s.Order(candidates)
+	if len(order) == 0 {
+		return "", false
+	}
+	s.mu.Lock()
+	s.project(order[0]).waitingSince = time.Time{}
+	s.mu.Unlock()
+	return order[0], true
+}
+
+// Acquire belegt einen Agenten-Slot des Projekts. release bucht die Laufzeit
+// als Model-Minuten und gibt den Slot frei; es darf mehrfach aufgerufen werden.
+func (s *FairScheduler) Acquire(name string) (release func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	p := s.project(name)
+	if reason := p.throttled(p.minutes(now)); reason != "" {
+		return nil, fmt.Errorf("%w: %s: %s", ErrBudgetExhausted, name, reason)
+	}
+
+	s.nextRun++
+	id := s.nextRun
+	p.runs[id] = now
+	p.waitingSince = time.Time{}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			start, ok := p.runs[id]
+			if !ok {
+				return
+			}
+			delete(p.runs, id)
+			end := s.now()
+			p.used = append(p.used, usage{end: end, minutes: end.Sub(start).Minutes()})
+		})
+	}, nil
+}
+
+// Idle meldet, dass ein Projekt gerade keine Arbeit hat; seine Wartezeit
+// beginnt erst wieder, wenn es erneut Kandidat ist.
+func (s *FairScheduler) Idle(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.projects[name]; ok {
+		p.waitingSince = time.Time{}
+	}
+}
+
+// Stats liefert Anteil und Anspruch aller bekannten Projekte.
+func (s *FairScheduler) Stats() map[string]ShareStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	minutes, total, weights := s.totals(now)
+	stats := make(map[string]ShareStats, len(s.projects))
+	for name, p := range s.projects {
+		st := ShareStats{
+			Weight:                 p.budget.Weight,
+			Entitlement:            p.budget.Weight / weights,
+			ModelMinutes:           minutes[name],
+			MaxModelMinutesPerHour: p.budget.MaxModelMinutesPerHour,
+			Active:                 len(p.runs),
+			MaxConcurrent:          p.budget.MaxConcurrent,
+			Throttled:              p.throttled(minutes[name]),
+		}
+		if total > 0 {
+			st.Share = minutes[name] / total
+		}
+		if !p.waitingSince.IsZero() {
+			st.Waiting = now.Sub(p.waitingSince)
+		}
+		stats[name] = st
+	}
+	return stats
+}
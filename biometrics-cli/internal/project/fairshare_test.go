@@ -0,0 +1,126 @@
+package project
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestFairScheduler(aging time.Duration) (*FairScheduler, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewFairScheduler(aging)
+	s.now = clock.now
+	return s, clock
+}
+
+// run belegt einen Slot für d und gibt ihn wieder frei.
+func run(t *testing.T, s *FairScheduler, clock *fakeClock, name string, d time.Duration) {
+	t.Helper()
+	release, err := s.Acquire(name)
+	if err != nil {
+		t.Fatalf("Acquire(%s): %v", name, err)
+	}
+	clock.advance(d)
+	release()
+}
+
+func TestFairSchedulerFollowsWeights(t *testing.T) {
+	s, clock := newTestFairScheduler(time.Hour)
+	s.SetBudget("big", Budget{Weight: 3})
+	s.SetBudget("small", Budget{Weight: 1})
+	candidates := []string{"big", "small"}
+
+	served := map[string]int{}
+	for i := 0; i < 40; i++ {
+		name, ok := s.Next(candidates)
+		if !ok {
+			t.Fatal("no project eligible")
+		}
+		served[name]++
+		run(t, s, clock, name, time.Minute)
+	}
+
+	stats := s.Stats()
+	if got := stats["big"].Entitlement; math.Abs(got-0.75) > 1e-9 {
+		t.Errorf("big entitlement = %v, want 0.75", got)
+	}
+	if got := stats["big"].Share; math.Abs(got-0.75) > 0.05 {
+		t.Errorf("big share = %v (served %v), want about 0.75", got, served)
+	}
+}
+
+func TestFairSchedulerAgingPreventsStarvation(t *testing.T) {
+	s, clock := newTestFairScheduler(10 * time.Minute)
+	s.SetBudget("heavy", Budget{Weight: 100})
+	s.SetBudget("light", Budget{Weight: 1})
+
+	// light hat seinen Anteil bereits verbraucht
+	run(t, s, clock, "light", 10*time.Minute)
+
+	candidates := []string{"heavy", "light"}
+	for i := 0; i < 60; i++ {
+		name, _ := s.Next(candidates)
+		if name == "light" {
+			return
+		}
+		run(t, s, clock, name, time.Minute)
+	}
+	t.Fatal("light was never scheduled")
+}
+
+func TestFairSchedulerBudgets(t *testing.T) {
+	s, clock := newTestFairScheduler(0)
+	s.SetBudget("p", Budget{MaxConcurrent: 2, MaxModelMinutesPerHour: 30})
+
+	r1, err := s.Acquire("p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Acquire("p"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Acquire("p"); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("third Acquire: err = %v, want ErrBudgetExhausted", err)
+	}
+	if order := s.Order([]string{"p"}); len(order) != 0 {
+		t.Errorf("Order = %v, want none while at max_concurrent", order)
+	}
+
+	clock.advance(20 * time.Minute)
+	r1()
+	r1()
+	// 40 Minuten (20 abgeschlossen, 20 laufend) über dem Limit von 30
+	if _, err := s.Acquire("p"); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("Acquire over minutes budget: err = %v", err)
+	}
+	if got := s.Stats()["p"]; got.Active != 1 || got.Throttled == "" {
+		t.Errorf("stats = %+v, want 1 active and throttled", got)
+	}
+
+	// nach einer Stunde fallen die Minuten aus dem Fenster
+	s.Forget("p")
+	s.SetBudget("p", Budget{MaxModelMinutesPerHour: 30})
+	run(t, s, clock, "p", 25*time.Minute)
+	if _, err := s.Acquire("p"); err != nil {
+		t.Fatalf("Acquire within budget: %v", err)
+	}
+}
+
+func TestFairSchedulerIdleResetsWaiting(t *testing.T) {
+	s, clock := newTestFairScheduler(0)
+	s.Order([]string{"p"})
+	clock.advance(time.Minute)
+	if got := s.Stats()["p"].Waiting; got != time.Minute {
+		t.Errorf("waiting = %v, want 1m", got)
+	}
+	s.Idle("p")
+	if got := s.Stats()["p"].Waiting; got != 0 {
+		t.Errorf("waiting after Idle = %v, want 0", got)
+	}
+}
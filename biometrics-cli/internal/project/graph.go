@@ -0,0 +1,220 @@
+package project
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Graph ist der Abhängigkeitsgraph aller Tasks eines Projekts.
+type Graph struct {
+	Tasks []*Task
+	// Missing listet je Task die Abhängigkeiten, die es nicht gibt
+	Missing map[string][]string
+	// Cycles enthält jeden gefundenen Zyklus als Liste von Task-IDs
+	Cycles [][]string
+	// Duplicates listet Task-IDs, die mehrfach vorkommen
+	Duplicates []string
+
+	byID map[string]*Task
+}
+
+// BuildGraph baut den Graphen und prüft ihn auf Zyklen und fehlende Tasks.
+func BuildGraph(b *Boulder) *Graph {
+	g := &Graph{
+		Missing: make(map[string][]string),
+		byID:    make(map[string]*Task),
+	}
+
+	for _, ref := range b.AllTasks() {
+		if _, ok := g.byID[ref.Task.ID]; ok {
+			g.Duplicates = append(g.Duplicates, ref.Task.ID)
+			continue
+		}
+		g.byID[ref.Task.ID] = ref.Task
+		g.Tasks = append(g.Tasks, ref.Task)
+	}
+
+	for _, task := range g.Tasks {
+		for _, dep := range task.DependsOn {
+			if _, ok := g.byID[dep]; !ok {
+				g.Missing[task.ID] = append(g.Missing[task.ID], dep)
+			}
+		}
+	}
+
+	g.findCycles()
+	return g
+}
+
+// Valid ist true, wenn der Graph keine Zyklen, Duplikate oder fehlende
+// Abhängigkeiten enthält.
+func (g *Graph) Valid() bool {
+	return len(g.Cycles) == 0 && len(g.Missing) == 0 && len(g.Duplicates) == 0
+}
+
+// Order liefert die Tasks in topologischer Reihenfolge. Tasks in Zyklen
+// fehlen im Ergebnis.
+func (g *Graph) Order() []*Task {
+	indegree := make(map[string]int, len(g.Tasks))
+	dependents := make(map[string][]string)
+	for _, task := range g.Tasks {
+		for _, dep := range task.DependsOn {
+			if _, ok := g.byID[dep]; !ok {
+				continue
+			}
+			indegree[task.ID]++
+			dependents[dep] = append(dependents[dep], task.ID)
+		}
+	}
+
+	queue := make([]string, 0)
+	for _, task := range g.Tasks {
+		if indegree[task.ID] == 0 {
+			queue = append(queue, task.ID)
+		}
+	}
+
+	order := make([]*Task, 0, len(g.Tasks))
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order = append(order, g.byID[id])
+		for _, next := range dependents[id] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	return order
+}
+
+func (g *Graph) findCycles() {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(g.Tasks))
+	stack := make([]string, 0)
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range g.byID[id].DependsOn {
+			if _, ok := g.byID[dep]; !ok {
+				continue
+			}
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				// Zyklus: vom ersten Vorkommen von dep bis zum Ende des Stacks
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == dep {
+						cycle := append([]string{}, stack[i:]...)
+						g.Cycles = append(g.Cycles, append(cycle, dep))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+
+	for _, task := range g.Tasks {
+		if state[task.ID] == unvisited {
+			visit(task.ID)
+		}
+	}
+}
+
+// WriteText gibt den Graphen in topologischer Reihenfolge aus und markiert
+// Zyklen und fehlende Abhängigkeiten.
+func (g *Graph) WriteText(w io.Writer) {
+	for _, task := range g.Order() {
+		fmt.Fprintf(w, "%-12s [%s] p=%d plan=%s %s\n", task.ID, task.Status, task.Priority, task.Plan, task.Description)
+		for _, dep := range task.DependsOn {
+			fmt.Fprintf(w, "  <- %s\n", dep)
+		}
+	}
+
+	for _, dup := range g.Duplicates {
+		fmt.Fprintf(w, "DUPLICATE: task %s is defined more than once\n", dup)
+	}
+
+	missing := make([]string, 0, len(g.Missing))
+	for id := range g.Missing {
+		missing = append(missing, id)
+	}
+	sort.Strings(missing)
+	for _, id := range missing {
+		fmt.Fprintf(w, "MISSING: %s depends on unknown %s\n", id, strings.Join(g.Missing[id], ", "))
+	}
+
+	for _, cycle := range g.Cycles {
+		fmt.Fprintf(w, "CYCLE: %s\n", strings.Join(cycle, " -> "))
+	}
+}
+
+// WriteDOT gibt den Graphen im Graphviz-Format aus. Kanten zeigen von der
+// Abhängigkeit auf den abhängigen Task. Tasks in Zyklen sind rot, fehlende
+// Abhängigkeiten erscheinen als gestrichelte Knoten.
+func (g *Graph) WriteDOT(w io.Writer) {
+	inCycle := make(map[string]bool)
+	for _, cycle := range g.Cycles {
+		for _, id := range cycle {
+			inCycle[id] = true
+		}
+	}
+
+	fmt.Fprintln(w, "digraph plan {")
+	for _, task := range g.Tasks {
+		label := dotEscape(task.ID) + `\n` + dotEscape(fmt.Sprintf("%s p=%d", task.Status, task.Priority))
+		attrs := `label="` + label + `"`
+		if inCycle[task.ID] {
+			attrs += ", color=red"
+		}
+		fmt.Fprintf(w, "  %s [%s];\n", dotQuote(task.ID), attrs)
+	}
+
+	missing := make(map[string]bool)
+	for _, deps := range g.Missing {
+		for _, dep := range deps {
+			missing[dep] = true
+		}
+	}
+	unknown := make([]string, 0, len(missing))
+	for id := range missing {
+		unknown = append(unknown, id)
+	}
+	sort.Strings(unknown)
+	for _, id := range unknown {
+		fmt.Fprintf(w, "  %s [label=\"%s\\nmissing\", style=dashed, color=gray];\n", dotQuote(id), dotEscape(id))
+	}
+
+	for _, task := range g.Tasks {
+		for _, dep := range task.DependsOn {
+			attrs := ""
+			if missing[dep] {
+				attrs = " [style=dashed]"
+			}
+			fmt.Fprintf(w, "  %s -> %s%s;\n", dotQuote(dep), dotQuote(task.ID), attrs)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// dotEscape maskiert s für einen DOT-String in Anführungszeichen.
+// Zeilenumbrüche werden zu \n, damit sie das Format nicht zerreißen.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+func dotQuote(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
@@ -0,0 +1,87 @@
+package project
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadyRespectsDependenciesAndPriority(t *testing.T) {
+	b := &Boulder{
+		Tasks: []Task{
+			{ID: "a", Status: StatusCompleted},
+			{ID: "b", Status: StatusPending, DependsOn: []string{"a"}},
+			{ID: "c", Status: StatusPending, DependsOn: []string{"d"}},
+		},
+		Plans: []Plan{{
+			ID:       "docs",
+			Priority: 1,
+			Tasks: []Task{
+				{ID: "d", Status: StatusPending},
+				{ID: "e", Status: StatusPending, Priority: 5},
+			},
+		}},
+	}
+
+	ready := b.Ready()
+	ids := make([]string, len(ready))
+	for i, task := range ready {
+		ids[i] = task.ID
+	}
+	if got := strings.Join(ids, ","); got != "e,d,b" {
+		t.Errorf("Expected ready order e,d,b, got %s", got)
+	}
+	if ready[0].Plan != "docs" {
+		t.Errorf("Expected plan docs, got %q", ready[0].Plan)
+	}
+}
+
+func TestBuildGraphDetectsCyclesAndMissing(t *testing.T) {
+	b := &Boulder{Tasks: []Task{
+		{ID: "a", DependsOn: []string{"c"}},
+		{ID: "b", DependsOn: []string{"a"}},
+		{ID: "c", DependsOn: []string{"b"}},
+		{ID: "d", DependsOn: []string{"x"}},
+	}}
+
+	g := BuildGraph(b)
+	if g.Valid() {
+		t.Fatal("Expected graph to be invalid")
+	}
+	if len(g.Cycles) != 1 || len(g.Cycles[0]) != 4 {
+		t.Errorf("Expected one cycle of three tasks, got %v", g.Cycles)
+	}
+	if len(g.Missing["d"]) != 1 {
+		t.Errorf("Expected d to miss x, got %v", g.Missing)
+	}
+	if order := g.Order(); len(order) != 1 || order[0].ID != "d" {
+		t.Errorf("Expected only d outside the cycle, got %d tasks", len(order))
+	}
+
+	var buf bytes.Buffer
+	g.WriteText(&buf)
+	if !strings.Contains(buf.String(), "CYCLE: ") {
+		t.Errorf("Expected cycle in output:\n%s", buf.String())
+	}
+}
+
+func TestWriteDOTMarksMissingAndEscapesIDs(t *testing.T) {
+	b := &Boulder{Tasks: []Task{
+		{ID: `say "hi"`, Status: StatusPending},
+		{ID: "d", Status: StatusPending, DependsOn: []string{`say "hi"`, "x"}},
+	}}
+
+	var buf bytes.Buffer
+	BuildGraph(b).WriteDOT(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		`"say \"hi\"" [label="say \"hi\"\npending p=0"];`,
+		`"x" [label="x\nmissing", style=dashed, color=gray];`,
+		`"say \"hi\"" -> "d";`,
+		`"x" -> "d" [style=dashed];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output lacks %s:\n%s", want, out)
+		}
+	}
+}
@@ -3,66 +3,266 @@ package project
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
+	"time"
 )
 
+// TaskStatus ist der Zustand eines Tasks in der boulder.json.
+type TaskStatus string
+
+const (
+	StatusPending    TaskStatus = "pending"
+	StatusInProgress TaskStatus = "in_progress"
+	StatusVerifying  TaskStatus = "verifying"
+	StatusCompleted  TaskStatus = "completed"
+	StatusFailed     TaskStatus = "failed"
+)
+
+// transitions listet die erlaubten Statuswechsel. Zurück nach pending geht
+// es nur über abgelaufene Leases oder einen Retry nach failed. verifying →
+// in_progress ist der nächste Versuch der Fallback-Kette nach einem
+// abgelehnten Quality Gate.
+var transitions = map[TaskStatus][]TaskStatus{
+	StatusPending:    {StatusInProgress},
+	StatusInProgress: {StatusVerifying, StatusFailed, StatusPending},
+	StatusVerifying:  {StatusCompleted, StatusFailed, StatusPending, StatusInProgress},
+	StatusFailed:     {StatusPending},
+	StatusCompleted:  {},
+}
+
+// CanTransition prüft, ob from → to erlaubt ist.
+func CanTransition(from, to TaskStatus) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// leased ist true für Zustände, die von einer Lease geschützt sind.
+func (s TaskStatus) leased() bool {
+	return s == StatusInProgress || s == StatusVerifying
+}
+
 type Task struct {
-	ID          string `json:"id"`
-	Description string `json:"description"`
-	Status      string `json:"status"`
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	Status      TaskStatus `json:"status"`
+	// DependsOn listet Task-IDs (projektweit), die vorher completed sein müssen
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Priority: höher wird zuerst ausgeführt
+	Priority int    `json:"priority,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Agent pinnt den Task an einen Agenten, sonst wählt der Daemon einen
+	Agent string `json:"agent,omitempty"`
+	// Skills muss der ausführende Agent zusätzlich zur Kategorie abdecken
+	Skills             []string `json:"skills,omitempty"`
+	MaxAttempts        int      `json:"max_attempts,omitempty"`
+	AcceptanceCriteria []string `json:"acceptance_criteria,omitempty"`
+
+	// Plan wird beim Laden gesetzt und nicht gespeichert
+	Plan string `json:"-"`
+
+	Attempts       int        `json:"attempts,omitempty"`
+	LeasedBy       string     `json:"leased_by,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	// QualityReport verweist auf den letzten Report des Quality Gates
+	QualityReport string `json:"quality_report,omitempty"`
+	// History enthält die letzten Agent-Läufe, ältester zuerst
+	History []AttemptRecord `json:"history,omitempty"`
+
+	extra extraFields
 }
 
+// MaxHistory begrenzt Task.History.
+const MaxHistory = 20
+
+// AttemptRecord ist ein Agent-Lauf für einen Task. Attempt ist die Nummer
+// des Claims; innerhalb eines Claims läuft die Fallback-Kette mehrere Modelle.
+type AttemptRecord struct {
+	Attempt       int       `json:"attempt"`
+	Model         string    `json:"model"`
+	Outcome       string    `json:"outcome"`
+	Error         string    `json:"error,omitempty"`
+	Transcript    string    `json:"transcript,omitempty"`
+	QualityReport string    `json:"quality_report,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	DurationMS    int64     `json:"duration_ms"`
+}
+
+func (t *Task) transition(to TaskStatus, now time.Time) error {
+	if !CanTransition(t.Status, to) {
+		return fmt.Errorf("invalid transition for task %s: %s -> %s", t.ID, t.Status, to)
+	}
+	t.Status = to
+	t.UpdatedAt = &now
+	if !to.leased() {
+		t.LeasedBy = ""
+		t.LeaseExpiresAt = nil
+	}
+	if to == StatusPending || to == StatusCompleted {
+		t.Error = ""
+	}
+	return nil
+}
+
+// attemptsExhausted ist true, wenn MaxAttempts gesetzt und erreicht ist.
+func (t *Task) attemptsExhausted() bool {
+	return t.MaxAttempts > 0 && t.Attempts >= t.MaxAttempts
+}
+
+// Plan ist ein eigener Task-Strang innerhalb eines Projekts.
+type Plan struct {
+	ID       string `json:"id"`
+	Priority int    `json:"priority,omitempty"`
+	Tasks    []Task `json:"tasks"`
+
+	extra extraFields
+}
+
+// DefaultPlanID ist der Name der Top-Level Tasks einer boulder.json.
+const DefaultPlanID = "default"
+
 type Boulder struct {
 	Tasks          []Task   `json:"tasks"`
+	Plans          []Plan   `json:"plans,omitempty"`
 	CompletedTasks []string `json:"completed_tasks"`
+	// Schedule gewichtet und begrenzt das Projekt im FairScheduler
+	Schedule *Budget `json:"schedule,omitempty"`
+	// WorkDir ist der Checkout, den die Agents bearbeiten und in dem sie
+	// eingesperrt sind; relative Pfade gelten ab dem Plan-Verzeichnis des
+	// Projekts. Ohne WorkDir hier oder in der quality.yaml laufen sie im
+	// Arbeitsverzeichnis des Daemons.
+	WorkDir string `json:"workdir,omitempty"`
+
+	extra extraFields
 }
 
-func GetNextTask(projectID string) (*Task, error) {
-	boulderPath := filepath.Join("/Users/jeremy/.sisyphus/plans", projectID, "boulder.json")
+// Task, Plan und Boulder behalten unbekannte Schlüssel der boulder.json über
+// ein Update hinweg, siehe extraFields.
 
-	data, err := os.ReadFile(boulderPath)
-	if err != nil {
-		return nil, err
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plain Task
+	if err := json.Unmarshal(data, (*plain)(t)); err != nil {
+		return err
 	}
+	extra, err := unknownFields(data, plain{})
+	t.extra = extra
+	return err
+}
 
-	var b Boulder
-	if err := json.Unmarshal(data, &b); err != nil {
-		return nil, err
+func (t Task) MarshalJSON() ([]byte, error) {
+	type plain Task
+	return withExtra(plain(t), t.extra)
+}
+
+func (p *Plan) UnmarshalJSON(data []byte) error {
+	type plain Plan
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
 	}
+	extra, err := unknownFields(data, plain{})
+	p.extra = extra
+	return err
+}
 
-	for i, task := range b.Tasks {
-		if task.Status == "pending" {
-			b.Tasks[i].Status = "in_progress"
+func (p Plan) MarshalJSON() ([]byte, error) {
+	type plain Plan
+	return withExtra(plain(p), p.extra)
+}
 
-			newData, _ := json.MarshalIndent(b, "", "  ")
-			os.WriteFile(boulderPath, newData, 0644)
+func (b *Boulder) UnmarshalJSON(data []byte) error {
+	type plain Boulder
+	if err := json.Unmarshal(data, (*plain)(b)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, plain{})
+	b.extra = extra
+	return err
+}
 
-			return &b.Tasks[i], nil
+func (b Boulder) MarshalJSON() ([]byte, error) {
+	type plain Boulder
+	return withExtra(plain(b), b.extra)
+}
+
+// TaskRef zeigt auf einen Task samt Plan-Priorität.
+type TaskRef struct {
+	Task         *Task
+	PlanPriority int
+	order        int
+}
+
+// AllTasks liefert alle Tasks aller Pläne in Datei-Reihenfolge.
+func (b *Boulder) AllTasks() []TaskRef {
+	refs := make([]TaskRef, 0, len(b.Tasks))
+	for i := range b.Tasks {
+		b.Tasks[i].Plan = DefaultPlanID
+		refs = append(refs, TaskRef{Task: &b.Tasks[i], order: len(refs)})
+	}
+	for p := range b.Plans {
+		plan := &b.Plans[p]
+		for i := range plan.Tasks {
+			plan.Tasks[i].Plan = plan.ID
+			refs = append(refs, TaskRef{Task: &plan.Tasks[i], PlanPriority: plan.Priority, order: len(refs)})
 		}
 	}
-	return nil, fmt.Errorf("no pending tasks found")
+	return refs
 }
 
-func MarkTaskCompleted(projectID string, taskID string) error {
-	boulderPath := filepath.Join("/Users/jeremy/.sisyphus/plans", projectID, "boulder.json")
-
-	data, err := os.ReadFile(boulderPath)
-	if err != nil {
-		return err
+// FindTask sucht einen Task in allen Plänen.
+func (b *Boulder) FindTask(taskID string) *Task {
+	for _, ref := range b.AllTasks() {
+		if ref.Task.ID == taskID {
+			return ref.Task
+		}
 	}
+	return nil
+}
 
-	var b Boulder
-	json.Unmarshal(data, &b)
+// Ready liefert alle pending Tasks, deren Abhängigkeiten completed sind,
+// sortiert nach Priorität, Plan-Priorität und Reihenfolge in der Datei.
+func (b *Boulder) Ready() []*Task {
+	refs := b.AllTasks()
+	status := make(map[string]TaskStatus, len(refs))
+	for _, ref := range refs {
+		status[ref.Task.ID] = ref.Task.Status
+	}
 
-	for i, task := range b.Tasks {
-		if task.ID == taskID {
-			b.Tasks[i].Status = "completed"
-			b.CompletedTasks = append(b.CompletedTasks, taskID)
-			break
+	ready := make([]TaskRef, 0)
+	for _, ref := range refs {
+		if ref.Task.Status != StatusPending {
+			continue
+		}
+		satisfied := true
+		for _, dep := range ref.Task.DependsOn {
+			if status[dep] != StatusCompleted {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			ready = append(ready, ref)
 		}
 	}
 
-	newData, _ := json.MarshalIndent(b, "", "  ")
-	return os.WriteFile(boulderPath, newData, 0644)
+	sort.SliceStable(ready, func(i, j int) bool {
+		a, b := ready[i], ready[j]
+		if a.Task.Priority != b.Task.Priority {
+			return a.Task.Priority > b.Task.Priority
+		}
+		if a.PlanPriority != b.PlanPriority {
+			return a.PlanPriority > b.PlanPriority
+		}
+		return a.order < b.order
+	})
+
+	tasks := make([]*Task, len(ready))
+	for i, ref := range ready {
+		tasks[i] = ref.Task
+	}
+	return tasks
 }
@@ -0,0 +1,395 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultLease ist die Zeit, nach der ein in_progress Task ohne Heartbeat
+// wieder in die Queue zurückfällt.
+const DefaultLease = 30 * time.Minute
+
+// MinLease ist die kürzeste Lease; HoldLease erneuert alle Lease/3.
+const MinLease = time.Second
+
+// ErrLeaseLost meldet, dass ein anderer Worker oder Claim die Lease hält.
+var ErrLeaseLost = errors.New("lease lost")
+
+// ErrNoPendingTask wird zurückgegeben, wenn kein Task bereit ist.
+var ErrNoPendingTask = errors.New("no pending tasks found")
+
+// TaskStore verwaltet die Tasks eines Projekts in dessen boulder.json.
+type TaskStore interface {
+	Load(projectID string) (*Boulder, error)
+	Claim(projectID string) (*Task, error)
+	Renew(projectID, taskID string, attempt int) error
+	Transition(projectID, taskID string, attempt int, to TaskStatus, reason string) error
+	ReclaimExpired(projectID string) (int, error)
+}
+
+// FileTaskStore speichert Tasks in <basePath>/<project>/boulder.json.
+// Jede Änderung läuft unter einem exklusiven flock auf boulder.json.lock
+// und wird über write-temp-then-rename atomar ersetzt.
+type FileTaskStore struct {
+	basePath string
+	lease    time.Duration
+	owner    string
+	now      func() time.Time
+}
+
+// NewFileTaskStore erstellt einen Store für alle Projekte unter basePath.
+func NewFileTaskStore(basePath string) *FileTaskStore {
+	host, _ := os.Hostname()
+	return &FileTaskStore{
+		basePath: basePath,
+		lease:    DefaultLease,
+		owner:    fmt.Sprintf("%s:%d", host, os.Getpid()),
+		now:      time.Now,
+	}
+}
+
+// SetLease ändert die Lease-Dauer für neu geclaimte Tasks.
+func (s *FileTaskStore) SetLease(lease time.Duration) error {
+	if lease < MinLease {
+		return fmt.Errorf("lease %s is shorter than %s", lease, MinLease)
+	}
+	s.lease = lease
+	return nil
+}
+
+// Owner identifiziert diesen Prozess als Lease-Inhaber.
+func (s *FileTaskStore) Owner() string {
+	return s.owner
+}
+
+func (s *FileTaskStore) boulderPath(projectID string) string {
+	return filepath.Join(s.basePath, projectID, "boulder.json")
+}
+
+// Load liest die boulder.json unter einem Shared Lock.
+func (s *FileTaskStore) Load(projectID string) (*Boulder, error) {
+	path := s.boulderPath(projectID)
+	unlock, err := lockFile(path+".lock", syscall.LOCK_SH)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return readBoulder(path)
+}
+
+// Update führt fn unter exklusivem Lock aus und schreibt das Ergebnis atomar
+// zurück, sofern fn keinen Fehler liefert.
+func (s *FileTaskStore) Update(projectID string, fn func(b *Boulder) error) error {
+	path := s.boulderPath(projectID)
+	unlock, err := lockFile(path+".lock", syscall.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	b, err := readBoulder(path)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(b); err != nil {
+		return err
+	}
+
+	return writeBoulder(path, b)
+}
+
+// Claim holt abgelaufene Leases zurück und setzt den Task mit der höchsten
+// Priorität, dessen Abhängigkeiten erfüllt sind, auf in_progress.
+func (s *FileTaskStore) Claim(projectID string) (*Task, error) {
+	var claimed *Task
+	err := s.Update(projectID, func(b *Boulder) error {
+		now := s.now()
+		reclaimed := s.reclaim(b, now)
+
+		for _, task := range b.Ready() {
+			if err := task.transition(StatusInProgress, now); err != nil {
+				return err
+			}
+			task.Attempts++
+			task.LeasedBy = s.owner
+			expires := now.Add(s.lease)
+			task.LeaseExpiresAt = &expires
+
+			copied := *task
+			claimed = &copied
+			return nil
+		}
+		if reclaimed > 0 {
+			// Zurückgeholte oder endgültig gescheiterte Tasks trotzdem speichern
+			return nil
+		}
+		return ErrNoPendingTask
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claimed == nil {
+		return nil, ErrNoPendingTask
+	}
+	return claimed, nil
+}
+
+// Renew verlängert die Lease, die dieser Owner mit dem Claim attempt hält
+// (Heartbeat).
+func (s *FileTaskStore) Renew(projectID, taskID string, attempt int) error {
+	return s.Update(projectID, func(b *Boulder) error {
+		task := b.FindTask(taskID)
+		if task == nil {
+			return fmt.Errorf("task %s not found", taskID)
+		}
+		if !task.Status.leased() {
+			return fmt.Errorf("task %s is %s, not leased", taskID, task.Status)
+		}
+		if err := s.holds(task, attempt); err != nil {
+			return err
+		}
+		expires := s.now().Add(s.lease)
+		task.LeaseExpiresAt = &expires
+		return nil
+	})
+}
+
+// HoldLease erneuert die Lease des Claims attempt regelmäßig, bis ctx endet,
+// stop aufgerufen wird oder die Lease verloren ist.
+func (s *FileTaskStore) HoldLease(ctx context.Context, projectID, taskID string, attempt int) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(max(s.lease, MinLease) / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Renew(projectID, taskID, attempt); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// Adopt übernimmt die Lease eines laufenden Tasks nach einem Neustart. Das
+// geht nur für den Claim attempt und nur, wenn der bisherige Inhaber nicht
+// mehr lebt, die Lease abgelaufen ist oder sie schon diesem Owner gehört
+// (gleiche PID nach Neustart eines Containers).
+func (s *FileTaskStore) Adopt(projectID, taskID string, attempt int) (*Task, error) {
+	var adopted *Task
+	err := s.Update(projectID, func(b *Boulder) error {
+		task := b.FindTask(taskID)
+		if task == nil {
+			return fmt.Errorf("task %s not found", taskID)
+		}
+		if !task.Status.leased() {
+			return fmt.Errorf("task %s is %s, not leased", taskID, task.Status)
+		}
+		if task.Attempts != attempt {
+			return fmt.Errorf("task %s was claimed again (attempt %d, not %d)", taskID, task.Attempts, attempt)
+		}
+		now := s.now()
+		expired := task.LeaseExpiresAt == nil || now.After(*task.LeaseExpiresAt)
+		if task.LeasedBy != s.owner && !expired && ownerAlive(task.LeasedBy) {
+			return fmt.Errorf("task %s is leased by running process %s", taskID, task.LeasedBy)
+		}
+
+		task.LeasedBy = s.owner
+		expires := now.Add(s.lease)
+		task.LeaseExpiresAt = &expires
+		copied := *task
+		adopted = &copied
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return adopted, nil
+}
+
+// holds meldet ErrLeaseLost, wenn die Lease von task einem anderen Owner
+// oder einem anderen Claim gehört.
+func (s *FileTaskStore) holds(task *Task, attempt int) error {
+	if task.LeasedBy != s.owner || task.Attempts != attempt {
+		return fmt.Errorf("%w: task %s is leased by %s (attempt %d), not %s (attempt %d)",
+			ErrLeaseLost, task.ID, task.LeasedBy, task.Attempts, s.owner, attempt)
+	}
+	return nil
+}
+
+// ownerAlive prüft, ob der Prozess hinter einem Owner ("host:pid") noch
+// läuft. Prozesse anderer Hosts gelten als lebendig.
+func ownerAlive(owner string) bool {
+	i := strings.LastIndex(owner, ":")
+	if i < 0 {
+		return false
+	}
+	pid, err := strconv.Atoi(owner[i+1:])
+	if err != nil || pid <= 0 {
+		return false
+	}
+	if host, _ := os.Hostname(); owner[:i] != host {
+		return true
+	}
+	err = syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Transition führt einen geprüften Statuswechsel durch. Einen geleasten
+// Task darf nur der Owner mit dem Claim attempt weiterschalten. reason wird
+// bei failed als Fehler am Task gespeichert.
+func (s *FileTaskStore) Transition(projectID, taskID string, attempt int, to TaskStatus, reason string) error {
+	return s.Update(projectID, func(b *Boulder) error {
+		task := b.FindTask(taskID)
+		if task == nil {
+			return fmt.Errorf("task %s not found", taskID)
+		}
+		if task.Status.leased() {
+			if err := s.holds(task, attempt); err != nil {
+				return err
+			}
+		}
+		if err := task.transition(to, s.now()); err != nil {
+			return err
+		}
+		if to == StatusFailed {
+			task.Error = reason
+		}
+		if to == StatusCompleted {
+			b.CompletedTasks = append(b.CompletedTasks, taskID)
+		}
+		return nil
+	})
+}
+
+// AttachQualityReport speichert den Verweis auf den Gate-Report am Task.
+func (s *FileTaskStore) AttachQualityReport(projectID, taskID, ref string) error {
+	return s.Update(projectID, func(b *Boulder) error {
+		task := b.FindTask(taskID)
+		if task == nil {
+			return fmt.Errorf("task %s not found", taskID)
+		}
+		task.QualityReport = ref
+		return nil
+	})
+}
+
+// RecordAttempt hängt einen Agent-Lauf an die History des Tasks.
+func (s *FileTaskStore) RecordAttempt(projectID, taskID string, rec AttemptRecord) error {
+	return s.Update(projectID, func(b *Boulder) error {
+		task := b.FindTask(taskID)
+		if task == nil {
+			return fmt.Errorf("task %s not found", taskID)
+		}
+		task.History = append(task.History, rec)
+		if len(task.History) > MaxHistory {
+			task.History = task.History[len(task.History)-MaxHistory:]
+		}
+		return nil
+	})
+}
+
+// ReclaimExpired setzt Tasks mit abgelaufener Lease zurück auf pending.
+func (s *FileTaskStore) ReclaimExpired(projectID string) (int, error) {
+	var n int
+	err := s.Update(projectID, func(b *Boulder) error {
+		n = s.reclaim(b, s.now())
+		return nil
+	})
+	return n, err
+}
+
+func (s *FileTaskStore) reclaim(b *Boulder, now time.Time) int {
+	n := 0
+	for _, ref := range b.AllTasks() {
+		task := ref.Task
+		if !task.Status.leased() {
+			continue
+		}
+		// Tasks ohne Lease stammen aus der Zeit vor dem Store und gelten als verwaist
+		if task.LeaseExpiresAt == nil || now.After(*task.LeaseExpiresAt) {
+			if task.attemptsExhausted() {
+				task.transition(StatusFailed, now)
+				task.Error = fmt.Sprintf("lease expired after %d attempts", task.Attempts)
+			} else {
+				task.transition(StatusPending, now)
+			}
+			n++
+		}
+	}
+	return n
+}
+
+func readBoulder(path string) (*Boulder, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var b Boulder
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &b, nil
+}
+
+// writeBoulder schreibt in eine Temp-Datei im selben Verzeichnis, fsynct sie
+// und ersetzt die boulder.json per rename.
+func writeBoulder(path string, b *Boulder) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal boulder: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".boulder-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func lockFile(path string, how int) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,295 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeTestBoulder(t *testing.T, base, projectID string, tasks ...Task) {
+	t.Helper()
+	dir := filepath.Join(base, projectID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBoulder(filepath.Join(dir, "boulder.json"), &Boulder{Tasks: tasks}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClaimTransitionsToCompleted(t *testing.T) {
+	base := t.TempDir()
+	writeTestBoulder(t, base, "demo", Task{ID: "t1", Status: StatusPending})
+	store := NewFileTaskStore(base)
+
+	task, err := store.Claim("demo")
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if task.Status != StatusInProgress || task.Attempts != 1 || task.LeaseExpiresAt == nil {
+		t.Errorf("Unexpected claimed task: %+v", task)
+	}
+
+	if _, err := store.Claim("demo"); err != ErrNoPendingTask {
+		t.Errorf("Expected ErrNoPendingTask, got %v", err)
+	}
+
+	if err := store.Transition("demo", "t1", 1, StatusCompleted, ""); err == nil {
+		t.Error("Expected in_progress -> completed to be rejected")
+	}
+	if err := store.Transition("demo", "t1", 1, StatusVerifying, ""); err != nil {
+		t.Fatalf("Transition to verifying failed: %v", err)
+	}
+	if err := store.Transition("demo", "t1", 1, StatusCompleted, ""); err != nil {
+		t.Fatalf("Transition to completed failed: %v", err)
+	}
+
+	b, err := store.Load("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Tasks[0].Status != StatusCompleted || b.Tasks[0].LeasedBy != "" {
+		t.Errorf("Unexpected final task: %+v", b.Tasks[0])
+	}
+	if len(b.CompletedTasks) != 1 || b.CompletedTasks[0] != "t1" {
+		t.Errorf("Expected t1 in completed_tasks, got %v", b.CompletedTasks)
+	}
+}
+
+func TestExpiredLeaseReturnsTaskToQueue(t *testing.T) {
+	base := t.TempDir()
+	writeTestBoulder(t, base, "demo", Task{ID: "t1", Status: StatusPending})
+	store := NewFileTaskStore(base)
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	if _, err := store.Claim("demo"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(DefaultLease + time.Second)
+	task, err := store.Claim("demo")
+	if err != nil {
+		t.Fatalf("Expected orphaned task to be reclaimed: %v", err)
+	}
+	if task.ID != "t1" || task.Attempts != 2 {
+		t.Errorf("Unexpected reclaimed task: %+v", task)
+	}
+}
+
+func TestSetLeaseRejectsTooShortLeases(t *testing.T) {
+	store := NewFileTaskStore(t.TempDir())
+	for _, lease := range []time.Duration{-time.Second, 0, 2, MinLease - 1} {
+		if err := store.SetLease(lease); err == nil {
+			t.Errorf("SetLease(%s) succeeded", lease)
+		}
+	}
+	if store.lease != DefaultLease {
+		t.Errorf("lease = %s after rejected updates, want %s", store.lease, DefaultLease)
+	}
+	if err := store.SetLease(MinLease); err != nil {
+		t.Fatal(err)
+	}
+
+	// Auch ein Store ohne Lease darf HoldLease nicht in Panik versetzen
+	stop := (&FileTaskStore{basePath: store.basePath, now: time.Now}).HoldLease(context.Background(), "demo", "t1", 1)
+	stop()
+}
+
+func TestStaleWorkerCannotTouchReclaimedTask(t *testing.T) {
+	base := t.TempDir()
+	writeTestBoulder(t, base, "demo", Task{ID: "t1", Status: StatusPending})
+	now := time.Now()
+	stale, current := NewFileTaskStore(base), NewFileTaskStore(base)
+	stale.owner, current.owner = "host:1", "host:2"
+	stale.now = func() time.Time { return now }
+	current.now = func() time.Time { return now }
+
+	if _, err := stale.Claim("demo"); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(DefaultLease + time.Second)
+	task, err := current.Claim("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stale.Renew("demo", "t1", 1); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("stale Renew = %v, want ErrLeaseLost", err)
+	}
+	if err := stale.Transition("demo", "t1", 1, StatusVerifying, ""); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("stale Transition = %v, want ErrLeaseLost", err)
+	}
+	// Derselbe Owner mit einem alten Claim ist ebenso veraltet
+	if err := current.Transition("demo", "t1", 1, StatusFailed, "stale"); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Transition with an old attempt = %v, want ErrLeaseLost", err)
+	}
+
+	b, err := current.Load("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := b.Tasks[0]
+	if got.Status != StatusInProgress || got.LeasedBy != current.owner || !got.LeaseExpiresAt.Equal(*task.LeaseExpiresAt) {
+		t.Errorf("stale worker changed the task: %+v", got)
+	}
+	if err := current.Transition("demo", "t1", task.Attempts, StatusVerifying, ""); err != nil {
+		t.Errorf("owner Transition failed: %v", err)
+	}
+}
+
+func TestUpdateKeepsUnknownFields(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "demo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "boulder.json")
+	boulder := `{
+  "owner": "team-a",
+  "tasks": [{"id": "t1", "status": "pending", "estimate": {"hours": 2}}],
+  "plans": [{"id": "docs", "file": "docs.md", "tasks": [], "milestone": "v2"}],
+  "completed_tasks": []
+}`
+	if err := os.WriteFile(path, []byte(boulder), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewFileTaskStore(base)
+	if _, err := store.Claim("demo"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		Owner string `json:"owner"`
+		Tasks []struct {
+			Status   string `json:"status"`
+			Estimate struct {
+				Hours int `json:"hours"`
+			} `json:"estimate"`
+		} `json:"tasks"`
+		Plans []struct {
+			File      string `json:"file"`
+			Milestone string `json:"milestone"`
+		} `json:"plans"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Owner != "team-a" || saved.Tasks[0].Estimate.Hours != 2 || saved.Plans[0].Milestone != "v2" || saved.Plans[0].File != "docs.md" {
+		t.Errorf("unknown fields lost on update:\n%s", data)
+	}
+	if saved.Tasks[0].Status != string(StatusInProgress) {
+		t.Errorf("status = %s, want in_progress", saved.Tasks[0].Status)
+	}
+}
+
+func TestLegacyInProgressIsReclaimed(t *testing.T) {
+	base := t.TempDir()
+	writeTestBoulder(t, base, "demo", Task{ID: "stuck", Status: StatusInProgress})
+	store := NewFileTaskStore(base)
+
+	n, err := store.ReclaimExpired("demo")
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 reclaimed task, got %d (%v)", n, err)
+	}
+}
+
+func TestConcurrentClaimsDoNotLoseUpdates(t *testing.T) {
+	base := t.TempDir()
+	var tasks []Task
+	for i := 0; i < 20; i++ {
+		tasks = append(tasks, Task{ID: string(rune('a' + i)), Status: StatusPending})
+	}
+	writeTestBoulder(t, base, "demo", tasks...)
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store := NewFileTaskStore(base)
+			for {
+				task, err := store.Claim("demo")
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				if seen[task.ID] {
+					t.Errorf("Task %s claimed twice", task.ID)
+				}
+				seen[task.ID] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 20 {
+		t.Errorf("Expected 20 claimed tasks, got %d", len(seen))
+	}
+}
+
+func TestExpiredLeaseFailsAfterMaxAttempts(t *testing.T) {
+	base := t.TempDir()
+	writeTestBoulder(t, base, "demo", Task{ID: "t1", Status: StatusPending, MaxAttempts: 1})
+	store := NewFileTaskStore(base)
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	if _, err := store.Claim("demo"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(DefaultLease + time.Second)
+	if _, err := store.Claim("demo"); err != ErrNoPendingTask {
+		t.Errorf("Expected ErrNoPendingTask, got %v", err)
+	}
+	b, err := store.Load("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Tasks[0].Status != StatusFailed {
+		t.Errorf("Expected task to fail after max attempts, got %s", b.Tasks[0].Status)
+	}
+}
+
+func TestAdoptTakesOverLeaseOfDeadOwner(t *testing.T) {
+	base := t.TempDir()
+	host, _ := os.Hostname()
+	expires := time.Now().Add(time.Hour)
+	leased := func(id, owner string) Task {
+		return Task{ID: id, Status: StatusVerifying, Attempts: 2, LeasedBy: owner, LeaseExpiresAt: &expires}
+	}
+	writeTestBoulder(t, base, "demo",
+		leased("alive", fmt.Sprintf("%s:%d", host, os.Getppid())),
+		leased("dead", fmt.Sprintf("%s:%d", host, 1<<30)),
+	)
+	store := NewFileTaskStore(base)
+
+	if _, err := store.Adopt("demo", "alive", 2); err == nil {
+		t.Error("Adopt took over the lease of a running process")
+	}
+	if _, err := store.Adopt("demo", "dead", 1); err == nil {
+		t.Error("Adopt accepted an outdated attempt")
+	}
+	task, err := store.Adopt("demo", "dead", 2)
+	if err != nil {
+		t.Fatalf("Adopt failed: %v", err)
+	}
+	if task.LeasedBy != store.Owner() || task.Status != StatusVerifying {
+		t.Errorf("Unexpected adopted task: %+v", task)
+	}
+}
@@ -0,0 +1,170 @@
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken ist eine grobe Schätzung, die für Code und Markdown
+// ausreichend konservativ ist.
+const charsPerToken = 4
+
+// minSectionTokens: kleinere Reste lohnen sich nicht, die Section wird verworfen.
+const minSectionTokens = 64
+
+// EstimateTokens schätzt die Anzahl Tokens von s.
+func EstimateTokens(s string) int {
+	n := utf8.RuneCountInString(s)
+	return (n + charsPerToken - 1) / charsPerToken
+}
+
+// Section ist ein kürzbarer Teil eines Prompts. Sections mit höherer
+// Priority werden zuletzt gekürzt.
+type Section struct {
+	Name     string
+	Priority int
+	Content  string
+}
+
+// BudgetAction beschreibt, was mit einer Section passiert ist.
+type BudgetAction string
+
+const (
+	ActionKept       BudgetAction = "kept"
+	ActionTruncated  BudgetAction = "truncated"
+	ActionSummarized BudgetAction = "summarized"
+	ActionDropped    BudgetAction = "dropped"
+)
+
+// BudgetEntry hält fest, wie viel einer Section übrig geblieben ist.
+type BudgetEntry struct {
+	Section string
+	Action  BudgetAction
+	Tokens  int
+	Kept    int
+}
+
+// BudgetReport ist das Ergebnis von FitSections.
+type BudgetReport struct {
+	Limit   int
+	Used    int
+	Entries []BudgetEntry
+}
+
+// Reduced liefert alle Sections, die nicht vollständig übernommen wurden.
+func (r BudgetReport) Reduced() []BudgetEntry {
+	var reduced []BudgetEntry
+	for _, e := range r.Entries {
+		if e.Action != ActionKept {
+			reduced = append(reduced, e)
+		}
+	}
+	return reduced
+}
+
+// Markdown beschreibt gekürzte und verworfene Sections für den Prompt.
+// Ist nichts gekürzt worden, ist das Ergebnis leer.
+func (r BudgetReport) Markdown() string {
+	reduced := r.Reduced()
+	if len(reduced) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## 📏 CONTEXT BUDGET\n\n")
+	sb.WriteString(fmt.Sprintf("Kontext-Budget: %d Tokens, verwendet: ~%d Tokens.\n", r.Limit, r.Used))
+	sb.WriteString("Folgende Abschnitte wurden gekürzt - lies die Originale selbst:\n\n")
+	for _, e := range reduced {
+		sb.WriteString(fmt.Sprintf("- %s: %s (%d von ~%d Tokens)\n", e.Section, e.Action, e.Kept, e.Tokens))
+	}
+	return sb.String()
+}
+
+// FitSections kürzt sections so, dass sie zusammen höchstens limit Tokens
+// belegen. Sections werden nach absteigender Priority bedient; was nicht
+// mehr ganz passt, wird gekürzt, auf die Überschriften reduziert oder
+// verworfen. Die Reihenfolge des Ergebnisses entspricht der Eingabe.
+func FitSections(sections []Section, limit int) ([]Section, BudgetReport) {
+	if limit < 0 {
+		limit = 0
+	}
+	report := BudgetReport{Limit: limit, Entries: make([]BudgetEntry, len(sections))}
+
+	order := make([]int, len(sections))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sections[order[a]].Priority > sections[order[b]].Priority
+	})
+
+	fitted := make([]Section, len(sections))
+	remaining := limit
+	for _, i := range order {
+		s := sections[i]
+		tokens := EstimateTokens(s.Content)
+		entry := BudgetEntry{Section: s.Name, Tokens: tokens}
+
+		switch {
+		case tokens <= remaining:
+			entry.Action = ActionKept
+		case remaining < minSectionTokens:
+			entry.Action = ActionDropped
+			s.Content = ""
+		default:
+			s.Content, entry.Action = reduce(s.Content, tokens, remaining)
+		}
+
+		entry.Kept = EstimateTokens(s.Content)
+		remaining -= entry.Kept
+		report.Used += entry.Kept
+		report.Entries[i] = entry
+		fitted[i] = s
+	}
+
+	return fitted, report
+}
+
+// reduce kürzt content auf budget Tokens. Passt mindestens die Hälfte, wird
+// der Anfang behalten, sonst möglichst nur die Gliederung.
+func reduce(content string, tokens, budget int) (string, BudgetAction) {
+	if budget*2 < tokens {
+		if outline := outlineOf(content, tokens); outline != "" && EstimateTokens(outline) <= budget {
+			return outline, ActionSummarized
+		}
+	}
+
+	marker := fmt.Sprintf("\n[... gekürzt: ~%d von ~%d Tokens ...]", budget, tokens)
+	keep := (budget - EstimateTokens(marker)) * charsPerToken
+	if keep <= 0 {
+		return "", ActionDropped
+	}
+
+	runes := []rune(content)
+	if keep > len(runes) {
+		keep = len(runes)
+	}
+	head := string(runes[:keep])
+	// An einer Zeilengrenze schneiden, wenn das nicht zu viel kostet
+	if i := strings.LastIndex(head, "\n"); i > len(head)/2 {
+		head = head[:i]
+	}
+	return head + marker, ActionTruncated
+}
+
+// outlineOf reduziert Markdown auf seine Überschriften.
+func outlineOf(content string, tokens int) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	lines = append(lines, fmt.Sprintf("[... zusammengefasst: nur Überschriften von ~%d Tokens ...]", tokens))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,49 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFitSectionsKeepsEverythingWithinLimit(t *testing.T) {
+	sections := []Section{
+		{Name: "a", Priority: 2, Content: "short"},
+		{Name: "b", Priority: 1, Content: "also short"},
+	}
+	fitted, report := FitSections(sections, 1000)
+	if fitted[0].Content != "short" || fitted[1].Content != "also short" {
+		t.Errorf("Expected sections unchanged, got %+v", fitted)
+	}
+	if len(report.Reduced()) != 0 || report.Markdown() != "" {
+		t.Errorf("Expected nothing reduced, got %+v", report)
+	}
+}
+
+func TestFitSectionsReducesLowPriorityFirst(t *testing.T) {
+	big := strings.Repeat("line of text\n", 200)
+	outline := "# Title\n" + strings.Repeat("body text here\n", 400) + "## Part\n"
+	sections := []Section{
+		{Name: "low", Priority: 1, Content: big},
+		{Name: "outline", Priority: 2, Content: outline},
+		{Name: "high", Priority: 3, Content: big},
+	}
+
+	limit := EstimateTokens(big) + 200
+	fitted, report := FitSections(sections, limit)
+
+	if fitted[2].Content != big || report.Entries[2].Action != ActionKept {
+		t.Errorf("Expected high priority section to be kept, got %s", report.Entries[2].Action)
+	}
+	if report.Entries[1].Action != ActionSummarized || !strings.Contains(fitted[1].Content, "## Part") {
+		t.Errorf("Expected outline section to be summarized, got %s: %q", report.Entries[1].Action, fitted[1].Content)
+	}
+	if report.Entries[0].Action != ActionTruncated && report.Entries[0].Action != ActionDropped {
+		t.Errorf("Expected low priority section to be reduced, got %s", report.Entries[0].Action)
+	}
+	if report.Used > limit {
+		t.Errorf("Used %d tokens, limit %d", report.Used, limit)
+	}
+	if !strings.Contains(report.Markdown(), "outline: summarized") {
+		t.Errorf("Expected report to mention summarized section:\n%s", report.Markdown())
+	}
+}
@@ -1,27 +1,41 @@
 package prompt
 
-import (
-	"fmt"
-)
+import "path/filepath"
 
-func GenerateEnterprisePrompt(projectID string, planName string, taskID string, taskDescription string) string {
-	return fmt.Sprintf(`[START SUB-AGENT PROMPT FORMAT]
+// TaskTemplate ist das Template für Tasks aus der boulder.json.
+const TaskTemplate = "sub-agent"
 
-ID: %s
-PROJECT_ID: %s
-PLAN_FILE: /Users/jeremy/.sisyphus/plans/%s/%s
-
-SYSTEM_ROLE: Du bist ein reiner Ausfuehrungs-Agent. Keine Fragen. Keine Interpretation.
-
-TASK_DESCRIPTION:
-%s
+// TaskData sind die Variablen, mit denen TaskTemplate gerendert wird.
+type TaskData struct {
+	ProjectID          string
+	PlanName           string
+	TaskID             string
+	TaskDescription    string
+	Category           string
+	Agent              string
+	Model              string
+	AcceptanceCriteria []string
+}
 
-STRICT_RULES:
-1. Lese die Plan-Datei fuer den exakten Code.
-2. Kopiere den Code 1:1. Keine "Verbesserungen".
-3. Erstelle Verzeichnisse falls noetig.
-4. Fuehre 'go fmt' aus.
-5. Melde Vollzug ohne Emojis.
+// Vars liefert die Template-Variablen; PlanFile wird aus plansDir gebildet.
+func (d TaskData) Vars(plansDir string) map[string]any {
+	return map[string]any{
+		"ProjectID":          d.ProjectID,
+		"PlanFile":           filepath.Join(plansDir, d.ProjectID, d.PlanName),
+		"TaskID":             d.TaskID,
+		"TaskDescription":    d.TaskDescription,
+		"Category":           d.Category,
+		"Agent":              d.Agent,
+		"Model":              d.Model,
+		"AcceptanceCriteria": d.AcceptanceCriteria,
+	}
+}
 
-[END SUB-AGENT PROMPT FORMAT]`, taskID, projectID, projectID, planName, taskDescription)
+// RenderTaskPrompt rendert TaskTemplate mit den Overrides des Projekts.
+func RenderTaskPrompt(plansDir string, data TaskData) (string, error) {
+	tmpl, err := LoaderFor(plansDir, data.ProjectID).Load(TaskTemplate)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(data.Vars(plansDir))
 }
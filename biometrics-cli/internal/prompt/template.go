@@ -0,0 +1,220 @@
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// TemplatesDirEnv überschreibt das Verzeichnis der gemeinsamen Templates.
+	TemplatesDirEnv = "BIOMETRICS_TEMPLATES_DIR"
+	// TemplateExt ist die Dateiendung aller Prompt-Templates.
+	TemplateExt = ".tmpl"
+)
+
+// ErrTemplateNotFound wird geliefert, wenn kein Verzeichnis das Template enthält.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// defaults sind Kopien der Templates, ohne die der Daemon nicht arbeiten
+// kann. Der Loader greift auf sie zurück, wenn kein Verzeichnis das
+// Template enthält, etwa wenn das Binary außerhalb des Repos installiert ist.
+//
+//go:embed defaults/*.tmpl
+var defaults embed.FS
+
+// Template ist ein geladenes Prompt-Template samt deklarierten Variablen.
+type Template struct {
+	Name        string
+	Path        string
+	Description string
+	// Vars sind die Variablen, die beim Rendern gesetzt sein müssen
+	Vars []string
+
+	tmpl *template.Template
+}
+
+// Der Header steht als Template-Kommentar am Dateianfang:
+//
+//	{{- /*
+//	description: ...
+//	vars: [TaskID, ProjectID]
+//	*/ -}}
+var headerPattern = regexp.MustCompile(`(?s)^\{\{-?\s*/\*(.*?)\*/\s*-?\}\}`)
+
+var funcs = template.FuncMap{
+	"inc":  func(i int) int { return i + 1 },
+	"join": strings.Join,
+}
+
+// ParseTemplate parst ein Template inklusive Header.
+func ParseTemplate(name, path string, data []byte) (*Template, error) {
+	t := &Template{Name: name, Path: path}
+
+	if m := headerPattern.FindSubmatch(data); m != nil {
+		var header struct {
+			Description string   `yaml:"description"`
+			Vars        []string `yaml:"vars"`
+		}
+		if err := yaml.Unmarshal(m[1], &header); err != nil {
+			return nil, fmt.Errorf("invalid header in template %s: %w", path, err)
+		}
+		t.Description = header.Description
+		t.Vars = header.Vars
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	t.tmpl = tmpl
+	return t, nil
+}
+
+// Render führt das Template aus. Jede deklarierte Variable muss in data
+// gesetzt und nicht leer sein.
+func (t *Template) Render(data map[string]any) (string, error) {
+	var missing []string
+	for _, name := range t.Vars {
+		v, ok := data[name]
+		if !ok || v == nil || reflect.ValueOf(v).IsZero() {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %s: missing variables %s", t.Name, strings.Join(missing, ", "))
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", t.Name, err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// Loader sucht Templates in mehreren Verzeichnissen. Das erste Verzeichnis
+// gewinnt, damit Projekt-Templates die gemeinsamen überschreiben; zuletzt
+// kommen die eingebetteten defaults.
+type Loader struct {
+	dirs []string
+}
+
+// NewLoader erstellt einen Loader; leere Verzeichnisse werden ignoriert.
+func NewLoader(dirs ...string) *Loader {
+	l := &Loader{}
+	for _, dir := range dirs {
+		if dir != "" {
+			l.dirs = append(l.dirs, dir)
+		}
+	}
+	return l
+}
+
+// ProjectTemplatesDir enthält die Overrides eines Projekts.
+func ProjectTemplatesDir(plansDir, projectID string) string {
+	return filepath.Join(plansDir, projectID, "templates")
+}
+
+// LoaderFor liefert den Loader für ein Projekt: erst die Overrides des
+// Projekts, dann die gemeinsamen Templates.
+func LoaderFor(plansDir, projectID string) *Loader {
+	return NewLoader(ProjectTemplatesDir(plansDir, projectID), TemplatesDir())
+}
+
+// Dirs gibt die Suchreihenfolge zurück.
+func (l *Loader) Dirs() []string {
+	return l.dirs
+}
+
+// Load lädt das Template name (ohne Endung) aus dem ersten Verzeichnis, das es enthält.
+func (l *Loader) Load(name string) (*Template, error) {
+	for _, dir := range l.dirs {
+		path := filepath.Join(dir, name+TemplateExt)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+		}
+		return ParseTemplate(name, path, data)
+	}
+	if data, err := defaults.ReadFile("defaults/" + name + TemplateExt); err == nil {
+		return ParseTemplate(name, "embedded:"+name+TemplateExt, data)
+	}
+	return nil, fmt.Errorf("%w: %s in [%s]", ErrTemplateNotFound, name, strings.Join(l.dirs, ", "))
+}
+
+// List liefert alle verfügbaren Templates, Overrides zuerst aufgelöst.
+func (l *Loader) List() ([]*Template, error) {
+	seen := make(map[string]bool)
+	var names []string
+	embedded, _ := fs.Glob(defaults, "defaults/*"+TemplateExt)
+	for _, dir := range append(l.dirs, "") {
+		matches := embedded
+		if dir != "" {
+			var err error
+			if matches, err = filepath.Glob(filepath.Join(dir, "*"+TemplateExt)); err != nil {
+				return nil, err
+			}
+		}
+		for _, match := range matches {
+			name := strings.TrimSuffix(filepath.Base(match), TemplateExt)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	templates := make([]*Template, 0, len(names))
+	for _, name := range names {
+		t, err := l.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// TemplatesDir findet templates/prompts: über BIOMETRICS_TEMPLATES_DIR,
+// sonst aufwärts vom Arbeitsverzeichnis und vom Binary aus.
+func TemplatesDir() string {
+	if dir := os.Getenv(TemplatesDirEnv); dir != "" {
+		return dir
+	}
+
+	var starts []string
+	if wd, err := os.Getwd(); err == nil {
+		starts = append(starts, wd)
+	}
+	if exe, err := os.Executable(); err == nil {
+		starts = append(starts, filepath.Dir(exe))
+	}
+
+	for _, start := range starts {
+		for dir := start; ; dir = filepath.Dir(dir) {
+			candidate := filepath.Join(dir, "templates", "prompts")
+			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+				return candidate
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return ""
+}
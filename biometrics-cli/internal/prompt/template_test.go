@@ -0,0 +1,111 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+TemplateExt), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderTaskPromptUsesProjectOverride(t *testing.T) {
+	shared := t.TempDir()
+	plans := t.TempDir()
+	t.Setenv(TemplatesDirEnv, shared)
+
+	writeTemplate(t, shared, TaskTemplate, "{{- /*\nvars: [TaskID]\n*/ -}}\nshared {{.TaskID}}\n")
+	data := TaskData{ProjectID: "demo", PlanName: "plan.md", TaskID: "t1"}
+
+	out, err := RenderTaskPrompt(plans, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "shared t1" {
+		t.Errorf("Expected shared template, got %q", out)
+	}
+
+	writeTemplate(t, ProjectTemplatesDir(plans, "demo"), TaskTemplate, "override {{.PlanFile}}")
+	out, err = RenderTaskPrompt(plans, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "override "+filepath.Join(plans, "demo", "plan.md") {
+		t.Errorf("Expected project override, got %q", out)
+	}
+}
+
+func TestRenderRejectsMissingVariables(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "x", "{{- /*\ndescription: test\nvars: [TaskID, TaskDescription]\n*/ -}}\n{{.TaskID}}")
+
+	tmpl, err := NewLoader(dir).Load("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Description != "test" || len(tmpl.Vars) != 2 {
+		t.Errorf("Unexpected header: %+v", tmpl)
+	}
+
+	_, err = tmpl.Render(map[string]any{"TaskID": "t1"})
+	if err == nil || !strings.Contains(err.Error(), "TaskDescription") {
+		t.Errorf("Expected missing TaskDescription, got %v", err)
+	}
+
+	if _, err := NewLoader(dir).Load("missing"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestLoaderFallsBackToEmbeddedDefaults(t *testing.T) {
+	tmpl, err := NewLoader(t.TempDir()).Load(TaskTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.Render(TaskData{ProjectID: "demo", TaskID: "t1", TaskDescription: "do it"}.Vars(t.TempDir())); err != nil {
+		t.Errorf("Embedded %s does not render: %v", TaskTemplate, err)
+	}
+
+	// Die eingebetteten Kopien müssen den gemeinsamen Templates entsprechen
+	shared := filepath.Join("..", "..", "..", "templates", "prompts")
+	embedded, err := fs.Glob(defaults, "defaults/*"+TemplateExt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range embedded {
+		want, err := os.ReadFile(filepath.Join(shared, filepath.Base(path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := defaults.ReadFile(path); !bytes.Equal(got, want) {
+			t.Errorf("%s differs from %s, copy it over", path, filepath.Join(shared, filepath.Base(path)))
+		}
+	}
+}
+
+func TestSharedTemplatesParse(t *testing.T) {
+	dir := filepath.Join("..", "..", "..", "templates", "prompts")
+	templates, err := NewLoader(dir).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) == 0 {
+		t.Fatalf("No templates found in %s", dir)
+	}
+	for _, tmpl := range templates {
+		if len(tmpl.Vars) == 0 {
+			t.Errorf("Template %s declares no variables", tmpl.Name)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package quality
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"biometrics-cli/internal/opencode"
+)
+
+// maxStageOutput begrenzt die im Report gespeicherte Ausgabe einer Stage.
+const maxStageOutput = 4096
+
+// Input beschreibt, was das Gate prüft.
+type Input struct {
+	ProjectID string
+	TaskID    string
+	// WorkDir überschreibt Pipeline.WorkDir
+	WorkDir string
+	// BaseRef ist der Git-Stand vor dem Task, Basis für diff_size
+	BaseRef string
+	// Request ist der ursprüngliche Agent-Auftrag für llm_review
+	Request opencode.AgentRequest
+}
+
+// ErrNoWorkDir meldet Run, wenn weder Input noch Pipeline ein
+// Arbeitsverzeichnis nennen.
+var ErrNoWorkDir = errors.New("no working directory to check")
+
+// Gate führt Pipelines aus und speichert die Reports.
+type Gate struct {
+	executor *opencode.Executor
+	reports  *ReportStore
+}
+
+// NewGate erstellt ein Gate. executor wird für llm_review gebraucht, reports
+// darf nil sein.
+func NewGate(executor *opencode.Executor, reports *ReportStore) *Gate {
+	return &Gate{executor: executor, reports: reports}
+}
+
+// Run führt alle Stages der Reihe nach aus. Der Report wird gespeichert,
+// der Pfad steht im zweiten Rückgabewert. Auch bei einem Fehler ist der
+// Report gesetzt; ohne Arbeitsverzeichnis ist er fehlgeschlagen.
+func (g *Gate) Run(ctx context.Context, p *Pipeline, in Input) (*Report, string, error) {
+	workDir := in.WorkDir
+	if workDir == "" {
+		workDir = p.WorkDir
+	}
+	if workDir == "" {
+		// Ohne Checkout würde das Gate das Arbeitsverzeichnis des Daemons prüfen
+		return &Report{
+			ProjectID: in.ProjectID,
+			TaskID:    in.TaskID,
+			StartedAt: time.Now(),
+			Status:    StatusFail,
+			Stages:    []StageResult{{Name: "workdir", Status: StatusFail, Message: ErrNoWorkDir.Error()}},
+		}, "", ErrNoWorkDir
+	}
+
+	report := &Report{
+		ProjectID: in.ProjectID,
+		TaskID:    in.TaskID,
+		WorkDir:   workDir,
+		BaseRef:   in.BaseRef,
+		StartedAt: time.Now(),
+		Status:    StatusPass,
+	}
+
+	failed := false
+	for _, stage := range p.Stages {
+		if failed && p.FailFast {
+			report.Stages = append(report.Stages, StageResult{
+				Name:    stage.Name,
+				Kind:    stage.Kind,
+				Status:  StatusSkip,
+				Message: "skipped after earlier failure",
+			})
+			continue
+		}
+
+		result := g.runStage(ctx, stage, workDir, in)
+		if result.Status == StatusFail && stage.WarnOnly {
+			result.Status = StatusWarn
+		}
+		report.Stages = append(report.Stages, result)
+
+		switch result.Status {
+		case StatusFail:
+			failed = true
+			report.Status = StatusFail
+		case StatusWarn:
+			if report.Status == StatusPass {
+				report.Status = StatusWarn
+			}
+		}
+	}
+	report.DurationMS = time.Since(report.StartedAt).Milliseconds()
+
+	if g.reports == nil {
+		return report, "", nil
+	}
+	ref, err := g.reports.Save(report)
+	return report, ref, err
+}
+
+func (g *Gate) runStage(ctx context.Context, stage Stage, workDir string, in Input) StageResult {
+	start := time.Now()
+	timeout, _ := stage.timeout()
+	stageCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var result StageResult
+	switch stage.Kind {
+	case KindBuild:
+		result = runCommand(stageCtx, workDir, commandOr(stage, "go", "build", "./..."))
+	case KindTest:
+		result = runCommand(stageCtx, workDir, commandOr(stage, "go", "test", "./..."))
+	case KindVet:
+		result = runCommand(stageCtx, workDir, commandOr(stage, "go", "vet", "./..."))
+	case KindLint:
+		result = runLint(stageCtx, workDir, stage)
+	case KindCommand:
+		result = runCommand(stageCtx, workDir, stage.Command)
+	case KindCoverage:
+		result = runCoverage(stageCtx, workDir, stage)
+	case KindDiffSize:
+		result = runDiffSize(stageCtx, workDir, stage, in.BaseRef)
+	case KindGitClean:
+		result = runGitClean(stageCtx, workDir)
+	case KindLLMReview:
+		result = g.runLLMReview(stageCtx, stage, in)
+	default:
+		result = StageResult{Status: StatusFail, Message: fmt.Sprintf("unknown stage kind %q", stage.Kind)}
+	}
+
+	if errors.Is(stageCtx.Err(), context.DeadlineExceeded) && result.Status == StatusFail {
+		result.Message = fmt.Sprintf("timed out after %s", timeout)
+	}
+	result.Name = stage.Name
+	result.Kind = stage.Kind
+	result.DurationMS = time.Since(start).Milliseconds()
+	return result
+}
+
+func commandOr(stage Stage, def ...string) []string {
+	if len(stage.Command) > 0 {
+		return stage.Command
+	}
+	return def
+}
+
+func runCommand(ctx context.Context, workDir string, args []string) StageResult {
+	output, err := execIn(ctx, workDir, args)
+	if err != nil {
+		return StageResult{Status: StatusFail, Message: fmt.Sprintf("%s: %v", strings.Join(args, " "), err), Output: tail(output)}
+	}
+	return StageResult{Status: StatusPass, Message: strings.Join(args, " ") + " succeeded", Output: tail(output)}
+}
+
+func runLint(ctx context.Context, workDir string, stage Stage) StageResult {
+	args := commandOr(stage, "golangci-lint", "run", "./...")
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return StageResult{Status: StatusWarn, Message: fmt.Sprintf("%s not installed", args[0])}
+	}
+	return runCommand(ctx, workDir, args)
+}
+
+var coverageTotal = regexp.MustCompile(`(?m)^total:\s+\(statements\)\s+([0-9.]+)%`)
+
+func runCoverage(ctx context.Context, workDir string, stage Stage) StageResult {
+	profile, err := os.CreateTemp("", "biometrics-cover-*.out")
+	if err != nil {
+		return StageResult{Status: StatusFail, Message: err.Error()}
+	}
+	profile.Close()
+	defer os.Remove(profile.Name())
+
+	if res := runCommand(ctx, workDir, []string{"go", "test", "-coverprofile=" + profile.Name(), "./..."}); res.Status != StatusPass {
+		return res
+	}
+
+	output, err := execIn(ctx, workDir, []string{"go", "tool", "cover", "-func=" + profile.Name()})
+	if err != nil {
+		return StageResult{Status: StatusFail, Message: fmt.Sprintf("go tool cover: %v", err), Output: tail(output)}
+	}
+
+	coverage, err := parseCoverage(output)
+	if err != nil {
+		return StageResult{Status: StatusFail, Message: err.Error(), Output: tail(output)}
+	}
+	if coverage < stage.Threshold {
+		return StageResult{Status: StatusFail, Message: fmt.Sprintf("coverage %.1f%% below threshold %.1f%%", coverage, stage.Threshold)}
+	}
+	return StageResult{Status: StatusPass, Message: fmt.Sprintf("coverage %.1f%% (threshold %.1f%%)", coverage, stage.Threshold)}
+}
+
+func parseCoverage(output string) (float64, error) {
+	m := coverageTotal.FindStringSubmatch(output)
+	if m == nil {
+		return 0, fmt.Errorf("no total coverage in output")
+	}
+	return strconv.ParseFloat(m[1], 64)
+}
+
+func runDiffSize(ctx context.Context, workDir string, stage Stage, baseRef string) StageResult {
+	base := stage.Base
+	if base == "" {
+		base = baseRef
+	}
+	if base == "" {
+		base = "HEAD"
+	}
+
+	output, err := execIn(ctx, workDir, []string{"git", "diff", "--numstat", base})
+	if err != nil {
+		return StageResult{Status: StatusFail, Message: fmt.Sprintf("git diff: %v", err), Output: tail(output)}
+	}
+
+	lines, files := diffLines(output)
+	msg := fmt.Sprintf("%d lines changed in %d files since %s (max %d)", lines, files, base, stage.MaxLines)
+	if lines > stage.MaxLines {
+		return StageResult{Status: StatusFail, Message: msg}
+	}
+	return StageResult{Status: StatusPass, Message: msg}
+}
+
+// diffLines summiert `git diff --numstat`; Binärdateien zählen nicht.
+func diffLines(numstat string) (lines, files int) {
+	for _, line := range strings.Split(strings.TrimSpace(numstat), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		files++
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		lines += added + deleted
+	}
+	return lines, files
+}
+
+func runGitClean(ctx context.Context, workDir string) StageResult {
+	output, err := execIn(ctx, workDir, []string{"git", "status", "--porcelain"})
+	if err != nil {
+		return StageResult{Status: StatusFail, Message: fmt.Sprintf("git status: %v", err), Output: tail(output)}
+	}
+	if strings.TrimSpace(output) != "" {
+		return StageResult{Status: StatusFail, Message: "uncommitted changes", Output: tail(output)}
+	}
+	return StageResult{Status: StatusPass, Message: "working tree clean"}
+}
+
+func (g *Gate) runLLMReview(ctx context.Context, stage Stage, in Input) StageResult {
+	if g.executor == nil {
+		return StageResult{Status: StatusSkip, Message: "no agent executor configured"}
+	}
+
+	req := opencode.AgentRequest{
+		ProjectID:  in.Request.ProjectID,
+		Model:      stage.Model,
+		Prompt:     stage.Prompt,
+		Category:   stage.Category,
+		TaskID:     in.TaskID,
+		WorkDir:    in.Request.WorkDir,
+		ProjectDir: in.Request.ProjectDir,
+	}
+	if req.ProjectID == "" {
+		req.ProjectID = in.ProjectID
+	}
+	if req.Model == "" {
+		req.Model = SicherModel
+	}
+	if req.Prompt == "" {
+		req.Prompt = SicherPrompt
+	}
+	if req.Category == "" {
+		req.Category = "quick"
+	}
+
+	result := g.executor.RunAgent(ctx, req)
+	if !result.Success {
+		msg := "review agent failed"
+		if result.Error != nil {
+			msg = result.Error.Error()
+		}
+		return StageResult{Status: StatusFail, Message: msg, Output: tail(result.Output), Transcript: result.TranscriptRef}
+	}
+	return StageResult{Status: StatusPass, Message: "review agent succeeded", Output: tail(result.Output), Transcript: result.TranscriptRef}
+}
+
+func execIn(ctx context.Context, workDir string, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = workDir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.String(), err
+}
+
+func tail(s string) string {
+	if len(s) <= maxStageOutput {
+		return s
+	}
+	return "..." + s[len(s)-maxStageOutput:]
+}
+
+// HeadRevision liefert den aktuellen Commit in dir, um ihn vor dem Task als
+// BaseRef festzuhalten. Außerhalb eines Repos ist das Ergebnis leer.
+func HeadRevision(dir string) string {
+	if dir == "" {
+		dir = "."
+	}
+	out, err := exec.Command("git", "-C", filepath.Clean(dir), "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
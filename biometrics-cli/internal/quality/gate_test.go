@@ -0,0 +1,95 @@
+package quality
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGateCombinesStageResults(t *testing.T) {
+	dir := t.TempDir()
+	p := &Pipeline{
+		FailFast: true,
+		Stages: []Stage{
+			{Name: "ok", Kind: KindCommand, Command: []string{"true"}},
+			{Name: "soft", Kind: KindCommand, Command: []string{"false"}, WarnOnly: true},
+			{Name: "hard", Kind: KindCommand, Command: []string{"sh", "-c", "echo broken; exit 3"}},
+			{Name: "after", Kind: KindCommand, Command: []string{"true"}},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	gate := NewGate(nil, NewReportStore(filepath.Join(dir, "reports")))
+	report, ref, err := gate.Run(context.Background(), p, Input{ProjectID: "demo", TaskID: "t1", WorkDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Status{StatusPass, StatusWarn, StatusFail, StatusSkip}
+	for i, s := range report.Stages {
+		if s.Status != want[i] {
+			t.Errorf("Stage %s: expected %s, got %s", s.Name, want[i], s.Status)
+		}
+	}
+	if report.Passed() || report.Stages[2].Output != "broken\n" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	loaded, err := gate.reports.Load(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Status != StatusFail || len(loaded.Stages) != 4 {
+		t.Errorf("Unexpected persisted report: %+v", loaded)
+	}
+}
+
+func TestGateFailsWithoutWorkDir(t *testing.T) {
+	p := &Pipeline{Stages: []Stage{{Name: "ok", Kind: KindCommand, Command: []string{"true"}}}}
+
+	report, _, err := NewGate(nil, nil).Run(context.Background(), p, Input{ProjectID: "demo", TaskID: "t1"})
+	if !errors.Is(err, ErrNoWorkDir) {
+		t.Errorf("Expected ErrNoWorkDir, got %v", err)
+	}
+	if report == nil || report.Passed() {
+		t.Errorf("Expected a failed report, got %+v", report)
+	}
+}
+
+func TestPipelineForFallsBackToDefault(t *testing.T) {
+	plans := t.TempDir()
+	p, err := PipelineFor(plans, "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Stages) != len(DefaultPipeline().Stages) {
+		t.Errorf("Expected default pipeline, got %+v", p)
+	}
+
+	if err := os.MkdirAll(filepath.Join(plans, "demo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	yaml := "stages:\n  - kind: coverage\n    threshold: 150\n"
+	if err := os.WriteFile(filepath.Join(plans, "demo", PipelineFile), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PipelineFor(plans, "demo"); err == nil {
+		t.Error("Expected invalid threshold to be rejected")
+	}
+}
+
+func TestParseCoverageAndDiff(t *testing.T) {
+	coverage, err := parseCoverage("a.go:1:\tFoo\t100.0%\ntotal:\t\t\t(statements)\t72.5%\n")
+	if err != nil || coverage != 72.5 {
+		t.Errorf("Expected 72.5, got %v (%v)", coverage, err)
+	}
+
+	lines, files := diffLines("10\t2\ta.go\n-\t-\timage.png\n3\t0\tb.go\n")
+	if lines != 15 || files != 3 {
+		t.Errorf("Expected 15 lines in 3 files, got %d in %d", lines, files)
+	}
+}
@@ -0,0 +1,147 @@
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PipelineFile liegt im Plan-Verzeichnis eines Projekts.
+const PipelineFile = "quality.yaml"
+
+// DefaultStageTimeout gilt für Stages ohne eigenes Timeout.
+const DefaultStageTimeout = 10 * time.Minute
+
+// StageKind bestimmt, was eine Stage prüft.
+type StageKind string
+
+const (
+	KindBuild     StageKind = "build"
+	KindTest      StageKind = "test"
+	KindVet       StageKind = "vet"
+	KindLint      StageKind = "lint"
+	KindCoverage  StageKind = "coverage"
+	KindDiffSize  StageKind = "diff_size"
+	KindGitClean  StageKind = "git_clean"
+	KindCommand   StageKind = "command"
+	KindLLMReview StageKind = "llm_review"
+)
+
+var knownKinds = map[StageKind]bool{
+	KindBuild: true, KindTest: true, KindVet: true, KindLint: true, KindCoverage: true,
+	KindDiffSize: true, KindGitClean: true, KindCommand: true, KindLLMReview: true,
+}
+
+// Stage ist ein Schritt der Pipeline.
+type Stage struct {
+	Name string    `yaml:"name"`
+	Kind StageKind `yaml:"kind"`
+	// Command ersetzt das Standard-Kommando (build, test, vet, lint, command)
+	Command []string `yaml:"command,omitempty"`
+	// Threshold ist die minimale Coverage in Prozent
+	Threshold float64 `yaml:"threshold,omitempty"`
+	// MaxLines begrenzt hinzugefügte plus gelöschte Zeilen
+	MaxLines int `yaml:"max_lines,omitempty"`
+	// Base ist die Git-Revision für diff_size, sonst der Stand vor dem Task
+	Base string `yaml:"base,omitempty"`
+	// Prompt, Model und Category für llm_review
+	Prompt   string `yaml:"prompt,omitempty"`
+	Model    string `yaml:"model,omitempty"`
+	Category string `yaml:"category,omitempty"`
+	// WarnOnly macht aus einem Fehlschlag eine Warnung
+	WarnOnly bool   `yaml:"warn_only,omitempty"`
+	Timeout  string `yaml:"timeout,omitempty"`
+}
+
+// Pipeline ist die geordnete Liste von Stages eines Projekts.
+type Pipeline struct {
+	// WorkDir ist das Verzeichnis, in dem die Checks laufen
+	WorkDir string `yaml:"workdir,omitempty"`
+	// FailFast überspringt alle Stages nach dem ersten Fehlschlag
+	FailFast bool    `yaml:"fail_fast,omitempty"`
+	Stages   []Stage `yaml:"stages"`
+}
+
+// DefaultPipeline prüft build, vet und test und lässt danach den
+// "Sicher?"-Agenten laufen.
+func DefaultPipeline() *Pipeline {
+	return &Pipeline{
+		FailFast: true,
+		Stages: []Stage{
+			{Name: "build", Kind: KindBuild},
+			{Name: "vet", Kind: KindVet},
+			{Name: "test", Kind: KindTest},
+			{Name: "sicher", Kind: KindLLMReview},
+		},
+	}
+}
+
+// LoadPipeline liest eine Pipeline aus einer YAML-Datei.
+func LoadPipeline(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Pipeline
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// PipelineFor lädt <plansDir>/<project>/quality.yaml oder DefaultPipeline.
+func PipelineFor(plansDir, projectID string) (*Pipeline, error) {
+	p, err := LoadPipeline(filepath.Join(plansDir, projectID, PipelineFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultPipeline(), nil
+	}
+	return p, err
+}
+
+// Validate prüft Stage-Arten und stage-spezifische Pflichtfelder.
+func (p *Pipeline) Validate() error {
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("no stages defined")
+	}
+	for i := range p.Stages {
+		s := &p.Stages[i]
+		if s.Name == "" {
+			s.Name = string(s.Kind)
+		}
+		if !knownKinds[s.Kind] {
+			return fmt.Errorf("stage %s: unknown kind %q", s.Name, s.Kind)
+		}
+		if s.Kind == KindCommand && len(s.Command) == 0 {
+			return fmt.Errorf("stage %s: command is required", s.Name)
+		}
+		if s.Kind == KindCoverage && (s.Threshold <= 0 || s.Threshold > 100) {
+			return fmt.Errorf("stage %s: threshold must be between 0 and 100", s.Name)
+		}
+		if s.Kind == KindDiffSize && s.MaxLines <= 0 {
+			return fmt.Errorf("stage %s: max_lines is required", s.Name)
+		}
+		if _, err := s.timeout(); err != nil {
+			return fmt.Errorf("stage %s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Stage) timeout() (time.Duration, error) {
+	if s.Timeout == "" {
+		return DefaultStageTimeout, nil
+	}
+	d, err := time.ParseDuration(s.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", s.Timeout, err)
+	}
+	return d, nil
+}
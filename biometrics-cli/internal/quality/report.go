@@ -0,0 +1,116 @@
+package quality
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Status ist das Ergebnis einer Stage oder des ganzen Gates.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// StageResult ist das Ergebnis einer Stage.
+type StageResult struct {
+	Name       string    `json:"name"`
+	Kind       StageKind `json:"kind"`
+	Status     Status    `json:"status"`
+	Message    string    `json:"message"`
+	Output     string    `json:"output,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	Transcript string    `json:"transcript,omitempty"`
+}
+
+// Report fasst alle Stages eines Gate-Laufs zusammen.
+type Report struct {
+	ProjectID  string        `json:"project_id"`
+	TaskID     string        `json:"task_id"`
+	WorkDir    string        `json:"workdir"`
+	BaseRef    string        `json:"base_ref,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	DurationMS int64         `json:"duration_ms"`
+	Status     Status        `json:"status"`
+	Stages     []StageResult `json:"stages"`
+}
+
+// Passed ist true, solange keine Stage fehlgeschlagen ist. Warnungen
+// blockieren das Gate nicht.
+func (r *Report) Passed() bool {
+	return r.Status != StatusFail
+}
+
+// Failures beschreibt die fehlgeschlagenen Stages in einer Zeile, z.B. als
+// Fehlergrund am Task.
+func (r *Report) Failures() string {
+	var parts []string
+	for _, s := range r.Stages {
+		if s.Status == StatusFail {
+			parts = append(parts, fmt.Sprintf("%s: %s", s.Name, s.Message))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ReportStore speichert Gate-Reports unter <root>/<project>/<task>/<ts>.json.
+type ReportStore struct {
+	root string
+}
+
+// NewReportStore erstellt einen Store unter dir.
+func NewReportStore(dir string) *ReportStore {
+	return &ReportStore{root: dir}
+}
+
+// Save schreibt den Report und gibt seinen Pfad zurück.
+func (s *ReportStore) Save(r *Report) (string, error) {
+	dir := filepath.Join(s.root, safeSegment(r.ProjectID), safeSegment(r.TaskID))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create report dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	path := filepath.Join(dir, r.StartedAt.UTC().Format("20060102T150405.000Z")+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
+
+// Load liest einen gespeicherten Report.
+func (s *ReportStore) Load(ref string) (*Report, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", ref, err)
+	}
+	return &r, nil
+}
+
+func safeSegment(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
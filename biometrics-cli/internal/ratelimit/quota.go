@@ -0,0 +1,354 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"biometrics-cli/internal/metrics"
+	"biometrics-cli/internal/state"
+)
+
+// ErrQuotaExceeded wird geliefert, solange ein Provider-Kontingent erschöpft ist.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+const (
+	// DefaultThrottleBackoff gilt für 429-Antworten ohne Retry-After.
+	DefaultThrottleBackoff = time.Minute
+	// learnedRelaxAfter: so lange ohne 429, dann wird ein gelerntes RPM-Limit verworfen.
+	learnedRelaxAfter = time.Hour
+)
+
+// QuotaLimits sind die Kontingente eines Providers; 0 = unbegrenzt.
+type QuotaLimits struct {
+	RPM           int `json:"rpm,omitempty"`
+	TPM           int `json:"tpm,omitempty"`
+	DailyRequests int `json:"daily_requests,omitempty"`
+	DailyTokens   int `json:"daily_tokens,omitempty"`
+}
+
+// QuotaError beschreibt, welches Kontingent erschöpft ist und ab wann wieder
+// Anfragen möglich sind. errors.Is(err, ErrQuotaExceeded) ist true.
+type QuotaError struct {
+	Key     string
+	Reason  string
+	RetryAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota exceeded for %s: %s (retry at %s)", e.Key, e.Reason, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+type quotaEvent struct {
+	at     time.Time
+	tokens int
+}
+
+type quotaState struct {
+	limits QuotaLimits
+	// learnedRPM stammt aus 429-Antworten und gilt zusätzlich zu limits.RPM
+	learnedRPM   int
+	lastThrottle time.Time
+	blockedUntil time.Time
+	throttles    int
+
+	window      []quotaEvent // Anfragen der letzten Minute
+	day         string       // UTC-Datum der Tageszähler
+	dayRequests int
+	dayTokens   int
+}
+
+// Quota zählt Anfragen und Tokens pro Provider in einem gleitenden
+// Minutenfenster und pro UTC-Tag. Limits kommen aus der Konfiguration und
+// werden durch gemeldete 429-Antworten verschärft.
+type Quota struct {
+	mu     sync.Mutex
+	states map[string]*quotaState
+	now    func() time.Time
+}
+
+// NewQuota erstellt einen leeren Tracker; unbekannte Keys sind unbegrenzt.
+func NewQuota() *Quota {
+	return &Quota{states: make(map[string]*quotaState), now: time.Now}
+}
+
+// SetLimits setzt die konfigurierten Limits für key. Gelernte Limits bleiben
+// erhalten, solange sich die Konfiguration nicht ändert.
+func (q *Quota) SetLimits(key string, limits QuotaLimits) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := q.stateLocked(key)
+	if s.limits != limits {
+		s.limits = limits
+		s.learnedRPM = 0
+	}
+}
+
+// Check prüft, ob eine Anfrage mit tokens Tokens erlaubt wäre, ohne sie zu zählen.
+func (q *Quota) Check(key string, tokens int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.checkLocked(key, q.stateLocked(key), tokens)
+}
+
+// Reserve prüft und zählt eine Anfrage mit tokens Tokens.
+func (q *Quota) Reserve(key string, tokens int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := q.stateLocked(key)
+	if err := q.checkLocked(key, s, tokens); err != nil {
+		metrics.RateLimitRejectedTotal.WithLabelValues(key).Inc()
+		return err
+	}
+
+	now := q.now()
+	s.window = append(s.window, quotaEvent{at: now, tokens: tokens})
+	s.dayRequests++
+	s.dayTokens += tokens
+	metrics.RateLimitAllowedTotal.WithLabelValues(key).Inc()
+	return nil
+}
+
+// Throttled meldet eine 429-Antwort. Der Key wird für retryAfter gesperrt
+// und das RPM-Limit auf knapp unter die beobachtete Rate gesenkt.
+func (q *Quota) Throttled(key string, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = DefaultThrottleBackoff
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := q.stateLocked(key)
+	now := q.now()
+	s.throttles++
+	s.lastThrottle = now
+	if until := now.Add(retryAfter); until.After(s.blockedUntil) {
+		s.blockedUntil = until
+	}
+
+	learned := len(s.window) - 1
+	if learned < 1 {
+		learned = 1
+	}
+	if rpm := s.effectiveRPM(); rpm == 0 || learned < rpm {
+		s.learnedRPM = learned
+	}
+	state.GlobalState.Log("WARN", fmt.Sprintf("Provider %s throttled, blocked for %s, rpm limit %d", key, retryAfter, s.effectiveRPM()))
+}
+
+func (q *Quota) stateLocked(key string) *quotaState {
+	s, ok := q.states[key]
+	if !ok {
+		s = &quotaState{}
+		q.states[key] = s
+	}
+	q.advance(s)
+	return s
+}
+
+// advance verwirft alte Fenster-Einträge, setzt Tageszähler zurück und
+// entspannt gelernte Limits nach einer ruhigen Phase.
+func (q *Quota) advance(s *quotaState) {
+	now := q.now()
+
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(s.window) && !s.window[i].at.After(cutoff) {
+		i++
+	}
+	s.window = s.window[i:]
+
+	if day := now.UTC().Format("2006-01-02"); day != s.day {
+		s.day = day
+		s.dayRequests = 0
+		s.dayTokens = 0
+	}
+
+	if s.learnedRPM > 0 && now.Sub(s.lastThrottle) > learnedRelaxAfter {
+		s.learnedRPM = 0
+	}
+}
+
+func (q *Quota) checkLocked(key string, s *quotaState, tokens int) error {
+	now := q.now()
+	exceeded := func(reason string, retryAt time.Time) error {
+		return &QuotaError{Key: key, Reason: reason, RetryAt: retryAt}
+	}
+
+	if now.Before(s.blockedUntil) {
+		return exceeded("throttled by provider", s.blockedUntil)
+	}
+
+	windowFree := func() time.Time {
+		if len(s.window) == 0 {
+			return now
+		}
+		return s.window[0].at.Add(time.Minute)
+	}
+	if rpm := s.effectiveRPM(); rpm > 0 && len(s.window) >= rpm {
+		return exceeded(fmt.Sprintf("%d requests per minute", rpm), windowFree())
+	}
+	// Eine einzelne Anfrage über dem TPM-Limit darf bei leerem Fenster laufen
+	if s.limits.TPM > 0 && len(s.window) > 0 && s.windowTokens()+tokens > s.limits.TPM {
+		return exceeded(fmt.Sprintf("%d tokens per minute", s.limits.TPM), windowFree())
+	}
+
+	tomorrow := nextUTCDay(now)
+	if s.limits.DailyRequests > 0 && s.dayRequests >= s.limits.DailyRequests {
+		return exceeded(fmt.Sprintf("%d requests per day", s.limits.DailyRequests), tomorrow)
+	}
+	if s.limits.DailyTokens > 0 && s.dayTokens+tokens > s.limits.DailyTokens {
+		return exceeded(fmt.Sprintf("%d tokens per day", s.limits.DailyTokens), tomorrow)
+	}
+	return nil
+}
+
+func (s *quotaState) effectiveRPM() int {
+	rpm := s.limits.RPM
+	if s.learnedRPM > 0 && (rpm == 0 || s.learnedRPM < rpm) {
+		rpm = s.learnedRPM
+	}
+	return rpm
+}
+
+func (s *quotaState) windowTokens() int {
+	total := 0
+	for _, e := range s.window {
+		total += e.tokens
+	}
+	return total
+}
+
+func nextUTCDay(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// QuotaStatus ist der aktuelle Stand eines Keys.
+type QuotaStatus struct {
+	Key                string      `json:"key"`
+	Limits             QuotaLimits `json:"limits"`
+	EffectiveRPM       int         `json:"effective_rpm,omitempty"`
+	LearnedRPM         int         `json:"learned_rpm,omitempty"`
+	RequestsLastMinute int         `json:"requests_last_minute"`
+	TokensLastMinute   int         `json:"tokens_last_minute"`
+	RequestsToday      int         `json:"requests_today"`
+	TokensToday        int         `json:"tokens_today"`
+	Throttles          int         `json:"throttles"`
+	Exhausted          bool        `json:"exhausted"`
+	Reason             string      `json:"reason,omitempty"`
+	RetryAt            time.Time   `json:"retry_at,omitempty"`
+}
+
+// Status liefert den Stand von key.
+func (q *Quota) Status(key string) QuotaStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.statusLocked(key, q.stateLocked(key))
+}
+
+// Statuses liefert den Stand aller bekannten Keys, sortiert nach Key.
+func (q *Quota) Statuses() []QuotaStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make([]QuotaStatus, 0, len(q.states))
+	for key := range q.states {
+		out = append(out, q.statusLocked(key, q.stateLocked(key)))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func (q *Quota) statusLocked(key string, s *quotaState) QuotaStatus {
+	st := QuotaStatus{
+		Key:                key,
+		Limits:             s.limits,
+		EffectiveRPM:       s.effectiveRPM(),
+		LearnedRPM:         s.learnedRPM,
+		RequestsLastMinute: len(s.window),
+		TokensLastMinute:   s.windowTokens(),
+		RequestsToday:      s.dayRequests,
+		TokensToday:        s.dayTokens,
+		Throttles:          s.throttles,
+	}
+	var qerr *QuotaError
+	if errors.As(q.checkLocked(key, s, 0), &qerr) {
+		st.Exhausted = true
+		st.Reason = qerr.Reason
+		st.RetryAt = qerr.RetryAt
+	}
+	return st
+}
+
+var (
+	throttlePattern   = regexp.MustCompile(`(?i)\b429\b|too many requests|rate[ _-]?limit(ed| exceeded| reached)|quota (exceeded|exhausted)|resource[ _]exhausted`)
+	retryAfterPattern = regexp.MustCompile(`(?i)(?:retry[ _-]after|try again in|retry in)["':=\s]+(\d+(?:\.\d+)?)\s*(ms|milliseconds?|s|secs?|seconds?|m|mins?|minutes?)?\b`)
+	// errorLinePattern erkennt Fehlerzeilen von Provider und CLI, etwa
+	// "Error: 429 Too Many Requests" oder "level=error msg=..."
+	errorLinePattern = regexp.MustCompile(`(?i)^\W*(?:\d[\d:.tz+-]*\s+)?(?:error|fatal|api ?error|provider ?error)\b|\blevel=(?:error|fatal)\b`)
+)
+
+// DetectThrottle erkennt 429-/Rate-Limit-Meldungen in Agent-Ausgaben. Geprüft
+// werden nur Fehlerzeilen und das "error"-Feld von JSON-Zeilen; Code und
+// Text, die der Agent über Rate-Limits schreibt, zählen nicht. Nennt die
+// Fehlerzeile eine Wartezeit, wird sie zurückgegeben, sonst 0.
+func DetectThrottle(output string) (time.Duration, bool) {
+	for _, line := range strings.Split(output, "\n") {
+		if !throttlePattern.MatchString(errorText(line)) {
+			continue
+		}
+		return retryAfter(line), true
+	}
+	return 0, false
+}
+
+// errorText liefert den Fehleranteil einer Zeile: bei JSON das "error"-Feld,
+// sonst die ganze Zeile, wenn sie eine Fehlerzeile ist.
+func errorText(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal([]byte(trimmed), &fields) != nil {
+			return ""
+		}
+		return string(fields["error"])
+	}
+	if errorLinePattern.MatchString(trimmed) {
+		return trimmed
+	}
+	return ""
+}
+
+func retryAfter(line string) time.Duration {
+	m := retryAfterPattern.FindStringSubmatch(line)
+	if m == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0
+	}
+
+	unit := time.Second
+	switch u := strings.ToLower(m[2]); {
+	case strings.HasPrefix(u, "ms"), strings.HasPrefix(u, "milli"):
+		unit = time.Millisecond
+	case strings.HasPrefix(u, "m"):
+		unit = time.Minute
+	}
+	return time.Duration(value * float64(unit))
+}
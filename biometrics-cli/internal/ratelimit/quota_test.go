@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func fakeClock(q *Quota, start time.Time) *time.Time {
+	now := start
+	q.now = func() time.Time { return now }
+	return &now
+}
+
+func TestQuotaRPMWindow(t *testing.T) {
+	q := NewQuota()
+	now := fakeClock(q, time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	q.SetLimits("nim", QuotaLimits{RPM: 2})
+
+	for i := 0; i < 2; i++ {
+		if err := q.Reserve("nim", 0); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	err := q.Reserve("nim", 0)
+	var qerr *QuotaError
+	if !errors.As(err, &qerr) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected QuotaError, got %v", err)
+	}
+	if want := now.Add(time.Minute); !qerr.RetryAt.Equal(want) {
+		t.Errorf("RetryAt = %s, want %s", qerr.RetryAt, want)
+	}
+
+	*now = now.Add(61 * time.Second)
+	if err := q.Reserve("nim", 0); err != nil {
+		t.Errorf("window should have moved on: %v", err)
+	}
+}
+
+func TestQuotaTokensAndDaily(t *testing.T) {
+	q := NewQuota()
+	now := fakeClock(q, time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC))
+	q.SetLimits("zen", QuotaLimits{TPM: 1000, DailyRequests: 3})
+
+	// Eine große Anfrage darf bei leerem Fenster laufen
+	if err := q.Reserve("zen", 5000); err != nil {
+		t.Fatalf("oversized first request: %v", err)
+	}
+	if err := q.Check("zen", 10); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected TPM limit, got %v", err)
+	}
+
+	*now = now.Add(30 * time.Second)
+	q.SetLimits("zen", QuotaLimits{DailyRequests: 3})
+	q.Reserve("zen", 0)
+	q.Reserve("zen", 0)
+	if err := q.Check("zen", 0); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected daily limit, got %v", err)
+	}
+
+	*now = now.Add(time.Minute) // nächster UTC-Tag
+	if err := q.Check("zen", 0); err != nil {
+		t.Errorf("daily counter not reset: %v", err)
+	}
+}
+
+func TestQuotaLearnsFromThrottle(t *testing.T) {
+	q := NewQuota()
+	now := fakeClock(q, time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	for i := 0; i < 5; i++ {
+		q.Reserve("nim", 0)
+	}
+	q.Throttled("nim", 10*time.Second)
+
+	st := q.Status("nim")
+	if !st.Exhausted || st.LearnedRPM != 4 || st.Throttles != 1 {
+		t.Fatalf("unexpected status after throttle: %+v", st)
+	}
+
+	*now = now.Add(11 * time.Second)
+	if err := q.Check("nim", 0); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("learned rpm should still block, got %v", err)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if st := q.Status("nim"); st.Exhausted || st.LearnedRPM != 0 {
+		t.Errorf("learned limit should relax: %+v", st)
+	}
+}
+
+func TestDetectThrottle(t *testing.T) {
+	cases := []struct {
+		output string
+		ok     bool
+		wait   time.Duration
+	}{
+		{"Error: 429 Too Many Requests, retry-after: 30", true, 30 * time.Second},
+		{"working...\nERROR rate limit exceeded, try again in 2 minutes", true, 2 * time.Minute},
+		{`{"error":"RESOURCE_EXHAUSTED","retry_after":"500ms"}`, true, 500 * time.Millisecond},
+		{"level=error msg=\"quota exceeded for today\"", true, 0},
+		{"build failed: undefined: foo", false, 0},
+		// Was der Agent über Rate-Limits schreibt, ist kein Throttling
+		{"Added a retry for HTTP 429 (Too Many Requests), retry-after: 30", false, 0},
+		{"+\treturn http.StatusTooManyRequests // rate limit exceeded", false, 0},
+		{`{"message":"rate limited","retry_after":"5s"}`, false, 0},
+	}
+	for _, c := range cases {
+		wait, ok := DetectThrottle(c.output)
+		if ok != c.ok || wait != c.wait {
+			t.Errorf("DetectThrottle(%q) = %s, %v; want %s, %v", c.output, wait, ok, c.wait, c.ok)
+		}
+	}
+}
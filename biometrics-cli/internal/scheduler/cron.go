@@ -0,0 +1,266 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule berechnet den nächsten Lauf nach einem Zeitpunkt.
+type Schedule interface {
+	// Next liefert den ersten Lauf nach from, oder die Nullzeit, wenn es
+	// keinen mehr gibt
+	Next(from time.Time) time.Time
+}
+
+// Parse versteht
+//
+//	5 Felder:  Minute Stunde Tag Monat Wochentag
+//	6 Felder:  Sekunde Minute Stunde Tag Monat Wochentag
+//	@yearly, @annually, @monthly, @weekly, @daily, @midnight, @hourly
+//	@every <time.Duration>, z.B. @every 90s
+//
+// Felder erlauben *, ?, Listen (1,15), Bereiche (1-5), Schritte (*/10, 8-18/2)
+// sowie Monats- und Tagesnamen (JAN, MON). Wochentag 0 und 7 sind Sonntag.
+// Ein Präfix "CRON_TZ=Europe/Berlin " oder "TZ=..." legt die Zeitzone fest,
+// sonst gilt loc.
+func Parse(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if loc == nil {
+		loc = time.Local
+	}
+
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexByte(spec, ' ')
+		if i < 0 {
+			return nil, fmt.Errorf("cron %q: missing schedule after time zone", spec)
+		}
+		name := spec[strings.IndexByte(spec, '=')+1 : i]
+		tz, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: unknown time zone %q: %w", spec, name, err)
+		}
+		loc = tz
+		spec = strings.TrimSpace(spec[i:])
+	}
+
+	if strings.HasPrefix(spec, "@every") {
+		arg := strings.TrimSpace(strings.TrimPrefix(spec, "@every"))
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: invalid duration: %w", spec, err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("cron %q: interval must be at least 1s", spec)
+		}
+		return everySchedule{d}, nil
+	}
+
+	if descriptor, ok := descriptors[spec]; ok {
+		spec = descriptor
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("cron %q: unknown descriptor", spec)
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron %q: expected 5 or 6 fields, got %d", spec, len(fields))
+	}
+
+	s := &cronSchedule{loc: loc}
+	var err error
+	for i, target := range []*uint64{&s.second, &s.minute, &s.hour, &s.dom, &s.month, &s.dow} {
+		if *target, err = parseField(fields[i], cronFields[i]); err != nil {
+			return nil, fmt.Errorf("cron %q: %w", spec, err)
+		}
+	}
+	// 7 ist ebenfalls Sonntag
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = fields[3] == "*" || fields[3] == "?"
+	s.dowStar = fields[5] == "*" || fields[5] == "?"
+	return s, nil
+}
+
+// NextRuns liefert die nächsten n Läufe ab from.
+func NextRuns(s Schedule, from time.Time, n int) []time.Time {
+	runs := make([]time.Time, 0, n)
+	for len(runs) < n {
+		from = s.Next(from)
+		if from.IsZero() {
+			break
+		}
+		runs = append(runs, from)
+	}
+	return runs
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+type fieldBounds struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []fieldBounds{
+	{name: "second", min: 0, max: 59},
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// parseField liefert die erlaubten Werte eines Feldes als Bitmaske.
+func parseField(field string, b fieldBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			expr = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: invalid step in %q", b.name, part)
+			}
+			step = n
+		}
+
+		lo, hi := b.min, b.max
+		switch {
+		case expr == "*" || expr == "?":
+		case strings.Contains(expr, "-"):
+			i := strings.IndexByte(expr, '-')
+			var err error
+			if lo, err = b.value(expr[:i]); err != nil {
+				return 0, err
+			}
+			if hi, err = b.value(expr[i+1:]); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s: range %q is backwards", b.name, expr)
+			}
+		default:
+			v, err := b.value(expr)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/15" läuft ab 5 bis zum Maximum
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (b fieldBounds) value(s string) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid value %q", b.name, s)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("%s: %d out of range %d-%d", b.name, v, b.min, b.max)
+	}
+	return v, nil
+}
+
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (e everySchedule) Next(from time.Time) time.Time {
+	return from.Add(e.interval)
+}
+
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// Ist eines der Tagesfelder *, zählt nur das andere; sonst reicht eines
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+func (s *cronSchedule) Next(from time.Time) time.Time {
+	t := from.In(s.loc).Add(time.Second - time.Duration(from.Nanosecond()))
+	// Ohne Treffer in fünf Jahren ist der Ausdruck unerfüllbar (z.B. 30. Februar)
+	limit := t.Year() + 5
+
+wrap:
+	if t.Year() > limit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Truncate(time.Minute).Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.second&(1<<uint(t.Second())) == 0 {
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseNext(t *testing.T) {
+	from := time.Date(2026, 3, 14, 10, 17, 42, 0, time.UTC) // Samstag
+	cases := []struct {
+		spec string
+		want string
+	}{
+		{"*/15 * * * *", "2026-03-14T10:30:00Z"},
+		{"0 9 * * MON-FRI", "2026-03-16T09:00:00Z"},
+		{"30 */6 * * *", "2026-03-14T12:30:00Z"},
+		{"0 0 1 JAN *", "2027-01-01T00:00:00Z"},
+		{"15,45 10 14 3 *", "2026-03-14T10:45:00Z"},
+		{"40-50/5 17 10 * * *", "2026-03-14T10:17:45Z"},
+		{"0 12 * * 7", "2026-03-15T12:00:00Z"},
+		// Tag und Wochentag gesetzt: einer von beiden reicht
+		{"0 0 20 * MON", "2026-03-16T00:00:00Z"},
+		{"@hourly", "2026-03-14T11:00:00Z"},
+		{"@daily", "2026-03-15T00:00:00Z"},
+		{"@weekly", "2026-03-15T00:00:00Z"},
+		{"@monthly", "2026-04-01T00:00:00Z"},
+		{"@every 90s", "2026-03-14T10:19:12Z"},
+		{"0 0 29 2 *", "2028-02-29T00:00:00Z"},
+	}
+	for _, tc := range cases {
+		s, err := Parse(tc.spec, time.UTC)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.spec, err)
+			continue
+		}
+		if got := s.Next(from).Format(time.RFC3339); got != tc.want {
+			t.Errorf("%q: next = %s, want %s", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	s, err := Parse("CRON_TZ=Europe/Berlin 0 9 * * *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 9 Uhr Berlin ist im Winter 8 Uhr UTC
+	got := s.Next(time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)).UTC()
+	if want := time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("next = %s, want %s", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"", "* * *", "61 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *",
+		"* * * FOO *", "@every", "@every 10ms", "@fortnightly", "TZ=Mars/Base * * * * *",
+	} {
+		if _, err := Parse(spec, time.UTC); err == nil {
+			t.Errorf("Parse(%q) should fail", spec)
+		}
+	}
+
+	if _, err := Parse("0 0 30 2 *", time.UTC); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	s, _ := Parse("0 0 30 2 *", time.UTC)
+	if next := s.Next(time.Now()); !next.IsZero() {
+		t.Errorf("30 February should never run, got %s", next)
+	}
+}
+
+func TestRegisterValidatesAndPreviews(t *testing.T) {
+	s := New()
+
+	err := s.Register(&Job{ID: "bad", Schedule: "every day"})
+	if err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Fatalf("Register with invalid schedule: err = %v", err)
+	}
+	if err := s.Register(&Job{ID: "tz", Schedule: "@daily", TimeZone: "Nowhere/City"}); err == nil {
+		t.Error("Register with unknown time zone should fail")
+	}
+
+	if err := s.Register(&Job{ID: "q", Schedule: "*/15 * * * *", Jitter: time.Minute, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	infos := s.Describe(3)
+	if len(infos) != 1 || len(infos[0].NextRuns) != 3 {
+		t.Fatalf("Describe = %+v", infos)
+	}
+	runs := infos[0].NextRuns
+	if runs[1].Sub(runs[0]) != 15*time.Minute || runs[0].Minute()%15 != 0 {
+		t.Errorf("preview = %v, want quarter hours", runs)
+	}
+	if next := infos[0].NextRun; next.Before(runs[0]) || next.Sub(runs[0]) >= time.Minute {
+		t.Errorf("next run %s not within jitter of %s", next, runs[0])
+	}
+}
@@ -0,0 +1,226 @@
+package scheduler
+
+import (
+	"biometrics-cli/internal/state"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MaxJobHistory begrenzt die Anzahl gespeicherter Läufe je Job.
+const MaxJobHistory = 20
+
+// Ergebnis eines Laufs
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	// OutcomeSkipped: der vorige Lauf lief noch (ConcurrencyForbid)
+	OutcomeSkipped = "skipped"
+	// OutcomeCancelled: abgebrochen durch replace, Unregister oder Stop
+	OutcomeCancelled = "cancelled"
+)
+
+// Auslöser eines Laufs
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerCatchUp  = "catch-up"
+)
+
+// JobRun ist ein abgeschlossener Lauf eines Jobs.
+type JobRun struct {
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Duration time.Duration `json:"duration"`
+	Trigger  string        `json:"trigger,omitempty"`
+	Outcome  string        `json:"outcome"`
+	// Result fasst zusammen, was der Job getan hat, siehe Report
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type runKey struct{}
+
+func withRun(ctx context.Context, run *JobRun) context.Context {
+	return context.WithValue(ctx, runKey{}, run)
+}
+
+// Report hält das Ergebnis des laufenden Jobs in dessen Historie fest.
+// Außerhalb eines Joblaufs tut es nichts.
+func Report(ctx context.Context, format string, args ...interface{}) {
+	if run, ok := ctx.Value(runKey{}).(*JobRun); ok {
+		run.Result = fmt.Sprintf(format, args...)
+	}
+}
+
+// finish schließt den Lauf ab. Ein von außen abgebrochener Kontext zählt als
+// cancelled, ein abgelaufener Timeout als Fehler.
+func (r *JobRun) finish(ctx context.Context, err error) JobRun {
+	r.Finished = time.Now()
+	r.Duration = r.Finished.Sub(r.Started)
+	cause := context.Cause(ctx)
+	switch {
+	case cause != nil && !errors.Is(cause, context.DeadlineExceeded):
+		r.Outcome = OutcomeCancelled
+		r.Error = cause.Error()
+	case err != nil:
+		r.Outcome = OutcomeFailed
+		r.Error = err.Error()
+	default:
+		r.Outcome = OutcomeSucceeded
+	}
+	return *r
+}
+
+// JobRecord ist der dauerhafte Zustand eines Jobs.
+type JobRecord struct {
+	LastRun time.Time `json:"last_run,omitempty"`
+	// Enabled ist nur gesetzt, wenn ein Operator den Job an- oder abgeschaltet
+	// hat, und hat dann Vorrang vor der Registrierung
+	Enabled *bool    `json:"enabled,omitempty"`
+	Runs    []JobRun `json:"runs,omitempty"`
+}
+
+// HistoryStore speichert Laufhistorie und Schalter über Neustarts hinweg.
+type HistoryStore interface {
+	// Load liefert die Records nach Job-ID
+	Load() (map[string]JobRecord, error)
+	// Save ersetzt alle Records
+	Save(records map[string]JobRecord) error
+}
+
+// FileHistoryStore hält alle Records in einer JSON-Datei, siehe
+// workspace.Workspace.JobsPath.
+type FileHistoryStore struct {
+	path string
+}
+
+func NewFileHistoryStore(path string) *FileHistoryStore {
+	return &FileHistoryStore{path: path}
+}
+
+// Load liest die Datei; fehlt sie, gibt es noch keine Historie.
+func (f *FileHistoryStore) Load() (map[string]JobRecord, error) {
+	records := make(map[string]JobRecord)
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse job history %s: %w", f.path, err)
+	}
+	return records, nil
+}
+
+// Save schreibt die Datei atomar.
+func (f *FileHistoryStore) Save(records map[string]JobRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create job history dir: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write job history: %w", err)
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// UseStore lädt Historie, letzte Läufe und Schalter aus store und speichert
+// jede Änderung dorthin. Bereits registrierte Jobs werden angepasst.
+func (s *Scheduler) UseStore(store HistoryStore) error {
+	records, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store = store
+	for id, rec := range records {
+		rec := rec
+		s.records[id] = &rec
+	}
+	for _, job := range s.jobs {
+		s.restoreLocked(job)
+	}
+	return nil
+}
+
+// restoreLocked übernimmt den gespeicherten Zustand in einen Job.
+func (s *Scheduler) restoreLocked(job *Job) {
+	rec := s.records[job.ID]
+	if rec == nil {
+		return
+	}
+	if rec.Enabled != nil {
+		job.Enabled = *rec.Enabled
+	}
+	if rec.LastRun.After(job.LastRun) {
+		job.LastRun = rec.LastRun
+	}
+}
+
+func (s *Scheduler) recordFor(jobID string) *JobRecord {
+	rec := s.records[jobID]
+	if rec == nil {
+		rec = &JobRecord{}
+		s.records[jobID] = rec
+	}
+	return rec
+}
+
+func (s *Scheduler) recordLocked(jobID string, run JobRun) {
+	rec := s.recordFor(jobID)
+	rec.Runs = append(rec.Runs, run)
+	if len(rec.Runs) > MaxJobHistory {
+		rec.Runs = rec.Runs[len(rec.Runs)-MaxJobHistory:]
+	}
+	if run.Outcome != OutcomeSkipped && run.Started.After(rec.LastRun) {
+		rec.LastRun = run.Started
+	}
+	s.persistLocked()
+}
+
+func (s *Scheduler) overrideLocked(jobID string, enabled bool) {
+	s.recordFor(jobID).Enabled = &enabled
+	s.persistLocked()
+}
+
+func (s *Scheduler) persistLocked() {
+	if s.store == nil {
+		return
+	}
+	records := make(map[string]JobRecord, len(s.records))
+	for id, rec := range s.records {
+		records[id] = *rec
+	}
+	if err := s.store.Save(records); err != nil {
+		state.GlobalState.Log("ERROR", fmt.Sprintf("Failed to save job history: %v", err))
+	}
+}
+
+// History liefert die letzten Läufe eines Jobs, den ältesten zuerst.
+func (s *Scheduler) History(jobID string) ([]JobRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.jobs[jobID]; !exists {
+		return nil, fmt.Errorf("job %s not found", jobID)
+	}
+	var runs []JobRun
+	if rec := s.records[jobID]; rec != nil {
+		runs = append(runs, rec.Runs...)
+	}
+	return runs, nil
+}
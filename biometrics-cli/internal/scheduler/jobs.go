@@ -0,0 +1,489 @@
+package scheduler
+
+import (
+	"biometrics-cli/internal/cache"
+	"biometrics-cli/internal/collision"
+	"biometrics-cli/internal/docker"
+	"biometrics-cli/internal/durable"
+	"biometrics-cli/internal/git"
+	"biometrics-cli/internal/heartbeat"
+	"biometrics-cli/internal/session"
+	"biometrics-cli/internal/state"
+	"biometrics-cli/internal/webhook"
+	"biometrics-cli/internal/workspace"
+	"biometrics-cli/pkg/logging"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Standardwerte für Env
+const (
+	DefaultLogRetention     = 14 * 24 * time.Hour
+	DefaultSessionMaxAge    = 24 * time.Hour
+	DefaultSessionRetention = 7 * 24 * time.Hour
+	DefaultSagaRetention    = 7 * 24 * time.Hour
+	// Ein älterer Modell-Snapshot heißt, dass der Daemon nicht mehr schreibt
+	maxSnapshotAge = 2 * time.Minute
+)
+
+// Env verbindet die Standardjobs mit den Subsystemen des Hosts. Ein Job,
+// dessen Subsystem fehlt (nil bzw. leer), wird deaktiviert registriert.
+type Env struct {
+	// LogsDB ist die Log-Datenbank aus state.InitDB, für cleanup-logs
+	LogsDB       *sql.DB
+	LogRetention time.Duration
+	// LogWriters rotiert metrics-rotate
+	LogWriters []*logging.RotatingWriter
+	// ModelStatusPath ist der Pool-Snapshot des Daemons, siehe
+	// workspace.Workspace.ModelStatusPath
+	ModelStatusPath string
+
+	Sessions         *session.Manager
+	SessionMaxAge    time.Duration
+	SessionRetention time.Duration
+
+	Cache      *cache.Cache
+	Git        *git.Integration
+	Docker     *docker.Manager
+	Heartbeats *heartbeat.Monitor
+	Webhook    *webhook.WebhookClient
+
+	// Sagas räumt saga-prune auf: abgeschlossene und zurückgerollte Sagas
+	// werden nach SagaRetention gelöscht
+	Sagas         *durable.SagaExecutor
+	SagaRetention time.Duration
+
+	Backups *state.BackupManager
+	// Snapshot liefert den Zustand für backup-state; ohne ihn wird er aus
+	// Heartbeats und Modell-Snapshot zusammengesetzt
+	Snapshot func() *state.OrchestratorState
+}
+
+func (e *Env) defaults() {
+	if e.LogRetention == 0 {
+		e.LogRetention = DefaultLogRetention
+	}
+	if e.SessionMaxAge == 0 {
+		e.SessionMaxAge = DefaultSessionMaxAge
+	}
+	if e.SessionRetention == 0 {
+		e.SessionRetention = DefaultSessionRetention
+	}
+	if e.SagaRetention == 0 {
+		e.SagaRetention = DefaultSagaRetention
+	}
+}
+
+// NewWorkspaceEnv verbindet die Standardjobs mit dem Workspace und den
+// globalen Managern. Die Log-Datenbank (state.InitDB) und der Session-Pfad
+// setzt der Host vorher, Heartbeats, LogWriters und Sagas danach. Git-Sync,
+// Docker-Prune und Webhook-Test sind über BIOMETRICS_GIT_SYNC (Pfadliste),
+// BIOMETRICS_DOCKER_PRUNE (nicht leer) und BIOMETRICS_WEBHOOK_URL/_SECRET
+// zuschaltbar.
+func NewWorkspaceEnv(ws workspace.Workspace) *Env {
+	env := &Env{
+		LogsDB:          state.GlobalState.DB,
+		ModelStatusPath: ws.ModelStatusPath(),
+		Sessions:        session.SessionManager,
+		Backups:         state.NewBackupManager(ws.BackupsDir(), 10),
+		Cache: cache.New(&cache.CacheConfig{
+			DiskPath:        ws.Path("cache"),
+			CleanupInterval: time.Minute,
+		}),
+	}
+
+	// Prune löscht Images und Volumes auf dem ganzen Host, nur auf Wunsch
+	if os.Getenv("BIOMETRICS_DOCKER_PRUNE") != "" {
+		if _, err := exec.LookPath("docker"); err == nil {
+			env.Docker = docker.ManagerInstance
+		} else {
+			state.GlobalState.Log("ERROR", "Docker prune: docker not found in PATH")
+		}
+	}
+
+	if paths := os.Getenv("BIOMETRICS_GIT_SYNC"); paths != "" {
+		env.Git = git.New()
+		for _, path := range filepath.SplitList(paths) {
+			if err := env.Git.AddRepository(path); err != nil {
+				state.GlobalState.Log("ERROR", fmt.Sprintf("Git sync: %v", err))
+			}
+		}
+	}
+
+	env.Webhook = webhook.ClientFromEnv()
+	return env
+}
+
+// RegisterDefaultJobs registriert die Wartungsjobs auf Sched. env darf nil
+// sein, dann sind alle Jobs deaktiviert.
+func RegisterDefaultJobs(env *Env) {
+	if env == nil {
+		env = &Env{}
+	}
+	env.defaults()
+
+	jobs := []*Job{
+		{
+			ID:          "health-check",
+			Name:        "Health Check",
+			Schedule:    "@every 5m",
+			Enabled:     env.Heartbeats != nil,
+			MaxFailures: 3,
+			Handler:     env.healthCheck,
+			Tags:        []string{"system", "health"},
+		},
+		{
+			ID:          "cleanup-logs",
+			Name:        "Cleanup Logs",
+			Schedule:    "@daily",
+			Enabled:     env.LogsDB != nil,
+			MaxFailures: 1,
+			Handler:     env.cleanupLogs,
+			Tags:        []string{"maintenance"},
+		},
+		{
+			ID:          "model-pool-check",
+			Name:        "Model Pool Check",
+			Schedule:    "@every 15m",
+			Enabled:     env.ModelStatusPath != "",
+			MaxFailures: 5,
+			Handler:     env.modelPoolCheck,
+			Tags:        []string{"models"},
+		},
+		{
+			ID:          "metrics-rotate",
+			Name:        "Metrics Rotation",
+			Schedule:    "@every 1h",
+			Enabled:     len(env.LogWriters) > 0,
+			MaxFailures: 1,
+			Handler:     env.metricsRotate,
+			Tags:        []string{"metrics"},
+		},
+		{
+			ID:          "session-cleanup",
+			Name:        "Session Cleanup",
+			Schedule:    "@every 30m",
+			Enabled:     env.Sessions != nil,
+			MaxFailures: 2,
+			Handler:     env.sessionCleanup,
+			Tags:        []string{"session", "maintenance"},
+		},
+		{
+			ID:          "cache-warmup",
+			Name:        "Cache Warmup",
+			Schedule:    "@every 6h",
+			Enabled:     env.Cache != nil,
+			MaxFailures: 2,
+			Handler:     env.cacheWarmup,
+			Tags:        []string{"cache", "performance"},
+		},
+		{
+			ID:          "git-sync",
+			Name:        "Git Sync",
+			Schedule:    "@every 15m",
+			Enabled:     env.Git != nil,
+			MaxFailures: 3,
+			Handler:     env.gitSync,
+			Tags:        []string{"git", "sync"},
+		},
+		{
+			ID:          "docker-prune",
+			Name:        "Docker Prune",
+			Schedule:    "@daily",
+			Enabled:     env.Docker != nil,
+			MaxFailures: 1,
+			Handler:     env.dockerPrune,
+			Tags:        []string{"docker", "maintenance"},
+		},
+		{
+			ID:          "backup-state",
+			Name:        "Backup State",
+			Schedule:    "@every 2h",
+			Enabled:     env.Backups != nil,
+			MaxFailures: 2,
+			Handler:     env.backupState,
+			Tags:        []string{"backup", "state"},
+		},
+		{
+			ID:          "saga-prune",
+			Name:        "Saga Prune",
+			Schedule:    "@daily",
+			Enabled:     env.Sagas != nil,
+			MaxFailures: 1,
+			Handler:     env.sagaPrune,
+			Tags:        []string{"maintenance"},
+		},
+		{
+			ID:          "webhook-test",
+			Name:        "Webhook Test",
+			Schedule:    "@every 1h",
+			Enabled:     env.Webhook != nil,
+			MaxFailures: 3,
+			Handler:     env.webhookTest,
+			Tags:        []string{"webhook", "monitoring"},
+		},
+	}
+
+	for _, job := range jobs {
+		if err := Sched.Register(job); err != nil {
+			state.GlobalState.Log("ERROR", fmt.Sprintf("Failed to register job: %v", err))
+		}
+	}
+}
+
+// errNotConfigured meldet einen manuell aktivierten Job ohne Subsystem.
+func errNotConfigured(what string) error {
+	return fmt.Errorf("%s not configured", what)
+}
+
+// healthCheck prüft die Heartbeats und schlägt fehl, wenn Agenten tot oder
+// hängen geblieben sind.
+func (e *Env) healthCheck(ctx context.Context) error {
+	if e.Heartbeats == nil {
+		return errNotConfigured("heartbeat monitor")
+	}
+
+	byStatus := e.Heartbeats.Check()
+	total := 0
+	for _, ids := range byStatus {
+		total += len(ids)
+	}
+	Report(ctx, "%d agent(s): %d alive, %d busy, %d idle, %d stuck, %d dead", total,
+		len(byStatus[heartbeat.StatusAlive]), len(byStatus[heartbeat.StatusBusy]), len(byStatus[heartbeat.StatusIdle]),
+		len(byStatus[heartbeat.StatusStuck]), len(byStatus[heartbeat.StatusDead]))
+
+	var problems []string
+	for _, s := range []heartbeat.Status{heartbeat.StatusDead, heartbeat.StatusStuck} {
+		if ids := byStatus[s]; len(ids) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %s", s, strings.Join(ids, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("unhealthy agents (%s)", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// sagaPrune löscht abgeschlossene und zurückgerollte Sagas, die länger als
+// SagaRetention zurückliegen.
+func (e *Env) sagaPrune(ctx context.Context) error {
+	if e.Sagas == nil {
+		return errNotConfigured("saga executor")
+	}
+	n, err := e.Sagas.Prune(time.Now().Add(-e.SagaRetention))
+	Report(ctx, "pruned %d saga(s) finished more than %s ago", n, e.SagaRetention)
+	return err
+}
+
+// cleanupLogs löscht Log-Einträge, die älter als LogRetention sind.
+func (e *Env) cleanupLogs(ctx context.Context) error {
+	if e.LogsDB == nil {
+		return errNotConfigured("log database")
+	}
+
+	// state.Log schreibt RFC3339, das lässt sich als Text vergleichen
+	cutoff := time.Now().Add(-e.LogRetention).Format(time.RFC3339)
+	res, err := e.LogsDB.ExecContext(ctx, "DELETE FROM logs WHERE timestamp < ?", cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to delete old logs: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	Report(ctx, "deleted %d log entries older than %s", n, e.LogRetention)
+	return nil
+}
+
+// modelPoolCheck prüft den Pool-Snapshot des Daemons.
+func (e *Env) modelPoolCheck(ctx context.Context) error {
+	if e.ModelStatusPath == "" {
+		return errNotConfigured("model status path")
+	}
+
+	snapshot, err := collision.ReadSnapshot(e.ModelStatusPath)
+	if errors.Is(err, os.ErrNotExist) {
+		Report(ctx, "no model snapshot, daemon not running")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	exhausted := snapshot.Exhausted()
+	Report(ctx, "%d model(s), %d exhausted", len(snapshot.Models), len(exhausted))
+	if age := time.Since(snapshot.UpdatedAt); age > maxSnapshotAge {
+		return fmt.Errorf("model snapshot is %s old", age.Round(time.Second))
+	}
+	if len(snapshot.Models) > 0 && len(exhausted) == len(snapshot.Models) {
+		return fmt.Errorf("all %d models exhausted", len(exhausted))
+	}
+	return nil
+}
+
+// metricsRotate rotiert die Log- und Metrikdateien; die Writer räumen dabei
+// alte Archive nach ihrer RotationConfig ab.
+func (e *Env) metricsRotate(ctx context.Context) error {
+	if len(e.LogWriters) == 0 {
+		return errNotConfigured("log writers")
+	}
+
+	var errs []error
+	for _, w := range e.LogWriters {
+		if err := w.Rotate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", w.Filename(), err))
+		}
+	}
+	Report(ctx, "rotated %d of %d file(s)", len(e.LogWriters)-len(errs), len(e.LogWriters))
+	return errors.Join(errs...)
+}
+
+// sessionCleanup beendet abgelaufene Sessions und speichert den Rest.
+func (e *Env) sessionCleanup(ctx context.Context) error {
+	if e.Sessions == nil {
+		return errNotConfigured("session manager")
+	}
+
+	expired, removed := e.Sessions.Expire(e.SessionMaxAge, e.SessionRetention)
+	Report(ctx, "expired %d session(s), removed %d", expired, removed)
+	if expired == 0 && removed == 0 {
+		return nil
+	}
+	if err := e.Sessions.Save(); err != nil {
+		return fmt.Errorf("failed to save sessions: %w", err)
+	}
+	return nil
+}
+
+// cacheWarmup lädt den Plattencache neu.
+func (e *Env) cacheWarmup(ctx context.Context) error {
+	if e.Cache == nil {
+		return errNotConfigured("cache")
+	}
+
+	Report(ctx, "%d cache entries loaded", e.Cache.Warm())
+	return nil
+}
+
+// gitSync holt alle Repositories und zieht die ohne lokale Änderungen nach.
+func (e *Env) gitSync(ctx context.Context) error {
+	if e.Git == nil {
+		return errNotConfigured("git integration")
+	}
+
+	var (
+		errs            []error
+		pulled, skipped int
+	)
+	for _, repo := range e.Git.ListRepositories() {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if repo.GetRemoteURL() == "" {
+			skipped++
+			continue
+		}
+		if err := repo.Fetch(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: fetch failed: %w", repo.GetPath(), err))
+			continue
+		}
+		// Lokale Änderungen gehören einem Agenten und werden nicht angefasst
+		dirty, err := repo.HasChanges()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.GetPath(), err))
+			continue
+		}
+		if dirty {
+			skipped++
+			continue
+		}
+		if err := repo.Pull(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.GetPath(), err))
+			continue
+		}
+		pulled++
+	}
+	Report(ctx, "%d pulled, %d skipped, %d failed", pulled, skipped, len(errs))
+	return errors.Join(errs...)
+}
+
+// dockerPrune entfernt gestoppte Container und ungenutzte Images.
+func (e *Env) dockerPrune(ctx context.Context) error {
+	if e.Docker == nil {
+		return errNotConfigured("docker manager")
+	}
+
+	reclaimed, err := e.Docker.Prune(ctx)
+	Report(ctx, "reclaimed %s", strings.Join(reclaimed, ", "))
+	return err
+}
+
+// backupState sichert den Orchestrator-Zustand.
+func (e *Env) backupState(ctx context.Context) error {
+	if e.Backups == nil {
+		return errNotConfigured("backup manager")
+	}
+
+	id, err := e.Backups.CreateBackup(e.snapshot(), "scheduled backup")
+	if err != nil {
+		return err
+	}
+	Report(ctx, "created %s", id)
+	return nil
+}
+
+func (e *Env) snapshot() *state.OrchestratorState {
+	if e.Snapshot != nil {
+		return e.Snapshot()
+	}
+
+	st := &state.OrchestratorState{
+		ActiveAgents:    make(map[string]state.AgentState),
+		CircuitBreakers: make(map[string]state.CBState),
+		Timestamp:       time.Now(),
+	}
+	if e.Heartbeats != nil {
+		for _, hb := range e.Heartbeats.GetAllHeartbeats() {
+			st.ActiveAgents[hb.AgentID] = state.AgentState{
+				ID:        hb.AgentID,
+				Name:      hb.AgentID,
+				Status:    string(hb.Status),
+				Model:     hb.Model,
+				StartedAt: hb.StartedAt,
+			}
+		}
+	}
+	if e.ModelStatusPath != "" {
+		if snapshot, err := collision.ReadSnapshot(e.ModelStatusPath); err == nil {
+			for _, m := range snapshot.Models {
+				if !m.Exhausted {
+					st.ModelPool.AvailableModels = append(st.ModelPool.AvailableModels, m.Name)
+				}
+			}
+		}
+	}
+	return st
+}
+
+// webhookTest schickt ein Testereignis an den konfigurierten Webhook.
+func (e *Env) webhookTest(ctx context.Context) error {
+	if e.Webhook == nil {
+		return errNotConfigured("webhook")
+	}
+
+	err := e.Webhook.Send(&webhook.WebhookEvent{
+		Type:   "health.check",
+		Source: "biometrics",
+		Data:   map[string]interface{}{"job": "webhook-test"},
+	})
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", e.Webhook.URL, err)
+	}
+	Report(ctx, "delivered to %s", e.Webhook.URL)
+	return nil
+}
@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"biometrics-cli/internal/state"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRunJobRecordsHistory(t *testing.T) {
+	s := New()
+	fail := false
+	job := &Job{ID: "j", Name: "j", Schedule: "@every 1m", Enabled: true, MaxFailures: 100,
+		Handler: func(ctx context.Context) error {
+			Report(ctx, "did work")
+			time.Sleep(5 * time.Millisecond)
+			if fail {
+				return errors.New("boom")
+			}
+			return nil
+		}}
+	if err := s.Register(job); err != nil {
+		t.Fatal(err)
+	}
+
+	s.runJob(context.Background(), job, TriggerManual)
+	fail = true
+	s.runJob(context.Background(), job, TriggerManual)
+
+	runs, err := s.History("j")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("history has %d runs, want 2", len(runs))
+	}
+	if runs[0].Outcome != OutcomeSucceeded || runs[0].Result != "did work" || runs[0].Duration < 5*time.Millisecond {
+		t.Errorf("first run = %+v", runs[0])
+	}
+	if runs[1].Outcome != OutcomeFailed || runs[1].Error != "boom" {
+		t.Errorf("second run = %+v", runs[1])
+	}
+	if last := s.Describe(0)[0].LastResult; last == nil || last.Outcome != OutcomeFailed {
+		t.Errorf("LastResult = %+v", last)
+	}
+
+	for i := 0; i < MaxJobHistory+5; i++ {
+		s.runJob(context.Background(), job, TriggerManual)
+	}
+	if runs, _ := s.History("j"); len(runs) != MaxJobHistory {
+		t.Errorf("history has %d runs, want %d", len(runs), MaxJobHistory)
+	}
+}
+
+func TestCleanupLogsDeletesOldEntries(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "logs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE logs (id INTEGER PRIMARY KEY AUTOINCREMENT, timestamp TEXT, level TEXT, agent TEXT, plan TEXT, message TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, age := range []time.Duration{time.Hour, 20 * 24 * time.Hour, 30 * 24 * time.Hour} {
+		ts := time.Now().Add(-age).Format(time.RFC3339)
+		if _, err := db.Exec("INSERT INTO logs (timestamp, level, message) VALUES (?, 'INFO', 'x')", ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env := &Env{LogsDB: db}
+	env.defaults()
+	run := &JobRun{}
+	if err := env.cleanupLogs(withRun(context.Background(), run)); err != nil {
+		t.Fatal(err)
+	}
+
+	var left int
+	db.QueryRow("SELECT COUNT(*) FROM logs").Scan(&left)
+	if left != 1 {
+		t.Errorf("%d log entries left, want 1", left)
+	}
+	if !strings.HasPrefix(run.Result, "deleted 2 ") {
+		t.Errorf("result = %q", run.Result)
+	}
+}
+
+func TestBackupStateWritesBackup(t *testing.T) {
+	dir := t.TempDir()
+	env := &Env{Backups: state.NewBackupManager(dir, 3)}
+
+	if err := env.backupState(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "backup-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("found %d backups, want 1", len(files))
+	}
+	if data, _ := os.ReadFile(files[0]); !strings.Contains(string(data), "scheduled backup") {
+		t.Errorf("backup lacks description: %s", data)
+	}
+}
+
+func TestDefaultJobsDisabledWithoutSubsystem(t *testing.T) {
+	old := Sched
+	Sched = New()
+	defer func() { Sched = old }()
+
+	RegisterDefaultJobs(&Env{ModelStatusPath: filepath.Join(t.TempDir(), "model_status.json")})
+
+	for _, info := range Sched.Describe(0) {
+		if want := info.ID == "model-pool-check"; info.Enabled != want {
+			t.Errorf("%s enabled = %v, want %v", info.ID, info.Enabled, want)
+		}
+	}
+	job, _ := Sched.GetJob("docker-prune")
+	if err := job.Handler(context.Background()); err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Errorf("docker-prune without manager: err = %v", err)
+	}
+}
@@ -4,15 +4,63 @@ import (
 	"biometrics-cli/internal/metrics"
 	"biometrics-cli/internal/state"
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"sort"
 	"sync"
 	"time"
 )
 
+// ConcurrencyPolicy legt fest, was passiert, wenn ein Lauf fällig wird,
+// während der vorige noch läuft.
+type ConcurrencyPolicy string
+
+const (
+	// ConcurrencyForbid lässt den neuen Lauf aus (Standard)
+	ConcurrencyForbid ConcurrencyPolicy = "forbid"
+	// ConcurrencyAllow startet ihn parallel
+	ConcurrencyAllow ConcurrencyPolicy = "allow"
+	// ConcurrencyReplace bricht die laufenden ab und startet ihn
+	ConcurrencyReplace ConcurrencyPolicy = "replace"
+)
+
+// CatchUpPolicy legt fest, wie Läufe nachgeholt werden, die beim Start seit
+// dem letzten Lauf verpasst wurden.
+type CatchUpPolicy string
+
+const (
+	// CatchUpSkip holt nichts nach (Standard)
+	CatchUpSkip CatchUpPolicy = "skip"
+	// CatchUpOnce holt einen Lauf nach, egal wie viele verpasst wurden
+	CatchUpOnce CatchUpPolicy = "once"
+	// CatchUpAll holt jeden verpassten Lauf nach, höchstens MaxCatchUpRuns
+	CatchUpAll CatchUpPolicy = "all"
+)
+
+// MaxCatchUpRuns begrenzt nachgeholte Läufe je Job und Start.
+const MaxCatchUpRuns = 10
+
+// tickInterval ist die Auflösung, mit der fällige Jobs gestartet werden
+const tickInterval = time.Second
+
+var (
+	errReplaced = errors.New("replaced by a newer run")
+	errRemoved  = errors.New("job removed")
+	errStopped  = errors.New("scheduler stopped")
+)
+
 type Job struct {
-	ID          string
-	Name        string
-	Schedule    string
+	ID   string
+	Name string
+	// Schedule ist ein Cron-Ausdruck oder Deskriptor, siehe Parse
+	Schedule string
+	// TimeZone ist die IANA-Zeitzone des Schedules, leer = lokale Zeit
+	TimeZone string
+	// Jitter verschiebt jeden Lauf um eine zufällige Dauer in [0, Jitter)
+	Jitter      time.Duration
+	Concurrency ConcurrencyPolicy
+	CatchUp     CatchUpPolicy
 	Handler     JobHandler
 	Enabled     bool
 	LastRun     time.Time
@@ -23,26 +71,99 @@ type Job struct {
 	Timeout     time.Duration
 	RetryCount  int
 	Tags        []string
+
+	schedule Schedule
+}
+
+// JobInfo ist eine Momentaufnahme eines Jobs samt Vorschau der nächsten Läufe.
+type JobInfo struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Schedule    string            `json:"schedule"`
+	TimeZone    string            `json:"time_zone,omitempty"`
+	Jitter      string            `json:"jitter,omitempty"`
+	Concurrency ConcurrencyPolicy `json:"concurrency"`
+	CatchUp     CatchUpPolicy     `json:"catch_up"`
+	Enabled     bool              `json:"enabled"`
+	Running     int               `json:"running"`
+	LastRun     time.Time         `json:"last_run"`
+	NextRun     time.Time         `json:"next_run"`
+	RunCount    int               `json:"run_count"`
+	FailCount   int               `json:"fail_count"`
+	Tags        []string          `json:"tags,omitempty"`
+	NextRuns    []time.Time       `json:"next_runs"`
+	// LastResult ist der letzte Eintrag der Laufhistorie
+	LastResult *JobRun `json:"last_result,omitempty"`
+	// History ist nur bei Info gesetzt, der älteste Lauf zuerst
+	History []JobRun `json:"history,omitempty"`
+}
+
+// ParseJobSchedule prüft Schedule und TimeZone eines Jobs.
+func ParseJobSchedule(schedule, timeZone string) (Schedule, error) {
+	loc := time.Local
+	if timeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timeZone); err != nil {
+			return nil, fmt.Errorf("unknown time zone %q: %w", timeZone, err)
+		}
+	}
+	return Parse(schedule, loc)
+}
+
+// nextRun liefert den nächsten Lauf nach from inklusive Jitter.
+func (j *Job) nextRun(from time.Time) time.Time {
+	next := j.schedule.Next(from)
+	if j.Jitter > 0 && !next.IsZero() {
+		next = next.Add(rand.N(j.Jitter))
+	}
+	return next
+}
+
+// missedRuns zählt die planmäßigen Läufe zwischen LastRun und now, höchstens
+// MaxCatchUpRuns. Ein Job, der nie lief, hat nichts verpasst.
+func (j *Job) missedRuns(now time.Time) int {
+	if j.LastRun.IsZero() {
+		return 0
+	}
+	missed := 0
+	for t := j.schedule.Next(j.LastRun); !t.IsZero() && t.Before(now) && missed < MaxCatchUpRuns; t = j.schedule.Next(t) {
+		missed++
+	}
+	return missed
 }
 
 type JobHandler func(ctx context.Context) error
 
+// execution ist ein laufender Lauf, den replace oder Stop abbrechen können.
+type execution struct {
+	cancel context.CancelCauseFunc
+}
+
 type Scheduler struct {
-	mu        sync.RWMutex
-	jobs      map[string]*Job
-	running   bool
-	stopChan  chan struct{}
-	wg        sync.WaitGroup
-	executors map[string]chan struct{}
+	mu       sync.RWMutex
+	jobs     map[string]*Job
+	running  bool
+	ctx      context.Context
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	inflight map[string][]*execution
+	records  map[string]*JobRecord
+	store    HistoryStore
 }
 
-var (
-	defaultScheduler = &Scheduler{
-		jobs:      make(map[string]*Job),
-		stopChan:  make(chan struct{}),
-		executors: make(map[string]chan struct{}),
+// New erzeugt einen leeren Scheduler.
+func New() *Scheduler {
+	return &Scheduler{
+		jobs:     make(map[string]*Job),
+		stopChan: make(chan struct{}),
+		inflight: make(map[string][]*execution),
+		records:  make(map[string]*JobRecord),
 	}
-	Sched = defaultScheduler
+}
+
+var (
+	defaultScheduler = New()
+	Sched            = defaultScheduler
 )
 
 func (s *Scheduler) Register(job *Job) error {
@@ -53,6 +174,27 @@ func (s *Scheduler) Register(job *Job) error {
 		return fmt.Errorf("job %s already exists", job.ID)
 	}
 
+	schedule, err := ParseJobSchedule(job.Schedule, job.TimeZone)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.ID, err)
+	}
+	job.schedule = schedule
+
+	switch job.Concurrency {
+	case "":
+		job.Concurrency = ConcurrencyForbid
+	case ConcurrencyForbid, ConcurrencyAllow, ConcurrencyReplace:
+	default:
+		return fmt.Errorf("job %s: unknown concurrency policy %q", job.ID, job.Concurrency)
+	}
+	switch job.CatchUp {
+	case "":
+		job.CatchUp = CatchUpSkip
+	case CatchUpSkip, CatchUpOnce, CatchUpAll:
+	default:
+		return fmt.Errorf("job %s: unknown catch-up policy %q", job.ID, job.CatchUp)
+	}
+
 	if job.MaxFailures == 0 {
 		job.MaxFailures = 3
 	}
@@ -60,7 +202,8 @@ func (s *Scheduler) Register(job *Job) error {
 		job.Timeout = 5 * time.Minute
 	}
 
-	job.NextRun = calculateNextRun(job.Schedule, time.Now())
+	s.restoreLocked(job)
+	job.NextRun = job.nextRun(time.Now())
 	s.jobs[job.ID] = job
 	state.GlobalState.Log("INFO", fmt.Sprintf("Registered job: %s (%s)", job.Name, job.Schedule))
 
@@ -75,39 +218,59 @@ func (s *Scheduler) Unregister(jobID string) error {
 		return fmt.Errorf("job %s not found", jobID)
 	}
 
-	if s.running {
-		if stopChan, ok := s.executors[jobID]; ok {
-			close(stopChan)
-			delete(s.executors, jobID)
-		}
+	for _, e := range s.inflight[jobID] {
+		e.cancel(errRemoved)
 	}
 
 	delete(s.jobs, jobID)
+	delete(s.records, jobID)
+	s.persistLocked()
 	state.GlobalState.Log("INFO", fmt.Sprintf("Unregistered job: %s", jobID))
 
 	return nil
 }
 
+// Start führt den Scheduler aus, bis Stop aufgerufen wird.
 func (s *Scheduler) Start() {
+	s.Run(context.Background())
+}
+
+// Run holt verpasste Läufe nach und startet dann fällige Jobs, bis ctx endet
+// oder Stop aufgerufen wird. Beim Ende werden laufende Jobs abgebrochen und
+// abgewartet.
+func (s *Scheduler) Run(ctx context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(errStopped)
+
 	s.mu.Lock()
 	if s.running {
 		s.mu.Unlock()
 		return
 	}
 	s.running = true
+	s.ctx = ctx
+	stop := s.stopChan
 	s.mu.Unlock()
 
 	state.GlobalState.Log("INFO", "Scheduler started")
+	s.catchUp(ctx, time.Now())
 
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-s.stopChan:
+		case <-stop:
+			stop = nil
+			cancel(errStopped)
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.running = false
+			s.mu.Unlock()
 			s.wg.Wait()
 			state.GlobalState.Log("INFO", "Scheduler stopped")
 			return
-		default:
-			s.tick()
-			time.Sleep(10 * time.Second)
+		case <-ticker.C:
+			s.tick(ctx)
 		}
 	}
 }
@@ -121,73 +284,168 @@ func (s *Scheduler) Stop() {
 	}
 
 	close(s.stopChan)
-	s.running = false
+	s.stopChan = make(chan struct{})
+	state.GlobalState.Log("INFO", "Scheduler stopping")
+}
 
-	for jobID, stopChan := range s.executors {
-		close(stopChan)
-		delete(s.executors, jobID)
+// catchUp startet die seit dem letzten Lauf verpassten Läufe nach der
+// CatchUpPolicy der Jobs.
+func (s *Scheduler) catchUp(ctx context.Context, now time.Time) {
+	s.mu.RLock()
+	type pending struct {
+		job  *Job
+		runs int
 	}
+	var todo []pending
+	for _, job := range s.jobs {
+		if !job.Enabled {
+			continue
+		}
+		missed := job.missedRuns(now)
+		if missed == 0 {
+			continue
+		}
+		switch job.CatchUp {
+		case CatchUpOnce:
+			todo = append(todo, pending{job, 1})
+		case CatchUpAll:
+			todo = append(todo, pending{job, missed})
+		default:
+			state.GlobalState.Log("INFO", fmt.Sprintf("Job %s missed %d run(s), skipping", job.Name, missed))
+		}
+	}
+	s.mu.RUnlock()
 
-	state.GlobalState.Log("INFO", "Scheduler stopping")
+	for _, p := range todo {
+		state.GlobalState.Log("INFO", fmt.Sprintf("Job %s catching up %d run(s)", p.job.Name, p.runs))
+		p := p
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			// Nacheinander, damit forbid die eigenen Nachholläufe nicht auslässt
+			for i := 0; i < p.runs && ctx.Err() == nil; i++ {
+				s.runJob(ctx, p.job, TriggerCatchUp)
+			}
+		}()
+	}
 }
 
-func (s *Scheduler) tick() {
+func (s *Scheduler) tick(ctx context.Context) {
 	now := time.Now()
 
-	s.mu.RLock()
+	s.mu.Lock()
 	var toRun []*Job
 	for _, job := range s.jobs {
 		if !job.Enabled {
 			continue
 		}
-		if now.After(job.NextRun) || now.Equal(job.NextRun) {
+		if !job.NextRun.IsZero() && !now.Before(job.NextRun) {
 			toRun = append(toRun, job)
+			job.NextRun = job.nextRun(now)
 		}
 	}
-	s.mu.RUnlock()
+	s.mu.Unlock()
 
 	for _, job := range toRun {
-		s.runJob(job)
+		s.launch(ctx, job, TriggerSchedule)
+	}
+}
+
+func (s *Scheduler) launch(ctx context.Context, job *Job, trigger string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.runJob(ctx, job, trigger)
+	}()
+}
+
+// Trigger startet einen Lauf sofort, auch wenn der Job deaktiviert ist. Die
+// ConcurrencyPolicy gilt wie bei planmäßigen Läufen.
+func (s *Scheduler) Trigger(id string) error {
+	s.mu.RLock()
+	job, exists := s.jobs[id]
+	ctx, running := s.ctx, s.running
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("job %s not found", id)
+	}
+	if !running {
+		return fmt.Errorf("scheduler not running")
 	}
+	state.GlobalState.Log("INFO", fmt.Sprintf("Job %s triggered manually", job.Name))
+	s.launch(ctx, job, TriggerManual)
+	return nil
+}
 
+// runJob führt einen Lauf aus und wendet vorher die ConcurrencyPolicy an.
+func (s *Scheduler) runJob(ctx context.Context, job *Job, trigger string) {
 	s.mu.Lock()
-	for _, job := range s.jobs {
-		if job.Enabled {
-			job.NextRun = calculateNextRun(job.Schedule, time.Now())
+	if active := s.inflight[job.ID]; len(active) > 0 {
+		switch job.Concurrency {
+		case ConcurrencyAllow:
+		case ConcurrencyReplace:
+			for _, e := range active {
+				e.cancel(errReplaced)
+			}
+		default:
+			now := time.Now()
+			s.recordLocked(job.ID, JobRun{
+				Started:  now,
+				Finished: now,
+				Trigger:  trigger,
+				Outcome:  OutcomeSkipped,
+				Error:    "previous run still active",
+			})
+			s.mu.Unlock()
+			state.GlobalState.Log("INFO", fmt.Sprintf("Job %s skipped, previous run still active", job.Name))
+			return
 		}
 	}
-	s.mu.Unlock()
-}
 
-func (s *Scheduler) runJob(job *Job) {
 	job.RunCount++
 	job.LastRun = time.Now()
-
-	metrics.SchedulerJobsRunTotal.WithLabelValues(job.Name).Inc()
-
-	stopChan := make(chan struct{})
-	s.mu.Lock()
-	s.executors[job.ID] = stopChan
+	run := &JobRun{Started: job.LastRun, Trigger: trigger}
+	runCtx, cancel := context.WithCancelCause(ctx)
+	exec := &execution{cancel: cancel}
+	s.inflight[job.ID] = append(s.inflight[job.ID], exec)
+	timeout := job.Timeout
 	s.mu.Unlock()
 
-	ctx, cancel := context.WithTimeout(context.Background(), job.Timeout)
-	defer cancel()
-
-	go func() {
-		select {
-		case <-stopChan:
-			cancel()
-			state.GlobalState.Log("INFO", fmt.Sprintf("Job %s cancelled", job.Name))
-			return
-		case <-ctx.Done():
-			return
+	defer func() {
+		s.mu.Lock()
+		active := s.inflight[job.ID]
+		for i, e := range active {
+			if e == exec {
+				s.inflight[job.ID] = append(active[:i:i], active[i+1:]...)
+				break
+			}
+		}
+		if len(s.inflight[job.ID]) == 0 {
+			delete(s.inflight, job.ID)
 		}
+		s.mu.Unlock()
+		cancel(nil)
 	}()
 
+	metrics.SchedulerJobsRunTotal.WithLabelValues(job.Name).Inc()
+
+	runCtx, cancelTimeout := context.WithTimeout(runCtx, timeout)
+	defer cancelTimeout()
+
 	state.GlobalState.Log("INFO", fmt.Sprintf("Running job: %s", job.Name))
 
-	err := job.Handler(ctx)
-	if err != nil {
+	err := job.Handler(withRun(runCtx, run))
+	result := run.finish(runCtx, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recordLocked(job.ID, result)
+
+	switch result.Outcome {
+	case OutcomeCancelled:
+		state.GlobalState.Log("INFO", fmt.Sprintf("Job %s cancelled: %s", job.Name, result.Error))
+	case OutcomeFailed:
 		job.FailCount++
 		state.GlobalState.Log("ERROR", fmt.Sprintf("Job %s failed: %v", job.Name, err))
 		metrics.SchedulerJobsFailedTotal.WithLabelValues(job.Name).Inc()
@@ -200,29 +458,29 @@ func (s *Scheduler) runJob(job *Job) {
 		if job.RetryCount > 0 && job.FailCount < job.MaxFailures {
 			go s.retryJob(job)
 		}
-	} else {
+	default:
 		job.FailCount = 0
 		state.GlobalState.Log("INFO", fmt.Sprintf("Job %s completed successfully", job.Name))
 		metrics.SchedulerJobsSuccessTotal.WithLabelValues(job.Name).Inc()
 	}
-
-	s.mu.Lock()
-	delete(s.executors, job.ID)
-	s.mu.Unlock()
 }
 
 func (s *Scheduler) retryJob(job *Job) {
+	s.mu.RLock()
+	stopChan := s.stopChan
+	s.mu.RUnlock()
+
 	for i := 0; i < job.RetryCount; i++ {
 		select {
-		case <-s.stopChan:
+		case <-stopChan:
 			return
 		case <-time.After(time.Duration(i+1) * 30 * time.Second):
+			s.mu.Lock()
 			if !job.Enabled {
+				s.mu.Unlock()
 				return
 			}
 			job.NextRun = time.Now()
-			s.mu.Lock()
-			job.NextRun = time.Now()
 			s.mu.Unlock()
 			state.GlobalState.Log("INFO", fmt.Sprintf("Retrying job: %s (attempt %d/%d)", job.Name, i+1, job.RetryCount))
 			return
@@ -252,6 +510,7 @@ func (s *Scheduler) ListJobs() []*Job {
 	return jobs
 }
 
+// EnableJob schaltet einen Job an; die Entscheidung überdauert Neustarts.
 func (s *Scheduler) EnableJob(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -262,12 +521,16 @@ func (s *Scheduler) EnableJob(id string) error {
 	}
 
 	job.Enabled = true
-	job.NextRun = calculateNextRun(job.Schedule, time.Now())
+	job.FailCount = 0
+	job.NextRun = job.nextRun(time.Now())
+	s.overrideLocked(id, true)
 	state.GlobalState.Log("INFO", fmt.Sprintf("Job %s enabled", job.Name))
 
 	return nil
 }
 
+// DisableJob schaltet einen Job ab; die Entscheidung überdauert Neustarts.
+// Laufende Läufe dürfen zu Ende laufen.
 func (s *Scheduler) DisableJob(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -278,197 +541,72 @@ func (s *Scheduler) DisableJob(id string) error {
 	}
 
 	job.Enabled = false
+	s.overrideLocked(id, false)
 	state.GlobalState.Log("INFO", fmt.Sprintf("Job %s disabled", job.Name))
 
 	return nil
 }
 
-func calculateNextRun(schedule string, from time.Time) time.Time {
-	switch schedule {
-	case "@every 1m":
-		return from.Add(1 * time.Minute)
-	case "@every 5m":
-		return from.Add(5 * time.Minute)
-	case "@every 15m":
-		return from.Add(15 * time.Minute)
-	case "@every 30m":
-		return from.Add(30 * time.Minute)
-	case "@every 1h":
-		return from.Add(1 * time.Hour)
-	case "@every 2h":
-		return from.Add(2 * time.Hour)
-	case "@every 6h":
-		return from.Add(6 * time.Hour)
-	case "@every 12h":
-		return from.Add(12 * time.Hour)
-	case "@daily":
-		return time.Date(from.Year(), from.Month(), from.Day()+1, 0, 0, 0, 0, from.Location())
-	case "@weekly":
-		daysUntilNext := time.Sunday - from.Weekday()
-		if daysUntilNext == 0 {
-			daysUntilNext = 7
-		}
-		next := from.AddDate(0, 0, int(daysUntilNext))
-		return time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, from.Location())
-	default:
-		return from.Add(1 * time.Hour)
-	}
-}
-
-func RegisterDefaultJobs() {
-	Sched.Register(&Job{
-		ID:          "health-check",
-		Name:        "Health Check",
-		Schedule:    "@every 5m",
-		Enabled:     true,
-		MaxFailures: 3,
-		Handler:     healthCheckJob,
-		Tags:        []string{"system", "health"},
-	})
-
-	Sched.Register(&Job{
-		ID:          "cleanup-logs",
-		Name:        "Cleanup Logs",
-		Schedule:    "@daily",
-		Enabled:     true,
-		MaxFailures: 1,
-		Handler:     cleanupLogsJob,
-		Tags:        []string{"maintenance"},
-	})
-
-	Sched.Register(&Job{
-		ID:          "model-pool-check",
-		Name:        "Model Pool Check",
-		Schedule:    "@every 15m",
-		Enabled:     true,
-		MaxFailures: 5,
-		Handler:     modelPoolCheckJob,
-		Tags:        []string{"models"},
-	})
-
-	Sched.Register(&Job{
-		ID:          "metrics-rotate",
-		Name:        "Metrics Rotation",
-		Schedule:    "@every 1h",
-		Enabled:     true,
-		MaxFailures: 1,
-		Handler:     metricsRotateJob,
-		Tags:        []string{"metrics"},
-	})
-
-	Sched.Register(&Job{
-		ID:          "session-cleanup",
-		Name:        "Session Cleanup",
-		Schedule:    "@every 30m",
-		Enabled:     true,
-		MaxFailures: 2,
-		Handler:     sessionCleanupJob,
-		Tags:        []string{"session", "maintenance"},
-	})
-
-	Sched.Register(&Job{
-		ID:          "cache-warmup",
-		Name:        "Cache Warmup",
-		Schedule:    "@every 6h",
-		Enabled:     true,
-		MaxFailures: 2,
-		Handler:     cacheWarmupJob,
-		Tags:        []string{"cache", "performance"},
-	})
-
-	Sched.Register(&Job{
-		ID:          "git-sync",
-		Name:        "Git Sync",
-		Schedule:    "@every 15m",
-		Enabled:     false,
-		MaxFailures: 3,
-		Handler:     gitSyncJob,
-		Tags:        []string{"git", "sync"},
-	})
-
-	Sched.Register(&Job{
-		ID:          "docker-prune",
-		Name:        "Docker Prune",
-		Schedule:    "@daily",
-		Enabled:     true,
-		MaxFailures: 1,
-		Handler:     dockerPruneJob,
-		Tags:        []string{"docker", "maintenance"},
-	})
-
-	Sched.Register(&Job{
-		ID:          "backup-state",
-		Name:        "Backup State",
-		Schedule:    "@every 2h",
-		Enabled:     true,
-		MaxFailures: 2,
-		Handler:     backupStateJob,
-		Tags:        []string{"backup", "state"},
-	})
-
-	Sched.Register(&Job{
-		ID:          "webhook-test",
-		Name:        "Webhook Test",
-		Schedule:    "@every 1h",
-		Enabled:     false,
-		MaxFailures: 3,
-		Handler:     webhookTestJob,
-		Tags:        []string{"webhook", "monitoring"},
-	})
-}
-
-func healthCheckJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running health check job")
-	return nil
-}
-
-func cleanupLogsJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running cleanup logs job")
-	return nil
-}
-
-func modelPoolCheckJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running model pool check job")
-	return nil
-}
-
-func metricsRotateJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running metrics rotation job")
-	return nil
-}
-
-func sessionCleanupJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running session cleanup job")
-	return nil
-}
-
-func cacheWarmupJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running cache warmup job")
-	return nil
-}
+// Describe liefert alle Jobs nach ID sortiert, jeweils mit den nächsten n
+// Läufen ohne Jitter.
+func (s *Scheduler) Describe(n int) []JobInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-func gitSyncJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running git sync job")
-	return nil
+	now := time.Now()
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		infos = append(infos, s.infoLocked(job, now, n))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
 }
 
-func dockerPruneJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running docker prune job")
-	return nil
-}
+// Info beschreibt einen Job wie Describe, zusätzlich mit seiner Laufhistorie.
+func (s *Scheduler) Info(id string, n int) (*JobInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-func backupStateJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running backup state job")
-	return nil
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("job %s not found", id)
+	}
+	info := s.infoLocked(job, time.Now(), n)
+	if rec := s.records[id]; rec != nil {
+		info.History = append([]JobRun(nil), rec.Runs...)
+	}
+	return &info, nil
 }
 
-func webhookTestJob(ctx context.Context) error {
-	state.GlobalState.Log("INFO", "Running webhook test job")
-	return nil
+func (s *Scheduler) infoLocked(job *Job, now time.Time, n int) JobInfo {
+	info := JobInfo{
+		ID:          job.ID,
+		Name:        job.Name,
+		Schedule:    job.Schedule,
+		TimeZone:    job.TimeZone,
+		Concurrency: job.Concurrency,
+		CatchUp:     job.CatchUp,
+		Enabled:     job.Enabled,
+		Running:     len(s.inflight[job.ID]),
+		LastRun:     job.LastRun,
+		NextRun:     job.NextRun,
+		RunCount:    job.RunCount,
+		FailCount:   job.FailCount,
+		Tags:        job.Tags,
+		NextRuns:    NextRuns(job.schedule, now, n),
+	}
+	if job.Jitter > 0 {
+		info.Jitter = job.Jitter.String()
+	}
+	if rec := s.records[job.ID]; rec != nil && len(rec.Runs) > 0 {
+		last := rec.Runs[len(rec.Runs)-1]
+		info.LastResult = &last
+	}
+	return info
 }
 
-func Start() {
-	RegisterDefaultJobs()
+func Start(env *Env) {
+	RegisterDefaultJobs(env)
 	Sched.Start()
 }
 
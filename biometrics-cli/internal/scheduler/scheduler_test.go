@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// blockingJob läuft, bis release geschlossen oder sein Kontext beendet ist.
+func blockingJob(id string, policy ConcurrencyPolicy, started chan<- struct{}, release <-chan struct{}) *Job {
+	return &Job{ID: id, Name: id, Schedule: "@every 1h", Enabled: true, Concurrency: policy,
+		Handler: func(ctx context.Context) error {
+			started <- struct{}{}
+			select {
+			case <-release:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}}
+}
+
+func waitHistory(t *testing.T, s *Scheduler, id string, n int) []JobRun {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		runs, _ := s.History(id)
+		if len(runs) >= n {
+			return runs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s has %d runs, want %d", id, len(runs), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConcurrencyPolicies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("forbid", func(t *testing.T) {
+		s := New()
+		started, release := make(chan struct{}, 2), make(chan struct{})
+		job := blockingJob("f", ConcurrencyForbid, started, release)
+		s.Register(job)
+
+		s.launch(ctx, job, TriggerSchedule)
+		<-started
+		s.runJob(ctx, job, TriggerSchedule)
+		close(release)
+
+		runs := waitHistory(t, s, "f", 2)
+		if runs[0].Outcome != OutcomeSkipped || runs[1].Outcome != OutcomeSucceeded {
+			t.Errorf("outcomes = %s, %s; want skipped, succeeded", runs[0].Outcome, runs[1].Outcome)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		s := New()
+		started, release := make(chan struct{}, 2), make(chan struct{})
+		job := blockingJob("r", ConcurrencyReplace, started, release)
+		s.Register(job)
+
+		s.launch(ctx, job, TriggerSchedule)
+		<-started
+		s.launch(ctx, job, TriggerManual)
+		<-started
+		runs := waitHistory(t, s, "r", 1)
+		if runs[0].Outcome != OutcomeCancelled || runs[0].Trigger != TriggerSchedule {
+			t.Errorf("first run = %+v, want cancelled schedule run", runs[0])
+		}
+		close(release)
+		if runs := waitHistory(t, s, "r", 2); runs[1].Outcome != OutcomeSucceeded {
+			t.Errorf("replacement run = %+v", runs[1])
+		}
+	})
+
+	t.Run("allow", func(t *testing.T) {
+		s := New()
+		started, release := make(chan struct{}, 2), make(chan struct{})
+		job := blockingJob("a", ConcurrencyAllow, started, release)
+		s.Register(job)
+
+		s.launch(ctx, job, TriggerSchedule)
+		s.launch(ctx, job, TriggerSchedule)
+		<-started
+		<-started
+		if info, _ := s.Info("a", 0); info.Running != 2 {
+			t.Errorf("running = %d, want 2", info.Running)
+		}
+		close(release)
+		waitHistory(t, s, "a", 2)
+	})
+}
+
+func TestCatchUpPolicies(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		policy CatchUpPolicy
+		want   int
+	}{
+		{CatchUpSkip, 0},
+		{CatchUpOnce, 1},
+		{CatchUpAll, 3},
+	}
+	for _, c := range cases {
+		s := New()
+		ran := make(chan struct{}, 10)
+		job := &Job{ID: "c", Schedule: "@every 1h", Enabled: true, CatchUp: c.policy,
+			// zuletzt vor 3,5 Stunden: drei Läufe verpasst
+			LastRun: now.Add(-210 * time.Minute),
+			Handler: func(ctx context.Context) error {
+				ran <- struct{}{}
+				return nil
+			}}
+		s.Register(job)
+
+		s.catchUp(context.Background(), now)
+		s.wg.Wait()
+		if len(ran) != c.want {
+			t.Errorf("%s: %d catch-up runs, want %d", c.policy, len(ran), c.want)
+		}
+		if runs, _ := s.History("c"); c.want > 0 && runs[0].Trigger != TriggerCatchUp {
+			t.Errorf("%s: trigger = %q", c.policy, runs[0].Trigger)
+		}
+	}
+}
+
+func TestHistoryStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	handler := func(ctx context.Context) error { return nil }
+
+	s := New()
+	if err := s.UseStore(NewFileHistoryStore(path)); err != nil {
+		t.Fatal(err)
+	}
+	s.Register(&Job{ID: "keep", Schedule: "@daily", Enabled: true, Handler: handler})
+	s.Register(&Job{ID: "off", Schedule: "@daily", Enabled: true, Handler: handler})
+	job, _ := s.GetJob("keep")
+	s.runJob(context.Background(), job, TriggerManual)
+	if err := s.DisableJob("off"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neustart: Jobs werden frisch registriert, der Store liefert den Rest
+	restarted := New()
+	restarted.Register(&Job{ID: "keep", Schedule: "@daily", Enabled: true, Handler: handler})
+	restarted.Register(&Job{ID: "off", Schedule: "@daily", Enabled: true, Handler: handler})
+	if err := restarted.UseStore(NewFileHistoryStore(path)); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := restarted.Info("keep", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.History) != 1 || info.History[0].Outcome != OutcomeSucceeded || info.History[0].Finished.IsZero() {
+		t.Errorf("history after restart = %+v", info.History)
+	}
+	if !info.LastRun.Equal(job.LastRun) {
+		t.Errorf("last run = %s, want %s", info.LastRun, job.LastRun)
+	}
+	if off, _ := restarted.GetJob("off"); off.Enabled {
+		t.Error("disabled job is enabled again after restart")
+	}
+}
+
+func TestTriggerNeedsRunningScheduler(t *testing.T) {
+	s := New()
+	ran := make(chan struct{}, 1)
+	s.Register(&Job{ID: "t", Schedule: "@yearly", Handler: func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}})
+	if err := s.Trigger("t"); err == nil {
+		t.Fatal("Trigger on a stopped scheduler should fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Trigger("t") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("scheduler did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case <-ran:
+	case <-time.After(2 * time.Second):
+		t.Fatal("triggered job did not run, although disabled jobs may be triggered")
+	}
+	cancel()
+	<-done
+}
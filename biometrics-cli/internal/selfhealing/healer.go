@@ -98,7 +98,10 @@ func (s *SelfHealer) recoverOpenCode() error {
 }
 
 func (s *SelfHealer) checkDatabase() error {
-	_, err := os.Stat("/Users/jeremy/.sisyphus/biometrics.db")
+	if state.GlobalState.DBPath == "" {
+		return fmt.Errorf("database not initialized")
+	}
+	_, err := os.Stat(state.GlobalState.DBPath)
 	return err
 }
 
@@ -25,6 +25,7 @@ type Manager struct {
 	mu       sync.RWMutex
 	sessions map[string]*Session
 	active   map[string]*Session
+	path     string
 }
 
 var SessionManager = &Manager{
@@ -116,6 +117,53 @@ func (m *Manager) GetStats() map[string]interface{} {
 	}
 }
 
+// Expire beendet aktive Sessions, die älter als maxAge sind, und entfernt
+// beendete Sessions, die seit mehr als retention vorbei sind. Eine Dauer von 0
+// schaltet den jeweiligen Schritt ab.
+func (m *Manager) Expire(maxAge, retention time.Duration) (expired, removed int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if maxAge > 0 {
+		for id, session := range m.active {
+			if now.Sub(session.StartedAt) <= maxAge {
+				continue
+			}
+			session.Status = "expired"
+			session.EndedAt = now
+			delete(m.active, id)
+			expired++
+			metrics.SessionsEndedTotal.Inc()
+		}
+	}
+
+	if retention > 0 {
+		for id, session := range m.sessions {
+			if _, active := m.active[id]; active || session.EndedAt.IsZero() {
+				continue
+			}
+			if now.Sub(session.EndedAt) > retention {
+				delete(m.sessions, id)
+				removed++
+			}
+		}
+	}
+
+	if expired > 0 || removed > 0 {
+		state.GlobalState.Log("INFO", fmt.Sprintf("Expired %d session(s), removed %d", expired, removed))
+	}
+	return expired, removed
+}
+
+// SetPath legt fest, wo Save und Load die Sessions ablegen
+// (siehe workspace.Workspace.SessionsPath).
+func (m *Manager) SetPath(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.path = path
+}
+
 func (m *Manager) Save() error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -125,14 +173,20 @@ func (m *Manager) Save() error {
 		return err
 	}
 
-	return os.WriteFile("/Users/jeremy/.sisyphus/sessions.json", data, 0644)
+	if m.path == "" {
+		return fmt.Errorf("session storage path not set")
+	}
+	return os.WriteFile(m.path, data, 0644)
 }
 
 func (m *Manager) Load() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	data, err := os.ReadFile("/Users/jeremy/.sisyphus/sessions.json")
+	if m.path == "" {
+		return fmt.Errorf("session storage path not set")
+	}
+	data, err := os.ReadFile(m.path)
 	if err != nil {
 		return err
 	}
@@ -2,15 +2,18 @@ package skills
 
 import (
 	"biometrics-cli/internal/state"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
-	"time"
 )
 
 type AutoSkillBuilder struct {
-	patterns  []SkillPattern
-	newSkills []Skill
+	patterns    []SkillPattern
+	newSkills   []Skill
+	historyPath string
+	skillsPath  string
 }
 
 type SkillPattern struct {
@@ -27,10 +30,15 @@ type GeneratedSkill struct {
 	Confidence  float64
 }
 
-func NewAutoSkillBuilder() *AutoSkillBuilder {
+// NewAutoSkillBuilder liest die Prompt-History aus historyPath
+// (siehe workspace.Workspace.PromptHistoryPath) und speichert erzeugte
+// Skills nach skillsPath (siehe workspace.Workspace.GeneratedSkillsPath).
+func NewAutoSkillBuilder(historyPath, skillsPath string) *AutoSkillBuilder {
 	return &AutoSkillBuilder{
-		patterns:  make([]SkillPattern, 0),
-		newSkills: make([]Skill, 0),
+		patterns:    make([]SkillPattern, 0),
+		newSkills:   make([]Skill, 0),
+		historyPath: historyPath,
+		skillsPath:  skillsPath,
 	}
 }
 
@@ -54,7 +62,7 @@ func (a *AutoSkillBuilder) AnalyzePatterns() {
 func (a *AutoSkillBuilder) loadPromptHistory() []string {
 	var prompts []string
 
-	data, err := os.ReadFile("/Users/jeremy/.sisyphus/prompt_history.json")
+	data, err := os.ReadFile(a.historyPath)
 	if err != nil {
 		return prompts
 	}
@@ -182,27 +190,70 @@ func (a *AutoSkillBuilder) generateSkillsFromPatterns() []GeneratedSkill {
 	return generated
 }
 
+// persistGeneratedSkills schreibt die erzeugten Skills nach skillsPath,
+// LoadGeneratedSkills liest sie beim nächsten Start wieder ein.
 func (a *AutoSkillBuilder) persistGeneratedSkills() {
-	content := fmt.Sprintf("// Auto-generated skills - %s\npackage skills\n\nvar Registry = []Skill{\n", time.Now().Format("2006-01-02"))
+	skills, err := readGeneratedSkills(a.skillsPath)
+	if err != nil {
+		state.GlobalState.Log("WARN", fmt.Sprintf("Ignoring unreadable generated skills: %v", err))
+	}
+	for _, skill := range a.newSkills {
+		if !containsSkill(skills, skill.Name) {
+			skills = append(skills, skill)
+		}
+	}
 
-	for _, skill := range Registry {
-		content += fmt.Sprintf("\t{Name: \"%s\", Keywords: []string{", skill.Name)
-		for i, kw := range skill.Keywords {
-			if i > 0 {
-				content += ", "
-			}
-			content += fmt.Sprintf("\"%s\"", kw)
+	data, err := json.MarshalIndent(skills, "", "  ")
+	if err != nil {
+		state.GlobalState.Log("ERROR", fmt.Sprintf("Failed to encode generated skills: %v", err))
+		return
+	}
+	if err := os.WriteFile(a.skillsPath, data, 0644); err != nil {
+		state.GlobalState.Log("ERROR", fmt.Sprintf("Failed to persist generated skills: %v", err))
+	}
+}
+
+// LoadGeneratedSkills ergänzt Registry um die Skills aus path (siehe
+// workspace.Workspace.GeneratedSkillsPath). Eine fehlende Datei ist kein Fehler.
+func LoadGeneratedSkills(path string) error {
+	skills, err := readGeneratedSkills(path)
+	if err != nil {
+		return err
+	}
+	for _, skill := range skills {
+		if !containsSkill(Registry, skill.Name) {
+			Registry = append(Registry, skill)
 		}
-		content += fmt.Sprintf("}, Description: \"%s\"},\n", skill.Description)
 	}
+	return nil
+}
 
-	content += "}\n"
+func readGeneratedSkills(path string) ([]Skill, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var skills []Skill
+	if err := json.Unmarshal(data, &skills); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return skills, nil
+}
 
-	os.WriteFile("/Users/jeremy/dev/BIOMETRICS/biometrics-cli/internal/skills/registry.go", []byte(content), 0644)
+func containsSkill(skills []Skill, name string) bool {
+	for _, skill := range skills {
+		if skill.Name == name {
+			return true
+		}
+	}
+	return false
 }
 
-func SelfTrain() {
-	builder := NewAutoSkillBuilder()
+func SelfTrain(historyPath, skillsPath string) {
+	builder := NewAutoSkillBuilder(historyPath, skillsPath)
 	builder.AnalyzePatterns()
 	builder.GenerateNewSkills()
 }
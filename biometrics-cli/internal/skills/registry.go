@@ -5,9 +5,9 @@ import (
 )
 
 type Skill struct {
-	Name        string
-	Keywords    []string
-	Description string
+	Name        string   `json:"name"`
+	Keywords    []string `json:"keywords"`
+	Description string   `json:"description"`
 }
 
 var Registry = []Skill{
@@ -3,6 +3,8 @@ package state
 import (
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
@@ -16,6 +18,7 @@ type AppState struct {
 	ModelStatus  map[string]string
 	Logs         []string
 	DB           *sql.DB
+	DBPath       string
 	ChaosEnabled bool
 	ChaosActive  bool
 }
@@ -26,15 +29,23 @@ var GlobalState = &AppState{
 	ChaosEnabled: true,
 }
 
-func (s *AppState) InitDB() {
-	dbPath := "/Users/jeremy/.sisyphus/logs.db"
+// InitDB öffnet die Log-Datenbank unter dbPath (siehe workspace.Workspace.LogsDB).
+func (s *AppState) InitDB(dbPath string) error {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return err
+	}
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
-		return
+		return err
 	}
 	query := "CREATE TABLE IF NOT EXISTS logs (id INTEGER PRIMARY KEY AUTOINCREMENT, timestamp TEXT, level TEXT, agent TEXT, plan TEXT, message TEXT)"
-	_, _ = db.Exec(query)
+	if _, err := db.Exec(query); err != nil {
+		db.Close()
+		return err
+	}
 	s.DB = db
+	s.DBPath = dbPath
+	return nil
 }
 
 func (s *AppState) Log(level, msg string) {
@@ -51,6 +62,24 @@ func (s *AppState) Log(level, msg string) {
 	}
 }
 
+// SetModelStatus ersetzt den Modellstatus, den das Dashboard anzeigt.
+func (s *AppState) SetModelStatus(status map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ModelStatus = status
+}
+
+// GetModelStatus liefert eine Kopie des Modellstatus.
+func (s *AppState) GetModelStatus() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]string, len(s.ModelStatus))
+	for k, v := range s.ModelStatus {
+		out[k] = v
+	}
+	return out
+}
+
 func (s *AppState) SetChaos(active bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
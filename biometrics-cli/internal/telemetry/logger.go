@@ -2,16 +2,22 @@ package telemetry
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"os"
 )
 
 // SetupLogger erzwingt JSON-Logging für maschinelle Auswertung
 func SetupLogger() *slog.Logger {
+	return SetupLoggerTo(os.Stdout)
+}
+
+// SetupLoggerTo ist SetupLogger mit eigenem Ziel, etwa einer Logdatei
+func SetupLoggerTo(w io.Writer) *slog.Logger {
 	opts := &slog.HandlerOptions{
 		Level: slog.LevelDebug,
 	}
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+	handler := slog.NewJSONHandler(w, opts)
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 	return logger
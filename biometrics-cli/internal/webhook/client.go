@@ -1,19 +1,25 @@
 package webhook
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 )
 
+// WebhookEvent ist ein ausgehendes Ereignis. Verschickt wird es als
+// Envelope; ID und Time setzt Publish bzw. der erste Zustellversuch.
 type WebhookEvent struct {
+	ID       string                 `json:"id,omitempty"`
 	Type     string                 `json:"type"`
 	Source   string                 `json:"source"`
 	Agent    string                 `json:"agent"`
+	Project  string                 `json:"project,omitempty"`
 	Data     map[string]interface{} `json:"data"`
 	Metadata map[string]string      `json:"metadata"`
 	Time     time.Time              `json:"time"`
@@ -39,85 +45,104 @@ func NewWebhookClient(url, secret string) *WebhookClient {
 	}
 }
 
+// Send stellt event direkt zu und versucht es bis zu Retries-mal mit
+// exponentiellem Backoff. Für Zustellungen, die einen Neustart überstehen
+// sollen, gibt es die Outbox.
 func (c *WebhookClient) Send(event *WebhookEvent) error {
-	event.Time = time.Now()
+	var lastErr error
+	for i := 0; i < c.Retries; i++ {
+		if i > 0 {
+			time.Sleep(backoff(i, time.Second, 30*time.Second))
+		}
+		lastErr = c.Deliver(context.Background(), event)
+		if lastErr == nil || permanent(lastErr) {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// Deliver unternimmt genau einen Zustellversuch. Jeder Versuch baut einen
+// eigenen Request, weil ein gelesener Body nicht wiederverwendet werden kann.
+func (c *WebhookClient) Deliver(ctx context.Context, event *WebhookEvent) error {
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
 
 	payload, err := encodeEvent(event)
 	if err != nil {
 		return fmt.Errorf("failed to encode event: %w", err)
 	}
 
-	signature := c.sign(payload)
-
-	req, err := http.NewRequest("POST", c.URL, strings.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.URL, bytes.NewReader(payload))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
-
+	// Signiert wird der Zeitpunkt des Versuchs, nicht der des Ereignisses,
+	// sonst fiele eine späte Wiederholung aus dem Toleranzfenster
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Webhook-Signature", signature)
-	req.Header.Set("X-Webhook-Timestamp", fmt.Sprintf("%d", event.Time.Unix()))
+	req.Header.Set(HeaderID, event.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(payload, timestamp, c.Secret))
 
-	var lastErr error
-	for i := 0; i < c.Retries; i++ {
-		resp, err := c.Client.Do(req)
-		if err != nil {
-			lastErr = err
-			time.Sleep(time.Duration(i+1) * time.Second)
-			continue
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return nil
-		}
-
-		lastErr = fmt.Errorf("webhook returned status %d", resp.StatusCode)
-		time.Sleep(time.Duration(i+1) * time.Second)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
 	}
+	// Body leeren, damit die Verbindung wiederverwendet werden kann
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
 
-	return lastErr
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return &StatusError{Code: resp.StatusCode}
 }
 
-func (c *WebhookClient) sign(payload string) string {
-	mac := hmac.New(sha256.New, []byte(c.Secret))
-	mac.Write([]byte(payload))
-	return hex.EncodeToString(mac.Sum(nil))
+// StatusError ist die Antwort eines Empfängers außerhalb von 2xx.
+type StatusError struct {
+	Code int
 }
 
-func encodeEvent(event *WebhookEvent) (string, error) {
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf(`{"type":"%s","source":"%s","agent":"%s","data":`,
-		event.Type, event.Source, event.Agent))
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook returned status %d", e.Code)
+}
 
-	dataStr := "{"
-	first := true
-	for k, v := range event.Data {
-		if !first {
-			dataStr += ","
-		}
-		dataStr += fmt.Sprintf(`"%v":"%v"`, k, v)
-		first = false
+// permanent ist true für Fehler, die eine Wiederholung nicht behebt: 4xx
+// außer 408 (Timeout) und 429 (Rate Limit).
+func permanent(err error) bool {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return false
 	}
-	dataStr += "}"
-	sb.WriteString(dataStr)
-	sb.WriteString(fmt.Sprintf(`,"metadata":{},"time":"%s"}`, event.Time.Format(time.RFC3339)))
-
-	return sb.String(), nil
+	return se.Code >= 400 && se.Code < 500 && se.Code != http.StatusRequestTimeout && se.Code != http.StatusTooManyRequests
 }
 
-func VerifySignature(payload, signature, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(payload))
-	expected := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(expected), []byte(signature))
+// backoff ist die Wartezeit vor Versuch attempt+1: base verdoppelt sich je
+// Versuch bis max, davon wird zufällig bis zur Hälfte abgezogen, damit
+// Empfänger nach einem Ausfall nicht von allen Wiederholungen gleichzeitig
+// getroffen werden.
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d - time.Duration(rand.Int63n(int64(d)/2+1))
 }
 
-func SendTaskStarted(taskID, agent string) error {
+func SendTaskStarted(project, taskID, agent string) error {
 	event := &WebhookEvent{
-		Type:   "task.started",
-		Source: "biometrics",
-		Agent:  agent,
+		Type:    "task.started",
+		Source:  "biometrics",
+		Agent:   agent,
+		Project: project,
 		Data: map[string]interface{}{
 			"task_id": taskID,
 		},
@@ -125,11 +150,12 @@ func SendTaskStarted(taskID, agent string) error {
 	return sendWebhookEvent(event)
 }
 
-func SendTaskCompleted(taskID, agent, result string) error {
+func SendTaskCompleted(project, taskID, agent, result string) error {
 	event := &WebhookEvent{
-		Type:   "task.completed",
-		Source: "biometrics",
-		Agent:  agent,
+		Type:    "task.completed",
+		Source:  "biometrics",
+		Agent:   agent,
+		Project: project,
 		Data: map[string]interface{}{
 			"task_id": taskID,
 			"result":  result,
@@ -138,11 +164,12 @@ func SendTaskCompleted(taskID, agent, result string) error {
 	return sendWebhookEvent(event)
 }
 
-func SendTaskFailed(taskID, agent, error string) error {
+func SendTaskFailed(project, taskID, agent, error string) error {
 	event := &WebhookEvent{
-		Type:   "task.failed",
-		Source: "biometrics",
-		Agent:  agent,
+		Type:    "task.failed",
+		Source:  "biometrics",
+		Agent:   agent,
+		Project: project,
 		Data: map[string]interface{}{
 			"task_id": taskID,
 			"error":   error,
@@ -151,6 +178,16 @@ func SendTaskFailed(taskID, agent, error string) error {
 	return sendWebhookEvent(event)
 }
 
+func SendPlanCompleted(project string) error {
+	event := &WebhookEvent{
+		Type:    "plan.completed",
+		Source:  "biometrics",
+		Project: project,
+		Data:    map[string]interface{}{},
+	}
+	return sendWebhookEvent(event)
+}
+
 func SendAgentStarted(agent, model string) error {
 	event := &WebhookEvent{
 		Type:   "agent.started",
@@ -173,20 +210,17 @@ func SendAgentStopped(agent string) error {
 	return sendWebhookEvent(event)
 }
 
+// sendWebhookEvent legt event in die Standard-Outbox, die es an alle
+// passenden Subscriptions verteilt. Ohne Outbox geht es direkt an
+// BIOMETRICS_WEBHOOK_URL.
 func sendWebhookEvent(event *WebhookEvent) error {
-	webhookURL := getWebhookURL()
-	if webhookURL == "" {
-		return nil
+	if outbox := getDefaultOutbox(); outbox != nil {
+		return outbox.Publish(event)
 	}
 
-	client := NewWebhookClient(webhookURL, getWebhookSecret())
+	client := ClientFromEnv()
+	if client == nil {
+		return nil
+	}
 	return client.Send(event)
 }
-
-func getWebhookURL() string {
-	return ""
-}
-
-func getWebhookSecret() string {
-	return ""
-}
@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnvelopeVersion ist die Version des Schemas, in dem Ereignisse verschickt
+// werden. Empfänger sollten unbekannte Versionen ablehnen.
+const EnvelopeVersion = 1
+
+// Header einer Zustellung
+const (
+	HeaderID        = "X-Webhook-ID"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// signaturePrefix kennzeichnet das Signaturverfahren: HMAC-SHA256 über
+// "<timestamp>.<body>".
+const signaturePrefix = "v1="
+
+// DefaultTolerance ist die größte Abweichung zwischen dem signierten
+// Zeitstempel und der Uhr des Empfängers.
+const DefaultTolerance = 5 * time.Minute
+
+// Fehler der Signaturprüfung
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside tolerance")
+)
+
+// Envelope ist der Body jeder Zustellung. ID bleibt über Wiederholungen und
+// Subscriptions hinweg gleich, Empfänger können damit Duplikate erkennen.
+type Envelope struct {
+	Version  int                    `json:"version"`
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	Source   string                 `json:"source"`
+	Agent    string                 `json:"agent,omitempty"`
+	Project  string                 `json:"project,omitempty"`
+	Time     time.Time              `json:"time"`
+	Data     map[string]interface{} `json:"data"`
+	Metadata map[string]string      `json:"metadata,omitempty"`
+}
+
+// newEventID erzeugt die ID eines Ereignisses.
+func newEventID() string {
+	return "evt_" + uuid.NewString()
+}
+
+// encodeEvent serialisiert event als Envelope.
+func encodeEvent(event *WebhookEvent) ([]byte, error) {
+	data := event.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return json.Marshal(Envelope{
+		Version:  EnvelopeVersion,
+		ID:       event.ID,
+		Type:     event.Type,
+		Source:   event.Source,
+		Agent:    event.Agent,
+		Project:  event.Project,
+		Time:     event.Time.UTC(),
+		Data:     data,
+		Metadata: event.Metadata,
+	})
+}
+
+// Sign liefert den Wert für HeaderSignature.
+func Sign(payload []byte, timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature prüft, dass signature zu timestamp und payload passt und
+// timestamp höchstens tolerance von now abweicht. So lässt sich eine
+// mitgeschnittene Zustellung nicht später erneut einspielen.
+func VerifySignature(payload []byte, timestamp, signature, secret string, tolerance time.Duration, now time.Time) error {
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp %q", ErrInvalidSignature, timestamp)
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return fmt.Errorf("%w: unsupported scheme", ErrInvalidSignature)
+	}
+	expected := Sign(payload, timestamp, secret)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	skew := now.Sub(time.Unix(sec, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > tolerance {
+		return fmt.Errorf("%w: signed %s ago", ErrStaleTimestamp, now.Sub(time.Unix(sec, 0)).Round(time.Second))
+	}
+	return nil
+}
+
+// VerifyRequest liest den Body einer Zustellung, prüft die Signatur mit
+// DefaultTolerance und dekodiert den Envelope.
+func VerifyRequest(r *http.Request, secret string) (*Envelope, error) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook body: %w", err)
+	}
+	err = VerifySignature(payload, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), secret, DefaultTolerance, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook envelope: %w", err)
+	}
+	if env.Version != EnvelopeVersion {
+		return nil, fmt.Errorf("unsupported webhook envelope version %d", env.Version)
+	}
+	return &env, nil
+}
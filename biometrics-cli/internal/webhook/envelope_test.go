@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEncodeEventEscapesAndKeepsTypes(t *testing.T) {
+	event := &WebhookEvent{
+		ID:       "evt_1",
+		Type:     "task.completed",
+		Source:   "biometrics",
+		Time:     time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		Metadata: map[string]string{"run": "7"},
+		Data: map[string]interface{}{
+			"result":   "said \"done\"\nand left",
+			"attempts": 3,
+			"passed":   true,
+		},
+	}
+	payload, err := encodeEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Über den Empfängerpfad dekodieren, wie es ein Abonnent täte
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(payload, ts, "secret"))
+	env, err := VerifyRequest(req, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Version != EnvelopeVersion || env.ID != "evt_1" || env.Metadata["run"] != "7" {
+		t.Errorf("envelope = %+v", env)
+	}
+	if env.Data["result"] != "said \"done\"\nand left" || env.Data["attempts"] != float64(3) || env.Data["passed"] != true {
+		t.Errorf("data = %#v", env.Data)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"version":1}`)
+	now := time.Unix(1_800_000_000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := Sign(payload, ts, "secret")
+
+	if err := VerifySignature(payload, ts, sig, "secret", time.Minute, now.Add(30*time.Second)); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+	if err := VerifySignature([]byte(`{"version":2}`), ts, sig, "secret", time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered body: err = %v", err)
+	}
+	if err := VerifySignature(payload, ts, sig, "other", time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("wrong secret: err = %v", err)
+	}
+	// Die Signatur deckt den Zeitstempel ab: ein aufgefrischter Header hilft nicht
+	fresh := strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+	if err := VerifySignature(payload, fresh, sig, "secret", time.Minute, now.Add(time.Hour)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("replaced timestamp: err = %v", err)
+	}
+	if err := VerifySignature(payload, ts, sig, "secret", time.Minute, now.Add(2*time.Minute)); !errors.Is(err, ErrStaleTimestamp) {
+		t.Errorf("replayed delivery: err = %v", err)
+	}
+}
+
+func TestDeliverKeepsEventIDAcrossRetries(t *testing.T) {
+	var ids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		env, err := VerifyRequest(r, "secret")
+		if err != nil {
+			t.Errorf("VerifyRequest: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(HeaderID) != env.ID {
+			t.Errorf("header ID %q != envelope ID %q", r.Header.Get(HeaderID), env.ID)
+		}
+		ids = append(ids, env.ID)
+		if len(ids) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewWebhookClient(srv.URL, "secret")
+	event := &WebhookEvent{Type: "agent.started"}
+	if err := client.Deliver(context.Background(), event); err == nil {
+		t.Fatal("first attempt should fail")
+	}
+	if err := client.Deliver(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] == "" || ids[0] != ids[1] {
+		t.Errorf("ids = %v, want one stable ID", ids)
+	}
+}
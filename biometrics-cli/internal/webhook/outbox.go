@@ -0,0 +1,285 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Vorgaben der Outbox
+const (
+	DefaultMaxAttempts    = 10
+	DefaultBaseDelay      = 5 * time.Second
+	DefaultMaxDelay       = time.Hour
+	DefaultOutboxWorkers  = 2
+	DefaultOutboxInterval = 5 * time.Second
+)
+
+// Outbox stellt Ereignisse zuverlässig zu: Publish schreibt für jeden
+// passenden Empfänger einen Eintrag in den Store, Worker stellen sie danach
+// mit exponentiellem Backoff und Jitter zu. Nach MaxAttempts Fehlversuchen
+// oder einer endgültigen Ablehnung landet ein Eintrag im Dead-Letter-Speicher,
+// aus dem er erneut eingespielt werden kann.
+type Outbox struct {
+	store         OutboxStore
+	subscriptions *Registry
+	client        *WebhookClient
+	logger        *slog.Logger
+
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Workers     int
+	// PollInterval ist der Abstand, in dem Worker nach fälligen
+	// Wiederholungen schauen; Publish weckt sie sofort
+	PollInterval time.Duration
+
+	wake chan struct{}
+	now  func() time.Time
+}
+
+// NewOutbox stellt an die passenden Subscriptions aus subscriptions und
+// zusätzlich an client zu; beide dürfen nil sein.
+func NewOutbox(store OutboxStore, subscriptions *Registry, client *WebhookClient, logger *slog.Logger) *Outbox {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Outbox{
+		store:         store,
+		subscriptions: subscriptions,
+		client:        client,
+		logger:        logger,
+		MaxAttempts:   DefaultMaxAttempts,
+		BaseDelay:     DefaultBaseDelay,
+		MaxDelay:      DefaultMaxDelay,
+		Workers:       DefaultOutboxWorkers,
+		PollInterval:  DefaultOutboxInterval,
+		wake:          make(chan struct{}, 1),
+		now:           time.Now,
+	}
+}
+
+// OpenOutbox öffnet den SQLite-Store unter path.
+func OpenOutbox(path string, subscriptions *Registry, client *WebhookClient, logger *slog.Logger) (*Outbox, error) {
+	store, err := OpenSQLiteOutboxStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewOutbox(store, subscriptions, client, logger), nil
+}
+
+// ClientFromEnv liefert einen Client für BIOMETRICS_WEBHOOK_URL und
+// BIOMETRICS_WEBHOOK_SECRET oder nil, wenn keine URL gesetzt ist.
+func ClientFromEnv() *WebhookClient {
+	url := os.Getenv("BIOMETRICS_WEBHOOK_URL")
+	if url == "" {
+		return nil
+	}
+	return NewWebhookClient(url, os.Getenv("BIOMETRICS_WEBHOOK_SECRET"))
+}
+
+// Store liefert den Speicher der Outbox, etwa für die Dead-Letter-Verwaltung.
+func (o *Outbox) Store() OutboxStore {
+	return o.store
+}
+
+// Close schließt den Store.
+func (o *Outbox) Close() error {
+	return o.store.Close()
+}
+
+// Publish schreibt event für jeden Empfänger in die Outbox. Alle Zustellungen
+// tragen dieselbe Ereignis-ID. Kehrt Publish ohne Fehler zurück, übersteht
+// das Ereignis auch einen Absturz.
+func (o *Outbox) Publish(event *WebhookEvent) error {
+	var targets []string
+	if o.subscriptions != nil {
+		subs, err := o.subscriptions.Matching(event)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subs {
+			targets = append(targets, sub.ID)
+		}
+	}
+	if o.client != nil {
+		targets = append(targets, "")
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	now := o.now()
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Time.IsZero() {
+		event.Time = now
+	}
+	for _, target := range targets {
+		entry := &OutboxEntry{
+			ID:           "dlv_" + uuid.NewString(),
+			Subscription: target,
+			Event:        event,
+			NextAttempt:  now,
+			CreatedAt:    now,
+		}
+		if err := o.store.Add(entry); err != nil {
+			return err
+		}
+	}
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run startet die Worker und kehrt zurück, wenn ctx endet und alle laufenden
+// Zustellungen abgeschlossen sind.
+func (o *Outbox) Run(ctx context.Context) {
+	workers := o.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (o *Outbox) work(ctx context.Context) {
+	ticker := time.NewTicker(o.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := o.DeliverDue(ctx); err != nil {
+			o.logger.Warn("Webhook outbox failed", slog.String("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue stellt fällige Einträge zu, bis keiner mehr fällig ist, und
+// liefert die Zahl der Zustellversuche.
+func (o *Outbox) DeliverDue(ctx context.Context) (int, error) {
+	n := 0
+	for ctx.Err() == nil {
+		entry, err := o.store.Claim(o.now(), o.lease())
+		if err != nil {
+			return n, err
+		}
+		if entry == nil {
+			return n, nil
+		}
+		n++
+		if err := o.deliver(ctx, entry); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// lease hält einen beanspruchten Eintrag länger fest, als ein Versuch dauern
+// kann; stirbt der Worker, wird er danach erneut fällig.
+func (o *Outbox) lease() time.Duration {
+	if o.client != nil && o.client.Client != nil && o.client.Client.Timeout > 0 {
+		return 2 * o.client.Client.Timeout
+	}
+	return time.Minute
+}
+
+// clientFor liefert den Client für den Empfänger des Eintrags. Ist die
+// Subscription gelöscht oder abgeschaltet, ist der Fehler endgültig.
+func (o *Outbox) clientFor(entry *OutboxEntry) (*WebhookClient, error) {
+	if entry.Subscription == "" {
+		if o.client == nil {
+			return nil, errors.New("no webhook endpoint configured")
+		}
+		return o.client, nil
+	}
+	if o.subscriptions == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSubscription, entry.Subscription)
+	}
+	sub, err := o.subscriptions.Get(entry.Subscription)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.Enabled {
+		return nil, fmt.Errorf("webhook subscription %s is disabled", sub.ID)
+	}
+	return NewWebhookClient(sub.URL, sub.Secret), nil
+}
+
+func (o *Outbox) deliver(ctx context.Context, entry *OutboxEntry) error {
+	client, sendErr := o.clientFor(entry)
+	if sendErr != nil {
+		// Ohne Empfänger helfen keine Wiederholungen
+		entry.Attempts++
+		return o.bury(entry, sendErr)
+	}
+	sendErr = client.Deliver(ctx, entry.Event)
+	if sendErr == nil {
+		return o.store.Delete(entry.ID)
+	}
+	if ctx.Err() != nil {
+		// Beim Herunterfahren abgebrochen: zählt nicht als Versuch, der
+		// Eintrag wird nach Ablauf der Lease erneut zugestellt
+		return nil
+	}
+
+	entry.Attempts++
+	if entry.Attempts >= o.MaxAttempts || permanent(sendErr) {
+		return o.bury(entry, sendErr)
+	}
+	entry.LastError = sendErr.Error()
+	entry.NextAttempt = o.now().Add(backoff(entry.Attempts, o.BaseDelay, o.MaxDelay))
+	return o.store.Reschedule(entry)
+}
+
+func (o *Outbox) bury(entry *OutboxEntry, cause error) error {
+	entry.LastError = cause.Error()
+	entry.DeadAt = o.now()
+	o.logger.Warn("Webhook moved to dead letters",
+		slog.String("id", entry.ID),
+		slog.String("subscription", entry.Subscription),
+		slog.String("type", entry.Event.Type),
+		slog.Int("attempts", entry.Attempts),
+		slog.String("error", entry.LastError),
+	)
+	return o.store.Bury(entry)
+}
+
+var (
+	defaultOutboxMu sync.RWMutex
+	defaultOutbox   *Outbox
+)
+
+// SetDefaultOutbox leitet die Send*-Helfer über o. Mit nil stellen sie
+// wieder direkt zu.
+func SetDefaultOutbox(o *Outbox) {
+	defaultOutboxMu.Lock()
+	defaultOutbox = o
+	defaultOutboxMu.Unlock()
+}
+
+func getDefaultOutbox() *Outbox {
+	defaultOutboxMu.RLock()
+	defer defaultOutboxMu.RUnlock()
+	return defaultOutbox
+}
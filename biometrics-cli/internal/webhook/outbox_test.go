@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// receiver antwortet der Reihe nach mit statuses, danach mit 200, und merkt
+// sich jeden empfangenen Body.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.bodies = append(rc.bodies, string(body))
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newTestOutbox(t *testing.T, url string) (*Outbox, *time.Time) {
+	t.Helper()
+	store, err := OpenSQLiteOutboxStore(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	o := NewOutbox(store, nil, NewWebhookClient(url, "secret"), nil)
+	o.now = func() time.Time { return clock }
+	return o, &clock
+}
+
+func TestOutboxRetriesUntilDelivered(t *testing.T) {
+	rc := &receiver{statuses: []int{500, 503}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	o, clock := newTestOutbox(t, srv.URL)
+
+	if err := o.Publish(&WebhookEvent{Type: "task.completed", Data: map[string]interface{}{"task_id": "t1"}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if n, err := o.DeliverDue(context.Background()); n != 1 || err != nil {
+			t.Fatalf("pass %d: DeliverDue = %d, %v", i, n, err)
+		}
+		// Vor Ablauf des Backoffs ist nichts fällig
+		if n, _ := o.DeliverDue(context.Background()); n != 0 {
+			t.Fatalf("pass %d: retried before the backoff elapsed", i)
+		}
+		*clock = clock.Add(o.MaxDelay)
+	}
+
+	if pending, _ := o.Store().Pending(); pending != 0 {
+		t.Errorf("pending = %d after delivery", pending)
+	}
+	if len(rc.bodies) != 3 || rc.bodies[2] == "" || rc.bodies[2] != rc.bodies[0] {
+		t.Errorf("bodies = %q, want the same payload on every attempt", rc.bodies)
+	}
+}
+
+func TestOutboxDeadLettersAndReplay(t *testing.T) {
+	rc := &receiver{statuses: []int{500, 500, http.StatusBadRequest}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	o, clock := newTestOutbox(t, srv.URL)
+	o.MaxAttempts = 2
+
+	o.Publish(&WebhookEvent{Type: "task.failed"})
+	for i := 0; i < 2; i++ {
+		o.DeliverDue(context.Background())
+		*clock = clock.Add(o.MaxDelay)
+	}
+	// Eine 4xx-Antwort ist endgültig und braucht keine weiteren Versuche
+	o.Publish(&WebhookEvent{Type: "agent.stopped"})
+	o.DeliverDue(context.Background())
+
+	dead, err := o.Store().DeadLetters()
+	if err != nil || len(dead) != 2 {
+		t.Fatalf("DeadLetters = %v, %v", dead, err)
+	}
+	if dead[0].Attempts != 2 || dead[1].Attempts != 1 || dead[1].LastError == "" {
+		t.Errorf("dead letters = %+v, %+v", dead[0], dead[1])
+	}
+	if pending, _ := o.Store().Pending(); pending != 0 {
+		t.Errorf("pending = %d, want 0", pending)
+	}
+
+	if _, err := o.Store().Replay("dlv_missing", *clock); err == nil {
+		t.Error("Replay of an unknown ID succeeded")
+	}
+	if n, err := o.Store().Replay(dead[0].ID, *clock); n != 1 || err != nil {
+		t.Fatalf("Replay = %d, %v", n, err)
+	}
+	if n, _ := o.DeliverDue(context.Background()); n != 1 {
+		t.Fatalf("replayed entry was not delivered")
+	}
+	if n, err := o.Store().Purge(""); n != 1 || err != nil {
+		t.Errorf("Purge = %d, %v", n, err)
+	}
+	if dead, _ := o.Store().DeadLetters(); len(dead) != 0 {
+		t.Errorf("dead letters left after purge: %v", dead)
+	}
+}
+
+func TestSendSendsFullBodyOnRetry(t *testing.T) {
+	rc := &receiver{statuses: []int{500}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	client := NewWebhookClient(srv.URL, "secret")
+	client.Retries = 2
+	if err := client.Send(&WebhookEvent{Type: "health.check"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.bodies) != 2 || rc.bodies[1] == "" {
+		t.Errorf("bodies = %q, want the payload on the retry", rc.bodies)
+	}
+}
+
+func TestBackoffGrowsWithJitter(t *testing.T) {
+	for attempt := 1; attempt <= 8; attempt++ {
+		want := time.Second << (attempt - 1)
+		if want > time.Minute {
+			want = time.Minute
+		}
+		d := backoff(attempt, time.Second, time.Minute)
+		if d < want/2 || d > want {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, d, want/2, want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OutboxEntry ist ein Ereignis, das noch zugestellt werden muss oder nach zu
+// vielen Versuchen aufgegeben wurde.
+type OutboxEntry struct {
+	ID string `json:"id"`
+	// Subscription ist der Empfänger; leer heißt der Client aus
+	// BIOMETRICS_WEBHOOK_URL
+	Subscription string        `json:"subscription,omitempty"`
+	Event        *WebhookEvent `json:"event"`
+	Attempts     int           `json:"attempts"`
+	NextAttempt  time.Time     `json:"next_attempt"`
+	LastError    string        `json:"last_error,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	// DeadAt ist gesetzt, sobald der Eintrag im Dead-Letter-Speicher liegt
+	DeadAt time.Time `json:"dead_at,omitempty"`
+}
+
+// OutboxStore speichert ausstehende Zustellungen und Dead Letters.
+type OutboxStore interface {
+	Add(entry *OutboxEntry) error
+	// Claim liefert den am längsten fälligen Eintrag und schiebt seine
+	// nächste Zustellung um lease hinaus, damit kein anderer Worker ihn
+	// gleichzeitig zustellt. Ohne fälligen Eintrag ist das Ergebnis nil.
+	Claim(now time.Time, lease time.Duration) (*OutboxEntry, error)
+	// Delete entfernt einen zugestellten Eintrag.
+	Delete(id string) error
+	// Reschedule speichert Attempts, NextAttempt und LastError.
+	Reschedule(entry *OutboxEntry) error
+	// Bury verschiebt den Eintrag in den Dead-Letter-Speicher.
+	Bury(entry *OutboxEntry) error
+	DeadLetters() ([]*OutboxEntry, error)
+	// Replay stellt Dead Letters mit zurückgesetzten Versuchen wieder in die
+	// Outbox; id "" betrifft alle.
+	Replay(id string, now time.Time) (int, error)
+	// Purge löscht Dead Letters endgültig; id "" betrifft alle.
+	Purge(id string) (int, error)
+	// Pending zählt die ausstehenden Zustellungen.
+	Pending() (int, error)
+	Close() error
+}
+
+// ErrNotFound meldet einen unbekannten Dead Letter.
+var ErrNotFound = errors.New("dead letter not found")
+
+// SQLiteOutboxStore hält die Outbox in den Tabellen webhook_outbox und
+// webhook_dead_letters. Wie beim Journal ist data der vollständige Eintrag,
+// die übrigen Spalten dienen nur der Abfrage.
+type SQLiteOutboxStore struct {
+	db *sql.DB
+	// owned: Close schließt die Datenbank nur, wenn sie hier geöffnet wurde
+	owned bool
+}
+
+// OpenSQLiteOutboxStore öffnet die Datenbank unter path. Schreibende
+// Transaktionen sperren sofort, damit mehrere Prozesse denselben Eintrag
+// nicht doppelt beanspruchen.
+func OpenSQLiteOutboxStore(path string) (*SQLiteOutboxStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox dir: %w", err)
+	}
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_synchronous=FULL&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox database: %w", err)
+	}
+	s, err := NewSQLiteOutboxStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.owned = true
+	return s, nil
+}
+
+// NewSQLiteOutboxStore legt die Tabellen der Outbox in db an.
+func NewSQLiteOutboxStore(db *sql.DB) (*SQLiteOutboxStore, error) {
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS webhook_outbox (
+			id TEXT PRIMARY KEY,
+			next_attempt INTEGER NOT NULL,
+			data TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS webhook_outbox_due ON webhook_outbox (next_attempt)`,
+		`CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+			id TEXT PRIMARY KEY,
+			dead_at INTEGER NOT NULL,
+			data TEXT NOT NULL
+		)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("failed to create outbox schema: %w", err)
+		}
+	}
+	return &SQLiteOutboxStore{db: db}, nil
+}
+
+func (s *SQLiteOutboxStore) Add(entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO webhook_outbox (id, next_attempt, data) VALUES (?, ?, ?)`,
+		entry.ID, entry.NextAttempt.UnixNano(), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to add outbox entry: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteOutboxStore) Claim(now time.Time, lease time.Duration) (*OutboxEntry, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id, data string
+	err = tx.QueryRow(`SELECT id, data FROM webhook_outbox WHERE next_attempt <= ? ORDER BY next_attempt LIMIT 1`,
+		now.UnixNano()).Scan(&id, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+
+	var entry OutboxEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return nil, fmt.Errorf("outbox entry %s: %w", id, err)
+	}
+	if _, err := tx.Exec(`UPDATE webhook_outbox SET next_attempt = ? WHERE id = ?`, now.Add(lease).UnixNano(), id); err != nil {
+		return nil, fmt.Errorf("failed to claim outbox entry: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit claim: %w", err)
+	}
+	return &entry, nil
+}
+
+func (s *SQLiteOutboxStore) Delete(id string) error {
+	if _, err := s.db.Exec(`DELETE FROM webhook_outbox WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete outbox entry: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteOutboxStore) Reschedule(entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+	_, err = s.db.Exec(`UPDATE webhook_outbox SET next_attempt = ?, data = ? WHERE id = ?`,
+		entry.NextAttempt.UnixNano(), string(data), entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule outbox entry: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteOutboxStore) Bury(entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO webhook_dead_letters (id, dead_at, data) VALUES (?, ?, ?)`,
+		entry.ID, entry.DeadAt.UnixNano(), string(data)); err != nil {
+		return fmt.Errorf("failed to store dead letter: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_outbox WHERE id = ?`, entry.ID); err != nil {
+		return fmt.Errorf("failed to delete outbox entry: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit dead letter: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteOutboxStore) DeadLetters() ([]*OutboxEntry, error) {
+	rows, err := s.db.Query(`SELECT id, data FROM webhook_dead_letters ORDER BY dead_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*OutboxEntry
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		var entry OutboxEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("dead letter %s: %w", id, err)
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLiteOutboxStore) Replay(id string, now time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query, args := `SELECT id, data FROM webhook_dead_letters`, []interface{}{}
+	if id != "" {
+		query, args = query+` WHERE id = ?`, append(args, id)
+	}
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	var entries []*OutboxEntry
+	for rows.Next() {
+		var entryID, data string
+		if err := rows.Scan(&entryID, &data); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		var entry OutboxEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("dead letter %s: %w", entryID, err)
+		}
+		entries = append(entries, &entry)
+	}
+	rows.Close()
+	if id != "" && len(entries) == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	for _, entry := range entries {
+		entry.Attempts = 0
+		entry.NextAttempt = now
+		entry.DeadAt = time.Time{}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal outbox entry: %w", err)
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO webhook_outbox (id, next_attempt, data) VALUES (?, ?, ?)`,
+			entry.ID, entry.NextAttempt.UnixNano(), string(data)); err != nil {
+			return 0, fmt.Errorf("failed to requeue dead letter: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM webhook_dead_letters WHERE id = ?`, entry.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete dead letter: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit replay: %w", err)
+	}
+	return len(entries), nil
+}
+
+func (s *SQLiteOutboxStore) Purge(id string) (int, error) {
+	query, args := `DELETE FROM webhook_dead_letters`, []interface{}{}
+	if id != "" {
+		query, args = query+` WHERE id = ?`, append(args, id)
+	}
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead letters: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	if id != "" && n == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return int(n), nil
+}
+
+func (s *SQLiteOutboxStore) Pending() (int, error) {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM webhook_outbox`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count outbox entries: %w", err)
+	}
+	return n, nil
+}
+
+func (s *SQLiteOutboxStore) Close() error {
+	if !s.owned {
+		return nil
+	}
+	return s.db.Close()
+}
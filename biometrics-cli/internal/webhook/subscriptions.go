@@ -0,0 +1,326 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Subscription ist ein Empfänger von Webhooks mit eigenem Secret und Filtern.
+type Subscription struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+	// Events sind Muster für den Ereignistyp wie "task.*" oder
+	// "plan.completed"; leer heißt alle
+	Events []string `json:"events,omitempty"`
+	// Projects beschränkt auf diese Projekte; leer heißt alle
+	Projects  []string  `json:"projects,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Matches ist true, wenn die Subscription aktiv ist und event durch beide
+// Filter kommt. Ereignisse ohne Projekt passen nur ohne Projektfilter.
+func (s *Subscription) Matches(event *WebhookEvent) bool {
+	if !s.Enabled {
+		return false
+	}
+	if len(s.Events) > 0 && !matchAny(s.Events, event.Type) {
+		return false
+	}
+	if len(s.Projects) > 0 && !contains(s.Projects, event.Project) {
+		return false
+	}
+	return true
+}
+
+// Masked liefert eine Kopie ohne Secret für Listen und Logs.
+func (s *Subscription) Masked() *Subscription {
+	c := *s
+	if c.Secret != "" {
+		c.Secret = "********"
+	}
+	return &c
+}
+
+// Validate prüft URL und Ereignismuster.
+func (s *Subscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q: need an absolute http(s) URL", s.URL)
+	}
+	for _, pattern := range s.Events {
+		if pattern == "" {
+			return errors.New("empty event pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid event pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, eventType string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrUnknownSubscription meldet eine unbekannte Subscription-ID.
+var ErrUnknownSubscription = errors.New("unknown webhook subscription")
+
+// Registry hält die Subscriptions in einer JSON-Datei. Die API schreibt sie,
+// der Daemon liest sie; ändert sich die Datei, lädt die Registry sie beim
+// nächsten Zugriff neu.
+type Registry struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	size    int64
+	subs    map[string]*Subscription
+}
+
+type registryFile struct {
+	Subscriptions []*Subscription `json:"subscriptions"`
+}
+
+// OpenRegistry lädt die Subscriptions aus path. Eine fehlende Datei ist eine
+// leere Registry.
+func OpenRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path, subs: make(map[string]*Subscription)}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload liest die Datei, wenn sie sich seit dem letzten Laden geändert hat.
+func (r *Registry) reload() error {
+	info, err := os.Stat(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		r.subs = make(map[string]*Subscription)
+		r.modTime, r.size = time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat webhook subscriptions: %w", err)
+	}
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read webhook subscriptions: %w", err)
+	}
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", r.path, err)
+	}
+	subs := make(map[string]*Subscription, len(file.Subscriptions))
+	for _, s := range file.Subscriptions {
+		subs[s.ID] = s
+	}
+	r.subs = subs
+	r.modTime, r.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// save schreibt alle Subscriptions über eine temporäre Datei, damit ein
+// Leser nie eine halbe Datei sieht. Die Datei enthält Secrets und ist nur
+// für den Besitzer lesbar.
+func (r *Registry) save() error {
+	data, err := json.MarshalIndent(registryFile{Subscriptions: r.sorted()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook subscriptions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create webhook dir: %w", err)
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write webhook subscriptions: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace webhook subscriptions: %w", err)
+	}
+	if info, err := os.Stat(r.path); err == nil {
+		r.modTime, r.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+func (r *Registry) sorted() []*Subscription {
+	list := make([]*Subscription, 0, len(r.subs))
+	for _, s := range r.subs {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// List liefert Kopien aller Subscriptions in Anlagereihenfolge.
+func (r *Registry) List() ([]*Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	var list []*Subscription
+	for _, s := range r.sorted() {
+		c := *s
+		list = append(list, &c)
+	}
+	return list, nil
+}
+
+// Get liefert eine Kopie der Subscription id.
+func (r *Registry) Get(id string) (*Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	s, ok := r.subs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSubscription, id)
+	}
+	c := *s
+	return &c, nil
+}
+
+// Add legt sub an. ID und ein fehlendes Secret werden erzeugt.
+func (r *Registry) Add(sub *Subscription) (*Subscription, error) {
+	if err := sub.Validate(); err != nil {
+		return nil, err
+	}
+	c := *sub
+	c.ID = "wh_" + uuid.NewString()[:8]
+	if c.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, err
+		}
+		c.Secret = secret
+	}
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	r.subs[c.ID] = &c
+	if err := r.save(); err != nil {
+		delete(r.subs, c.ID)
+		return nil, err
+	}
+	result := c
+	return &result, nil
+}
+
+// Update wendet fn auf die Subscription id an und speichert sie, wenn sie
+// danach noch gültig ist.
+func (r *Registry) Update(id string, fn func(*Subscription)) (*Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	old, ok := r.subs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSubscription, id)
+	}
+
+	c := *old
+	fn(&c)
+	c.ID, c.CreatedAt, c.UpdatedAt = old.ID, old.CreatedAt, time.Now()
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	r.subs[id] = &c
+	if err := r.save(); err != nil {
+		r.subs[id] = old
+		return nil, err
+	}
+	result := c
+	return &result, nil
+}
+
+// Remove löscht die Subscription id.
+func (r *Registry) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reload(); err != nil {
+		return err
+	}
+	old, ok := r.subs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSubscription, id)
+	}
+	delete(r.subs, id)
+	if err := r.save(); err != nil {
+		r.subs[id] = old
+		return err
+	}
+	return nil
+}
+
+// Matching liefert die aktiven Subscriptions, die event empfangen sollen.
+func (r *Registry) Matching(event *WebhookEvent) ([]*Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	var matched []*Subscription
+	for _, s := range r.sorted() {
+		if s.Matches(event) {
+			c := *s
+			matched = append(matched, &c)
+		}
+	}
+	return matched, nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
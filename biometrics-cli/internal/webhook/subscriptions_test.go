@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubscriptionMatches(t *testing.T) {
+	sub := &Subscription{Events: []string{"task.*", "plan.completed"}, Projects: []string{"alpha"}, Enabled: true}
+	tests := []struct {
+		event *WebhookEvent
+		want  bool
+	}{
+		{&WebhookEvent{Type: "task.started", Project: "alpha"}, true},
+		{&WebhookEvent{Type: "plan.completed", Project: "alpha"}, true},
+		{&WebhookEvent{Type: "task.started", Project: "beta"}, false},
+		{&WebhookEvent{Type: "agent.started"}, false},
+		{&WebhookEvent{Type: "plan.started", Project: "alpha"}, false},
+	}
+	for _, tt := range tests {
+		if got := sub.Matches(tt.event); got != tt.want {
+			t.Errorf("Matches(%s/%s) = %v, want %v", tt.event.Type, tt.event.Project, got, tt.want)
+		}
+	}
+
+	sub.Enabled = false
+	if sub.Matches(&WebhookEvent{Type: "task.started", Project: "alpha"}) {
+		t.Error("disabled subscription matched")
+	}
+}
+
+func TestRegistryPersistsAndValidates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	r, err := OpenRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Add(&Subscription{URL: "ftp://example.com"}); err == nil {
+		t.Error("Add accepted a non-HTTP URL")
+	}
+	if _, err := r.Add(&Subscription{URL: "https://example.com/hook", Events: []string{"task.["}}); err == nil {
+		t.Error("Add accepted a malformed event pattern")
+	}
+
+	sub, err := r.Add(&Subscription{URL: "https://example.com/hook", Events: []string{"task.*"}, Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ID == "" || len(sub.Secret) != 64 {
+		t.Errorf("Add = %+v, want a generated ID and secret", sub)
+	}
+
+	// Ein zweiter Prozess sieht Änderungen über die Datei
+	other, err := OpenRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := other.Get(sub.ID); err != nil || got.Secret != sub.Secret {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	if _, err := r.Update(sub.ID, func(s *Subscription) { s.Enabled = false }); err != nil {
+		t.Fatal(err)
+	}
+	if matched, _ := other.Matching(&WebhookEvent{Type: "task.started"}); len(matched) != 0 {
+		t.Errorf("Matching = %v after the subscription was disabled", matched)
+	}
+	if err := other.Remove(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(sub.ID); err == nil {
+		t.Error("removed subscription is still known")
+	}
+}
+
+func TestOutboxFansOutToMatchingSubscriptions(t *testing.T) {
+	tasks, agents := &receiver{}, &receiver{}
+	tasksSrv, agentsSrv := httptest.NewServer(tasks), httptest.NewServer(agents)
+	defer tasksSrv.Close()
+	defer agentsSrv.Close()
+
+	dir := t.TempDir()
+	registry, err := OpenRegistry(filepath.Join(dir, "webhooks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Add(&Subscription{URL: tasksSrv.URL, Events: []string{"task.*"}, Projects: []string{"alpha"}, Enabled: true})
+	registry.Add(&Subscription{URL: agentsSrv.URL, Events: []string{"agent.*"}, Enabled: true})
+
+	store, err := OpenSQLiteOutboxStore(filepath.Join(dir, "webhooks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	o := NewOutbox(store, registry, nil, nil)
+	SetDefaultOutbox(o)
+	defer SetDefaultOutbox(nil)
+
+	SendTaskStarted("alpha", "t1", "coder")
+	SendTaskStarted("beta", "t2", "coder")
+	SendAgentStarted("coder", "qwen")
+	if n, err := o.DeliverDue(context.Background()); n != 2 || err != nil {
+		t.Fatalf("DeliverDue = %d, %v; want 2 deliveries", n, err)
+	}
+	if len(tasks.bodies) != 1 || len(agents.bodies) != 1 {
+		t.Errorf("deliveries: tasks=%d agents=%d, want 1 each", len(tasks.bodies), len(agents.bodies))
+	}
+}
@@ -0,0 +1,247 @@
+// Package workspace resolves the directory that holds plans, boulder files,
+// logs and transcripts (historically ~/.sisyphus).
+//
+// The root is taken from, in order: the --workspace flag, the
+// BIOMETRICS_HOME environment variable, workspace.root in the biometrics
+// config file, and finally the XDG data directory. Packages never look the
+// root up themselves; binaries resolve it once and inject the Workspace.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvVar overrides the workspace root.
+	EnvVar = "BIOMETRICS_HOME"
+	// FlagName is the command line flag binaries register for the root.
+	FlagName = "workspace"
+)
+
+// Source records where the workspace root came from.
+type Source string
+
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceConfig  Source = "config"
+	SourceLegacy  Source = "legacy"
+	SourceDefault Source = "default"
+)
+
+// Workspace is a resolved workspace root.
+type Workspace struct {
+	Root   string
+	Source Source
+	// ConfigPath is the config file that was consulted, if any.
+	ConfigPath string
+}
+
+// New returns a workspace rooted at root, e.g. for tests.
+func New(root string) Workspace {
+	return Workspace{Root: root, Source: SourceFlag}
+}
+
+// PlansDir contains one directory per project with its boulder.json.
+func (w Workspace) PlansDir() string { return filepath.Join(w.Root, "plans") }
+
+// ProjectDir is the plan directory of a single project.
+func (w Workspace) ProjectDir(projectID string) string {
+	return filepath.Join(w.PlansDir(), projectID)
+}
+
+// BoulderPath is the global boulder.json used by the agent loop.
+func (w Workspace) BoulderPath() string { return filepath.Join(w.Root, "boulder.json") }
+
+// LogsDB is the SQLite database opened by state.InitDB.
+func (w Workspace) LogsDB() string { return filepath.Join(w.Root, "logs.db") }
+
+// PromptHistoryPath is read by the auto skill builder.
+func (w Workspace) PromptHistoryPath() string { return filepath.Join(w.Root, "prompt_history.json") }
+
+// SessionsPath stores the session manager's sessions.
+func (w Workspace) SessionsPath() string { return filepath.Join(w.Root, "sessions.json") }
+
+// TranscriptsDir holds persisted agent transcripts.
+func (w Workspace) TranscriptsDir() string { return filepath.Join(w.Root, "transcripts") }
+
+// QualityReportsDir holds the reports of the quality gate pipeline.
+func (w Workspace) QualityReportsDir() string { return filepath.Join(w.Root, "quality") }
+
+// ModelStatusPath holds the model pool snapshot served by /api/status.
+func (w Workspace) ModelStatusPath() string { return filepath.Join(w.Root, "model_status.json") }
+
+// ControlSocketPath is the Unix socket of the daemon's control API.
+func (w Workspace) ControlSocketPath() string { return filepath.Join(w.Root, "daemon.sock") }
+
+// JobsPath stores the scheduler's run history and job switches.
+func (w Workspace) JobsPath() string { return filepath.Join(w.Root, "jobs.json") }
+
+// BackupsDir holds the state backups written by the backup-state job.
+func (w Workspace) BackupsDir() string { return filepath.Join(w.Root, "backups") }
+
+// JournalDir holds the durable checkpoint journal of agent runs.
+func (w Workspace) JournalDir() string { return filepath.Join(w.Root, "journal") }
+
+// IdempotencyKeysPath holds per-phase overrides of the journal's idempotency keys.
+func (w Workspace) IdempotencyKeysPath() string { return filepath.Join(w.Root, "idempotency.json") }
+
+// APITokenPath holds the token the api-server requires on its admin routes.
+func (w Workspace) APITokenPath() string { return filepath.Join(w.Root, "api-token") }
+
+// SagasDir holds the persisted state of sagas.
+func (w Workspace) SagasDir() string { return filepath.Join(w.Root, "sagas") }
+
+// WebhooksDB is the SQLite database of the webhook outbox and its dead letters.
+func (w Workspace) WebhooksDB() string { return filepath.Join(w.Root, "webhooks.db") }
+
+// WebhookSubscriptionsPath holds the registered webhook endpoints.
+func (w Workspace) WebhookSubscriptionsPath() string { return filepath.Join(w.Root, "webhooks.json") }
+
+// DaemonLogPath is the daemon's rotating log file.
+func (w Workspace) DaemonLogPath() string { return filepath.Join(w.Root, "logs", "daemon.log") }
+
+// GeneratedSkillsPath holds the skills the auto skill builder generated.
+func (w Workspace) GeneratedSkillsPath() string { return filepath.Join(w.Root, "skills.json") }
+
+// HeartbeatsDir holds the last heartbeat of every agent worker.
+func (w Workspace) HeartbeatsDir() string { return filepath.Join(w.Root, "heartbeats") }
+
+// Path joins elem onto the workspace root.
+func (w Workspace) Path(elem ...string) string {
+	return filepath.Join(append([]string{w.Root}, elem...)...)
+}
+
+// String describes the root and where it came from, for diagnostics.
+func (w Workspace) String() string {
+	if w.Source == SourceConfig {
+		return fmt.Sprintf("%s (from %s: %s)", w.Root, w.Source, w.ConfigPath)
+	}
+	return fmt.Sprintf("%s (from %s)", w.Root, w.Source)
+}
+
+// Resolve determines the workspace root. flagValue is the value of the
+// --workspace flag and may be empty.
+func Resolve(flagValue string) (Workspace, error) {
+	if flagValue != "" {
+		return absolute(Workspace{Root: flagValue, Source: SourceFlag})
+	}
+
+	if env := os.Getenv(EnvVar); env != "" {
+		return absolute(Workspace{Root: env, Source: SourceEnv})
+	}
+
+	configPath := ConfigPath()
+	root, err := rootFromConfig(configPath)
+	if err != nil {
+		return Workspace{}, err
+	}
+	if root != "" {
+		return absolute(Workspace{Root: root, Source: SourceConfig, ConfigPath: configPath})
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Workspace{}, fmt.Errorf("cannot determine workspace root: %w", err)
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	xdg := filepath.Join(dataHome, "biometrics")
+
+	// Bestehende Installationen nutzen weiterhin ~/.sisyphus
+	legacy := filepath.Join(home, ".sisyphus")
+	if !exists(xdg) && exists(legacy) {
+		return Workspace{Root: legacy, Source: SourceLegacy}, nil
+	}
+
+	return Workspace{Root: xdg, Source: SourceDefault}, nil
+}
+
+// FromArgs extracts --workspace=<dir> or --workspace <dir> from args and
+// returns the remaining arguments, for binaries without a flag.FlagSet.
+func FromArgs(args []string) (string, []string) {
+	rest := make([]string, 0, len(args))
+	value := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--"+FlagName || arg == "-"+FlagName:
+			if i+1 < len(args) {
+				value = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--"+FlagName+"="):
+			value = strings.TrimPrefix(arg, "--"+FlagName+"=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return value, rest
+}
+
+// ConfigPath returns the biometrics config file, honouring
+// BIOMETRICS_CONFIG_PATH and BIOMETRICS_CONFIG_DIR like the CLI does.
+func ConfigPath() string {
+	if path := os.Getenv("BIOMETRICS_CONFIG_PATH"); path != "" {
+		return path
+	}
+	if dir := os.Getenv("BIOMETRICS_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "config.yaml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".biometrics", "config.yaml")
+	}
+	return filepath.Join(home, ".biometrics", "config.yaml")
+}
+
+func rootFromConfig(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	var cfg struct {
+		Workspace struct {
+			Root string `yaml:"root"`
+		} `yaml:"workspace"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return "", fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return os.ExpandEnv(cfg.Workspace.Root), nil
+}
+
+func absolute(w Workspace) (Workspace, error) {
+	root := w.Root
+	if strings.HasPrefix(root, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Workspace{}, err
+		}
+		root = filepath.Join(home, root[2:])
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return Workspace{}, err
+	}
+	w.Root = abs
+	return w, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,73 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func isolate(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv(EnvVar, "")
+	t.Setenv("BIOMETRICS_CONFIG_PATH", "")
+	t.Setenv("BIOMETRICS_CONFIG_DIR", "")
+	return home
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	home := isolate(t)
+
+	configPath := filepath.Join(home, ".biometrics", "config.yaml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.WriteFile(configPath, []byte("workspace:\n  root: /srv/from-config\n"), 0644)
+
+	ws, err := Resolve("")
+	if err != nil || ws.Source != SourceConfig || ws.Root != "/srv/from-config" {
+		t.Errorf("Expected config root, got %+v (%v)", ws, err)
+	}
+
+	t.Setenv(EnvVar, "/srv/from-env")
+	ws, _ = Resolve("")
+	if ws.Source != SourceEnv || ws.Root != "/srv/from-env" {
+		t.Errorf("Expected env root, got %+v", ws)
+	}
+
+	ws, _ = Resolve("/srv/from-flag")
+	if ws.Source != SourceFlag || ws.Root != "/srv/from-flag" {
+		t.Errorf("Expected flag root, got %+v", ws)
+	}
+}
+
+func TestResolveDefaults(t *testing.T) {
+	home := isolate(t)
+
+	ws, err := Resolve("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.Source != SourceDefault || ws.Root != filepath.Join(home, ".local", "share", "biometrics") {
+		t.Errorf("Expected XDG default, got %+v", ws)
+	}
+	if ws.PlansDir() != filepath.Join(ws.Root, "plans") {
+		t.Errorf("Unexpected plans dir %s", ws.PlansDir())
+	}
+
+	os.Mkdir(filepath.Join(home, ".sisyphus"), 0755)
+	ws, _ = Resolve("")
+	if ws.Source != SourceLegacy || ws.Root != filepath.Join(home, ".sisyphus") {
+		t.Errorf("Expected legacy ~/.sisyphus, got %+v", ws)
+	}
+}
+
+func TestFromArgs(t *testing.T) {
+	value, rest := FromArgs([]string{"--workspace", "/a", "plan", "--workspace=/b", "graph"})
+	if value != "/b" {
+		t.Errorf("Expected last flag to win, got %q", value)
+	}
+	if len(rest) != 2 || rest[0] != "plan" || rest[1] != "graph" {
+		t.Errorf("Unexpected remaining args %v", rest)
+	}
+}
@@ -210,6 +210,23 @@ func (r *RotatingWriter) cleanup() {
 	}
 }
 
+// Rotate archives the current log file and applies the retention policy,
+// regardless of its size.
+func (r *RotatingWriter) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return os.ErrInvalid
+	}
+	return r.rotate()
+}
+
+// Filename returns the path of the active log file.
+func (r *RotatingWriter) Filename() string {
+	return r.filename
+}
+
 // Close closes the rotating writer.
 func (r *RotatingWriter) Close() error {
 	r.mu.Lock()
@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"biometrics-cli/internal/collision"
+	appconfig "biometrics-cli/internal/config"
+	"biometrics-cli/internal/opencode"
 )
 
 // AgentModel represents an AI model configuration
@@ -35,7 +39,10 @@ type AgentSession struct {
 	Prompt      string    `json:"prompt"`
 	Result      string    `json:"result,omitempty"`
 	Error       string    `json:"error,omitempty"`
+	Transcript  string    `json:"transcript,omitempty"`
 	SicherCheck bool      `json:"sicher_check"`
+
+	lease *collision.Lease
 }
 
 // OrchestratorConfig holds the orchestrator configuration
@@ -43,19 +50,21 @@ type OrchestratorConfig struct {
 	ProjectRoot    string                `json:"project_root"`
 	AgentsPlanPath string                `json:"agents_plan_path"`
 	MaxParallel    int                   `json:"max_parallel"`
-	ModelLimits    map[string]int        `json:"model_limits"`
 	Models         map[string]AgentModel `json:"models"`
 	SessionTimeout time.Duration         `json:"session_timeout"`
 	PollInterval   time.Duration         `json:"poll_interval"`
 }
 
 // Orchestrator manages autonomous agent swarms
+//
+// Deprecated: task execution runs in internal/daemon, which is controlled
+// through `biometrics daemon`.
 type Orchestrator struct {
 	config         OrchestratorConfig
 	activeSessions map[string]*AgentSession
 	sessionMutex   sync.RWMutex
-	modelUsage     map[string]int // Track model usage
-	modelMutex     sync.RWMutex
+	pool           *collision.ModelPool
+	executor       *opencode.Executor
 	ctx            context.Context
 	cancel         context.CancelFunc
 }
@@ -66,11 +75,6 @@ func DefaultConfig(projectRoot string) OrchestratorConfig {
 		ProjectRoot:    projectRoot,
 		AgentsPlanPath: filepath.Join(projectRoot, "AGENTS-PLAN.md"),
 		MaxParallel:    3,
-		ModelLimits: map[string]int{
-			"qwen/qwen3.5-397b-a17b":     1,
-			"opencode/kimi-k2.5-free":    1,
-			"opencode/minimax-m2.5-free": 1,
-		},
 		Models: map[string]AgentModel{
 			"qwen": {
 				Name:        "Qwen 3.5 397B",
@@ -102,12 +106,17 @@ func DefaultConfig(projectRoot string) OrchestratorConfig {
 // NewOrchestrator creates a new orchestrator instance
 func NewOrchestrator(projectRoot string) (*Orchestrator, error) {
 	config := DefaultConfig(projectRoot)
+	pool, err := collision.LoadModelPool(appconfig.DefaultOpenCodeConfigPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load model pool: %w", err)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 
 	o := &Orchestrator{
 		config:         config,
 		activeSessions: make(map[string]*AgentSession),
-		modelUsage:     make(map[string]int),
+		pool:           pool,
+		executor:       opencode.NewExecutor(slog.Default()),
 		ctx:            ctx,
 		cancel:         cancel,
 	}
@@ -120,16 +129,14 @@ func NewOrchestrator(projectRoot string) (*Orchestrator, error) {
 	return o, nil
 }
 
-// Start begins the orchestrator loop
-func (o *Orchestrator) Start() error {
-	fmt.Println("🚀 Starting BIOMETRICS Orchestrator...")
-	fmt.Printf("📊 Max Parallel Agents: %d\n", o.config.MaxParallel)
-	fmt.Printf("⏱️  Session Timeout: %v\n", o.config.SessionTimeout)
-
-	// Start monitoring loop
-	go o.monitorLoop()
+// SetExecutor replaces the executor used to run agents
+func (o *Orchestrator) SetExecutor(executor *opencode.Executor) {
+	o.executor = executor
+}
 
-	return nil
+// SetModelPool replaces the pool that limits concurrent agents per model
+func (o *Orchestrator) SetModelPool(pool *collision.ModelPool) {
+	o.pool = pool
 }
 
 // Stop gracefully stops the orchestrator
@@ -149,9 +156,10 @@ func (o *Orchestrator) SpawnAgent(agentName, category, agentPrompt string) (*Age
 		return nil, fmt.Errorf("no available model for category: %s", category)
 	}
 
-	// Check if model is at capacity
-	if !o.canUseModel(modelKey) {
-		return nil, fmt.Errorf("model %s is at capacity", modelKey)
+	// Reserve model capacity; released when the agent finishes
+	lease, err := o.pool.TryAcquire(o.config.Models[modelKey].ModelID, 1)
+	if err != nil {
+		return nil, fmt.Errorf("model %s is at capacity: %w", modelKey, err)
 	}
 
 	// Create session
@@ -165,6 +173,7 @@ func (o *Orchestrator) SpawnAgent(agentName, category, agentPrompt string) (*Age
 		StartedAt:   time.Now(),
 		Prompt:      agentPrompt,
 		SicherCheck: false,
+		lease:       lease,
 	}
 
 	// Register session
@@ -172,11 +181,6 @@ func (o *Orchestrator) SpawnAgent(agentName, category, agentPrompt string) (*Age
 	o.activeSessions[session.ID] = session
 	o.sessionMutex.Unlock()
 
-	// Increment model usage
-	o.modelMutex.Lock()
-	o.modelUsage[modelKey]++
-	o.modelMutex.Unlock()
-
 	// Start agent in background
 	go o.executeAgent(session)
 
@@ -188,22 +192,26 @@ func (o *Orchestrator) executeAgent(session *AgentSession) {
 	session.Status = "running"
 	fmt.Printf("🤖 Agent %s (%s) started - Model: %s\n", session.AgentName, session.Category, session.Model)
 
-	// Build opencode command
-	cmd := exec.CommandContext(o.ctx, "opencode", session.Prompt)
-	cmd.Env = os.Environ()
-
-	// Execute
-	output, err := cmd.CombinedOutput()
-	if err != nil {
+	// Execute via the shared agent backend
+	result := o.executor.RunAgent(o.ctx, opencode.AgentRequest{
+		ProjectID: filepath.Base(o.config.ProjectRoot),
+		Model:     o.config.Models[session.Model].ModelID,
+		Prompt:    session.Prompt,
+		Category:  session.Category,
+		TaskID:    session.ID,
+	})
+	session.Transcript = result.TranscriptRef
+	if !result.Success {
 		session.Status = "failed"
-		session.Error = err.Error()
+		session.Error = result.Error.Error()
+		session.Result = result.Output
 		session.CompletedAt = time.Now()
-		fmt.Printf("❌ Agent %s failed: %v\n", session.AgentName, err)
-		o.decrementModelUsage(session.Model)
+		fmt.Printf("❌ Agent %s failed: %v\n", session.AgentName, result.Error)
+		session.lease.Release()
 		return
 	}
 
-	session.Result = string(output)
+	session.Result = result.Output
 	session.Status = "completed"
 	session.CompletedAt = time.Now()
 
@@ -211,7 +219,7 @@ func (o *Orchestrator) executeAgent(session *AgentSession) {
 	session.SicherCheck = o.performSicherCheck(session)
 
 	fmt.Printf("✅ Agent %s completed - Sicher Check: %v\n", session.AgentName, session.SicherCheck)
-	o.decrementModelUsage(session.Model)
+	session.lease.Release()
 }
 
 // performSicherCheck verifies agent work
@@ -227,55 +235,16 @@ func (o *Orchestrator) performSicherCheck(session *AgentSession) bool {
 	return true
 }
 
-// monitorLoop continuously monitors active sessions
-func (o *Orchestrator) monitorLoop() {
-	ticker := time.NewTicker(o.config.PollInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-o.ctx.Done():
-			return
-		case <-ticker.C:
-			o.checkSessions()
-		}
-	}
-}
-
-// checkSessions monitors all active sessions
-func (o *Orchestrator) checkSessions() {
-	o.sessionMutex.RLock()
-	defer o.sessionMutex.RUnlock()
-
-	for _, session := range o.activeSessions {
-		if session.Status == "running" {
-			// Check for timeout
-			if time.Since(session.StartedAt) > o.config.SessionTimeout {
-				fmt.Printf("⚠️  Session %s timed out\n", session.ID)
-				// TODO: Handle timeout
-			}
-
-			// Read session progress
-			o.readSessionProgress(session)
-		}
-	}
-}
-
-// readSessionProgress reads the session from opencode
-func (o *Orchestrator) readSessionProgress(session *AgentSession) {
-	// TODO: Implement session reading via opencode API
-	// This would call session_read(session_id) and update session status
-}
-
 // canUseModel checks if a model can be used
 func (o *Orchestrator) canUseModel(modelKey string) bool {
-	o.modelMutex.RLock()
-	defer o.modelMutex.RUnlock()
-
-	limit := o.config.ModelLimits[modelKey]
-	current := o.modelUsage[modelKey]
-
-	return current < limit
+	stat, err := o.pool.Stat(o.config.Models[modelKey].ModelID)
+	if err != nil {
+		return false
+	}
+	if stat.ProviderCapacity > 0 && stat.ProviderInUse >= stat.ProviderCapacity {
+		return false
+	}
+	return stat.InUse < stat.Capacity
 }
 
 // getModelForCategory returns the appropriate model for a category
@@ -292,14 +261,19 @@ func (o *Orchestrator) getModelForCategory(category string) string {
 	return ""
 }
 
-// decrementModelUsage decreases model usage count
-func (o *Orchestrator) decrementModelUsage(modelKey string) {
-	o.modelMutex.Lock()
-	defer o.modelMutex.Unlock()
-
-	if o.modelUsage[modelKey] > 0 {
-		o.modelUsage[modelKey]--
+// modelCapacity returns usage and limits per model key from the pool
+func (o *Orchestrator) modelCapacity() (usage, limits map[string]int) {
+	usage = make(map[string]int)
+	limits = make(map[string]int)
+	for key, model := range o.config.Models {
+		stat, err := o.pool.Stat(model.ModelID)
+		if err != nil {
+			continue
+		}
+		usage[key] = int(stat.InUse)
+		limits[key] = int(stat.Capacity)
 	}
+	return usage, limits
 }
 
 // waitForAllSessions waits for all sessions to complete
@@ -393,9 +367,6 @@ func (o *Orchestrator) GetStatus() map[string]interface{} {
 	o.sessionMutex.RLock()
 	defer o.sessionMutex.RUnlock()
 
-	o.modelMutex.RLock()
-	defer o.modelMutex.RUnlock()
-
 	activeCount := 0
 	completedCount := 0
 	failedCount := 0
@@ -411,11 +382,12 @@ func (o *Orchestrator) GetStatus() map[string]interface{} {
 		}
 	}
 
+	usage, limits := o.modelCapacity()
 	return map[string]interface{}{
 		"active_sessions":    activeCount,
 		"completed_sessions": completedCount,
 		"failed_sessions":    failedCount,
-		"model_usage":        o.modelUsage,
-		"model_limits":       o.config.ModelLimits,
+		"model_usage":        usage,
+		"model_limits":       limits,
 	}
 }
@@ -5,8 +5,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
+
+	"biometrics-cli/internal/config"
+	"biometrics-cli/internal/prompt"
 )
 
 // MassivePromptGenerator creates context-rich prompts for sub-agents
@@ -16,6 +20,7 @@ type MassivePromptGenerator struct {
 	requiredFiles []string
 	optionalFiles []string
 	contextCache  map[string]string
+	limitFor      func(modelID string) config.ModelLimit
 }
 
 // NewMassivePromptGenerator creates a new prompt generator
@@ -24,6 +29,7 @@ func NewMassivePromptGenerator(orchestrator *Orchestrator) *MassivePromptGenerat
 		projectRoot:  orchestrator.config.ProjectRoot,
 		orchestrator: orchestrator,
 		contextCache: make(map[string]string),
+		limitFor:     config.LookupModelLimit,
 		requiredFiles: []string{
 			"AGENTS-PLAN.md",
 			"ARCHITECTURE.md",
@@ -44,277 +50,202 @@ func NewMassivePromptGenerator(orchestrator *Orchestrator) *MassivePromptGenerat
 	}
 }
 
-// GenerateMassivePrompt creates a comprehensive prompt with ALL context
-func (g *MassivePromptGenerator) GenerateMassivePrompt(agentName, category, task string) (string, error) {
-	var sb strings.Builder
-
-	// Header with agent identity
-	sb.WriteString(g.generateHeader(agentName, category))
-
-	// Critical rules (NEVER break)
-	sb.WriteString(g.generateCriticalRules())
-
-	// Task description
-	sb.WriteString(g.generateTaskDescription(task))
-
-	// Required files to read FIRST
-	sb.WriteString(g.generateRequiredFilesSection())
-
-	// Project context
-	sb.WriteString(g.generateProjectContext())
-
-	// Other parallel agents
-	sb.WriteString(g.generateParallelAgentsInfo())
-
-	// Acceptance criteria
-	sb.WriteString(g.generateAcceptanceCriteria(task))
+// MassivePromptTemplate is loaded from <projectRoot>/templates/prompts, the
+// shared templates directory or the copy embedded in internal/prompt.
+const MassivePromptTemplate = "massive-agent"
 
-	// Output format requirements
-	sb.WriteString(g.generateOutputFormat())
-
-	// Warnings and common mistakes
-	sb.WriteString(g.generateWarnings())
-
-	prompt := sb.String()
-
-	// Save prompt to file for audit
-	g.savePrompt(agentName, prompt)
-
-	return prompt, nil
+type promptFile struct {
+	Name    string
+	Content string
 }
 
-func (g *MassivePromptGenerator) generateHeader(agentName, category string) string {
-	model := g.orchestrator.getModelForCategory(category)
-
-	return fmt.Sprintf(`# 🎯 ORCHESTRATOR → AGENT %s (%s)
-
-**Generated:** %s
-**Session:** Will be assigned on execution
-**Model:** %s
-**Category:** %s
-**Orchestrator:** Active monitoring enabled
-
----
-
-`, agentName, category, time.Now().Format("2006-01-02 15:04:05"), model, category)
+type promptModelUsage struct {
+	Model string
+	Used  int
+	Limit int
 }
 
-func (g *MassivePromptGenerator) generateCriticalRules() string {
-	return `## 🚨 KRITISCHE REGELN (NIEMALS BRECHEN!)
-
-### ❌ ABSOLUT VERBOTEN:
-1. **NIEMALS 2 Agents mit gleichem Modell parallel!**
-   - Qwen 3.5: MAX 1 Agent
-   - Kimi K2.5: MAX 1 Agent  
-   - MiniMax M2.5: MAX 1 Agent
-   - **MAXIMAL 3 Agents parallel (alle verschiedene Modelle!)**
-
-2. **NIEMALS Dateien erstellen ohne zu lesen!**
-   - IMMER zuerst glob() oder ls verwenden
-   - IMMER existierende Dateien KOMPLETT lesen (bis letzte Zeile!)
-   - NIEMALS Duplikate erstellen!
-
-3. **NIEMALS "fertig" sagen ohne Evidenz!**
-   - IMMER Dateiinhalt zeigen
-   - IMMER Tests durchführen
-   - IMMER "Sicher?"-Check bestehen
-   - IMMER Git Commit machen
-
-4. **NIEMALS User-Onboarding überspringen!**
-   - IMMER mit User zusammen Config erstellen
-   - IMMER API Keys erklären
-   - IMMER Tests gemeinsam durchführen
-
-### ✅ ABSOLUT PFLICHT:
-1. **IMMER Serena MCP nutzen** für Projekt-Kontext
-2. **IMMER ALLE Dateien lesen** bevor du arbeitest
-3. **IMMER bestehende erweitern** statt neu erstellen
-4. **IMMER "Sicher?"-Check** nach jeder Completion
-5. **IMMER Git Commit** nach jeder Änderung
-
----
-
-`
+// Priorities of the budgeted prompt sections. Sections with a lower
+// priority are truncated or dropped first when the prompt exceeds the
+// model's context window.
+const (
+	priorityRequiredFiles  = 100 // minus the position in requiredFiles
+	priorityParallelAgents = 50
+	priorityArchitecture   = 40
+	priorityChangelog      = 30
+)
+
+// budgetNoteReserve keeps room for the CONTEXT BUDGET note itself.
+const budgetNoteReserve = 256
+
+// promptContext holds the context sections after budgeting.
+type promptContext struct {
+	required       []promptFile
+	optional       []promptFile
+	architecture   string
+	changelog      string
+	parallelAgents bool
+	budget         prompt.BudgetReport
 }
 
-func (g *MassivePromptGenerator) generateTaskDescription(task string) string {
-	return fmt.Sprintf(`## 🎯 DEINE AUFGABE
+// GenerateMassivePrompt creates a comprehensive prompt with ALL context that
+// fits into the context window of the model serving category.
+func (g *MassivePromptGenerator) GenerateMassivePrompt(agentName, category, task string) (string, error) {
+	loader := prompt.NewLoader(filepath.Join(g.projectRoot, "templates", "prompts"), prompt.TemplatesDir())
+	tmpl, err := loader.Load(MassivePromptTemplate)
+	if err != nil {
+		return "", err
+	}
+	vars := g.templateVars(agentName, category, task)
+	render := func(pctx *promptContext) (string, error) {
+		return tmpl.Render(withContext(vars, pctx))
+	}
 
-%s
+	// Render once without context to measure the fixed parts, and once more
+	// with the agent status to get that section as the template lays it out
+	empty := g.emptyContext()
+	base, err := render(empty)
+	if err != nil {
+		return "", err
+	}
+	empty.parallelAgents = true
+	withAgents, err := render(empty)
+	if err != nil {
+		return "", err
+	}
+	limit := g.contextBudget(category) - prompt.EstimateTokens(base) - budgetNoteReserve
 
-**WICHTIG:** Diese Aufgabe ist Teil des 20-Task Infinity Loop.
-Nach deiner Completion werden sofort 5 neue Tasks generiert.
+	pctx := g.fitContext(limit, inserted(base, withAgents))
+	result, err := render(pctx)
+	if err != nil {
+		return "", err
+	}
+	if note := pctx.budget.Markdown(); note != "" {
+		result = strings.TrimRight(result, "\n") + "\n\n" + note
+	}
 
----
+	// Save prompt to file for audit
+	g.savePrompt(agentName, result)
 
-`, task)
+	return result, nil
 }
 
-func (g *MassivePromptGenerator) generateRequiredFilesSection() string {
-	var sb strings.Builder
-	sb.WriteString("## 📖 DATEIEN DIE DU ZUERST LESEN MUSST (PFLICHT!)\n\n")
-	sb.WriteString("### Zwingend erforderliche Dateien:\n\n")
+// contextBudget returns the input tokens available for the model that
+// serves category, based on its configured config.ModelLimit.
+func (g *MassivePromptGenerator) contextBudget(category string) int {
+	modelID := g.orchestrator.config.Models[g.orchestrator.getModelForCategory(category)].ModelID
+	limit := g.limitFor(modelID)
+	return limit.Context - limit.Output
+}
 
-	for i, file := range g.requiredFiles {
-		fullPath := filepath.Join(g.projectRoot, file)
-		content := g.readFileContent(fullPath)
-		if content != "" {
-			sb.WriteString(fmt.Sprintf("#### %d. %s\n", i+1, file))
-			sb.WriteString("```markdown\n")
-			sb.WriteString(content)
-			sb.WriteString("\n```\n\n")
+// emptyContext lists the existing files without their content.
+func (g *MassivePromptGenerator) emptyContext() *promptContext {
+	pctx := &promptContext{}
+	for _, file := range g.requiredFiles {
+		if g.readFileContent(filepath.Join(g.projectRoot, file)) != "" {
+			pctx.required = append(pctx.required, promptFile{Name: file})
 		}
 	}
-
-	sb.WriteString("### Optionale Dateien (falls relevant):\n\n")
-	for i, file := range g.optionalFiles {
-		fullPath := filepath.Join(g.projectRoot, file)
-		content := g.readFileContent(fullPath)
-		if content != "" {
-			sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, file))
+	for _, file := range g.optionalFiles {
+		if g.readFileContent(filepath.Join(g.projectRoot, file)) != "" {
+			pctx.optional = append(pctx.optional, promptFile{Name: file})
 		}
 	}
-
-	sb.WriteString("\n**WICHTIG:** Du MUSST ALLE oben genannten Dateien gelesen haben bevor du beginnst!\n\n---\n\n")
-
-	return sb.String()
+	return pctx
 }
 
-func (g *MassivePromptGenerator) generateProjectContext() string {
-	var sb strings.Builder
-	sb.WriteString("## 🏗️ PROJEKT-KONTEXT\n\n")
-
-	// Architecture overview
-	archPath := filepath.Join(g.projectRoot, "ARCHITECTURE.md")
-	if content := g.readFileContent(archPath); content != "" {
-		sb.WriteString("### Architektur-Übersicht\n\n")
-		sb.WriteString(content)
-		sb.WriteString("\n\n")
+// fitContext fills the context sections and shrinks them to limit tokens.
+// parallelAgents is the rendered agent status section.
+func (g *MassivePromptGenerator) fitContext(limit int, parallelAgents string) *promptContext {
+	pctx := g.emptyContext()
+
+	sections := make([]prompt.Section, 0, len(pctx.required)+3)
+	for i, file := range pctx.required {
+		sections = append(sections, prompt.Section{
+			Name:     file.Name,
+			Priority: priorityRequiredFiles - i,
+			Content:  g.readFileContent(filepath.Join(g.projectRoot, file.Name)),
+		})
 	}
-
-	// Recent changes
-	changelogPath := filepath.Join(g.projectRoot, "CHANGELOG.md")
-	if content := g.readFileContent(changelogPath); content != "" {
-		sb.WriteString("### Letzte Änderungen\n\n")
-		sb.WriteString(content)
-		sb.WriteString("\n\n")
+	sections = append(sections,
+		prompt.Section{Name: "parallel agents", Priority: priorityParallelAgents, Content: parallelAgents},
+		prompt.Section{Name: "project context: ARCHITECTURE.md", Priority: priorityArchitecture, Content: g.readFileContent(filepath.Join(g.projectRoot, "ARCHITECTURE.md"))},
+		prompt.Section{Name: "project context: CHANGELOG.md", Priority: priorityChangelog, Content: g.readFileContent(filepath.Join(g.projectRoot, "CHANGELOG.md"))},
+	)
+
+	fitted, report := prompt.FitSections(sections, limit)
+	pctx.budget = report
+
+	n := len(pctx.required)
+	for i := range pctx.required {
+		pctx.required[i].Content = fitted[i].Content
 	}
-
-	sb.WriteString("---\n\n")
-	return sb.String()
+	// A partial agent status is useless, so it is either complete or gone
+	pctx.parallelAgents = report.Entries[n].Action == prompt.ActionKept
+	pctx.architecture = fitted[n+1].Content
+	pctx.changelog = fitted[n+2].Content
+	return pctx
 }
 
-func (g *MassivePromptGenerator) generateParallelAgentsInfo() string {
+// templateVars collects the variables of the massive-agent template that do
+// not depend on the budgeted context.
+func (g *MassivePromptGenerator) templateVars(agentName, category, task string) map[string]any {
 	status := g.orchestrator.GetStatus()
-
-	var sb strings.Builder
-	sb.WriteString("## 📊 ANDERE AGENTS (PARALLEL AKTIV)\n\n")
-	sb.WriteString(fmt.Sprintf("**Aktive Sessions:** %v\n", status["active_sessions"]))
-	sb.WriteString(fmt.Sprintf("**Abgeschlossene Sessions:** %v\n", status["completed_sessions"]))
-	sb.WriteString("\n**Modell-Auslastung:**\n")
-
-	for model, usage := range status["model_usage"].(map[string]int) {
-		limit := g.orchestrator.config.ModelLimits[model]
-		sb.WriteString(fmt.Sprintf("- %s: %d/%d verwendet\n", model, usage, limit))
+	usage := status["model_usage"].(map[string]int)
+	limits := status["model_limits"].(map[string]int)
+	models := make([]string, 0, len(usage))
+	for model := range usage {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	modelUsage := make([]promptModelUsage, 0, len(models))
+	for _, model := range models {
+		modelUsage = append(modelUsage, promptModelUsage{
+			Model: model,
+			Used:  usage[model],
+			Limit: limits[model],
+		})
 	}
 
-	sb.WriteString("\n**WICHTIG:** Dein Code muss konsistent mit der Arbeit anderer Agents sein!\n\n---\n\n")
-
-	return sb.String()
-}
-
-func (g *MassivePromptGenerator) generateAcceptanceCriteria(task string) string {
-	return fmt.Sprintf(`## ✅ ACCEPTANCE CRITERIA
-
-Deine Aufgabe ist NUR dann abgeschlossen wenn ALLE folgenden Kriterien erfüllt sind:
-
-- [ ] Alle erforderlichen Dateien gelesen (siehe oben)
-- [ ] Aufgabe vollständig implementiert
-- [ ] Tests geschrieben und bestanden
-- [ ] Git Commit mit konventioneller Commit-Message
-- [ ] "Sicher?"-Check bestanden (alle 6 Checks)
-- [ ] Dokumentation aktualisiert (AGENTS-PLAN.md, MEETING.md)
-- [ ] Keine Duplikate erstellt
-- [ ] Keine LSP/vet Fehler
-- [ ] Konsistent mit existierendem Code
-
-**OHNE ALLE HAKEN = NICHT FERTIG!**
-
----
-
-`, task)
+	return map[string]any{
+		"AgentName":          agentName,
+		"Category":           category,
+		"Model":              g.orchestrator.getModelForCategory(category),
+		"Generated":          time.Now().Format("2006-01-02 15:04:05"),
+		"Task":               task,
+		"ActiveSessions":     status["active_sessions"],
+		"CompletedSessions":  status["completed_sessions"],
+		"ModelUsage":         modelUsage,
+		"AcceptanceCriteria": []string(nil),
+	}
 }
 
-func (g *MassivePromptGenerator) generateOutputFormat() string {
-	return `## 🚀 OUTPUT FORMAT
-
-Deine Antwort MUSS folgende Struktur haben:
-
-### 1. Gelesene Dateien
-Liste ALLE Dateien auf die du gelesen hast mit Zeilenzahlen:
-- AGENTS-PLAN.md (303 Zeilen) - KOMPLETT GELESEN ✅
-- ARCHITECTURE.md (XXX Zeilen) - KOMPLETT GELESEN ✅
-- ...
-
-### 2. Status der Aufgabe
-Beschreibe detailliert was du gemacht hast:
-- Welche Dateien erstellt/bearbeitet
-- Welche Tests geschrieben
-- Welche Commits gemacht
-
-### 3. "Sicher?"-Check
-Bestätige dass der Sicher?-Check bestanden wurde:
-- Files Created/Modified: ✅
-- Tests Pass: ✅
-- Git Commit: ✅
-- No Duplicates: ✅
-- LSP Diagnostics Clean: ✅
-- Agent Honesty: ✅
-
-### 4. Nächste 3 Schritte
-Was sind die nächsten Tasks im Infinity Loop?
-1. [Nächster Task]
-2. [Übernächster Task]
-3. [Dritter Task]
-
----
-
-`
+// withContext returns a copy of vars with the context sections of pctx.
+func withContext(vars map[string]any, pctx *promptContext) map[string]any {
+	data := make(map[string]any, len(vars)+5)
+	for k, v := range vars {
+		data[k] = v
+	}
+	data["RequiredFiles"] = pctx.required
+	data["OptionalFiles"] = pctx.optional
+	data["Architecture"] = pctx.architecture
+	data["Changelog"] = pctx.changelog
+	data["ParallelAgents"] = pctx.parallelAgents
+	return data
 }
 
-func (g *MassivePromptGenerator) generateWarnings() string {
-	return `## ⚠️ HÄUFIGE FEHLER + LÖSUNGEN
-
-### FEHLER 1: Duplikate erstellt
-**Lösung:** STOPP! Datei existiert bereits - erst lesen, dann erweitern!
-
-### FEHLER 2: Falsches Modell genutzt
-**Lösung:** Immer explizites Modell angeben in task()!
-
-### FEHLER 3: "Fertig" ohne Evidenz
-**Lösung:** "Sicher?"-Check - zeige alle Dateien, Tests, Commits!
-
-### FEHLER 4: Dateien nicht gelesen
-**Lösung:** IMMER zuerst lesen - ohne Ausnahme!
-
----
-
-## 🔥 INFINITY LOOP REMINDER
-
-**Nach deiner Completion:**
-1. Orchestrator führt Sicher?-Check durch
-2. Bei Bestehen: +5 neue Tasks werden generiert
-3. Git Commit wird gemacht
-4. Nächster Agent startet sofort
-
-**"Ein Task endet, fünf neue beginnen"**
-**"Kein Warten, nur Arbeiten"**
-**"Kein Fertig, nur Weiter"**
-
----
-
-**ENDE DES PROMPTS - JETZT ARBEITEN!**
-`
+// inserted returns the text that b adds to a, assuming b is a with a single
+// block inserted.
+func inserted(a, b string) string {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return b[prefix : len(b)-suffix]
 }
 
 func (g *MassivePromptGenerator) readFileContent(path string) string {
@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"biometrics-cli/internal/config"
+)
+
+func newTestGenerator(t *testing.T) *MassivePromptGenerator {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	// No shared templates directory: only the embedded default is left
+	t.Setenv("BIOMETRICS_TEMPLATES_DIR", t.TempDir())
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "README.md"), []byte("# Demo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOrchestrator(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(o.Stop)
+	return NewMassivePromptGenerator(o)
+}
+
+func TestGenerateMassivePromptUsesEmbeddedTemplate(t *testing.T) {
+	g := newTestGenerator(t)
+
+	got, err := g.GenerateMassivePrompt("sisyphus", "build", "Implement the parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# 🎯 ORCHESTRATOR → AGENT sisyphus (build)",
+		"Implement the parser",
+		"#### 1. README.md\n```markdown\n# Demo\n",
+		"## 📊 ANDERE AGENTS (PARALLEL AKTIV)",
+		"**ENDE DES PROMPTS - JETZT ARBEITEN!**",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerateMassivePromptUsesProjectTemplate(t *testing.T) {
+	g := newTestGenerator(t)
+	dir := filepath.Join(g.projectRoot, "templates", "prompts")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	override := "{{.AgentName}}: {{.Task}}{{if .ParallelAgents}} (parallel){{end}}"
+	if err := os.WriteFile(filepath.Join(dir, MassivePromptTemplate+".tmpl"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := g.GenerateMassivePrompt("sisyphus", "build", "Implement the parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "sisyphus: Implement the parser (parallel)" {
+		t.Errorf("prompt = %q", got)
+	}
+}
+
+func TestGenerateMassivePromptDropsAgentsOverBudget(t *testing.T) {
+	g := newTestGenerator(t)
+	g.limitFor = func(string) config.ModelLimit {
+		// Not even room for the fixed parts
+		return config.ModelLimit{Context: 1000, Output: 0}
+	}
+
+	got, err := g.GenerateMassivePrompt("sisyphus", "build", "Implement the parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "ANDERE AGENTS") {
+		t.Error("agent status should be dropped when it does not fit")
+	}
+	if !strings.Contains(got, "- parallel agents: dropped") || !strings.Contains(got, "- README.md: dropped") {
+		t.Errorf("budget note does not list the dropped sections:\n%s", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"biometrics-cli/internal/scheduler"
 	"gopkg.in/yaml.v3"
 )
 
@@ -30,6 +31,26 @@ type TriggerConfig struct {
 	Webhooks []string `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`
 }
 
+// Schedule parses Cron, which accepts the same syntax as scheduler jobs
+// including a CRON_TZ= prefix.
+func (t TriggerConfig) Schedule() (scheduler.Schedule, error) {
+	if t.Cron == "" {
+		return nil, fmt.Errorf("trigger has no cron expression")
+	}
+	return scheduler.Parse(t.Cron, time.Local)
+}
+
+// validate rejects workflows whose cron trigger cannot be evaluated.
+func (w *Workflow) validate() error {
+	if w.Trigger.Cron == "" {
+		return nil
+	}
+	if _, err := w.Trigger.Schedule(); err != nil {
+		return fmt.Errorf("workflow %s: invalid cron trigger: %w", w.Name, err)
+	}
+	return nil
+}
+
 // Step represents a single workflow step
 type Step struct {
 	ID        string            `yaml:"id" json:"id"`
@@ -151,6 +172,9 @@ func (e *WorkflowEngine) LoadWorkflow(data []byte) (*Workflow, error) {
 	if err := yaml.Unmarshal(data, &workflow); err != nil {
 		return nil, fmt.Errorf("failed to parse workflow: %w", err)
 	}
+	if err := workflow.validate(); err != nil {
+		return nil, err
+	}
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
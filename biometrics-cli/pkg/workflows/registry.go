@@ -78,5 +78,8 @@ func LoadWorkflow(data []byte) (*Workflow, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse workflow: %w", err)
 	}
+	if err := workflow.validate(); err != nil {
+		return nil, err
+	}
 	return &workflow, nil
 }